@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"todolist/internal/adapters"
+	"todolist/internal/pkg/export"
+	"todolist/internal/repository"
+)
+
+// exportData writes the personal data archive of a user, as
+// POST /api/v1/user/export does.
+func exportData(args []string) error {
+	flags, configFile := commandFlags("export")
+	ref := flags.String("user", "", "name or ID of the user")
+	out := flags.String("out", "-", "archive file to write, - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, db, err := connect(*configFile)
+	if err != nil {
+		return err
+	}
+	userRepo := repository.NewUserRepositoryAdapter(db)
+	accountUseCase := adapters.NewAccountAdapter(repository.NewAccountRepositoryAdapter(db), userRepo, repository.NewTransactor(db), cfg.DeletionGracePeriod)
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	target, err := findUser(ctx, userRepo, *ref)
+	if err != nil {
+		return err
+	}
+	data, err := accountUseCase.ExportData(ctx, target.ID)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return errors.Wrap(err, "failed to create archive file")
+		}
+		defer f.Close()
+		w = f
+	}
+	if err = export.WriteArchive(w, data, time.Now().UTC()); err != nil {
+		return err
+	}
+	if *out != "-" {
+		fmt.Fprintf(os.Stderr, "exported user %s to %s\n", target.Name, *out)
+	}
+	return nil
+}
+
+// importData adds the tasks and categories of an archive to a user.
+func importData(args []string) error {
+	flags, configFile := commandFlags("import")
+	ref := flags.String("user", "", "name or ID of the user")
+	in := flags.String("in", "", "archive file written by export")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *in == "" {
+		return errors.New("--in is required")
+	}
+
+	cfg, db, err := connect(*configFile)
+	if err != nil {
+		return err
+	}
+	userRepo := repository.NewUserRepositoryAdapter(db)
+	accountUseCase := adapters.NewAccountAdapter(repository.NewAccountRepositoryAdapter(db), userRepo, repository.NewTransactor(db), cfg.DeletionGracePeriod)
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	target, err := findUser(ctx, userRepo, *ref)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(*in)
+	if err != nil {
+		return errors.Wrap(err, "failed to open archive file")
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return errors.Wrap(err, "failed to read archive file")
+	}
+	data, err := export.ReadArchive(f, info.Size())
+	if err != nil {
+		return err
+	}
+
+	result, err := accountUseCase.ImportData(ctx, target.ID, data)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %d tasks and %d new categories into user %s\n", result.Tasks, result.Categories, target.Name)
+	return nil
+}
+
+// purgeTrash deletes the accounts whose deletion grace period is over, the
+// same as the background job of serve.
+func purgeTrash(args []string) error {
+	flags, configFile := commandFlags("purge-trash")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, db, err := connect(*configFile)
+	if err != nil {
+		return err
+	}
+	accountUseCase := adapters.NewAccountAdapter(
+		repository.NewAccountRepositoryAdapter(db),
+		repository.NewUserRepositoryAdapter(db),
+		repository.NewTransactor(db),
+		cfg.DeletionGracePeriod,
+	)
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	deleted, err := accountUseCase.PurgeDueDeletions(ctx)
+	fmt.Printf("deleted %d accounts\n", deleted)
+	return err
+}
@@ -2,27 +2,20 @@ package main
 
 import (
 	"context"
-	"errors"
-	"log"
-	"net/http"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
-	"time"
 
-	zlog "github.com/rs/zerolog/log"
-
-	"github.com/avast/retry-go"
-	"github.com/go-chi/chi/v5"
-	"gorm.io/driver/postgres"
+	"github.com/pkg/errors"
 	"gorm.io/gorm"
 
 	"todolist/config"
 	_ "todolist/docs"
-	"todolist/internal/api/handlers"
-
-	"github.com/rs/zerolog"
-	httpSwagger "github.com/swaggo/http-swagger"
+	"todolist/internal/database"
+	"todolist/internal/logging"
 )
 
 // @title Plan&Do API
@@ -36,67 +29,88 @@ import (
 // @in header
 // @name Authorization
 
-func main() {
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
 
-	cfg, err := config.BuildConfig()
-	if err != nil {
-		log.Panic("failed parse config: ", err)
-	}
+var commands = []command{
+	{"serve", "serve [--migrate] [--print-config]", serve},
+	{"migrate", "migrate up|down|status", migrate},
+	{"user", "user create|disable|reset-password", user},
+	{"export", "export --user NAME|ID [--out FILE]", exportData},
+	{"import", "import --user NAME|ID --in FILE", importData},
+	{"purge-trash", "purge-trash", purgeTrash},
+}
 
-	db, err := connectWithRetry(cfg.PostgresConfig.String())
-	if err != nil {
-		log.Panic("Could not connect to DB after retries: ", err)
+// main runs the command named by the first argument; without one, or with
+// only flags, it serves the API as before the commands existed.
+func main() {
+	args := os.Args[1:]
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		args = append([]string{"serve"}, args...)
 	}
-	zerolog.SetGlobalLevel(zerolog.TraceLevel)
-	r := chi.NewRouter()
-	r.Get("/swagger/*", httpSwagger.WrapHandler)
-
-	handlersBuilder := handlers.NewHandlers(&cfg.ServiceConfig, db, r)
-	handlersBuilder.InitHandlers()
 
-	srv := &http.Server{
-		Addr:    "0.0.0.0:8080",
-		Handler: r,
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			if err := cmd.run(args[1:]); err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
-	go func() {
-		zlog.Trace().Msg("starting server")
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Panic("failed to start server")
-		}
-	}()
+	usage()
+	if args[0] != "help" {
+		os.Exit(2)
+	}
+}
 
-	<-done
-	zlog.Trace().Msg("stopping server")
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: main <command> [--config FILE] [flags]")
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintln(os.Stderr, "  "+cmd.usage)
+	}
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// commandFlags returns a flag set with the --config flag every command takes.
+func commandFlags(name string) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file, env variables override it")
+	return flags, configFile
+}
 
-	if err := srv.Shutdown(ctx); err != nil {
-		zlog.Error().Msg("failed to stop server")
-		return
+// connect loads the config and opens the database for the admin commands.
+func connect(configFile string) (*config.Config, *gorm.DB, error) {
+	cfg, err := config.BuildConfig(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = logging.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		return nil, nil, err
 	}
+	if cfg.Storage == config.StorageMemory {
+		return nil, nil, errors.Errorf("this command needs SERVICE_STORAGE=%s or %s", config.StoragePostgres, config.StorageSQLite)
+	}
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, db, nil
+}
 
-	zlog.Trace().Msg("server stopped")
+// openDatabase opens the SQL database of the configured storage.
+func openDatabase(cfg *config.Config) (*gorm.DB, error) {
+	if cfg.Storage == config.StorageSQLite {
+		return database.OpenSQLite(cfg.SQLiteConfig)
+	}
+	return database.Open(cfg.PostgresConfig)
 }
 
-func connectWithRetry(dsn string) (*gorm.DB, error) {
-	var db *gorm.DB
-	err := retry.Do(
-		func() error {
-			var err error
-			db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-			if err != nil {
-				log.Printf("DB connection failed: %v. Retrying...", err)
-			}
-			return err
-		},
-		retry.Attempts(5),
-		retry.Delay(1*time.Second),
-		retry.MaxDelay(10*time.Second),
-		retry.DelayType(retry.BackOffDelay),
-	)
-	return db, err
+// commandContext is cancelled by SIGINT or SIGTERM.
+func commandContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 }
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"todolist/internal/migrations"
+)
+
+func migrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up|down|status")
+	}
+	action := args[0]
+
+	flags, configFile := commandFlags("migrate " + action)
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	_, db, err := connect(*configFile)
+	if err != nil {
+		return err
+	}
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	switch action {
+	case "up":
+		return migrateUp(ctx, migrator)
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			fmt.Println("no migrations to revert")
+			return nil
+		}
+		fmt.Printf("reverted %04d_%s\n", reverted.Version, reverted.Name)
+		return nil
+	case "status":
+		states, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, state := range states {
+			appliedAt := "pending"
+			if state.AppliedAt != nil {
+				appliedAt = state.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", state.Version, state.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return errors.Errorf("unknown migrate action %q, expected up, down or status", action)
+	}
+}
+
+func migrateUp(ctx context.Context, migrator *migrations.Migrator) error {
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Println("schema is up to date")
+	}
+	return nil
+}
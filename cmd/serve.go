@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"todolist/config"
+	"todolist/internal/adapters"
+	"todolist/internal/api/handlers"
+	"todolist/internal/health"
+	"todolist/internal/jobs"
+	"todolist/internal/logging"
+	"todolist/internal/metrics"
+	"todolist/internal/migrations"
+	"todolist/internal/repository"
+	"todolist/internal/repository/memory"
+	"todolist/internal/server"
+	"todolist/internal/tracing"
+
+	"github.com/rs/zerolog/log"
+	httpSwagger "github.com/swaggo/http-swagger"
+)
+
+// serve runs the HTTP API until SIGINT or SIGTERM.
+func serve(args []string) error {
+	flags, configFile := commandFlags("serve")
+	printConfig := flags.Bool("print-config", false, "print the effective config with secrets redacted and exit")
+	migrate := flags.Bool("migrate", false, "apply pending migrations before serving")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	done := make(chan os.Signal, 1)
+	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+
+	cfg, err := config.BuildConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	if *printConfig {
+		return cfg.Print(os.Stdout)
+	}
+
+	if err = logging.Setup(cfg.LogLevel, cfg.LogFormat); err != nil {
+		return err
+	}
+	for _, setting := range cfg.InsecureSettings() {
+		log.Warn().Str("environment", cfg.Environment).Msg("insecure setting: " + setting)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.TracingConfig.Exporter,
+		ServiceName: cfg.TracingConfig.ServiceName,
+		SampleRatio: cfg.SampleRatio,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to set up tracing")
+	}
+
+	repos, db, healthChecker, err := openStorage(cfg, *migrate)
+	if err != nil {
+		return err
+	}
+
+	r := chi.NewRouter()
+	r.Use(
+		logging.RequestID,
+		tracing.HTTPMiddleware,
+		logging.RequestLogger,
+		metrics.HTTPMiddleware,
+		server.CORS(cfg.CORSAllowedOrigins, cfg.CORSAllowedMethods),
+		server.MaxBodySize(cfg.MaxBodyBytes),
+	)
+	r.Get("/swagger/*", httpSwagger.WrapHandler)
+	r.Handle("/metrics", metrics.Handler())
+
+	r.Get("/healthz", handlers.Liveness())
+	r.Get("/readyz", handlers.Readiness(healthChecker, cfg.ReadinessTimeout))
+
+	handlersBuilder := handlers.NewHandlers(cfg, repos, db, r)
+	handlersBuilder.InitHandlers()
+
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	accountUseCase := adapters.NewAccountAdapter(repos.Accounts, repos.Users, repos.Transactor, cfg.DeletionGracePeriod)
+	go jobs.RunDeletionPurge(jobsCtx, accountUseCase, cfg.DeletionPurgeInterval)
+
+	srv, err := server.New(r, server.Options{
+		Addr:              cfg.ServerConfig.Addr,
+		TLSCertFile:       cfg.TLSCertFile,
+		TLSKeyFile:        cfg.TLSKeyFile,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		log.Info().Str("addr", srv.Addr()).Bool("tls", srv.TLS()).Msg("starting server")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Panic().Err(err).Msg("failed to start server")
+		}
+	}()
+
+	<-done
+	log.Info().Msg("stopping server")
+	stopJobs()
+
+	healthChecker.StartDraining()
+	time.Sleep(cfg.ShutdownDrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownGrace)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		return errors.Wrap(err, "failed to stop server")
+	}
+
+	// Flush the spans of the last requests.
+	if err := shutdownTracing(ctx); err != nil {
+		log.Error().Err(err).Msg("failed to stop tracing")
+	}
+
+	log.Info().Msg("server stopped")
+	return nil
+}
+
+// openStorage returns the repositories for the configured storage. With
+// postgres or sqlite it connects, instruments the pool and checks the
+// schema; memory storage has no db and nothing to migrate.
+func openStorage(cfg *config.Config, migrate bool) (*adapters.Repositories, *gorm.DB, *health.Checker, error) {
+	if cfg.Storage == config.StorageMemory {
+		log.Warn().Msg("memory storage: all data is lost when the server stops")
+		return memoryRepositories(), nil, health.NewChecker(nil, nil), nil
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if migrate {
+		if err = migrateUp(context.Background(), migrator); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	if err = db.Use(tracing.GormPlugin{}); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to register db tracing")
+	}
+	if err = db.Use(metrics.GormPlugin{}); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to register db metrics")
+	}
+	dbName := cfg.PostgresConfig.DbName
+	if cfg.Storage == config.StorageSQLite {
+		dbName = cfg.SQLiteConfig.Path
+	}
+	if err = metrics.RegisterDBStats(db, dbName); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to register db pool metrics")
+	}
+
+	return sqlRepositories(db), db, health.NewChecker(db, migrator), nil
+}
+
+// sqlRepositories returns every repository on top of db.
+func sqlRepositories(db *gorm.DB) *adapters.Repositories {
+	return &adapters.Repositories{
+		Tasks:      repository.NewGormTaskRepository(db),
+		Categories: repository.NewCategoryRepositoryAdapter(db),
+		Users:      repository.NewUserRepositoryAdapter(db),
+		Identities: repository.NewIdentityRepositoryAdapter(db),
+		Admin:      repository.NewAdminRepositoryAdapter(db),
+		Accounts:   repository.NewAccountRepositoryAdapter(db),
+		Stats:      repository.NewStatsRepositoryAdapter(db),
+		Workflow:   repository.NewWorkflowRepositoryAdapter(db),
+		Transactor: repository.NewTransactor(db),
+	}
+}
+
+// memoryRepositories returns every repository on top of one fresh store.
+func memoryRepositories() *adapters.Repositories {
+	store := memory.NewStore()
+	return &adapters.Repositories{
+		Tasks:      memory.NewTaskRepository(store),
+		Categories: memory.NewCategoryRepository(store),
+		Users:      memory.NewUserRepository(store),
+		Identities: memory.NewIdentityRepository(store),
+		Admin:      memory.NewAdminRepository(store),
+		Accounts:   memory.NewAccountRepository(store),
+		Stats:      memory.NewStatsRepository(store),
+		Workflow:   memory.NewWorkflowRepository(store),
+		Transactor: memory.NewTransactor(store),
+	}
+}
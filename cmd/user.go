@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"todolist/internal/adapters"
+	"todolist/internal/models"
+	auth_utils "todolist/internal/pkg/authUtils"
+	"todolist/internal/repository"
+)
+
+func user(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: user create|disable|reset-password")
+	}
+	action := args[0]
+
+	flags, configFile := commandFlags("user " + action)
+	ref := flags.String("user", "", "name or ID of the user")
+	name := flags.String("name", "", "name of the new user")
+	password := flags.String("password", "", "password, read from stdin when empty")
+	role := flags.String("role", models.RoleUser, "role of the new user: user or admin")
+	temporary := flags.Bool("temporary", false, "require the user to change the reset password at the next sign-in")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	cfg, db, err := connect(*configFile)
+	if err != nil {
+		return err
+	}
+	userRepo := repository.NewUserRepositoryAdapter(db)
+	userUseCase := adapters.NewAuthService(userRepo, repository.NewTransactor(db), auth_utils.NewJWTTokenHandler(), cfg.JWTSecret)
+	adminUseCase := adapters.NewAdminAdapter(repository.NewAdminRepositoryAdapter(db))
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	switch action {
+	case "create":
+		secret, err := readPassword(*password)
+		if err != nil {
+			return err
+		}
+		// An invalid role must not leave a user behind with the default one.
+		var created *models.User
+		err = userUseCase.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := userUseCase.SignUp(ctx, &models.UserAuth{Name: *name, Password: secret}); err != nil {
+				return err
+			}
+			var err error
+			if created, err = findUser(ctx, userRepo, *name); err != nil {
+				return err
+			}
+			if *role == models.RoleUser {
+				return nil
+			}
+			return adminUseCase.SetRole(ctx, created.ID, *role)
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created user %s with id %s and role %s\n", created.Name, created.ID, *role)
+		return nil
+	case "disable":
+		target, err := findUser(ctx, userRepo, *ref)
+		if err != nil {
+			return err
+		}
+		if err = adminUseCase.DisableUser(ctx, target.ID); err != nil {
+			return err
+		}
+		fmt.Printf("disabled user %s\n", target.Name)
+		return nil
+	case "reset-password":
+		target, err := findUser(ctx, userRepo, *ref)
+		if err != nil {
+			return err
+		}
+		secret, err := readPassword(*password)
+		if err != nil {
+			return err
+		}
+		err = userUseCase.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := userUseCase.ResetPassword(ctx, target.ID, secret); err != nil {
+				return err
+			}
+			if !*temporary {
+				return nil
+			}
+			return adminUseCase.ForcePasswordReset(ctx, target.ID)
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reset password of user %s\n", target.Name)
+		return nil
+	default:
+		return errors.Errorf("unknown user action %q, expected create, disable or reset-password", action)
+	}
+}
+
+// findUser looks the user up by ID or, failing that, by name.
+func findUser(ctx context.Context, repo adapters.IUserRepository, ref string) (*models.User, error) {
+	if ref == "" {
+		return nil, errors.New("--user is required")
+	}
+	if id, err := uuid.Parse(ref); err == nil {
+		return repo.GetUserByID(ctx, id)
+	}
+	return repo.GetUserByName(ctx, ref)
+}
+
+// readPassword returns the flag value or else the first line of stdin, so
+// passwords can stay out of the shell history.
+func readPassword(flagValue string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	fmt.Fprint(os.Stderr, "password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.Wrap(err, "failed to read password from stdin")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
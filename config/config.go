@@ -2,40 +2,186 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
+	"todolist/internal/ratelimit"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/pkg/errors"
 )
 
 type Config struct {
-	PostgresConfig `envPrefix:"POSTGRES_"`
-	ServiceConfig  `envPrefix:"SERVICE_"`
+	PostgresConfig  `envPrefix:"POSTGRES_"`
+	SQLiteConfig    `envPrefix:"SQLITE_"`
+	ServiceConfig   `envPrefix:"SERVICE_"`
+	OIDCConfig      `envPrefix:"OIDC_"`
+	TracingConfig   `envPrefix:"TRACING_"`
+	RateLimitConfig `envPrefix:"RATE_LIMIT_"`
+	ServerConfig    `envPrefix:"SERVER_"`
+}
+
+// ServerConfig is the HTTP server setup. Setting both TLS files switches the
+// server to HTTPS; CORS is off until origins are listed.
+type ServerConfig struct {
+	Addr        string `env:"ADDR" envDefault:"0.0.0.0:8080"`
+	TLSCertFile string `env:"TLS_CERT_FILE"`
+	TLSKeyFile  string `env:"TLS_KEY_FILE"`
+
+	ReadHeaderTimeout time.Duration `env:"READ_HEADER_TIMEOUT" envDefault:"5s"`
+	ReadTimeout       time.Duration `env:"READ_TIMEOUT" envDefault:"30s"`
+	WriteTimeout      time.Duration `env:"WRITE_TIMEOUT" envDefault:"90s"`
+	IdleTimeout       time.Duration `env:"IDLE_TIMEOUT" envDefault:"120s"`
+	MaxBodyBytes      int64         `env:"MAX_BODY_BYTES" envDefault:"1048576"`
+
+	CORSAllowedOrigins []string `env:"CORS_ALLOWED_ORIGINS"`
+	CORSAllowedMethods []string `env:"CORS_ALLOWED_METHODS" envDefault:"GET,POST,PUT,PATCH,DELETE,OPTIONS"`
+
+	// ShutdownGrace is how long in-flight requests get to finish on shutdown.
+	ShutdownGrace time.Duration `env:"SHUTDOWN_GRACE" envDefault:"10s"`
 }
 
 type ServiceConfig struct {
+	// Environment is development or production; production refuses to start
+	// with InsecureSettings.
+	Environment string `env:"ENVIRONMENT" envDefault:"development"`
+	// Storage is postgres, sqlite or memory. SQLite keeps everything in one
+	// file for single-user and embedded deployments; memory needs no
+	// database and loses all data on restart, which suits demos only.
+	Storage     string        `env:"STORAGE" envDefault:"postgres"`
 	TaskTimeout time.Duration `env:"TASK_TIMEOUT" envDefault:"1m"`
-	JWTSecret   string        `env:"JWT_SECRET" envDefault:"secret"`
+	JWTSecret   string        `env:"JWT_SECRET" envDefault:"secret" secret:"true"`
+
+	// LogLevel is a zerolog level name, LogFormat is json or console.
+	LogLevel  string `env:"LOG_LEVEL" envDefault:"info"`
+	LogFormat string `env:"LOG_FORMAT" envDefault:"json"`
+
+	// DeletionGracePeriod is how long a deleted account can still be restored.
+	DeletionGracePeriod   time.Duration `env:"DELETION_GRACE_PERIOD" envDefault:"720h"`
+	DeletionPurgeInterval time.Duration `env:"DELETION_PURGE_INTERVAL" envDefault:"1h"`
+
+	// ReadinessTimeout bounds the dependency checks of /readyz.
+	ReadinessTimeout time.Duration `env:"READINESS_TIMEOUT" envDefault:"2s"`
+	// ShutdownDrainDelay is how long /readyz reports 503 before the server
+	// stops accepting connections, so load balancers can take it out.
+	ShutdownDrainDelay time.Duration `env:"SHUTDOWN_DRAIN_DELAY" envDefault:"5s"`
 }
 
 type PostgresConfig struct {
-	Host     string `env:"HOST,required"`
-	Port     int    `env:"PORT,required"`
-	User     string `env:"USER,required"`
-	Password string `env:"PASSWORD,required"`
-	DbName   string `env:"DB_NAME,required"`
+	// Required with postgres storage, see validate.
+	Host     string `env:"HOST"`
+	Port     int    `env:"PORT"`
+	User     string `env:"USER"`
+	Password string `env:"PASSWORD" secret:"true"`
+	DbName   string `env:"DB_NAME"`
+
+	SSLMode         string `env:"SSLMODE" envDefault:"disable"`
+	ApplicationName string `env:"APPLICATION_NAME" envDefault:"todolist"`
+	// StatementTimeout makes Postgres cancel longer statements, zero disables it.
+	StatementTimeout time.Duration `env:"STATEMENT_TIMEOUT" envDefault:"30s"`
+
+	MaxOpenConns    int           `env:"MAX_OPEN_CONNS" envDefault:"25"`
+	MaxIdleConns    int           `env:"MAX_IDLE_CONNS" envDefault:"10"`
+	ConnMaxLifetime time.Duration `env:"CONN_MAX_LIFETIME" envDefault:"30m"`
+	ConnMaxIdleTime time.Duration `env:"CONN_MAX_IDLE_TIME" envDefault:"5m"`
+
+	// ReplicaHost enables a read replica for list queries, reached with the
+	// same credentials and options as the primary.
+	ReplicaHost string `env:"REPLICA_HOST"`
+	ReplicaPort int    `env:"REPLICA_PORT" envDefault:"5432"`
+}
+
+// SQLiteConfig is the database file of the sqlite storage. BusyTimeout is
+// how long a write waits for another one to finish before failing.
+type SQLiteConfig struct {
+	Path        string        `env:"PATH" envDefault:"todolist.db"`
+	BusyTimeout time.Duration `env:"BUSY_TIMEOUT" envDefault:"5s"`
+}
+
+type OIDCConfig struct {
+	Enabled      bool          `env:"ENABLED" envDefault:"false"`
+	IssuerURL    string        `env:"ISSUER_URL"`
+	ClientID     string        `env:"CLIENT_ID"`
+	ClientSecret string        `env:"CLIENT_SECRET" secret:"true"`
+	RedirectURL  string        `env:"REDIRECT_URL"`
+	Scopes       []string      `env:"SCOPES" envDefault:"openid,profile,email"`
+	StateTTL     time.Duration `env:"STATE_TTL" envDefault:"10m"`
+}
+
+// TracingConfig selects the span exporter: none, stdout or otlp. The OTLP
+// endpoint is read from the standard OTEL_EXPORTER_OTLP_ENDPOINT variable.
+type TracingConfig struct {
+	Exporter    string  `env:"EXPORTER" envDefault:"none"`
+	ServiceName string  `env:"SERVICE_NAME" envDefault:"todolist"`
+	SampleRatio float64 `env:"SAMPLE_RATIO" envDefault:"1"`
+}
+
+// RateLimitConfig sets the token buckets per route group as name:requests/period.
+// Backend is memory for a single instance or postgres to share the buckets
+// between replicas.
+type RateLimitConfig struct {
+	Enabled bool              `env:"ENABLED" envDefault:"true"`
+	Backend string            `env:"BACKEND" envDefault:"memory"`
+	Routes  map[string]string `env:"ROUTES" envDefault:"sign-in:10/1m,sign-up:5/1h,task-list:60/1m,default:300/1m"`
+	// TrustForwardedFor keys anonymous clients by X-Forwarded-For instead of
+	// the peer address; enable only behind a proxy that sets the header.
+	TrustForwardedFor bool `env:"TRUST_FORWARDED_FOR" envDefault:"false"`
+
+	// Limits holds the parsed Routes.
+	Limits map[string]ratelimit.Limit
 }
 
 func (pc PostgresConfig) String() string {
-	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d", pc.Host, pc.User, pc.Password, pc.DbName, pc.Port)
+	return pc.dsn(pc.Host, pc.Port)
+}
+
+// ReplicaString is the DSN of the read replica.
+func (pc PostgresConfig) ReplicaString() string {
+	return pc.dsn(pc.ReplicaHost, pc.ReplicaPort)
+}
+
+func (pc PostgresConfig) dsn(host string, port int) string {
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=%s application_name=%s statement_timeout=%d",
+		quoteDSN(host), quoteDSN(pc.User), quoteDSN(pc.Password), quoteDSN(pc.DbName), port,
+		quoteDSN(pc.SSLMode), quoteDSN(pc.ApplicationName), pc.StatementTimeout.Milliseconds())
 }
 
-func BuildConfig() (*Config, error) {
+// quoteDSN quotes a keyword/value DSN value, so passwords with spaces or
+// quotes survive.
+func quoteDSN(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// BuildConfig layers the settings: defaults, then the config file if a path
+// is given, then env variables. Secrets can also come from NAME_FILE.
+func BuildConfig(configFile string) (*Config, error) {
+	vars := make(map[string]string)
+	if configFile != "" {
+		fileVars, err := readFile(configFile)
+		if err != nil {
+			return nil, err
+		}
+		if err = resolveSecretFiles(fileVars); err != nil {
+			return nil, errors.Wrap(err, "failed to read secrets of config file")
+		}
+		vars = fileVars
+	}
+
+	envVars := environ()
+	if err := resolveSecretFiles(envVars); err != nil {
+		return nil, errors.Wrap(err, "failed to read secrets from env")
+	}
+	for name, value := range envVars {
+		vars[name] = value
+	}
+
 	cfg := Config{}
-	err := env.Parse(&cfg)
+	err := env.ParseWithOptions(&cfg, env.Options{Environment: vars})
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build cfg from env")
 	}
 
+	if err = cfg.validate(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
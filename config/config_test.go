@@ -0,0 +1,221 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresConfig_String(t *testing.T) {
+	pc := PostgresConfig{
+		Host:             "db",
+		Port:             5432,
+		User:             "todo",
+		Password:         `it's a \ secret`,
+		DbName:           "todolist",
+		SSLMode:          "require",
+		ApplicationName:  "todolist api",
+		StatementTimeout: 15 * time.Second,
+		ReplicaHost:      "replica",
+		ReplicaPort:      6432,
+	}
+
+	parsed, err := pgconn.ParseConfig(pc.String())
+	require.NoError(t, err)
+	assert.Equal(t, "db", parsed.Host)
+	assert.Equal(t, uint16(5432), parsed.Port)
+	assert.Equal(t, `it's a \ secret`, parsed.Password)
+	assert.NotNil(t, parsed.TLSConfig, "sslmode=require enables TLS")
+	assert.Equal(t, "todolist api", parsed.RuntimeParams["application_name"])
+	assert.Equal(t, "15000", parsed.RuntimeParams["statement_timeout"])
+
+	replica, err := pgconn.ParseConfig(pc.ReplicaString())
+	require.NoError(t, err)
+	assert.Equal(t, "replica", replica.Host)
+	assert.Equal(t, uint16(6432), replica.Port)
+	assert.Equal(t, parsed.Password, replica.Password)
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// setRequiredEnv sets the settings without defaults, except those a test
+// wants to take from elsewhere.
+func setRequiredEnv(t *testing.T, skip ...string) {
+	t.Helper()
+	required := map[string]string{
+		"POSTGRES_HOST":     "db",
+		"POSTGRES_PORT":     "5432",
+		"POSTGRES_USER":     "todo",
+		"POSTGRES_PASSWORD": "password",
+		"POSTGRES_DB_NAME":  "todolist",
+	}
+	for name, value := range required {
+		if !slices.Contains(skip, name) {
+			t.Setenv(name, value)
+		}
+	}
+}
+
+func TestBuildConfig_YAMLWithEnvOverride(t *testing.T) {
+	setRequiredEnv(t, "POSTGRES_HOST")
+	t.Setenv("SERVER_ADDR", "127.0.0.1:9090")
+	path := writeFile(t, "config.yaml", `
+postgres:
+  host: db.internal
+  max_open_conns: 40
+server:
+  addr: 0.0.0.0:8080
+  cors_allowed_origins:
+    - https://a.example.com
+    - https://b.example.com
+rate_limit:
+  routes:
+    sign-in: 3/1m
+`)
+
+	cfg, err := BuildConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, "db.internal", cfg.PostgresConfig.Host)
+	assert.Equal(t, 40, cfg.MaxOpenConns)
+	assert.Equal(t, "127.0.0.1:9090", cfg.ServerConfig.Addr, "env overrides the file")
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, cfg.CORSAllowedOrigins)
+	assert.Equal(t, map[string]string{"sign-in": "3/1m"}, cfg.Routes)
+	assert.Equal(t, 30*time.Second, cfg.StatementTimeout, "defaults fill the rest")
+}
+
+func TestBuildConfig_TOML(t *testing.T) {
+	setRequiredEnv(t)
+	path := writeFile(t, "config.toml", `
+[service]
+task_timeout = "30s"
+log_format = "console"
+`)
+
+	cfg, err := BuildConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cfg.TaskTimeout)
+	assert.Equal(t, "console", cfg.LogFormat)
+}
+
+func TestBuildConfig_UnknownFileSetting(t *testing.T) {
+	setRequiredEnv(t)
+	path := writeFile(t, "config.yaml", "postgres:\n  hots: db\n")
+
+	_, err := BuildConfig(path)
+	assert.ErrorContains(t, err, "postgres.hots")
+}
+
+func TestBuildConfig_SecretFiles(t *testing.T) {
+	setRequiredEnv(t, "POSTGRES_PASSWORD")
+	t.Setenv("POSTGRES_PASSWORD_FILE", writeFile(t, "db_password", "from-file\n"))
+
+	cfg, err := BuildConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", cfg.Password, "trailing newline is dropped")
+
+	t.Setenv("POSTGRES_PASSWORD", "from-env")
+	_, err = BuildConfig("")
+	assert.Error(t, err, "a secret set both directly and from a file is ambiguous")
+
+}
+
+func TestBuildConfig_TLSFilesAreNotSecretFiles(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("SERVER_TLS_CERT_FILE", "/etc/tls/tls.crt")
+	t.Setenv("SERVER_TLS_KEY_FILE", "/etc/tls/tls.key")
+
+	cfg, err := BuildConfig("")
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/tls/tls.crt", cfg.TLSCertFile, "settings named *_FILE are plain values")
+}
+
+func TestBuildConfig_Production(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("SERVICE_ENVIRONMENT", EnvironmentProduction)
+
+	_, err := BuildConfig("")
+	assert.ErrorContains(t, err, "SERVICE_JWT_SECRET")
+
+	t.Setenv("SERVICE_JWT_SECRET", strings.Repeat("k", minJWTSecretLength))
+	_, err = BuildConfig("")
+	assert.ErrorContains(t, err, "POSTGRES_SSLMODE disable", "the default sslmode is plain text")
+
+	for _, mode := range []string{"allow", "prefer"} {
+		t.Setenv("POSTGRES_SSLMODE", mode)
+		_, err = BuildConfig("")
+		assert.ErrorContains(t, err, "POSTGRES_SSLMODE "+mode)
+	}
+
+	t.Setenv("POSTGRES_SSLMODE", "verify-full")
+	cfg, err := BuildConfig("")
+	require.NoError(t, err)
+	assert.Empty(t, cfg.InsecureSettings())
+
+	t.Setenv("SERVER_CORS_ALLOWED_ORIGINS", "*")
+	_, err = BuildConfig("")
+	assert.ErrorContains(t, err, "SERVER_CORS_ALLOWED_ORIGINS")
+}
+
+func TestBuildConfig_Storage(t *testing.T) {
+	setRequiredEnv(t, "POSTGRES_HOST", "POSTGRES_PASSWORD")
+
+	_, err := BuildConfig("")
+	assert.ErrorContains(t, err, "POSTGRES_HOST, POSTGRES_PASSWORD required with postgres storage")
+
+	t.Setenv("SERVICE_STORAGE", StorageMemory)
+	_, err = BuildConfig("")
+	assert.NoError(t, err, "memory storage needs no database")
+
+	t.Setenv("RATE_LIMIT_BACKEND", "postgres")
+	_, err = BuildConfig("")
+	assert.ErrorContains(t, err, "RATE_LIMIT_BACKEND")
+
+	t.Setenv("SERVICE_STORAGE", StorageSQLite)
+	_, err = BuildConfig("")
+	assert.ErrorContains(t, err, "RATE_LIMIT_BACKEND", "sqlite storage has no shared buckets either")
+
+	t.Setenv("RATE_LIMIT_BACKEND", "memory")
+	cfg, err := BuildConfig("")
+	require.NoError(t, err, "sqlite storage needs no server")
+	assert.Equal(t, "todolist.db", cfg.SQLiteConfig.Path)
+
+	t.Setenv("SERVICE_STORAGE", StorageMemory)
+	t.Setenv("SERVICE_ENVIRONMENT", EnvironmentProduction)
+	t.Setenv("SERVICE_JWT_SECRET", strings.Repeat("k", minJWTSecretLength))
+	_, err = BuildConfig("")
+	assert.ErrorContains(t, err, "not allowed in production")
+}
+
+func TestConfig_Print(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("SERVICE_JWT_SECRET", "jwt-secret-value")
+
+	cfg, err := BuildConfig("")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, cfg.Print(&buf))
+	out := buf.String()
+	assert.NotContains(t, out, "jwt-secret-value")
+	assert.NotContains(t, out, "password: password")
+	assert.Contains(t, out, "jwt_secret: '[REDACTED]'")
+	assert.Contains(t, out, "host: db")
+
+	// The output is a valid config file.
+	path := writeFile(t, "printed.yaml", out)
+	_, err = readFile(path)
+	assert.NoError(t, err)
+}
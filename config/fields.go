@@ -0,0 +1,50 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// field is a config setting as seen by env: its full variable name and the
+// file section and key it maps to.
+type field struct {
+	name    string
+	section string
+	key     string
+	secret  bool
+	value   reflect.Value
+}
+
+// fields lists every setting of cfg in declaration order. Settings are
+// marked secret with a `secret:"true"` tag: they can be read from a *_FILE
+// variable and are redacted when printed.
+func fields(cfg *Config) []field {
+	var result []field
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		prefix := root.Type().Field(i).Tag.Get("envPrefix")
+		section := strings.ToLower(strings.TrimSuffix(prefix, "_"))
+		result = append(result, sectionFields(root.Field(i), prefix, section)...)
+	}
+	return result
+}
+
+func sectionFields(v reflect.Value, prefix, section string) []field {
+	var result []field
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		tag, ok := sf.Tag.Lookup("env")
+		if !ok {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		result = append(result, field{
+			name:    prefix + name,
+			section: section,
+			key:     strings.ToLower(name),
+			secret:  sf.Tag.Get("secret") == "true",
+			value:   v.Field(i),
+		})
+	}
+	return result
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// fileSuffix marks a variable holding the path of a file with the value of a
+// secret, as Docker secrets are mounted.
+const fileSuffix = "_FILE"
+
+// readFile loads a YAML or TOML config file into env variables. Sections and
+// keys are the lower-cased variable names, e.g. POSTGRES_HOST is host under
+// postgres. Lists may be written as lists, maps as maps.
+func readFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config file")
+	}
+
+	var doc map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &doc)
+	case ".toml":
+		err = toml.Unmarshal(content, &doc)
+	default:
+		return nil, errors.Errorf("unsupported config file %s, expected .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse config file %s", path)
+	}
+
+	known := make(map[string]field)
+	for _, f := range fields(&Config{}) {
+		known[f.name] = f
+		if f.secret {
+			known[f.name+fileSuffix] = f
+		}
+	}
+
+	vars := make(map[string]string)
+	if err = flatten(doc, "", known, vars); err != nil {
+		return nil, errors.Wrapf(err, "invalid config file %s", path)
+	}
+	return vars, nil
+}
+
+func flatten(doc map[string]interface{}, prefix string, known map[string]field, vars map[string]string) error {
+	for key, value := range doc {
+		name := prefix + strings.ToUpper(key)
+
+		f, isSetting := known[name]
+		if nested, ok := value.(map[string]interface{}); ok && !(isSetting && f.value.Kind() == reflect.Map) {
+			if err := flatten(nested, name+"_", known, vars); err != nil {
+				return err
+			}
+			continue
+		}
+		if !isSetting {
+			return errors.Errorf("unknown setting %s", strings.ToLower(strings.ReplaceAll(name, "_", ".")))
+		}
+		vars[name] = formatFileValue(value)
+	}
+	return nil
+}
+
+// formatFileValue writes a file value the way env expects it in a variable.
+func formatFileValue(value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ",")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(v))
+		for _, key := range keys {
+			items = append(items, key+":"+fmt.Sprint(v[key]))
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// resolveSecretFiles replaces NAME_FILE variables of secret settings with
+// NAME holding the file content. Setting both is an error, since it is not
+// clear which one was meant.
+func resolveSecretFiles(vars map[string]string) error {
+	for _, f := range fields(&Config{}) {
+		if !f.secret {
+			continue
+		}
+		path, ok := vars[f.name+fileSuffix]
+		if !ok {
+			continue
+		}
+		if _, ok = vars[f.name]; ok {
+			return errors.Errorf("both %s and %s%s are set", f.name, f.name, fileSuffix)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s%s", f.name, fileSuffix)
+		}
+		vars[f.name] = strings.TrimRight(string(content), "\r\n")
+		delete(vars, f.name+fileSuffix)
+	}
+	return nil
+}
+
+func environ() map[string]string {
+	vars := make(map[string]string)
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		vars[key] = value
+	}
+	return vars
+}
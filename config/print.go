@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Print writes the effective config as YAML in the config file layout, with
+// secrets redacted, so the output can be used as a starting config file.
+func (c *Config) Print(w io.Writer) error {
+	doc := make(map[string]map[string]interface{})
+	for _, f := range fields(c) {
+		if doc[f.section] == nil {
+			doc[f.section] = make(map[string]interface{})
+		}
+		doc[f.section][f.key] = printValue(f)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return errors.Wrap(err, "failed to print config")
+	}
+	return errors.Wrap(encoder.Close(), "failed to print config")
+}
+
+func printValue(f field) interface{} {
+	if f.secret {
+		if f.value.IsZero() {
+			return ""
+		}
+		return redacted
+	}
+
+	switch v := f.value.Interface().(type) {
+	case time.Duration:
+		return v.String()
+	case []string:
+		if v == nil {
+			return []string{}
+		}
+		return v
+	}
+
+	if f.value.Kind() == reflect.Map {
+		keys := f.value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		m := make(map[string]string, len(keys))
+		for _, key := range keys {
+			m[key.String()] = fmt.Sprint(f.value.MapIndex(key).Interface())
+		}
+		return m
+	}
+	return f.value.Interface()
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"todolist/internal/ratelimit"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	EnvironmentDevelopment = "development"
+	EnvironmentProduction  = "production"
+)
+
+const (
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+	StorageMemory   = "memory"
+)
+
+const (
+	defaultJWTSecret   = "secret"
+	minJWTSecretLength = 32
+)
+
+// validate checks settings that depend on each other and parses the rate
+// limits. In production it also refuses the insecure settings.
+func (c *Config) validate() error {
+	switch c.Environment {
+	case EnvironmentDevelopment, EnvironmentProduction:
+	default:
+		return errors.Errorf("unknown SERVICE_ENVIRONMENT %q, expected %s or %s", c.Environment, EnvironmentDevelopment, EnvironmentProduction)
+	}
+
+	switch c.Storage {
+	case StoragePostgres:
+		if err := c.PostgresConfig.validate(); err != nil {
+			return err
+		}
+	case StorageSQLite:
+		if c.SQLiteConfig.Path == "" {
+			return errors.New("SQLITE_PATH required with sqlite storage")
+		}
+	case StorageMemory:
+		if c.Environment == EnvironmentProduction {
+			return errors.New("SERVICE_STORAGE=memory loses all data on restart and is not allowed in production")
+		}
+	default:
+		return errors.Errorf("unknown SERVICE_STORAGE %q, expected %s, %s or %s", c.Storage, StoragePostgres, StorageSQLite, StorageMemory)
+	}
+	if c.Storage != StoragePostgres && c.RateLimitConfig.Backend == "postgres" {
+		return errors.New("RATE_LIMIT_BACKEND=postgres needs SERVICE_STORAGE=postgres")
+	}
+
+	if c.OIDCConfig.Enabled && (c.IssuerURL == "" || c.ClientID == "" || c.RedirectURL == "") {
+		return errors.New("OIDC_ISSUER_URL, OIDC_CLIENT_ID and OIDC_REDIRECT_URL are required when OIDC is enabled")
+	}
+
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE must be set together")
+	}
+
+	if c.RateLimitConfig.Backend != "memory" && c.RateLimitConfig.Backend != "postgres" {
+		return errors.Errorf("unknown RATE_LIMIT_BACKEND %q, expected memory or postgres", c.RateLimitConfig.Backend)
+	}
+	c.RateLimitConfig.Limits = make(map[string]ratelimit.Limit, len(c.RateLimitConfig.Routes))
+	for name, value := range c.RateLimitConfig.Routes {
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid RATE_LIMIT_ROUTES entry %s", name)
+		}
+		c.RateLimitConfig.Limits[name] = limit
+	}
+
+	if insecure := c.InsecureSettings(); c.Environment == EnvironmentProduction && len(insecure) > 0 {
+		return errors.Errorf("refusing to start in production with insecure settings: %s", strings.Join(insecure, "; "))
+	}
+	return nil
+}
+
+// validate checks that the connection settings without defaults are set.
+func (pc PostgresConfig) validate() error {
+	var missing []string
+	for _, setting := range []struct {
+		name  string
+		unset bool
+	}{
+		{"POSTGRES_HOST", pc.Host == ""},
+		{"POSTGRES_PORT", pc.Port == 0},
+		{"POSTGRES_USER", pc.User == ""},
+		{"POSTGRES_PASSWORD", pc.Password == ""},
+		{"POSTGRES_DB_NAME", pc.DbName == ""},
+	} {
+		if setting.unset {
+			missing = append(missing, setting.name)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("%s required with postgres storage", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// InsecureSettings lists the settings that are fine for local development
+// but must not reach production.
+func (c *Config) InsecureSettings() []string {
+	var insecure []string
+	if c.JWTSecret == defaultJWTSecret || len(c.JWTSecret) < minJWTSecretLength {
+		insecure = append(insecure, "SERVICE_JWT_SECRET is the default or shorter than 32 bytes")
+	}
+	for _, origin := range c.CORSAllowedOrigins {
+		if origin == "*" {
+			insecure = append(insecure, "SERVER_CORS_ALLOWED_ORIGINS allows any origin")
+		}
+	}
+	if c.OIDCConfig.Enabled && !strings.HasPrefix(c.IssuerURL, "https://") {
+		insecure = append(insecure, "OIDC_ISSUER_URL is not https")
+	}
+	// These modes fall back to, or start with, a plain-text connection.
+	if c.Storage == StoragePostgres && slices.Contains([]string{"disable", "allow", "prefer"}, c.SSLMode) {
+		insecure = append(insecure, "POSTGRES_SSLMODE "+c.SSLMode+" does not require TLS")
+	}
+	return insecure
+}
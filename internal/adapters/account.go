@@ -0,0 +1,187 @@
+package adapters
+
+import (
+	"context"
+	"time"
+	"todolist/internal/metrics"
+	"todolist/internal/models"
+	"todolist/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type IAccountRepository interface {
+	GetAccountData(ctx context.Context, userID uuid.UUID) (*models.AccountData, error)
+	ScheduleDeletion(ctx context.Context, userID uuid.UUID, at time.Time) error
+	CancelDeletion(ctx context.Context, userID uuid.UUID) error
+	ListDueDeletions(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+	DeleteIfDue(ctx context.Context, userID uuid.UUID, now time.Time) (bool, error)
+	ImportData(ctx context.Context, userID uuid.UUID, data *models.AccountData) (*models.ImportResult, error)
+}
+
+// AccountAdapter serves the personal data export and the deferred account
+// deletion: a deletion request only schedules it, the account is removed by
+// PurgeDueDeletions once the grace period is over.
+type AccountAdapter struct {
+	models.Transactor
+	accountRepo IAccountRepository
+	userRepo    IUserRepository
+	gracePeriod time.Duration
+	now         func() time.Time
+}
+
+func NewAccountAdapter(accountRepo IAccountRepository, userRepo IUserRepository, transactor models.Transactor, gracePeriod time.Duration) *AccountAdapter {
+	return &AccountAdapter{
+		Transactor:  transactor,
+		accountRepo: accountRepo,
+		userRepo:    userRepo,
+		gracePeriod: gracePeriod,
+		now:         time.Now,
+	}
+}
+
+// ExportData returns everything stored about the user except credentials.
+func (a *AccountAdapter) ExportData(ctx context.Context, userID uuid.UUID) (*models.AccountData, error) {
+	ctx, span := tracing.Start(ctx, "AccountAdapter.ExportData")
+	defer span.End()
+
+	data, err := a.accountRepo.GetAccountData(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to collect data of user with id %v", userID)
+	}
+	data.User.Password = ""
+	data.User.TOTPSecret = ""
+
+	recordActivity(ctx, a.userRepo, userID, models.ActivityDataExported)
+	return data, nil
+}
+
+// ImportData adds the tasks and categories of an export to the user. Nothing
+// is added when any of them is invalid.
+func (a *AccountAdapter) ImportData(ctx context.Context, userID uuid.UUID, data *models.AccountData) (*models.ImportResult, error) {
+	ctx, span := tracing.Start(ctx, "AccountAdapter.ImportData")
+	defer span.End()
+
+	for _, cat := range data.Categories {
+		if cat.Name == "" {
+			return nil, errors.Errorf("Category %s has no name", cat.ID)
+		}
+	}
+	for _, task := range data.Tasks {
+		if task.Title == "" {
+			return nil, errors.Errorf("Task %s has no title", task.ID)
+		}
+	}
+	statuses := make([]models.StatusBody, 0, len(data.Statuses))
+	for _, status := range data.Statuses {
+		statuses = append(statuses, models.StatusBody{Name: status.Name})
+	}
+	if err := models.ValidateStatuses(statuses); err != nil {
+		return nil, errors.Wrap(err, "Archived workflow")
+	}
+
+	var result *models.ImportResult
+	err := a.WithinTransaction(ctx, func(ctx context.Context) error {
+		if _, err := a.userRepo.GetUserByID(ctx, userID); err != nil {
+			return errors.Wrapf(err, "Failed to get user with id %v", userID)
+		}
+
+		var err error
+		result, err = a.accountRepo.ImportData(ctx, userID, data)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to import data of user with id %v", userID)
+		}
+		tasks := float64(result.Tasks)
+		a.AfterCommit(ctx, func() { metrics.TasksCreated.Add(tasks) })
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordActivity(ctx, a.userRepo, userID, models.ActivityDataImported)
+	return result, nil
+}
+
+// ScheduleDeletion returns the moment the account will be deleted. Repeated
+// requests keep the date set by the first one.
+func (a *AccountAdapter) ScheduleDeletion(ctx context.Context, userID uuid.UUID) (time.Time, error) {
+	ctx, span := tracing.Start(ctx, "AccountAdapter.ScheduleDeletion")
+	defer span.End()
+
+	var at time.Time
+	scheduled := false
+	err := a.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := a.userRepo.GetUserByID(ctx, userID)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get user with id %v", userID)
+		}
+		if user.DeletionScheduledAt != nil {
+			at = *user.DeletionScheduledAt
+			return nil
+		}
+
+		at = a.now().Add(a.gracePeriod).UTC()
+		err = a.accountRepo.ScheduleDeletion(ctx, userID, at)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to schedule deletion of user with id %v", userID)
+		}
+		scheduled = true
+		return nil
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !scheduled {
+		return at, nil
+	}
+
+	recordActivity(ctx, a.userRepo, userID, models.ActivityDeletionScheduled)
+	return at, nil
+}
+
+func (a *AccountAdapter) CancelDeletion(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "AccountAdapter.CancelDeletion")
+	defer span.End()
+
+	err := a.accountRepo.CancelDeletion(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to cancel deletion of user with id %v", userID)
+	}
+
+	recordActivity(ctx, a.userRepo, userID, models.ActivityDeletionCancelled)
+	return nil
+}
+
+// PurgeDueDeletions deletes the accounts whose grace period is over and
+// returns how many were deleted. An account whose deletion was cancelled
+// after the listing is skipped. A failure for one account does not stop the
+// others; the first error is returned and the account is retried next run.
+func (a *AccountAdapter) PurgeDueDeletions(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "AccountAdapter.PurgeDueDeletions")
+	defer span.End()
+
+	now := a.now()
+	ids, err := a.accountRepo.ListDueDeletions(ctx, now)
+	if err != nil {
+		return 0, errors.Wrap(err, "Failed to list scheduled deletions")
+	}
+
+	var firstErr error
+	deleted := 0
+	for _, id := range ids {
+		isDeleted, err := a.accountRepo.DeleteIfDue(ctx, id, now)
+		if err == nil && !isDeleted {
+			continue
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "Failed to delete user with id %v", id)
+			}
+			continue
+		}
+		deleted++
+	}
+	return deleted, firstErr
+}
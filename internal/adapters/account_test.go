@@ -0,0 +1,218 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+	"time"
+	mock_adapters "todolist/internal/adapters/mocks"
+	"todolist/internal/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAccountAdapter(ctrl *gomock.Controller, now time.Time) (*AccountAdapter, *mock_adapters.MockIAccountRepository, *mock_adapters.MockIUserRepository) {
+	accountRepo := mock_adapters.NewMockIAccountRepository(ctrl)
+	userRepo := mock_adapters.NewMockIUserRepository(ctrl)
+	adapter := NewAccountAdapter(accountRepo, userRepo, inlineTransactor(ctrl), 72*time.Hour)
+	adapter.now = func() time.Time { return now }
+	return adapter, accountRepo, userRepo
+}
+
+func TestAccountAdapter_ExportData(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	adapter, accountRepo, userRepo := newTestAccountAdapter(ctrl, time.Now())
+	userID := uuid.New()
+
+	gomock.InOrder(
+		accountRepo.EXPECT().
+			GetAccountData(gomock.Any(), userID).
+			Return(&models.AccountData{
+				User:  models.User{ID: userID, Name: "testuser", Password: "hash", TOTPSecret: "secret"},
+				Tasks: []models.TaskFullInfo{{ID: uuid.New(), Title: "task"}},
+			}, nil),
+		userRepo.EXPECT().
+			RecordActivity(gomock.Any(), userID, models.ActivityDataExported).
+			Return(errors.New("db is down")),
+	)
+
+	data, err := adapter.ExportData(context.Background(), userID)
+	require.NoError(t, err, "a failed activity record does not fail the export")
+	assert.Empty(t, data.User.Password)
+	assert.Empty(t, data.User.TOTPSecret)
+	assert.Len(t, data.Tasks, 1)
+}
+
+func TestAccountAdapter_ScheduleDeletion(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	alreadyScheduled := now.Add(24 * time.Hour)
+	userID := uuid.New()
+
+	tests := []struct {
+		name          string
+		mockSetup     func(accountRepo *mock_adapters.MockIAccountRepository, userRepo *mock_adapters.MockIUserRepository)
+		expectedAt    time.Time
+		expectedError error
+	}{
+		{
+			name: "schedules after grace period",
+			mockSetup: func(accountRepo *mock_adapters.MockIAccountRepository, userRepo *mock_adapters.MockIUserRepository) {
+				gomock.InOrder(
+					userRepo.EXPECT().GetUserByID(gomock.Any(), userID).Return(&models.User{ID: userID}, nil),
+					accountRepo.EXPECT().ScheduleDeletion(gomock.Any(), userID, now.Add(72*time.Hour)).Return(nil),
+					userRepo.EXPECT().RecordActivity(gomock.Any(), userID, models.ActivityDeletionScheduled).Return(nil),
+				)
+			},
+			expectedAt: now.Add(72 * time.Hour),
+		},
+		{
+			name: "repeated request keeps the first date",
+			mockSetup: func(accountRepo *mock_adapters.MockIAccountRepository, userRepo *mock_adapters.MockIUserRepository) {
+				userRepo.EXPECT().
+					GetUserByID(gomock.Any(), userID).
+					Return(&models.User{ID: userID, DeletionScheduledAt: &alreadyScheduled}, nil)
+			},
+			expectedAt: alreadyScheduled,
+		},
+		{
+			name: "user not found",
+			mockSetup: func(accountRepo *mock_adapters.MockIAccountRepository, userRepo *mock_adapters.MockIUserRepository) {
+				userRepo.EXPECT().GetUserByID(gomock.Any(), userID).Return(nil, models.ErrUserNotFound)
+			},
+			expectedError: models.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			adapter, accountRepo, userRepo := newTestAccountAdapter(ctrl, now)
+			tt.mockSetup(accountRepo, userRepo)
+
+			at, err := adapter.ScheduleDeletion(context.Background(), userID)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedAt, at)
+			}
+		})
+	}
+}
+
+func TestAccountAdapter_CancelDeletion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	adapter, accountRepo, userRepo := newTestAccountAdapter(ctrl, time.Now())
+	userID := uuid.New()
+
+	gomock.InOrder(
+		accountRepo.EXPECT().CancelDeletion(gomock.Any(), userID).Return(nil),
+		userRepo.EXPECT().RecordActivity(gomock.Any(), userID, models.ActivityDeletionCancelled).Return(nil),
+		accountRepo.EXPECT().CancelDeletion(gomock.Any(), userID).Return(models.ErrDeletionNotScheduled),
+	)
+
+	assert.NoError(t, adapter.CancelDeletion(context.Background(), userID))
+	assert.ErrorIs(t, adapter.CancelDeletion(context.Background(), userID), models.ErrDeletionNotScheduled)
+}
+
+func TestAccountAdapter_PurgeDueDeletions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	adapter, accountRepo, _ := newTestAccountAdapter(ctrl, now)
+	failing, cancelled, deleted := uuid.New(), uuid.New(), uuid.New()
+
+	gomock.InOrder(
+		accountRepo.EXPECT().ListDueDeletions(gomock.Any(), now).Return([]uuid.UUID{failing, cancelled, deleted}, nil),
+		accountRepo.EXPECT().DeleteIfDue(gomock.Any(), failing, now).Return(false, errors.New("db is down")),
+		accountRepo.EXPECT().DeleteIfDue(gomock.Any(), cancelled, now).Return(false, nil),
+		accountRepo.EXPECT().DeleteIfDue(gomock.Any(), deleted, now).Return(true, nil),
+	)
+
+	count, err := adapter.PurgeDueDeletions(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 1, count, "a failure does not stop the remaining deletions")
+}
+
+func TestAccountAdapter_ImportData(t *testing.T) {
+	userID := uuid.New()
+	category := models.Category{ID: uuid.New(), Name: "home"}
+	valid := &models.AccountData{
+		Tasks:      []models.TaskFullInfo{{ID: uuid.New(), Title: "buy milk", Categories: []models.Category{{ID: category.ID}}}},
+		Categories: []models.Category{category},
+	}
+
+	tests := []struct {
+		name          string
+		data          *models.AccountData
+		mockSetup     func(accountRepo *mock_adapters.MockIAccountRepository, userRepo *mock_adapters.MockIUserRepository)
+		expected      *models.ImportResult
+		expectedError error
+	}{
+		{
+			name: "imports tasks and categories",
+			data: valid,
+			mockSetup: func(accountRepo *mock_adapters.MockIAccountRepository, userRepo *mock_adapters.MockIUserRepository) {
+				gomock.InOrder(
+					userRepo.EXPECT().GetUserByID(gomock.Any(), userID).Return(&models.User{ID: userID}, nil),
+					accountRepo.EXPECT().ImportData(gomock.Any(), userID, valid).Return(&models.ImportResult{Categories: 1, Tasks: 1}, nil),
+					userRepo.EXPECT().RecordActivity(gomock.Any(), userID, models.ActivityDataImported).Return(nil),
+				)
+			},
+			expected: &models.ImportResult{Categories: 1, Tasks: 1},
+		},
+		{
+			name: "task without title",
+			data: &models.AccountData{Tasks: []models.TaskFullInfo{{ID: uuid.New()}}},
+			mockSetup: func(accountRepo *mock_adapters.MockIAccountRepository, userRepo *mock_adapters.MockIUserRepository) {
+			},
+			expectedError: errors.New("has no title"),
+		},
+		{
+			name: "workflow with one status",
+			data: &models.AccountData{Statuses: []models.TaskStatus{{ID: uuid.New(), Name: "todo"}}},
+			mockSetup: func(accountRepo *mock_adapters.MockIAccountRepository, userRepo *mock_adapters.MockIUserRepository) {
+			},
+			expectedError: models.ErrInvalidWorkflow,
+		},
+		{
+			name: "user not found",
+			data: valid,
+			mockSetup: func(accountRepo *mock_adapters.MockIAccountRepository, userRepo *mock_adapters.MockIUserRepository) {
+				userRepo.EXPECT().GetUserByID(gomock.Any(), userID).Return(nil, models.ErrUserNotFound)
+			},
+			expectedError: models.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			adapter, accountRepo, userRepo := newTestAccountAdapter(ctrl, time.Now())
+			tt.mockSetup(accountRepo, userRepo)
+
+			result, err := adapter.ImportData(context.Background(), userID, tt.data)
+			switch {
+			case errors.Is(tt.expectedError, models.ErrUserNotFound), errors.Is(tt.expectedError, models.ErrInvalidWorkflow):
+				assert.ErrorIs(t, err, tt.expectedError)
+			case tt.expectedError != nil:
+				assert.ErrorContains(t, err, tt.expectedError.Error())
+			default:
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package adapters
+
+import (
+	"context"
+	"todolist/internal/models"
+	"todolist/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type IAdminRepository interface {
+	ListUsers(ctx context.Context, search string, pageIndex, recordsPerPage int) ([]models.UserSummary, error)
+	GetUserSummary(ctx context.Context, userID uuid.UUID) (*models.UserSummary, error)
+	SetUserDisabled(ctx context.Context, userID uuid.UUID, disabled bool) error
+	RequirePasswordReset(ctx context.Context, userID uuid.UUID) error
+	SetUserRole(ctx context.Context, userID uuid.UUID, role string) error
+}
+
+type AdminAdapter struct {
+	repository IAdminRepository
+}
+
+func NewAdminAdapter(repository IAdminRepository) *AdminAdapter {
+	return &AdminAdapter{repository: repository}
+}
+
+func (a *AdminAdapter) ListUsers(ctx context.Context, search string, pageIndex, recordsPerPage int) ([]models.UserSummary, error) {
+	ctx, span := tracing.Start(ctx, "AdminAdapter.ListUsers")
+	defer span.End()
+
+	users, err := a.repository.ListUsers(ctx, search, pageIndex, recordsPerPage)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list users")
+	}
+	return users, nil
+}
+
+func (a *AdminAdapter) GetUser(ctx context.Context, userID uuid.UUID) (*models.UserSummary, error) {
+	ctx, span := tracing.Start(ctx, "AdminAdapter.GetUser")
+	defer span.End()
+
+	user, err := a.repository.GetUserSummary(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get user with id: %s", userID)
+	}
+	return user, nil
+}
+
+func (a *AdminAdapter) DisableUser(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "AdminAdapter.DisableUser")
+	defer span.End()
+
+	err := a.repository.SetUserDisabled(ctx, userID, true)
+	if err != nil {
+		return errors.Wrapf(err, "failed to disable user with id: %s", userID)
+	}
+	return nil
+}
+
+func (a *AdminAdapter) EnableUser(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "AdminAdapter.EnableUser")
+	defer span.End()
+
+	err := a.repository.SetUserDisabled(ctx, userID, false)
+	if err != nil {
+		return errors.Wrapf(err, "failed to enable user with id: %s", userID)
+	}
+	return nil
+}
+
+func (a *AdminAdapter) ForcePasswordReset(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "AdminAdapter.ForcePasswordReset")
+	defer span.End()
+
+	err := a.repository.RequirePasswordReset(ctx, userID)
+	if err != nil {
+		return errors.Wrapf(err, "failed to force password reset for user with id: %s", userID)
+	}
+	return nil
+}
+
+func (a *AdminAdapter) SetRole(ctx context.Context, userID uuid.UUID, role string) error {
+	ctx, span := tracing.Start(ctx, "AdminAdapter.SetRole")
+	defer span.End()
+
+	if role != models.RoleUser && role != models.RoleAdmin {
+		return errors.Errorf("unknown role %q, expected %s or %s", role, models.RoleUser, models.RoleAdmin)
+	}
+	err := a.repository.SetUserRole(ctx, userID, role)
+	if err != nil {
+		return errors.Wrapf(err, "failed to set role of user with id: %s", userID)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+	mock_adapters "todolist/internal/adapters/mocks"
+	"todolist/internal/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdminAdapter_ListUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockIAdminRepository(ctrl)
+	adapter := NewAdminAdapter(mockRepo)
+
+	users := []models.UserSummary{
+		{ID: uuid.New(), Name: "alice", Role: models.RoleAdmin, TaskCount: 3, CategoryCount: 1},
+		{ID: uuid.New(), Name: "bob", Role: models.RoleUser, Disabled: true},
+	}
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		expected      []models.UserSummary
+		expectedError error
+	}{
+		{
+			name: "successful listing",
+			mockSetup: func() {
+				mockRepo.EXPECT().ListUsers(gomock.Any(), "a", 1, 20).Return(users, nil)
+			},
+			expected: users,
+		},
+		{
+			name: "repository error",
+			mockSetup: func() {
+				mockRepo.EXPECT().ListUsers(gomock.Any(), "a", 1, 20).Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("failed to list users"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			result, err := adapter.ListUsers(context.Background(), "a", 1, 20)
+
+			if tt.expectedError != nil {
+				assert.Contains(t, err.Error(), tt.expectedError.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestAdminAdapter_UserActions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockIAdminRepository(ctrl)
+	adapter := NewAdminAdapter(mockRepo)
+
+	testID := uuid.New()
+
+	tests := []struct {
+		name          string
+		action        func(ctx context.Context, userID uuid.UUID) error
+		mockSetup     func()
+		expectedError error
+	}{
+		{
+			name:   "disable user",
+			action: adapter.DisableUser,
+			mockSetup: func() {
+				mockRepo.EXPECT().SetUserDisabled(gomock.Any(), testID, true).Return(nil)
+			},
+		},
+		{
+			name:   "enable user",
+			action: adapter.EnableUser,
+			mockSetup: func() {
+				mockRepo.EXPECT().SetUserDisabled(gomock.Any(), testID, false).Return(nil)
+			},
+		},
+		{
+			name:   "force password reset",
+			action: adapter.ForcePasswordReset,
+			mockSetup: func() {
+				mockRepo.EXPECT().RequirePasswordReset(gomock.Any(), testID).Return(nil)
+			},
+		},
+		{
+			name:   "unknown user",
+			action: adapter.DisableUser,
+			mockSetup: func() {
+				mockRepo.EXPECT().SetUserDisabled(gomock.Any(), testID, true).Return(models.ErrUserNotFound)
+			},
+			expectedError: models.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			err := tt.action(context.Background(), testID)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestAdminAdapter_SetRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockIAdminRepository(ctrl)
+	adapter := NewAdminAdapter(mockRepo)
+	testID := uuid.New()
+
+	mockRepo.EXPECT().SetUserRole(gomock.Any(), testID, models.RoleAdmin).Return(nil)
+	assert.NoError(t, adapter.SetRole(context.Background(), testID, models.RoleAdmin))
+
+	assert.Error(t, adapter.SetRole(context.Background(), testID, "root"), "unknown roles never reach the repository")
+}
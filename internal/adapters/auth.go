@@ -2,11 +2,20 @@ package adapters
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
 	"todolist/internal/models"
 	auth_utils "todolist/internal/pkg/authUtils"
+	"todolist/internal/pkg/totp"
+	"todolist/internal/tracing"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -17,16 +26,32 @@ type IUserRepository interface {
 	CheckTaskOwnership(ctx context.Context, userID uuid.UUID, taskID uuid.UUID) (bool, error)
 	CheckCategoriesOwnership(ctx context.Context, userID uuid.UUID, categories []uuid.UUID) (bool, error)
 	DeleteUser(ctx context.Context, userID uuid.UUID) error
+	SetTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error
+	EnableTOTP(ctx context.Context, userID uuid.UUID, recoveryCodeHashes []string) error
+	DisableTOTP(ctx context.Context, userID uuid.UUID) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	RecordActivity(ctx context.Context, userID uuid.UUID, event string) error
 }
 
+const (
+	totpIssuer        = "Plan&Do"
+	recoveryCodeCount = 10
+)
+
+// UserAdapter embeds the Transactor it shares with the other adapters, see
+// TaskAdapter.
 type UserAdapter struct {
+	models.Transactor
 	userRepo     IUserRepository
 	key          string
 	tokenHandler auth_utils.ITokenHandler
 }
 
-func NewAuthService(repo IUserRepository, token auth_utils.ITokenHandler, k string) *UserAdapter {
+func NewAuthService(repo IUserRepository, transactor models.Transactor, token auth_utils.ITokenHandler, k string) *UserAdapter {
 	return &UserAdapter{
+		Transactor:   transactor,
 		userRepo:     repo,
 		tokenHandler: token,
 		key:          k,
@@ -34,6 +59,9 @@ func NewAuthService(repo IUserRepository, token auth_utils.ITokenHandler, k stri
 }
 
 func (serv *UserAdapter) SignUp(ctx context.Context, candidate *models.UserAuth) error {
+	ctx, span := tracing.Start(ctx, "UserAdapter.SignUp")
+	defer span.End()
+
 	var err error
 	if candidate.Name == "" {
 		err = errors.New("Failed to login with empty login")
@@ -47,7 +75,7 @@ func (serv *UserAdapter) SignUp(ctx context.Context, candidate *models.UserAuth)
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(candidate.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return errors.Wrapf(err, "Error in generating hash for password %s", candidate.Password)
+		return errors.Wrapf(err, "Error in generating hash for password of user %s", candidate.Name)
 	}
 
 	candidateHashedPasswd := *candidate
@@ -61,39 +89,355 @@ func (serv *UserAdapter) SignUp(ctx context.Context, candidate *models.UserAuth)
 	return nil
 }
 
-func (serv *UserAdapter) SignIn(ctx context.Context, candidate *models.UserAuth) (string, error) {
+// SignIn checks the password. Users with two-factor authentication get a
+// challenge token instead of the access token and finish with SignInSecondFactor.
+func (serv *UserAdapter) SignIn(ctx context.Context, candidate *models.UserAuth) (*models.SignInResult, error) {
+	ctx, span := tracing.Start(ctx, "UserAdapter.SignIn")
+	defer span.End()
+
 	var user *models.User
 	var err error
 	var tokenStr string
 	if candidate.Name == "" {
 		err = errors.New("Failed to login with empty login")
-		return "", err
+		return nil, err
 	}
 
 	if candidate.Password == "" {
 		err = errors.Errorf("Empty password for user with login %s", candidate.Name)
-		return "", err
+		return nil, err
 	}
 	user, err = serv.userRepo.GetUserByName(ctx, candidate.Name)
 
 	if err != nil {
 		err = errors.Wrapf(err, "Failed to get user %s", candidate.Name)
-		return "", err
+		return nil, err
 	}
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(candidate.Password))
 	if err != nil {
 		err = errors.Wrapf(err, "Invalid password for user %s", candidate.Name)
-		return "", err
+		return nil, err
 	}
+
+	if user.Disabled {
+		return nil, errors.Wrapf(models.ErrUserDisabled, "Failed to sign in user %s", candidate.Name)
+	}
+	if user.PasswordResetRequired {
+		return nil, errors.Wrapf(models.ErrPasswordReset, "Failed to sign in user %s", candidate.Name)
+	}
+
+	if user.TOTPEnabled {
+		tokenStr, err = serv.tokenHandler.GenerateChallengeToken(*user, serv.key)
+		if err != nil {
+			err = errors.Wrapf(err, "Failed to generate challenge token for user: %s", candidate.Name)
+			return nil, err
+		}
+		return &models.SignInResult{ChallengeToken: tokenStr}, nil
+	}
+
 	tokenStr, err = serv.tokenHandler.GenerateToken(*user, serv.key)
 	if err != nil {
 		err = errors.Wrapf(err, "Failed to generate token for user: %s", candidate.Name)
+		return nil, err
+	}
+	recordActivity(ctx, serv.userRepo, user.ID, models.ActivitySignIn)
+	return &models.SignInResult{Token: tokenStr}, nil
+}
+
+// SignInSecondFactor completes a sign-in started by SignIn. The code is either
+// a TOTP code not used before or one of the unused recovery codes.
+func (serv *UserAdapter) SignInSecondFactor(ctx context.Context, challengeToken string, code string) (string, error) {
+	ctx, span := tracing.Start(ctx, "UserAdapter.SignInSecondFactor")
+	defer span.End()
+
+	payload, err := serv.tokenHandler.ParseChallengeToken(challengeToken, serv.key)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to parse challenge token")
+	}
+
+	user, err := serv.userRepo.GetUserByID(ctx, payload.ID)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to get user %s", payload.Login)
+	}
+	if user.Disabled {
+		return "", errors.Wrapf(models.ErrUserDisabled, "Failed to sign in user %s", user.Name)
+	}
+
+	if err = serv.checkSecondFactor(ctx, user, code); err != nil {
 		return "", err
 	}
+
+	tokenStr, err := serv.tokenHandler.GenerateToken(*user, serv.key)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to generate token for user: %s", user.Name)
+	}
+	recordActivity(ctx, serv.userRepo, user.ID, models.ActivitySignIn)
 	return tokenStr, nil
 }
 
+// Authorize returns the user an access token was issued to, as it is now. A
+// token outlives changes an administrator makes to the account, so every
+// request checks that the user still exists, is not disabled and has no
+// pending password reset.
+func (serv *UserAdapter) Authorize(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "UserAdapter.Authorize")
+	defer span.End()
+
+	user, err := serv.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get user with id %v", userID)
+	}
+	if user.Disabled {
+		return nil, errors.Wrapf(models.ErrUserDisabled, "Failed to authorize user %s", user.Name)
+	}
+	if user.PasswordResetRequired {
+		return nil, errors.Wrapf(models.ErrPasswordReset, "Failed to authorize user %s", user.Name)
+	}
+	return user, nil
+}
+
+// EnrollTOTP generates a new shared secret. Two-factor authentication is
+// enabled only after ConfirmTOTP proves the authenticator app has it.
+func (serv *UserAdapter) EnrollTOTP(ctx context.Context, userID uuid.UUID) (*models.TOTPEnrollment, error) {
+	ctx, span := tracing.Start(ctx, "UserAdapter.EnrollTOTP")
+	defer span.End()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	err = serv.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = serv.userRepo.GetUserByID(ctx, userID)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get user with id %v", userID)
+		}
+		if user.TOTPEnabled {
+			return models.ErrTOTPAlreadyEnabled
+		}
+
+		err = serv.userRepo.SetTOTPSecret(ctx, userID, secret)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to save totp secret for user with id %v", userID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, user.Name, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication and returns the recovery
+// codes. They are stored hashed, so this is the only time they are shown.
+func (serv *UserAdapter) ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "UserAdapter.ConfirmTOTP")
+	defer span.End()
+
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		recoveryCode, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, recoveryCode)
+		hashes = append(hashes, hashRecoveryCode(recoveryCode))
+	}
+
+	// The code is spent only if two-factor authentication gets enabled.
+	err := serv.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := serv.userRepo.GetUserByID(ctx, userID)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get user with id %v", userID)
+		}
+		if user.TOTPEnabled {
+			return models.ErrTOTPAlreadyEnabled
+		}
+		if user.TOTPSecret == "" {
+			return models.ErrTOTPNotEnrolled
+		}
+		step, ok := totp.Verify(code, user.TOTPSecret, time.Now())
+		if !ok {
+			return models.ErrInvalidTOTPCode
+		}
+		if err = serv.useTOTPStep(ctx, user, step); err != nil {
+			return err
+		}
+
+		err = serv.userRepo.EnableTOTP(ctx, userID, hashes)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to enable totp for user with id %v", userID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	recordActivity(ctx, serv.userRepo, userID, models.ActivityTwoFactorEnabled)
+	return codes, nil
+}
+
+func (serv *UserAdapter) DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	ctx, span := tracing.Start(ctx, "UserAdapter.DisableTOTP")
+	defer span.End()
+
+	err := serv.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := serv.userRepo.GetUserByID(ctx, userID)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get user with id %v", userID)
+		}
+		if !user.TOTPEnabled {
+			return models.ErrTOTPNotEnrolled
+		}
+
+		if err = serv.checkSecondFactor(ctx, user, code); err != nil {
+			return err
+		}
+
+		err = serv.userRepo.DisableTOTP(ctx, userID)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to disable totp for user with id %v", userID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	recordActivity(ctx, serv.userRepo, userID, models.ActivityTwoFactorDisabled)
+	return nil
+}
+
+func (serv *UserAdapter) checkSecondFactor(ctx context.Context, user *models.User, code string) error {
+	if !user.TOTPEnabled {
+		return models.ErrTOTPNotEnrolled
+	}
+
+	if step, ok := totp.Verify(code, user.TOTPSecret, time.Now()); ok {
+		return serv.useTOTPStep(ctx, user, step)
+	}
+
+	used, err := serv.userRepo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(code))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to check recovery code for user %s", user.Name)
+	}
+	if !used {
+		return models.ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// useTOTPStep accepts a valid TOTP code once: its time step has to be later
+// than the last accepted one, so a code seen by someone else cannot be
+// replayed while it is still valid.
+func (serv *UserAdapter) useTOTPStep(ctx context.Context, user *models.User, step int64) error {
+	used, err := serv.userRepo.UseTOTPStep(ctx, user.ID, step)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to record totp code for user %s", user.Name)
+	}
+	if !used {
+		return errors.Wrap(models.ErrInvalidTOTPCode, "code already used")
+	}
+	return nil
+}
+
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "failed to generate recovery code")
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(buf))
+	return code[:4] + "-" + code[4:], nil
+}
+
+// hashRecoveryCode normalizes the code the way users tend to type it.
+// Recovery codes are random, so a fast hash is enough.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// ChangePassword replaces the password after checking the current one. It is
+// also the way out of a password reset forced by an administrator.
+func (serv *UserAdapter) ChangePassword(ctx context.Context, candidate *models.UserAuth, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "UserAdapter.ChangePassword")
+	defer span.End()
+
+	if newPassword == "" {
+		return errors.Errorf("Empty new password for user with login %s", candidate.Name)
+	}
+
+	user, err := serv.userRepo.GetUserByName(ctx, candidate.Name)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get user %s", candidate.Name)
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(candidate.Password))
+	if err != nil {
+		return errors.Wrapf(err, "Invalid password for user %s", candidate.Name)
+	}
+	if user.Disabled {
+		return errors.Wrapf(models.ErrUserDisabled, "Failed to change password of user %s", candidate.Name)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.Wrapf(err, "Error in generating hash for new password of user %s", candidate.Name)
+	}
+
+	err = serv.userRepo.UpdatePassword(ctx, user.ID, string(hash))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to change password of user %s", candidate.Name)
+	}
+	recordActivity(ctx, serv.userRepo, user.ID, models.ActivityPasswordChanged)
+	return nil
+}
+
+// ResetPassword sets a new password without the current one, for operators
+// helping a locked out user.
+func (serv *UserAdapter) ResetPassword(ctx context.Context, userID uuid.UUID, newPassword string) error {
+	ctx, span := tracing.Start(ctx, "UserAdapter.ResetPassword")
+	defer span.End()
+
+	if newPassword == "" {
+		return errors.Errorf("Empty new password for user with id %v", userID)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.Wrapf(err, "Error in generating hash for new password of user with id %v", userID)
+	}
+
+	err = serv.userRepo.UpdatePassword(ctx, userID, string(hash))
+	if err != nil {
+		return errors.Wrapf(err, "Failed to reset password of user with id %v", userID)
+	}
+	recordActivity(ctx, serv.userRepo, userID, models.ActivityPasswordChanged)
+	return nil
+}
+
+// recordActivity adds an entry to the account activity log. The log is
+// informational, so a failure to write it does not fail the operation.
+func recordActivity(ctx context.Context, repo IUserRepository, userID uuid.UUID, event string) {
+	if err := repo.RecordActivity(ctx, userID, event); err != nil {
+		log.Warn().
+			Err(err).
+			Str("user_id", userID.String()).
+			Str("event", event).
+			Msg("failed to record account activity")
+	}
+}
+
 func (serv *UserAdapter) CheckTaskOwnership(ctx context.Context, userID uuid.UUID, taskID uuid.UUID) (bool, error) {
+	ctx, span := tracing.Start(ctx, "UserAdapter.CheckTaskOwnership")
+	defer span.End()
+
 	isTaskOwned, err := serv.userRepo.CheckTaskOwnership(ctx, userID, taskID)
 	if err != nil {
 		return false, errors.Wrap(err, "Error in checking task ownership")
@@ -102,6 +446,9 @@ func (serv *UserAdapter) CheckTaskOwnership(ctx context.Context, userID uuid.UUI
 }
 
 func (serv *UserAdapter) CheckCategoriesOwnership(ctx context.Context, userID uuid.UUID, categories []uuid.UUID) (bool, error) {
+	ctx, span := tracing.Start(ctx, "UserAdapter.CheckCategoriesOwnership")
+	defer span.End()
+
 	areCategoriesOwned, err := serv.userRepo.CheckCategoriesOwnership(ctx, userID, categories)
 	if err != nil {
 		return false, errors.Wrap(err, "Error in checking task ownership")
@@ -110,6 +457,9 @@ func (serv *UserAdapter) CheckCategoriesOwnership(ctx context.Context, userID uu
 }
 
 func (serv *UserAdapter) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "UserAdapter.DeleteUser")
+	defer span.End()
+
 	err := serv.userRepo.DeleteUser(ctx, userID)
 	if err != nil {
 		err = errors.Wrapf(err, "Failed to delete user with id %v", userID)
@@ -13,13 +13,30 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// inlineTransactor runs every unit of work right away, like a transaction
+// that always commits.
+func inlineTransactor(ctrl *gomock.Controller) *mock_adapters.MockTransactor {
+	transactor := mock_adapters.NewMockTransactor(ctrl)
+	transactor.EXPECT().
+		WithinTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		}).
+		AnyTimes()
+	transactor.EXPECT().
+		AfterCommit(gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, fn func()) { fn() }).
+		AnyTimes()
+	return transactor
+}
+
 func TestUserAdapter_SignUp(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockRepo := mock_adapters.NewMockIUserRepository(ctrl)
 	mockTokenHandler := mock_adapters.NewMockITokenHandler(ctrl)
-	adapter := NewAuthService(mockRepo, mockTokenHandler, "test-key")
+	adapter := NewAuthService(mockRepo, inlineTransactor(ctrl), mockTokenHandler, "test-key")
 
 	tests := []struct {
 		name          string
@@ -100,7 +117,7 @@ func TestUserAdapter_SignIn(t *testing.T) {
 
 	mockRepo := mock_adapters.NewMockIUserRepository(ctrl)
 	mockTokenHandler := mock_adapters.NewMockITokenHandler(ctrl)
-	adapter := NewAuthService(mockRepo, mockTokenHandler, "test-key")
+	adapter := NewAuthService(mockRepo, inlineTransactor(ctrl), mockTokenHandler, "test-key")
 
 	testUser := &models.User{
 		ID:       uuid.New(),
@@ -129,6 +146,9 @@ func TestUserAdapter_SignIn(t *testing.T) {
 					mockTokenHandler.EXPECT().
 						GenerateToken(*testUser, "test-key").
 						Return("test-token", nil),
+					mockRepo.EXPECT().
+						RecordActivity(gomock.Any(), testUser.ID, models.ActivitySignIn).
+						Return(nil),
 				)
 			},
 			expectedToken: "test-token",
@@ -148,6 +168,34 @@ func TestUserAdapter_SignIn(t *testing.T) {
 			expectedToken: "",
 			expectedError: errors.Wrapf(errors.New("crypto/bcrypt: hashedPassword is not the hash of the given password"), "Invalid password for user %s", "testuser"),
 		},
+		{
+			name: "disabled user",
+			candidate: &models.UserAuth{
+				Name:     "disabled",
+				Password: "password123",
+			},
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetUserByName(gomock.Any(), "disabled").
+					Return(&models.User{ID: uuid.New(), Name: "disabled", Password: testUser.Password, Disabled: true}, nil)
+			},
+			expectedToken: "",
+			expectedError: models.ErrUserDisabled,
+		},
+		{
+			name: "password reset required",
+			candidate: &models.UserAuth{
+				Name:     "reset",
+				Password: "password123",
+			},
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetUserByName(gomock.Any(), "reset").
+					Return(&models.User{ID: uuid.New(), Name: "reset", Password: testUser.Password, PasswordResetRequired: true}, nil)
+			},
+			expectedToken: "",
+			expectedError: models.ErrPasswordReset,
+		},
 		{
 			name: "user not found",
 			candidate: &models.UserAuth{
@@ -167,12 +215,13 @@ func TestUserAdapter_SignIn(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockSetup()
-			token, err := adapter.SignIn(context.Background(), tt.candidate)
+			result, err := adapter.SignIn(context.Background(), tt.candidate)
 
 			if tt.expectedError != nil {
 				assert.Contains(t, err.Error(), tt.expectedError.Error())
 			} else {
-				assert.Equal(t, tt.expectedToken, token)
+				assert.Equal(t, tt.expectedToken, result.Token)
+				assert.False(t, result.ChallengeRequired())
 				assert.Nil(t, err)
 			}
 		})
@@ -185,7 +234,7 @@ func TestUserAdapter_DeleteUser(t *testing.T) {
 
 	mockRepo := mock_adapters.NewMockIUserRepository(ctrl)
 	mockTokenHandler := mock_adapters.NewMockITokenHandler(ctrl)
-	adapter := NewAuthService(mockRepo, mockTokenHandler, "test-key")
+	adapter := NewAuthService(mockRepo, inlineTransactor(ctrl), mockTokenHandler, "test-key")
 
 	testID := uuid.New()
 
@@ -231,6 +280,84 @@ func TestUserAdapter_DeleteUser(t *testing.T) {
 	}
 }
 
+func TestUserAdapter_ChangePassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockIUserRepository(ctrl)
+	mockTokenHandler := mock_adapters.NewMockITokenHandler(ctrl)
+	adapter := NewAuthService(mockRepo, inlineTransactor(ctrl), mockTokenHandler, "test-key")
+
+	testUser := &models.User{
+		ID:                    uuid.New(),
+		Name:                  "testuser",
+		Password:              generateHash(t, "password123"),
+		PasswordResetRequired: true,
+	}
+
+	tests := []struct {
+		name          string
+		candidate     *models.UserAuth
+		newPassword   string
+		mockSetup     func()
+		expectedError error
+	}{
+		{
+			name:        "successful change",
+			candidate:   &models.UserAuth{Name: "testuser", Password: "password123"},
+			newPassword: "new-password",
+			mockSetup: func() {
+				gomock.InOrder(
+					mockRepo.EXPECT().
+						GetUserByName(gomock.Any(), "testuser").
+						Return(testUser, nil),
+					mockRepo.EXPECT().
+						UpdatePassword(gomock.Any(), testUser.ID, gomock.Any()).
+						DoAndReturn(func(ctx context.Context, _ uuid.UUID, hash string) error {
+							assert.Nil(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password")))
+							return nil
+						}),
+					mockRepo.EXPECT().
+						RecordActivity(gomock.Any(), testUser.ID, models.ActivityPasswordChanged).
+						Return(nil),
+				)
+			},
+			expectedError: nil,
+		},
+		{
+			name:        "wrong current password",
+			candidate:   &models.UserAuth{Name: "testuser", Password: "wrongpassword"},
+			newPassword: "new-password",
+			mockSetup: func() {
+				mockRepo.EXPECT().
+					GetUserByName(gomock.Any(), "testuser").
+					Return(testUser, nil)
+			},
+			expectedError: errors.New("Invalid password for user testuser"),
+		},
+		{
+			name:          "empty new password",
+			candidate:     &models.UserAuth{Name: "testuser", Password: "password123"},
+			newPassword:   "",
+			mockSetup:     func() {},
+			expectedError: errors.New("Empty new password for user with login testuser"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			err := adapter.ChangePassword(context.Background(), tt.candidate, tt.newPassword)
+
+			if tt.expectedError != nil {
+				assert.Contains(t, err.Error(), tt.expectedError.Error())
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+}
+
 // Вспомогательная функция для генерации хэша
 func generateHash(t *testing.T, password string) string {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -239,3 +366,31 @@ func generateHash(t *testing.T, password string) string {
 	}
 	return string(hash)
 }
+
+func TestUserAdapter_ResetPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockIUserRepository(ctrl)
+	adapter := NewAuthService(mockRepo, inlineTransactor(ctrl), mock_adapters.NewMockITokenHandler(ctrl), "test-key")
+	userID := uuid.New()
+
+	gomock.InOrder(
+		mockRepo.EXPECT().
+			UpdatePassword(gomock.Any(), userID, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, _ uuid.UUID, hash string) error {
+				assert.Nil(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password")))
+				return nil
+			}),
+		mockRepo.EXPECT().
+			RecordActivity(gomock.Any(), userID, models.ActivityPasswordChanged).
+			Return(nil),
+		mockRepo.EXPECT().
+			UpdatePassword(gomock.Any(), userID, gomock.Any()).
+			Return(models.ErrUserNotFound),
+	)
+
+	assert.NoError(t, adapter.ResetPassword(context.Background(), userID, "new-password"))
+	assert.ErrorIs(t, adapter.ResetPassword(context.Background(), userID, "new-password"), models.ErrUserNotFound)
+	assert.Error(t, adapter.ResetPassword(context.Background(), userID, ""))
+}
@@ -3,36 +3,50 @@ package adapters
 import (
 	"context"
 	"todolist/internal/models"
+	"todolist/internal/tracing"
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
 
 type CategoryRepository interface {
-	CreateCategory(ctx context.Context, body *models.CategoryBody) error
-	Delete(ctx context.Context, id uuid.UUID) error
+	// CreateCategory returns the created category, or the one created
+	// earlier with the same body.IdempotencyKey.
+	CreateCategory(ctx context.Context, body *models.CategoryBody) (*models.Category, error)
+	// Delete removes the category of the user and returns
+	// models.ErrCategoryNotFound for the category of another user.
+	Delete(ctx context.Context, id, userID uuid.UUID) error
 	GetAll(ctx context.Context, pageIndex, recordsPerPage int, userID uuid.UUID) ([]models.Category, error)
 }
 
+// CategoryAdapter embeds the Transactor it shares with the other adapters,
+// see TaskAdapter.
 type CategoryAdapter struct {
+	models.Transactor
 	repository CategoryRepository
 }
 
-func NewCategoryAdapter(repository CategoryRepository) *CategoryAdapter {
-	return &CategoryAdapter{repository: repository}
+func NewCategoryAdapter(repository CategoryRepository, transactor models.Transactor) *CategoryAdapter {
+	return &CategoryAdapter{Transactor: transactor, repository: repository}
 }
 
-func (c *CategoryAdapter) CreateCategory(ctx context.Context, body *models.CategoryBody) error {
-	err := c.repository.CreateCategory(ctx, body)
+func (c *CategoryAdapter) CreateCategory(ctx context.Context, body *models.CategoryBody) (*models.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryAdapter.CreateCategory")
+	defer span.End()
+
+	category, err := c.repository.CreateCategory(ctx, body)
 	if err != nil {
-		return errors.Wrap(err, "failed to create category")
+		return nil, errors.Wrap(err, "failed to create category")
 	}
 
-	return nil
+	return category, nil
 }
 
-func (c *CategoryAdapter) Delete(ctx context.Context, id uuid.UUID) error {
-	err := c.repository.Delete(ctx, id)
+func (c *CategoryAdapter) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "CategoryAdapter.Delete")
+	defer span.End()
+
+	err := c.repository.Delete(ctx, id, userID)
 	if err != nil {
 		return errors.Wrap(err, "failed to delete category")
 	}
@@ -40,6 +54,9 @@ func (c *CategoryAdapter) Delete(ctx context.Context, id uuid.UUID) error {
 }
 
 func (c *CategoryAdapter) GetAll(ctx context.Context, pageIndex, recordsPerPage int, userID uuid.UUID) ([]models.Category, error) {
+	ctx, span := tracing.Start(ctx, "CategoryAdapter.GetAll")
+	defer span.End()
+
 	categories, err := c.repository.GetAll(ctx, pageIndex, recordsPerPage, userID)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get all categories")
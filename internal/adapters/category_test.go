@@ -17,13 +17,15 @@ func TestCategoryAdapter_CreateCategory(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := mock_adapters.NewMockCategoryRepository(ctrl)
-	adapter := NewCategoryAdapter(mockRepo)
+	adapter := NewCategoryAdapter(mockRepo, inlineTransactor(ctrl))
+	created := &models.Category{ID: uuid.New(), Name: "Test Category"}
 
 	tests := []struct {
 		name          string
 		ctx           context.Context
 		body          *models.CategoryBody
 		mockSetup     func()
+		expected      *models.Category
 		expectedError error
 	}{
 		{
@@ -31,8 +33,9 @@ func TestCategoryAdapter_CreateCategory(t *testing.T) {
 			ctx:  context.Background(),
 			body: &models.CategoryBody{Name: "Test Category"},
 			mockSetup: func() {
-				mockRepo.EXPECT().CreateCategory(gomock.Any(), gomock.Any()).Return(nil)
+				mockRepo.EXPECT().CreateCategory(gomock.Any(), gomock.Any()).Return(created, nil)
 			},
+			expected:      created,
 			expectedError: nil,
 		},
 		{
@@ -40,7 +43,7 @@ func TestCategoryAdapter_CreateCategory(t *testing.T) {
 			ctx:  context.Background(),
 			body: &models.CategoryBody{Name: "Test Category"},
 			mockSetup: func() {
-				mockRepo.EXPECT().CreateCategory(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+				mockRepo.EXPECT().CreateCategory(gomock.Any(), gomock.Any()).Return(nil, errors.New("db error"))
 			},
 			expectedError: errors.New("failed to create category"),
 		},
@@ -49,13 +52,14 @@ func TestCategoryAdapter_CreateCategory(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockSetup()
-			err := adapter.CreateCategory(tt.ctx, tt.body)
+			category, err := adapter.CreateCategory(tt.ctx, tt.body)
 
 			if tt.expectedError != nil {
 				assert.Contains(t, err.Error(), tt.expectedError.Error())
 			} else {
 				assert.Nil(t, err)
 			}
+			assert.Equal(t, tt.expected, category)
 		})
 	}
 }
@@ -65,9 +69,10 @@ func TestCategoryAdapter_Delete(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := mock_adapters.NewMockCategoryRepository(ctrl)
-	adapter := NewCategoryAdapter(mockRepo)
+	adapter := NewCategoryAdapter(mockRepo, inlineTransactor(ctrl))
 
 	testID := uuid.New()
+	testUserID := uuid.New()
 
 	tests := []struct {
 		name          string
@@ -79,7 +84,7 @@ func TestCategoryAdapter_Delete(t *testing.T) {
 			name: "successful deletion",
 			id:   testID,
 			mockSetup: func() {
-				mockRepo.EXPECT().Delete(gomock.Any(), testID).Return(nil)
+				mockRepo.EXPECT().Delete(gomock.Any(), testID, testUserID).Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -87,7 +92,7 @@ func TestCategoryAdapter_Delete(t *testing.T) {
 			name: "repository error",
 			id:   testID,
 			mockSetup: func() {
-				mockRepo.EXPECT().Delete(gomock.Any(), testID).Return(errors.New("db error"))
+				mockRepo.EXPECT().Delete(gomock.Any(), testID, testUserID).Return(errors.New("db error"))
 			},
 			expectedError: errors.New("failed to delete category"),
 		},
@@ -96,7 +101,7 @@ func TestCategoryAdapter_Delete(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockSetup()
-			err := adapter.Delete(context.Background(), tt.id)
+			err := adapter.Delete(context.Background(), tt.id, testUserID)
 
 			if tt.expectedError != nil {
 				assert.Contains(t, err.Error(), tt.expectedError.Error())
@@ -112,7 +117,7 @@ func TestCategoryAdapter_GetAll(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockRepo := mock_adapters.NewMockCategoryRepository(ctrl)
-	adapter := NewCategoryAdapter(mockRepo)
+	adapter := NewCategoryAdapter(mockRepo, inlineTransactor(ctrl))
 
 	testUserID := uuid.New()
 	testCategories := []models.Category{{ID: uuid.New(), Name: "Test 1"}, {ID: uuid.New(), Name: "Test 2"}}
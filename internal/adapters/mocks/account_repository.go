@@ -0,0 +1,126 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: todolist/internal/adapters (interfaces: IAccountRepository)
+
+// Package mock_adapters is a generated GoMock package.
+package mock_adapters
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+	models "todolist/internal/models"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockIAccountRepository is a mock of IAccountRepository interface.
+type MockIAccountRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIAccountRepositoryMockRecorder
+}
+
+// MockIAccountRepositoryMockRecorder is the mock recorder for MockIAccountRepository.
+type MockIAccountRepositoryMockRecorder struct {
+	mock *MockIAccountRepository
+}
+
+// NewMockIAccountRepository creates a new mock instance.
+func NewMockIAccountRepository(ctrl *gomock.Controller) *MockIAccountRepository {
+	mock := &MockIAccountRepository{ctrl: ctrl}
+	mock.recorder = &MockIAccountRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIAccountRepository) EXPECT() *MockIAccountRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CancelDeletion mocks base method.
+func (m *MockIAccountRepository) CancelDeletion(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelDeletion", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CancelDeletion indicates an expected call of CancelDeletion.
+func (mr *MockIAccountRepositoryMockRecorder) CancelDeletion(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelDeletion", reflect.TypeOf((*MockIAccountRepository)(nil).CancelDeletion), arg0, arg1)
+}
+
+// DeleteIfDue mocks base method.
+func (m *MockIAccountRepository) DeleteIfDue(arg0 context.Context, arg1 uuid.UUID, arg2 time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIfDue", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteIfDue indicates an expected call of DeleteIfDue.
+func (mr *MockIAccountRepositoryMockRecorder) DeleteIfDue(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIfDue", reflect.TypeOf((*MockIAccountRepository)(nil).DeleteIfDue), arg0, arg1, arg2)
+}
+
+// GetAccountData mocks base method.
+func (m *MockIAccountRepository) GetAccountData(arg0 context.Context, arg1 uuid.UUID) (*models.AccountData, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountData", arg0, arg1)
+	ret0, _ := ret[0].(*models.AccountData)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountData indicates an expected call of GetAccountData.
+func (mr *MockIAccountRepositoryMockRecorder) GetAccountData(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountData", reflect.TypeOf((*MockIAccountRepository)(nil).GetAccountData), arg0, arg1)
+}
+
+// ImportData mocks base method.
+func (m *MockIAccountRepository) ImportData(arg0 context.Context, arg1 uuid.UUID, arg2 *models.AccountData) (*models.ImportResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportData", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.ImportResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportData indicates an expected call of ImportData.
+func (mr *MockIAccountRepositoryMockRecorder) ImportData(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportData", reflect.TypeOf((*MockIAccountRepository)(nil).ImportData), arg0, arg1, arg2)
+}
+
+// ListDueDeletions mocks base method.
+func (m *MockIAccountRepository) ListDueDeletions(arg0 context.Context, arg1 time.Time) ([]uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDueDeletions", arg0, arg1)
+	ret0, _ := ret[0].([]uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDueDeletions indicates an expected call of ListDueDeletions.
+func (mr *MockIAccountRepositoryMockRecorder) ListDueDeletions(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDueDeletions", reflect.TypeOf((*MockIAccountRepository)(nil).ListDueDeletions), arg0, arg1)
+}
+
+// ScheduleDeletion mocks base method.
+func (m *MockIAccountRepository) ScheduleDeletion(arg0 context.Context, arg1 uuid.UUID, arg2 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleDeletion", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ScheduleDeletion indicates an expected call of ScheduleDeletion.
+func (mr *MockIAccountRepositoryMockRecorder) ScheduleDeletion(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleDeletion", reflect.TypeOf((*MockIAccountRepository)(nil).ScheduleDeletion), arg0, arg1, arg2)
+}
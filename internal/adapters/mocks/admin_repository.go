@@ -0,0 +1,109 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: todolist/internal/adapters (interfaces: IAdminRepository)
+
+// Package mock_adapters is a generated GoMock package.
+package mock_adapters
+
+import (
+	context "context"
+	reflect "reflect"
+	models "todolist/internal/models"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockIAdminRepository is a mock of IAdminRepository interface.
+type MockIAdminRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIAdminRepositoryMockRecorder
+}
+
+// MockIAdminRepositoryMockRecorder is the mock recorder for MockIAdminRepository.
+type MockIAdminRepositoryMockRecorder struct {
+	mock *MockIAdminRepository
+}
+
+// NewMockIAdminRepository creates a new mock instance.
+func NewMockIAdminRepository(ctrl *gomock.Controller) *MockIAdminRepository {
+	mock := &MockIAdminRepository{ctrl: ctrl}
+	mock.recorder = &MockIAdminRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIAdminRepository) EXPECT() *MockIAdminRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetUserSummary mocks base method.
+func (m *MockIAdminRepository) GetUserSummary(arg0 context.Context, arg1 uuid.UUID) (*models.UserSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserSummary", arg0, arg1)
+	ret0, _ := ret[0].(*models.UserSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserSummary indicates an expected call of GetUserSummary.
+func (mr *MockIAdminRepositoryMockRecorder) GetUserSummary(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSummary", reflect.TypeOf((*MockIAdminRepository)(nil).GetUserSummary), arg0, arg1)
+}
+
+// ListUsers mocks base method.
+func (m *MockIAdminRepository) ListUsers(arg0 context.Context, arg1 string, arg2, arg3 int) ([]models.UserSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]models.UserSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockIAdminRepositoryMockRecorder) ListUsers(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockIAdminRepository)(nil).ListUsers), arg0, arg1, arg2, arg3)
+}
+
+// RequirePasswordReset mocks base method.
+func (m *MockIAdminRepository) RequirePasswordReset(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequirePasswordReset", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequirePasswordReset indicates an expected call of RequirePasswordReset.
+func (mr *MockIAdminRepositoryMockRecorder) RequirePasswordReset(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequirePasswordReset", reflect.TypeOf((*MockIAdminRepository)(nil).RequirePasswordReset), arg0, arg1)
+}
+
+// SetUserDisabled mocks base method.
+func (m *MockIAdminRepository) SetUserDisabled(arg0 context.Context, arg1 uuid.UUID, arg2 bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserDisabled", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserDisabled indicates an expected call of SetUserDisabled.
+func (mr *MockIAdminRepositoryMockRecorder) SetUserDisabled(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserDisabled", reflect.TypeOf((*MockIAdminRepository)(nil).SetUserDisabled), arg0, arg1, arg2)
+}
+
+// SetUserRole mocks base method.
+func (m *MockIAdminRepository) SetUserRole(arg0 context.Context, arg1 uuid.UUID, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRole", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserRole indicates an expected call of SetUserRole.
+func (mr *MockIAdminRepositoryMockRecorder) SetUserRole(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRole", reflect.TypeOf((*MockIAdminRepository)(nil).SetUserRole), arg0, arg1, arg2)
+}
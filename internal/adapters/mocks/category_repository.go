@@ -37,11 +37,12 @@ func (m *MockCategoryRepository) EXPECT() *MockCategoryRepositoryMockRecorder {
 }
 
 // CreateCategory mocks base method.
-func (m *MockCategoryRepository) CreateCategory(arg0 context.Context, arg1 *models.CategoryBody) error {
+func (m *MockCategoryRepository) CreateCategory(arg0 context.Context, arg1 *models.CategoryBody) (*models.Category, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateCategory", arg0, arg1)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(*models.Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // CreateCategory indicates an expected call of CreateCategory.
@@ -51,17 +52,17 @@ func (mr *MockCategoryRepositoryMockRecorder) CreateCategory(arg0, arg1 interfac
 }
 
 // Delete mocks base method.
-func (m *MockCategoryRepository) Delete(arg0 context.Context, arg1 uuid.UUID) error {
+func (m *MockCategoryRepository) Delete(arg0 context.Context, arg1, arg2 uuid.UUID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockCategoryRepositoryMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockCategoryRepositoryMockRecorder) Delete(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCategoryRepository)(nil).Delete), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockCategoryRepository)(nil).Delete), arg0, arg1, arg2)
 }
 
 // GetAll mocks base method.
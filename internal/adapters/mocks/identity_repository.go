@@ -0,0 +1,66 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: todolist/internal/adapters (interfaces: IIdentityRepository)
+
+// Package mock_adapters is a generated GoMock package.
+package mock_adapters
+
+import (
+	context "context"
+	reflect "reflect"
+	models "todolist/internal/models"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIIdentityRepository is a mock of IIdentityRepository interface.
+type MockIIdentityRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIIdentityRepositoryMockRecorder
+}
+
+// MockIIdentityRepositoryMockRecorder is the mock recorder for MockIIdentityRepository.
+type MockIIdentityRepositoryMockRecorder struct {
+	mock *MockIIdentityRepository
+}
+
+// NewMockIIdentityRepository creates a new mock instance.
+func NewMockIIdentityRepository(ctrl *gomock.Controller) *MockIIdentityRepository {
+	mock := &MockIIdentityRepository{ctrl: ctrl}
+	mock.recorder = &MockIIdentityRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIIdentityRepository) EXPECT() *MockIIdentityRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateUserWithIdentity mocks base method.
+func (m *MockIIdentityRepository) CreateUserWithIdentity(arg0 context.Context, arg1 *models.UserAuth, arg2 models.ExternalIdentity) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUserWithIdentity", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUserWithIdentity indicates an expected call of CreateUserWithIdentity.
+func (mr *MockIIdentityRepositoryMockRecorder) CreateUserWithIdentity(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserWithIdentity", reflect.TypeOf((*MockIIdentityRepository)(nil).CreateUserWithIdentity), arg0, arg1, arg2)
+}
+
+// GetUserByIdentity mocks base method.
+func (m *MockIIdentityRepository) GetUserByIdentity(arg0 context.Context, arg1 models.ExternalIdentity) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByIdentity", arg0, arg1)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByIdentity indicates an expected call of GetUserByIdentity.
+func (mr *MockIIdentityRepositoryMockRecorder) GetUserByIdentity(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByIdentity", reflect.TypeOf((*MockIIdentityRepository)(nil).GetUserByIdentity), arg0, arg1)
+}
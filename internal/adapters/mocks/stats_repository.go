@@ -0,0 +1,52 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: todolist/internal/adapters (interfaces: IStatsRepository)
+
+// Package mock_adapters is a generated GoMock package.
+package mock_adapters
+
+import (
+	context "context"
+	reflect "reflect"
+	models "todolist/internal/models"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockIStatsRepository is a mock of IStatsRepository interface.
+type MockIStatsRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIStatsRepositoryMockRecorder
+}
+
+// MockIStatsRepositoryMockRecorder is the mock recorder for MockIStatsRepository.
+type MockIStatsRepositoryMockRecorder struct {
+	mock *MockIStatsRepository
+}
+
+// NewMockIStatsRepository creates a new mock instance.
+func NewMockIStatsRepository(ctrl *gomock.Controller) *MockIStatsRepository {
+	mock := &MockIStatsRepository{ctrl: ctrl}
+	mock.recorder = &MockIStatsRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIStatsRepository) EXPECT() *MockIStatsRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetStats mocks base method.
+func (m *MockIStatsRepository) GetStats(arg0 context.Context, arg1 uuid.UUID, arg2 models.StatsQuery) (*models.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStats", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStats indicates an expected call of GetStats.
+func (mr *MockIStatsRepositoryMockRecorder) GetStats(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStats", reflect.TypeOf((*MockIStatsRepository)(nil).GetStats), arg0, arg1, arg2)
+}
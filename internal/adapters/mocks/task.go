@@ -37,11 +37,13 @@ func (m *MockTaskRepository) EXPECT() *MockTaskRepositoryMockRecorder {
 }
 
 // CreateTask mocks base method.
-func (m *MockTaskRepository) CreateTask(ctx context.Context, userId uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) error {
+func (m *MockTaskRepository) CreateTask(ctx context.Context, userId uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) (*models.TaskFullInfo, bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateTask", ctx, userId, body, categoryIDs)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(*models.TaskFullInfo)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // CreateTask indicates an expected call of CreateTask.
@@ -51,17 +53,17 @@ func (mr *MockTaskRepositoryMockRecorder) CreateTask(ctx, userId, body, category
 }
 
 // Delete mocks base method.
-func (m *MockTaskRepository) Delete(ctx context.Context, id uuid.UUID) error {
+func (m *MockTaskRepository) Delete(ctx context.Context, id uuid.UUID, ifVersion int64) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id, ifVersion)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockTaskRepositoryMockRecorder) Delete(ctx, id interface{}) *gomock.Call {
+func (mr *MockTaskRepositoryMockRecorder) Delete(ctx, id, ifVersion interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTaskRepository)(nil).Delete), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockTaskRepository)(nil).Delete), ctx, id, ifVersion)
 }
 
 // GetAll mocks base method.
@@ -94,12 +96,60 @@ func (mr *MockTaskRepositoryMockRecorder) GetByID(ctx, id interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockTaskRepository)(nil).GetByID), ctx, id)
 }
 
+// List mocks base method.
+func (m *MockTaskRepository) List(ctx context.Context, userId uuid.UUID, filter models.TaskFilter, pageIndex, recordsPerPage int) ([]models.TaskShortInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, userId, filter, pageIndex, recordsPerPage)
+	ret0, _ := ret[0].([]models.TaskShortInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockTaskRepositoryMockRecorder) List(ctx, userId, filter, pageIndex, recordsPerPage interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockTaskRepository)(nil).List), ctx, userId, filter, pageIndex, recordsPerPage)
+}
+
+// Move mocks base method.
+func (m *MockTaskRepository) Move(ctx context.Context, id, statusID uuid.UUID, position int, ifVersion int64) (*models.TaskFullInfo, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Move", ctx, id, statusID, position, ifVersion)
+	ret0, _ := ret[0].(*models.TaskFullInfo)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Move indicates an expected call of Move.
+func (mr *MockTaskRepositoryMockRecorder) Move(ctx, id, statusID, position, ifVersion interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Move", reflect.TypeOf((*MockTaskRepository)(nil).Move), ctx, id, statusID, position, ifVersion)
+}
+
+// Patch mocks base method.
+func (m *MockTaskRepository) Patch(ctx context.Context, id uuid.UUID, patch *models.TaskPatch) (*models.TaskFullInfo, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Patch", ctx, id, patch)
+	ret0, _ := ret[0].(*models.TaskFullInfo)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Patch indicates an expected call of Patch.
+func (mr *MockTaskRepositoryMockRecorder) Patch(ctx, id, patch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Patch", reflect.TypeOf((*MockTaskRepository)(nil).Patch), ctx, id, patch)
+}
+
 // ToggleDone mocks base method.
-func (m *MockTaskRepository) ToggleDone(ctx context.Context, id uuid.UUID) error {
+func (m *MockTaskRepository) ToggleDone(ctx context.Context, id uuid.UUID) (bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ToggleDone", ctx, id)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // ToggleDone indicates an expected call of ToggleDone.
@@ -35,6 +35,21 @@ func (m *MockITokenHandler) EXPECT() *MockITokenHandlerMockRecorder {
 	return m.recorder
 }
 
+// GenerateChallengeToken mocks base method.
+func (m *MockITokenHandler) GenerateChallengeToken(arg0 models.User, arg1 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GenerateChallengeToken", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GenerateChallengeToken indicates an expected call of GenerateChallengeToken.
+func (mr *MockITokenHandlerMockRecorder) GenerateChallengeToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateChallengeToken", reflect.TypeOf((*MockITokenHandler)(nil).GenerateChallengeToken), arg0, arg1)
+}
+
 // GenerateToken mocks base method.
 func (m *MockITokenHandler) GenerateToken(arg0 models.User, arg1 string) (string, error) {
 	m.ctrl.T.Helper()
@@ -50,6 +65,21 @@ func (mr *MockITokenHandlerMockRecorder) GenerateToken(arg0, arg1 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateToken", reflect.TypeOf((*MockITokenHandler)(nil).GenerateToken), arg0, arg1)
 }
 
+// ParseChallengeToken mocks base method.
+func (m *MockITokenHandler) ParseChallengeToken(arg0, arg1 string) (*auth_utils.Payload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseChallengeToken", arg0, arg1)
+	ret0, _ := ret[0].(*auth_utils.Payload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseChallengeToken indicates an expected call of ParseChallengeToken.
+func (mr *MockITokenHandlerMockRecorder) ParseChallengeToken(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseChallengeToken", reflect.TypeOf((*MockITokenHandler)(nil).ParseChallengeToken), arg0, arg1)
+}
+
 // ParseToken mocks base method.
 func (m *MockITokenHandler) ParseToken(arg0, arg1 string) (*auth_utils.Payload, error) {
 	m.ctrl.T.Helper()
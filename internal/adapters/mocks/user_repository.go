@@ -94,6 +94,34 @@ func (mr *MockIUserRepositoryMockRecorder) DeleteUser(arg0, arg1 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockIUserRepository)(nil).DeleteUser), arg0, arg1)
 }
 
+// DisableTOTP mocks base method.
+func (m *MockIUserRepository) DisableTOTP(arg0 context.Context, arg1 uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockIUserRepositoryMockRecorder) DisableTOTP(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockIUserRepository)(nil).DisableTOTP), arg0, arg1)
+}
+
+// EnableTOTP mocks base method.
+func (m *MockIUserRepository) EnableTOTP(arg0 context.Context, arg1 uuid.UUID, arg2 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP.
+func (mr *MockIUserRepositoryMockRecorder) EnableTOTP(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockIUserRepository)(nil).EnableTOTP), arg0, arg1, arg2)
+}
+
 // GetUserByID mocks base method.
 func (m *MockIUserRepository) GetUserByID(arg0 context.Context, arg1 uuid.UUID) (*models.User, error) {
 	m.ctrl.T.Helper()
@@ -123,3 +151,75 @@ func (mr *MockIUserRepositoryMockRecorder) GetUserByName(arg0, arg1 interface{})
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByName", reflect.TypeOf((*MockIUserRepository)(nil).GetUserByName), arg0, arg1)
 }
+
+// RecordActivity mocks base method.
+func (m *MockIUserRepository) RecordActivity(arg0 context.Context, arg1 uuid.UUID, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordActivity", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordActivity indicates an expected call of RecordActivity.
+func (mr *MockIUserRepositoryMockRecorder) RecordActivity(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordActivity", reflect.TypeOf((*MockIUserRepository)(nil).RecordActivity), arg0, arg1, arg2)
+}
+
+// SetTOTPSecret mocks base method.
+func (m *MockIUserRepository) SetTOTPSecret(arg0 context.Context, arg1 uuid.UUID, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTOTPSecret", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTOTPSecret indicates an expected call of SetTOTPSecret.
+func (mr *MockIUserRepositoryMockRecorder) SetTOTPSecret(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTOTPSecret", reflect.TypeOf((*MockIUserRepository)(nil).SetTOTPSecret), arg0, arg1, arg2)
+}
+
+// UpdatePassword mocks base method.
+func (m *MockIUserRepository) UpdatePassword(arg0 context.Context, arg1 uuid.UUID, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePassword", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePassword indicates an expected call of UpdatePassword.
+func (mr *MockIUserRepositoryMockRecorder) UpdatePassword(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePassword", reflect.TypeOf((*MockIUserRepository)(nil).UpdatePassword), arg0, arg1, arg2)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockIUserRepository) UseRecoveryCode(arg0 context.Context, arg1 uuid.UUID, arg2 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockIUserRepositoryMockRecorder) UseRecoveryCode(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockIUserRepository)(nil).UseRecoveryCode), arg0, arg1, arg2)
+}
+
+// UseTOTPStep mocks base method.
+func (m *MockIUserRepository) UseTOTPStep(arg0 context.Context, arg1 uuid.UUID, arg2 int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseTOTPStep", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseTOTPStep indicates an expected call of UseTOTPStep.
+func (mr *MockIUserRepositoryMockRecorder) UseTOTPStep(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseTOTPStep", reflect.TypeOf((*MockIUserRepository)(nil).UseTOTPStep), arg0, arg1, arg2)
+}
@@ -0,0 +1,82 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: todolist/internal/adapters (interfaces: IWorkflowRepository)
+
+// Package mock_adapters is a generated GoMock package.
+package mock_adapters
+
+import (
+	context "context"
+	reflect "reflect"
+	models "todolist/internal/models"
+
+	gomock "github.com/golang/mock/gomock"
+	uuid "github.com/google/uuid"
+)
+
+// MockIWorkflowRepository is a mock of IWorkflowRepository interface.
+type MockIWorkflowRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIWorkflowRepositoryMockRecorder
+}
+
+// MockIWorkflowRepositoryMockRecorder is the mock recorder for MockIWorkflowRepository.
+type MockIWorkflowRepositoryMockRecorder struct {
+	mock *MockIWorkflowRepository
+}
+
+// NewMockIWorkflowRepository creates a new mock instance.
+func NewMockIWorkflowRepository(ctrl *gomock.Controller) *MockIWorkflowRepository {
+	mock := &MockIWorkflowRepository{ctrl: ctrl}
+	mock.recorder = &MockIWorkflowRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIWorkflowRepository) EXPECT() *MockIWorkflowRepositoryMockRecorder {
+	return m.recorder
+}
+
+// GetBoard mocks base method.
+func (m *MockIWorkflowRepository) GetBoard(arg0 context.Context, arg1 uuid.UUID) ([]models.BoardColumn, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBoard", arg0, arg1)
+	ret0, _ := ret[0].([]models.BoardColumn)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBoard indicates an expected call of GetBoard.
+func (mr *MockIWorkflowRepositoryMockRecorder) GetBoard(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBoard", reflect.TypeOf((*MockIWorkflowRepository)(nil).GetBoard), arg0, arg1)
+}
+
+// GetStatuses mocks base method.
+func (m *MockIWorkflowRepository) GetStatuses(arg0 context.Context, arg1 uuid.UUID) ([]models.TaskStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStatuses", arg0, arg1)
+	ret0, _ := ret[0].([]models.TaskStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStatuses indicates an expected call of GetStatuses.
+func (mr *MockIWorkflowRepositoryMockRecorder) GetStatuses(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStatuses", reflect.TypeOf((*MockIWorkflowRepository)(nil).GetStatuses), arg0, arg1)
+}
+
+// SetStatuses mocks base method.
+func (m *MockIWorkflowRepository) SetStatuses(arg0 context.Context, arg1 uuid.UUID, arg2 []models.StatusBody) ([]models.TaskStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetStatuses", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.TaskStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetStatuses indicates an expected call of SetStatuses.
+func (mr *MockIWorkflowRepositoryMockRecorder) SetStatuses(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetStatuses", reflect.TypeOf((*MockIWorkflowRepository)(nil).SetStatuses), arg0, arg1, arg2)
+}
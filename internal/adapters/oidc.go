@@ -0,0 +1,299 @@
+package adapters
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+	"todolist/internal/models"
+	auth_utils "todolist/internal/pkg/authUtils"
+	"todolist/internal/tracing"
+	"unicode/utf8"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+)
+
+const (
+	maxUserNameLength = 50
+	// maxUserNameAttempts bounds the suffixed names tried for a taken one.
+	maxUserNameAttempts = 10
+)
+
+type IIdentityRepository interface {
+	GetUserByIdentity(ctx context.Context, identity models.ExternalIdentity) (*models.User, error)
+	CreateUserWithIdentity(ctx context.Context, user *models.UserAuth, identity models.ExternalIdentity) (*models.User, error)
+}
+
+type OIDCOptions struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	StateTTL     time.Duration
+}
+
+const loginPurpose = "oidc_login"
+
+// loginClaims hold the per-login secrets between the redirect to the provider
+// and the callback. They travel signed in a cookie of the user agent which
+// started the login, so the callback works on any replica and a state issued
+// to another browser is refused.
+type loginClaims struct {
+	State    string `json:"state"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	Purpose  string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+type OIDCAdapter struct {
+	identityRepo IIdentityRepository
+	userRepo     IUserRepository
+	tokenHandler auth_utils.ITokenHandler
+	key          string
+	opts         OIDCOptions
+
+	providerMu sync.Mutex
+	provider   *oidc.Provider
+}
+
+func NewOIDCAdapter(identityRepo IIdentityRepository, userRepo IUserRepository, token auth_utils.ITokenHandler, k string, opts OIDCOptions) *OIDCAdapter {
+	return &OIDCAdapter{
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		tokenHandler: token,
+		key:          k,
+		opts:         opts,
+	}
+}
+
+// AuthCodeURL starts an authorization code flow with PKCE. It returns the
+// provider URL the user agent has to be redirected to and the login cookie
+// value it has to bring back to the callback.
+func (a *OIDCAdapter) AuthCodeURL(ctx context.Context) (string, string, error) {
+	ctx, span := tracing.Start(ctx, "OIDCAdapter.AuthCodeURL")
+	defer span.End()
+
+	provider, err := a.getProvider(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to generate state")
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to generate nonce")
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	login, err := jwt.NewWithClaims(jwt.SigningMethodHS256, loginClaims{
+		State:    state,
+		Verifier: verifier,
+		Nonce:    nonce,
+		Purpose:  loginPurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(a.opts.StateTTL)),
+		},
+	}).SignedString([]byte(a.key))
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to sign login state")
+	}
+
+	return a.oauthConfig(provider).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), login, nil
+}
+
+// Exchange finishes the flow started by AuthCodeURL: it checks the state
+// against the login cookie, redeems the code, verifies the ID token, finds or
+// creates the linked user and issues our own token for it.
+func (a *OIDCAdapter) Exchange(ctx context.Context, login, state, code string) (string, error) {
+	ctx, span := tracing.Start(ctx, "OIDCAdapter.Exchange")
+	defer span.End()
+
+	pending, err := a.parseLogin(login, state)
+	if err != nil {
+		return "", err
+	}
+
+	provider, err := a.getProvider(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	oauthToken, err := a.oauthConfig(provider).Exchange(ctx, code, oauth2.VerifierOption(pending.Verifier))
+	if err != nil {
+		return "", errors.Wrapf(models.ErrExternalAuth, "failed to exchange authorization code: %v", err)
+	}
+
+	rawIDToken, ok := oauthToken.Extra("id_token").(string)
+	if !ok {
+		return "", errors.Wrap(models.ErrExternalAuth, "no id_token in token response")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: a.opts.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return "", errors.Wrapf(models.ErrExternalAuth, "failed to verify id_token: %v", err)
+	}
+	if idToken.Nonce != pending.Nonce {
+		return "", errors.Wrap(models.ErrExternalAuth, "id_token nonce mismatch")
+	}
+
+	var claims struct {
+		PreferredUsername string `json:"preferred_username"`
+		Email             string `json:"email"`
+	}
+	if err = idToken.Claims(&claims); err != nil {
+		return "", errors.Wrap(err, "failed to parse id_token claims")
+	}
+
+	identity := models.ExternalIdentity{Issuer: idToken.Issuer, Subject: idToken.Subject}
+	user, err := a.identityRepo.GetUserByIdentity(ctx, identity)
+	if errors.Is(err, models.ErrUserNotFound) {
+		user, err = a.createUser(ctx, identity, claims.PreferredUsername, claims.Email)
+	}
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to get user for identity %s", identity.Subject)
+	}
+	if user.Disabled {
+		return "", errors.Wrapf(models.ErrUserDisabled, "Failed to sign in user %s", user.Name)
+	}
+
+	tokenStr, err := a.tokenHandler.GenerateToken(*user, a.key)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to generate token for user: %s", user.Name)
+	}
+	recordActivity(ctx, a.userRepo, user.ID, models.ActivitySignIn)
+	return tokenStr, nil
+}
+
+func (a *OIDCAdapter) createUser(ctx context.Context, identity models.ExternalIdentity, preferredName, email string) (*models.User, error) {
+	name, err := a.freeUserName(ctx, preferredName, email, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	// External users sign in through their provider only, so they get a
+	// password nobody knows.
+	password, err := randomToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate password")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error in generating hash for password")
+	}
+
+	user, err := a.identityRepo.CreateUserWithIdentity(ctx, &models.UserAuth{Name: name, Password: string(hash)}, identity)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create user: %s", name)
+	}
+	return user, nil
+}
+
+// freeUserName picks the first usable name from the claims, cut to
+// maxUserNameLength characters, and adds random suffixes until it finds one
+// no other account has.
+func (a *OIDCAdapter) freeUserName(ctx context.Context, candidates ...string) (string, error) {
+	name := ""
+	for _, candidate := range candidates {
+		if candidate != "" {
+			name = candidate
+			break
+		}
+	}
+	name = truncateName(name, maxUserNameLength)
+	// The suffix is a dash and six hex digits.
+	base := truncateName(name, maxUserNameLength-7)
+
+	candidate := name
+	for attempt := 0; ; attempt++ {
+		_, err := a.userRepo.GetUserByName(ctx, candidate)
+		if errors.Is(err, models.ErrUserNotFound) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", errors.Wrapf(err, "Failed to get user %s", candidate)
+		}
+		if attempt == maxUserNameAttempts {
+			return "", errors.Errorf("no free user name for %s", name)
+		}
+
+		suffix := make([]byte, 3)
+		if _, err = rand.Read(suffix); err != nil {
+			return "", errors.Wrap(err, "failed to generate user name suffix")
+		}
+		candidate = fmt.Sprintf("%s-%s", base, hex.EncodeToString(suffix))
+	}
+}
+
+// truncateName cuts name to at most n characters, never inside one.
+func truncateName(name string, n int) string {
+	if utf8.RuneCountInString(name) <= n {
+		return name
+	}
+	return string([]rune(name)[:n])
+}
+
+func (a *OIDCAdapter) getProvider(ctx context.Context) (*oidc.Provider, error) {
+	a.providerMu.Lock()
+	defer a.providerMu.Unlock()
+
+	if a.provider != nil {
+		return a.provider, nil
+	}
+
+	provider, err := oidc.NewProvider(ctx, a.opts.IssuerURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to discover OIDC provider %s", a.opts.IssuerURL)
+	}
+	a.provider = provider
+	return provider, nil
+}
+
+func (a *OIDCAdapter) oauthConfig(provider *oidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     a.opts.ClientID,
+		ClientSecret: a.opts.ClientSecret,
+		RedirectURL:  a.opts.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       a.opts.Scopes,
+	}
+}
+
+// parseLogin returns the secrets of the login cookie when its signature is
+// ours, it has not expired and it was issued with the state.
+func (a *OIDCAdapter) parseLogin(login, state string) (*loginClaims, error) {
+	claims := &loginClaims{}
+	_, err := jwt.ParseWithClaims(login, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return []byte(a.key), nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(models.ErrInvalidLoginState, err.Error())
+	}
+	if claims.Purpose != loginPurpose || subtle.ConstantTimeCompare([]byte(claims.State), []byte(state)) != 1 {
+		return nil, models.ErrInvalidLoginState
+	}
+	return claims, nil
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,434 @@
+package adapters
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	mock_adapters "todolist/internal/adapters/mocks"
+	"todolist/internal/models"
+	"unicode/utf8"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID = "todolist"
+	testKeyID    = "test-key"
+)
+
+type fakeGrant struct {
+	challenge string
+	nonce     string
+}
+
+// fakeOIDCProvider is a minimal OpenID Connect provider: discovery, JWKS and
+// a token endpoint which checks the PKCE verifier.
+type fakeOIDCProvider struct {
+	server  *httptest.Server
+	key     *rsa.PrivateKey
+	subject string
+	name    string
+
+	mu     sync.Mutex
+	grants map[string]fakeGrant
+}
+
+func newFakeOIDCProvider(t *testing.T, subject, name string) *fakeOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	p := &fakeOIDCProvider{
+		key:     key,
+		subject: subject,
+		name:    name,
+		grants:  make(map[string]fakeGrant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/keys", p.keys)
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+func (p *fakeOIDCProvider) discovery(w http.ResponseWriter, _ *http.Request) {
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                p.server.URL,
+		"authorization_endpoint":                p.server.URL + "/authorize",
+		"token_endpoint":                        p.server.URL + "/token",
+		"jwks_uri":                              p.server.URL + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (p *fakeOIDCProvider) keys(w http.ResponseWriter, _ *http.Request) {
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": testKeyID,
+			"alg": "RS256",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *fakeOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	grant, ok := p.grants[r.PostForm.Get("code")]
+	delete(p.grants, r.PostForm.Get("code"))
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                p.server.URL,
+		"sub":                p.subject,
+		"aud":                testClientID,
+		"iat":                time.Now().Unix(),
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"nonce":              grant.nonce,
+		"preferred_username": p.name,
+	})
+	idToken.Header["kid"] = testKeyID
+	signed, err := idToken.SignedString(p.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+// authorize plays the user agent: it follows the redirect URL, "logs in" and
+// returns the state and code the provider would send to the callback.
+func (p *fakeOIDCProvider) authorize(t *testing.T, authURL string) (string, string) {
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+
+	query := parsed.Query()
+	require.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	code := uuid.NewString()
+	p.mu.Lock()
+	p.grants[code] = fakeGrant{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	p.mu.Unlock()
+
+	return query.Get("state"), code
+}
+
+func TestOIDCAdapter_Exchange(t *testing.T) {
+	existingUser := &models.User{ID: uuid.New(), Name: "alice"}
+
+	tests := []struct {
+		name          string
+		mockSetup     func(identityRepo *mock_adapters.MockIIdentityRepository, userRepo *mock_adapters.MockIUserRepository, tokenHandler *mock_adapters.MockITokenHandler, issuer string)
+		tamper        func(login, state, code string) (string, string, string)
+		expectedToken string
+		expectedError error
+	}{
+		{
+			name: "first login creates user",
+			mockSetup: func(identityRepo *mock_adapters.MockIIdentityRepository, userRepo *mock_adapters.MockIUserRepository, tokenHandler *mock_adapters.MockITokenHandler, issuer string) {
+				identity := models.ExternalIdentity{Issuer: issuer, Subject: "subject-1"}
+				created := &models.User{ID: uuid.New(), Name: "alice"}
+				gomock.InOrder(
+					identityRepo.EXPECT().
+						GetUserByIdentity(gomock.Any(), identity).
+						Return(nil, models.ErrUserNotFound),
+					userRepo.EXPECT().
+						GetUserByName(gomock.Any(), "alice").
+						Return(nil, models.ErrUserNotFound),
+					identityRepo.EXPECT().
+						CreateUserWithIdentity(gomock.Any(), gomock.Any(), identity).
+						DoAndReturn(func(ctx context.Context, user *models.UserAuth, _ models.ExternalIdentity) (*models.User, error) {
+							assert.Equal(t, "alice", user.Name)
+							assert.NotEmpty(t, user.Password)
+							return created, nil
+						}),
+					tokenHandler.EXPECT().
+						GenerateToken(*created, "test-key").
+						Return("new-user-token", nil),
+					userRepo.EXPECT().
+						RecordActivity(gomock.Any(), created.ID, models.ActivitySignIn).
+						Return(nil),
+				)
+			},
+			expectedToken: "new-user-token",
+		},
+		{
+			name: "linked identity signs in existing user",
+			mockSetup: func(identityRepo *mock_adapters.MockIIdentityRepository, userRepo *mock_adapters.MockIUserRepository, tokenHandler *mock_adapters.MockITokenHandler, issuer string) {
+				gomock.InOrder(
+					identityRepo.EXPECT().
+						GetUserByIdentity(gomock.Any(), models.ExternalIdentity{Issuer: issuer, Subject: "subject-1"}).
+						Return(existingUser, nil),
+					tokenHandler.EXPECT().
+						GenerateToken(*existingUser, "test-key").
+						Return("existing-user-token", nil),
+					userRepo.EXPECT().
+						RecordActivity(gomock.Any(), existingUser.ID, models.ActivitySignIn).
+						Return(nil),
+				)
+			},
+			expectedToken: "existing-user-token",
+		},
+		{
+			name: "taken user name gets suffix",
+			mockSetup: func(identityRepo *mock_adapters.MockIIdentityRepository, userRepo *mock_adapters.MockIUserRepository, tokenHandler *mock_adapters.MockITokenHandler, issuer string) {
+				gomock.InOrder(
+					identityRepo.EXPECT().
+						GetUserByIdentity(gomock.Any(), gomock.Any()).
+						Return(nil, models.ErrUserNotFound),
+					userRepo.EXPECT().
+						GetUserByName(gomock.Any(), "alice").
+						Return(existingUser, nil),
+					userRepo.EXPECT().
+						GetUserByName(gomock.Any(), gomock.Any()).
+						Return(nil, models.ErrUserNotFound),
+					identityRepo.EXPECT().
+						CreateUserWithIdentity(gomock.Any(), gomock.Any(), gomock.Any()).
+						DoAndReturn(func(ctx context.Context, user *models.UserAuth, _ models.ExternalIdentity) (*models.User, error) {
+							assert.True(t, strings.HasPrefix(user.Name, "alice-"))
+							return &models.User{ID: uuid.New(), Name: user.Name}, nil
+						}),
+					tokenHandler.EXPECT().
+						GenerateToken(gomock.Any(), "test-key").
+						Return("suffixed-user-token", nil),
+					userRepo.EXPECT().
+						RecordActivity(gomock.Any(), gomock.Any(), models.ActivitySignIn).
+						Return(nil),
+				)
+			},
+			expectedToken: "suffixed-user-token",
+		},
+		{
+			name: "unknown state",
+			tamper: func(login, _, code string) (string, string, string) {
+				return login, "forged-state", code
+			},
+			expectedError: models.ErrInvalidLoginState,
+		},
+		{
+			name: "code redeemed without matching verifier",
+			tamper: func(login, state, _ string) (string, string, string) {
+				return login, state, "stolen-code"
+			},
+			expectedError: models.ErrExternalAuth,
+		},
+		{
+			name: "no login cookie",
+			tamper: func(_, state, code string) (string, string, string) {
+				return "", state, code
+			},
+			expectedError: models.ErrInvalidLoginState,
+		},
+		{
+			name: "login cookie signed with another key",
+			tamper: func(login, state, code string) (string, string, string) {
+				forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, loginClaims{State: state, Purpose: loginPurpose}).SignedString([]byte("other-key"))
+				require.NoError(t, err)
+				return forged, state, code
+			},
+			expectedError: models.ErrInvalidLoginState,
+		},
+		{
+			name: "auth token instead of login cookie",
+			tamper: func(_, state, code string) (string, string, string) {
+				token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"state": state}).SignedString([]byte("test-key"))
+				require.NoError(t, err)
+				return token, state, code
+			},
+			expectedError: models.ErrInvalidLoginState,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			provider := newFakeOIDCProvider(t, "subject-1", "alice")
+			identityRepo := mock_adapters.NewMockIIdentityRepository(ctrl)
+			userRepo := mock_adapters.NewMockIUserRepository(ctrl)
+			tokenHandler := mock_adapters.NewMockITokenHandler(ctrl)
+			if tt.mockSetup != nil {
+				tt.mockSetup(identityRepo, userRepo, tokenHandler, provider.server.URL)
+			}
+
+			adapter := NewOIDCAdapter(identityRepo, userRepo, tokenHandler, "test-key", OIDCOptions{
+				IssuerURL:   provider.server.URL,
+				ClientID:    testClientID,
+				RedirectURL: "http://localhost:8080/api/v1/oidc/callback",
+				Scopes:      []string{"openid", "profile"},
+				StateTTL:    time.Minute,
+			})
+
+			authURL, login, err := adapter.AuthCodeURL(context.Background())
+			require.NoError(t, err)
+
+			state, code := provider.authorize(t, authURL)
+			if tt.tamper != nil {
+				login, state, code = tt.tamper(login, state, code)
+			}
+
+			token, err := adapter.Exchange(context.Background(), login, state, code)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedToken, token)
+			}
+		})
+	}
+}
+
+func TestOIDCAdapter_LoginIsBoundToBrowser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := newFakeOIDCProvider(t, "subject-1", "alice")
+	adapter := NewOIDCAdapter(mock_adapters.NewMockIIdentityRepository(ctrl), mock_adapters.NewMockIUserRepository(ctrl), mock_adapters.NewMockITokenHandler(ctrl), "test-key", OIDCOptions{
+		IssuerURL: provider.server.URL,
+		ClientID:  testClientID,
+		StateTTL:  time.Minute,
+	})
+
+	// The attacker starts a login and lures the victim to the callback with
+	// the attacker's state and code; the victim's browser has its own cookie.
+	attackerURL, _, err := adapter.AuthCodeURL(context.Background())
+	require.NoError(t, err)
+	_, victimLogin, err := adapter.AuthCodeURL(context.Background())
+	require.NoError(t, err)
+	state, code := provider.authorize(t, attackerURL)
+
+	_, err = adapter.Exchange(context.Background(), victimLogin, state, code)
+	assert.ErrorIs(t, err, models.ErrInvalidLoginState)
+}
+
+func TestOIDCAdapter_LoginExpires(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := newFakeOIDCProvider(t, "subject-1", "alice")
+	adapter := NewOIDCAdapter(mock_adapters.NewMockIIdentityRepository(ctrl), mock_adapters.NewMockIUserRepository(ctrl), mock_adapters.NewMockITokenHandler(ctrl), "test-key", OIDCOptions{
+		IssuerURL: provider.server.URL,
+		ClientID:  testClientID,
+		StateTTL:  -time.Minute,
+	})
+
+	authURL, login, err := adapter.AuthCodeURL(context.Background())
+	require.NoError(t, err)
+	state, code := provider.authorize(t, authURL)
+
+	_, err = adapter.Exchange(context.Background(), login, state, code)
+	assert.ErrorIs(t, err, models.ErrInvalidLoginState)
+}
+
+func TestOIDCAdapter_CodeIsSingleUse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	provider := newFakeOIDCProvider(t, "subject-1", "alice")
+	identityRepo := mock_adapters.NewMockIIdentityRepository(ctrl)
+	tokenHandler := mock_adapters.NewMockITokenHandler(ctrl)
+	user := &models.User{ID: uuid.New(), Name: "alice"}
+
+	identityRepo.EXPECT().GetUserByIdentity(gomock.Any(), gomock.Any()).Return(user, nil)
+	tokenHandler.EXPECT().GenerateToken(*user, "test-key").Return("token", nil)
+	userRepo := mock_adapters.NewMockIUserRepository(ctrl)
+	userRepo.EXPECT().RecordActivity(gomock.Any(), user.ID, models.ActivitySignIn).Return(nil)
+
+	adapter := NewOIDCAdapter(identityRepo, userRepo, tokenHandler, "test-key", OIDCOptions{
+		IssuerURL: provider.server.URL,
+		ClientID:  testClientID,
+		StateTTL:  time.Minute,
+	})
+
+	authURL, login, err := adapter.AuthCodeURL(context.Background())
+	require.NoError(t, err)
+	state, code := provider.authorize(t, authURL)
+
+	_, err = adapter.Exchange(context.Background(), login, state, code)
+	require.NoError(t, err)
+
+	// The callback clears the cookie; a copy of it is still useless because
+	// the provider redeems a code once.
+	_, err = adapter.Exchange(context.Background(), login, state, code)
+	assert.ErrorIs(t, err, models.ErrExternalAuth)
+}
+
+func TestOIDCAdapter_FreeUserName(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepo := mock_adapters.NewMockIUserRepository(ctrl)
+	adapter := NewOIDCAdapter(mock_adapters.NewMockIIdentityRepository(ctrl), userRepo, mock_adapters.NewMockITokenHandler(ctrl), "test-key", OIDCOptions{})
+	ctx := context.Background()
+	long := strings.Repeat("ж", maxUserNameLength+10)
+
+	userRepo.EXPECT().GetUserByName(gomock.Any(), gomock.Any()).Return(nil, models.ErrUserNotFound)
+	name, err := adapter.freeUserName(ctx, "", long)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("ж", maxUserNameLength), name, "cut by characters, not bytes")
+
+	// The name and the first suffixed one are both taken.
+	var tried []string
+	userRepo.EXPECT().
+		GetUserByName(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, name string) (*models.User, error) {
+			tried = append(tried, name)
+			if len(tried) < 3 {
+				return &models.User{Name: name}, nil
+			}
+			return nil, models.ErrUserNotFound
+		}).
+		Times(3)
+	name, err = adapter.freeUserName(ctx, long)
+	require.NoError(t, err)
+	require.Len(t, tried, 3)
+	assert.Equal(t, tried[2], name, "the suffixed candidate is checked too")
+	assert.NotEqual(t, tried[1], tried[2])
+	assert.True(t, strings.HasPrefix(name, strings.Repeat("ж", maxUserNameLength-7)+"-"))
+	assert.Equal(t, maxUserNameLength, utf8.RuneCountInString(name))
+
+	userRepo.EXPECT().GetUserByName(gomock.Any(), gomock.Any()).Return(&models.User{}, nil).Times(maxUserNameAttempts + 1)
+	_, err = adapter.freeUserName(ctx, "alice")
+	assert.Error(t, err, "gives up when every candidate is taken")
+}
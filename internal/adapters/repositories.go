@@ -0,0 +1,20 @@
+package adapters
+
+import "todolist/internal/models"
+
+// Repositories is the storage behind the adapters. The SQL set, for Postgres
+// or SQLite, and the in-memory one are put together by the caller, so the
+// repository packages do not depend on this one.
+type Repositories struct {
+	Tasks      TaskRepository
+	Categories CategoryRepository
+	Users      IUserRepository
+	Identities IIdentityRepository
+	Admin      IAdminRepository
+	Accounts   IAccountRepository
+	Stats      IStatsRepository
+	Workflow   IWorkflowRepository
+	// Transactor groups calls on the repositories above into one unit of
+	// work.
+	Transactor models.Transactor
+}
@@ -0,0 +1,34 @@
+package adapters
+
+import (
+	"context"
+	"todolist/internal/models"
+	"todolist/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type IStatsRepository interface {
+	// GetStats summarises the tasks of the user, see models.Stats.
+	GetStats(ctx context.Context, userID uuid.UUID, query models.StatsQuery) (*models.Stats, error)
+}
+
+type StatsAdapter struct {
+	repository IStatsRepository
+}
+
+func NewStatsAdapter(repository IStatsRepository) *StatsAdapter {
+	return &StatsAdapter{repository: repository}
+}
+
+func (s *StatsAdapter) GetStats(ctx context.Context, userID uuid.UUID, query models.StatsQuery) (*models.Stats, error) {
+	ctx, span := tracing.Start(ctx, "StatsAdapter.GetStats")
+	defer span.End()
+
+	stats, err := s.repository.GetStats(ctx, userID, query)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get stats of user with id: %s", userID)
+	}
+	return stats, nil
+}
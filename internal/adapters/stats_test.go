@@ -0,0 +1,63 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+	"time"
+	mock_adapters "todolist/internal/adapters/mocks"
+	"todolist/internal/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsAdapter_GetStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockIStatsRepository(ctrl)
+	adapter := NewStatsAdapter(mockRepo)
+
+	userID := uuid.New()
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	query := models.StatsQuery{From: from, To: from.AddDate(0, 0, 7), Period: models.PeriodDay, Now: from.AddDate(0, 0, 6)}
+	stats := &models.Stats{Open: 2, Completed: 3, Completions: []models.PeriodCount{{Start: from, Count: 3}}, Streak: 1}
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		expected      *models.Stats
+		expectedError error
+	}{
+		{
+			name: "successful stats",
+			mockSetup: func() {
+				mockRepo.EXPECT().GetStats(gomock.Any(), userID, query).Return(stats, nil)
+			},
+			expected: stats,
+		},
+		{
+			name: "repository error",
+			mockSetup: func() {
+				mockRepo.EXPECT().GetStats(gomock.Any(), userID, query).Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("failed to get stats of user with id: " + userID.String()),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			result, err := adapter.GetStats(context.Background(), userID, query)
+
+			if tt.expectedError != nil {
+				assert.Contains(t, err.Error(), tt.expectedError.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
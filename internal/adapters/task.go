@@ -2,7 +2,9 @@ package adapters
 
 import (
 	"context"
+	"todolist/internal/metrics"
 	"todolist/internal/models"
+	"todolist/internal/tracing"
 
 	"github.com/pkg/errors"
 
@@ -11,32 +13,58 @@ import (
 
 //go:generate mockgen -source=task.go -destination=mocks/task.go
 type TaskRepository interface {
-	CreateTask(ctx context.Context, userId uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) error
+	// CreateTask returns the created task, or the one created earlier with
+	// the same body.IdempotencyKey; the flag is true for a new task.
+	CreateTask(ctx context.Context, userId uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) (*models.TaskFullInfo, bool, error)
 	Update(ctx context.Context, id uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.TaskFullInfo, error)
 	GetAll(ctx context.Context, userId uuid.UUID, pageIndex, recordsPerPage int) ([]models.TaskShortInfo, error)
-	Delete(ctx context.Context, id uuid.UUID) error
-	ToggleDone(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, userId uuid.UUID, filter models.TaskFilter, pageIndex, recordsPerPage int) ([]models.TaskShortInfo, error)
+	// Patch changes only the fields set in patch and returns the result. The
+	// flag is true when the patch completed the task.
+	Patch(ctx context.Context, id uuid.UUID, patch *models.TaskPatch) (*models.TaskFullInfo, bool, error)
+	// Delete fails with ErrTaskModified when ifVersion is not zero and the
+	// task has another version.
+	Delete(ctx context.Context, id uuid.UUID, ifVersion int64) error
+	// ToggleDone returns the new state of the task.
+	ToggleDone(ctx context.Context, id uuid.UUID) (bool, error)
+	// Move puts the task at position in the column of statusID and returns
+	// the result. It fails with ErrStatusNotFound when the status is not in
+	// the workflow of the owner of the task. The flag is true when the move
+	// completed the task.
+	Move(ctx context.Context, id, statusID uuid.UUID, position int, ifVersion int64) (*models.TaskFullInfo, bool, error)
 }
 
+// TaskAdapter embeds the Transactor it shares with the other adapters, so a
+// unit of work begun on it also covers their calls. The task counters are
+// incremented only once the unit of work a change belongs to commits.
 type TaskAdapter struct {
+	models.Transactor
 	repository TaskRepository
 }
 
-func NewTaskAdapter(repository TaskRepository) *TaskAdapter {
-	return &TaskAdapter{repository: repository}
+func NewTaskAdapter(repository TaskRepository, transactor models.Transactor) *TaskAdapter {
+	return &TaskAdapter{Transactor: transactor, repository: repository}
 }
 
-func (t *TaskAdapter) CreateTask(ctx context.Context, userId uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) error {
-	err := t.repository.CreateTask(ctx, userId, body, categoryIDs)
+func (t *TaskAdapter) CreateTask(ctx context.Context, userId uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) (*models.TaskFullInfo, error) {
+	ctx, span := tracing.Start(ctx, "TaskAdapter.CreateTask")
+	defer span.End()
+
+	task, created, err := t.repository.CreateTask(ctx, userId, body, categoryIDs)
 	if err != nil {
-		return errors.Wrap(err, "failed to create task")
+		return nil, errors.Wrap(err, "failed to create task")
 	}
-
-	return nil
+	if created {
+		t.AfterCommit(ctx, metrics.TasksCreated.Inc)
+	}
+	return task, nil
 }
 
 func (t *TaskAdapter) Update(ctx context.Context, id uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) error {
+	ctx, span := tracing.Start(ctx, "TaskAdapter.Update")
+	defer span.End()
+
 	err := t.repository.Update(ctx, id, body, categoryIDs)
 	if err != nil {
 		return errors.Wrapf(err, "failed to update task with id: %s", id)
@@ -44,7 +72,24 @@ func (t *TaskAdapter) Update(ctx context.Context, id uuid.UUID, body *models.Tas
 	return nil
 }
 
+func (t *TaskAdapter) Patch(ctx context.Context, id uuid.UUID, patch *models.TaskPatch) (*models.TaskFullInfo, error) {
+	ctx, span := tracing.Start(ctx, "TaskAdapter.Patch")
+	defer span.End()
+
+	task, completed, err := t.repository.Patch(ctx, id, patch)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to patch task with id: %s", id)
+	}
+	if completed {
+		t.AfterCommit(ctx, metrics.TasksCompleted.Inc)
+	}
+	return task, nil
+}
+
 func (t *TaskAdapter) GetByID(ctx context.Context, id uuid.UUID) (*models.TaskFullInfo, error) {
+	ctx, span := tracing.Start(ctx, "TaskAdapter.GetByID")
+	defer span.End()
+
 	task, err := t.repository.GetByID(ctx, id)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to get task by id: %s", id)
@@ -53,6 +98,9 @@ func (t *TaskAdapter) GetByID(ctx context.Context, id uuid.UUID) (*models.TaskFu
 }
 
 func (t *TaskAdapter) GetAll(ctx context.Context, userId uuid.UUID, pageIndex, recordsPerPage int) ([]models.TaskShortInfo, error) {
+	ctx, span := tracing.Start(ctx, "TaskAdapter.GetAll")
+	defer span.End()
+
 	tasks, err := t.repository.GetAll(ctx, userId, pageIndex, recordsPerPage)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get all tasks")
@@ -60,18 +108,52 @@ func (t *TaskAdapter) GetAll(ctx context.Context, userId uuid.UUID, pageIndex, r
 	return tasks, nil
 }
 
-func (t *TaskAdapter) Delete(ctx context.Context, id uuid.UUID) error {
-	err := t.repository.Delete(ctx, id)
+func (t *TaskAdapter) List(ctx context.Context, userId uuid.UUID, filter models.TaskFilter, pageIndex, recordsPerPage int) ([]models.TaskShortInfo, error) {
+	ctx, span := tracing.Start(ctx, "TaskAdapter.List")
+	defer span.End()
+
+	tasks, err := t.repository.List(ctx, userId, filter, pageIndex, recordsPerPage)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list tasks")
+	}
+	return tasks, nil
+}
+
+func (t *TaskAdapter) Delete(ctx context.Context, id uuid.UUID, ifVersion int64) error {
+	ctx, span := tracing.Start(ctx, "TaskAdapter.Delete")
+	defer span.End()
+
+	err := t.repository.Delete(ctx, id, ifVersion)
 	if err != nil {
 		return errors.Wrapf(err, "failed to delete task with id: %s", id)
 	}
 	return nil
 }
 
-func (t *TaskAdapter) ToggleDone(ctx context.Context, id uuid.UUID) error {
-	err := t.repository.ToggleDone(ctx, id)
+func (t *TaskAdapter) ToggleDone(ctx context.Context, id uuid.UUID) (bool, error) {
+	ctx, span := tracing.Start(ctx, "TaskAdapter.ToggleDone")
+	defer span.End()
+
+	isDone, err := t.repository.ToggleDone(ctx, id)
 	if err != nil {
-		return errors.Wrapf(err, "failed to toggle task done status with id: %s", id)
+		return false, errors.Wrapf(err, "failed to toggle task done status with id: %s", id)
 	}
-	return nil
+	if isDone {
+		t.AfterCommit(ctx, metrics.TasksCompleted.Inc)
+	}
+	return isDone, nil
+}
+
+func (t *TaskAdapter) Move(ctx context.Context, id, statusID uuid.UUID, position int, ifVersion int64) (*models.TaskFullInfo, error) {
+	ctx, span := tracing.Start(ctx, "TaskAdapter.Move")
+	defer span.End()
+
+	task, completed, err := t.repository.Move(ctx, id, statusID, position, ifVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to move task with id: %s", id)
+	}
+	if completed {
+		t.AfterCommit(ctx, metrics.TasksCompleted.Inc)
+	}
+	return task, nil
 }
@@ -4,23 +4,29 @@ import (
 	"context"
 	"testing"
 	mock_adapters "todolist/internal/adapters/mocks"
+	"todolist/internal/metrics"
 	"todolist/internal/models"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestTaskAdapter_CreateTask(t *testing.T) {
 	type mockBehavior func(r *mock_adapters.MockTaskRepository, ctx context.Context, userID uuid.UUID, body *models.TaskBody, catIDs []uuid.UUID)
 
+	created := &models.TaskFullInfo{ID: uuid.New(), Title: "task"}
+
 	testTable := []struct {
 		name        string
 		userID      uuid.UUID
 		body        *models.TaskBody
 		categoryIDs []uuid.UUID
 		mock        mockBehavior
+		expected    *models.TaskFullInfo
 		expectedErr error
 	}{
 		{
@@ -29,8 +35,9 @@ func TestTaskAdapter_CreateTask(t *testing.T) {
 			body:        &models.TaskBody{Title: "task"},
 			categoryIDs: []uuid.UUID{uuid.New()},
 			mock: func(r *mock_adapters.MockTaskRepository, ctx context.Context, userID uuid.UUID, body *models.TaskBody, catIDs []uuid.UUID) {
-				r.EXPECT().CreateTask(ctx, userID, body, catIDs).Return(nil)
+				r.EXPECT().CreateTask(gomock.Any(), userID, body, catIDs).Return(created, true, nil)
 			},
+			expected:    created,
 			expectedErr: nil,
 		},
 		{
@@ -39,7 +46,7 @@ func TestTaskAdapter_CreateTask(t *testing.T) {
 			body:        &models.TaskBody{Title: "fail"},
 			categoryIDs: []uuid.UUID{uuid.New()},
 			mock: func(r *mock_adapters.MockTaskRepository, ctx context.Context, userID uuid.UUID, body *models.TaskBody, catIDs []uuid.UUID) {
-				r.EXPECT().CreateTask(ctx, userID, body, catIDs).Return(errors.New("repo error"))
+				r.EXPECT().CreateTask(gomock.Any(), userID, body, catIDs).Return(nil, false, errors.New("repo error"))
 			},
 			expectedErr: errors.Wrap(errors.New("repo error"), "failed to create task"),
 		},
@@ -54,18 +61,59 @@ func TestTaskAdapter_CreateTask(t *testing.T) {
 			ctx := context.Background()
 			tc.mock(mockRepo, ctx, tc.userID, tc.body, tc.categoryIDs)
 
-			adapter := NewTaskAdapter(mockRepo)
-			err := adapter.CreateTask(ctx, tc.userID, tc.body, tc.categoryIDs)
+			adapter := NewTaskAdapter(mockRepo, inlineTransactor(ctrl))
+			task, err := adapter.CreateTask(ctx, tc.userID, tc.body, tc.categoryIDs)
 
 			if tc.expectedErr != nil {
 				assert.EqualError(t, err, tc.expectedErr.Error())
 			} else {
 				assert.NoError(t, err)
 			}
+			assert.Equal(t, tc.expected, task)
 		})
 	}
 }
 
+func TestTaskAdapter_CountsAfterCommit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockTaskRepository(ctrl)
+	transactor := mock_adapters.NewMockTransactor(ctrl)
+	adapter := NewTaskAdapter(mockRepo, transactor)
+	ctx := context.Background()
+	userID := uuid.New()
+	body := &models.TaskBody{Title: "task", IdempotencyKey: "key"}
+
+	var queued []func()
+	transactor.EXPECT().
+		AfterCommit(gomock.Any(), gomock.Any()).
+		Do(func(ctx context.Context, fn func()) { queued = append(queued, fn) }).
+		Times(2)
+	gomock.InOrder(
+		mockRepo.EXPECT().CreateTask(gomock.Any(), userID, body, nil).Return(&models.TaskFullInfo{}, true, nil),
+		mockRepo.EXPECT().CreateTask(gomock.Any(), userID, body, nil).Return(&models.TaskFullInfo{}, false, nil),
+	)
+	mockRepo.EXPECT().ToggleDone(gomock.Any(), gomock.Any()).Return(true, nil)
+
+	created := testutil.ToFloat64(metrics.TasksCreated)
+	completed := testutil.ToFloat64(metrics.TasksCompleted)
+	_, err := adapter.CreateTask(ctx, userID, body, nil)
+	require.NoError(t, err)
+	_, err = adapter.CreateTask(ctx, userID, body, nil)
+	require.NoError(t, err, "a replay of the same key creates nothing")
+	_, err = adapter.ToggleDone(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, created, testutil.ToFloat64(metrics.TasksCreated), "nothing counts before the commit")
+	assert.Equal(t, completed, testutil.ToFloat64(metrics.TasksCompleted))
+
+	for _, fn := range queued {
+		fn()
+	}
+	assert.Equal(t, created+1, testutil.ToFloat64(metrics.TasksCreated))
+	assert.Equal(t, completed+1, testutil.ToFloat64(metrics.TasksCompleted))
+}
+
 func TestTaskAdapter_Update(t *testing.T) {
 	type mockBehavior func(r *mock_adapters.MockTaskRepository, ctx context.Context, taskID uuid.UUID, body *models.TaskBody, catIDs []uuid.UUID)
 
@@ -83,7 +131,7 @@ func TestTaskAdapter_Update(t *testing.T) {
 			body:        &models.TaskBody{Title: "updated title"},
 			categoryIDs: []uuid.UUID{uuid.New()},
 			mock: func(r *mock_adapters.MockTaskRepository, ctx context.Context, taskID uuid.UUID, body *models.TaskBody, catIDs []uuid.UUID) {
-				r.EXPECT().Update(ctx, taskID, body, catIDs).Return(nil)
+				r.EXPECT().Update(gomock.Any(), taskID, body, catIDs).Return(nil)
 			},
 			expectedErr: nil,
 		},
@@ -93,7 +141,7 @@ func TestTaskAdapter_Update(t *testing.T) {
 			body:        &models.TaskBody{Title: "will fail"},
 			categoryIDs: []uuid.UUID{uuid.New()},
 			mock: func(r *mock_adapters.MockTaskRepository, ctx context.Context, taskID uuid.UUID, body *models.TaskBody, catIDs []uuid.UUID) {
-				r.EXPECT().Update(ctx, taskID, body, catIDs).Return(errors.New("update failed"))
+				r.EXPECT().Update(gomock.Any(), taskID, body, catIDs).Return(errors.New("update failed"))
 			},
 			expectedErr: errors.New("update failed"),
 		},
@@ -108,7 +156,7 @@ func TestTaskAdapter_Update(t *testing.T) {
 			ctx := context.Background()
 			tc.mock(mockRepo, ctx, tc.taskID, tc.body, tc.categoryIDs)
 
-			adapter := NewTaskAdapter(mockRepo)
+			adapter := NewTaskAdapter(mockRepo, inlineTransactor(ctrl))
 			err := adapter.Update(ctx, tc.taskID, tc.body, tc.categoryIDs)
 
 			if tc.expectedErr != nil {
@@ -138,7 +186,7 @@ func TestTaskAdapter_GetByID(t *testing.T) {
 					ID:    taskID,
 					Title: "Test Task",
 				}
-				r.EXPECT().GetByID(ctx, taskID).Return(task, nil)
+				r.EXPECT().GetByID(gomock.Any(), taskID).Return(task, nil)
 			},
 			expectedTask: &models.TaskFullInfo{
 				ID:    uuid.Nil, // заменим позже в тесте
@@ -150,7 +198,7 @@ func TestTaskAdapter_GetByID(t *testing.T) {
 			name:   "repository error",
 			taskID: uuid.New(),
 			mock: func(r *mock_adapters.MockTaskRepository, ctx context.Context, taskID uuid.UUID) {
-				r.EXPECT().GetByID(ctx, taskID).Return(nil, errors.New("not found"))
+				r.EXPECT().GetByID(gomock.Any(), taskID).Return(nil, errors.New("not found"))
 			},
 			expectedTask: nil,
 			expectedErr:  errors.New("not found"),
@@ -172,7 +220,7 @@ func TestTaskAdapter_GetByID(t *testing.T) {
 
 			tc.mock(mockRepo, ctx, tc.taskID)
 
-			adapter := NewTaskAdapter(mockRepo)
+			adapter := NewTaskAdapter(mockRepo, inlineTransactor(ctrl))
 			task, err := adapter.GetByID(ctx, tc.taskID)
 
 			assert.Equal(t, tc.expectedTask, task)
@@ -208,7 +256,7 @@ func TestTaskAdapter_GetAll(t *testing.T) {
 					{ID: uuid.New(), Title: "Task 1"},
 					{ID: uuid.New(), Title: "Task 2"},
 				}
-				r.EXPECT().GetAll(ctx, userID, pageIndex, recordsPerPage).Return(tasks, nil)
+				r.EXPECT().GetAll(gomock.Any(), userID, pageIndex, recordsPerPage).Return(tasks, nil)
 			},
 			expectedTasks: []models.TaskShortInfo{
 				{Title: "Task 1"},
@@ -222,7 +270,7 @@ func TestTaskAdapter_GetAll(t *testing.T) {
 			pageIndex:      2,
 			recordsPerPage: 5,
 			mock: func(r *mock_adapters.MockTaskRepository, ctx context.Context, userID uuid.UUID, pageIndex, recordsPerPage int) {
-				r.EXPECT().GetAll(ctx, userID, pageIndex, recordsPerPage).Return(nil, errors.New("db error"))
+				r.EXPECT().GetAll(gomock.Any(), userID, pageIndex, recordsPerPage).Return(nil, errors.New("db error"))
 			},
 			expectedTasks: nil,
 			expectedErr:   errors.Wrap(errors.New("db error"), "failed to get all tasks"),
@@ -238,7 +286,7 @@ func TestTaskAdapter_GetAll(t *testing.T) {
 			ctx := context.Background()
 			tc.mock(mockRepo, ctx, tc.userID, tc.pageIndex, tc.recordsPerPage)
 
-			adapter := NewTaskAdapter(mockRepo)
+			adapter := NewTaskAdapter(mockRepo, inlineTransactor(ctrl))
 			tasks, err := adapter.GetAll(ctx, tc.userID, tc.pageIndex, tc.recordsPerPage)
 
 			if tc.expectedErr != nil {
@@ -268,7 +316,7 @@ func TestTaskAdapter_Delete(t *testing.T) {
 			name:   "success",
 			taskID: uuid.New(),
 			mock: func(r *mock_adapters.MockTaskRepository, ctx context.Context, taskID uuid.UUID) {
-				r.EXPECT().Delete(ctx, taskID).Return(nil)
+				r.EXPECT().Delete(gomock.Any(), taskID, int64(3)).Return(nil)
 			},
 			expectedErr: nil,
 		},
@@ -276,9 +324,9 @@ func TestTaskAdapter_Delete(t *testing.T) {
 			name:   "repository error",
 			taskID: uuid.New(),
 			mock: func(r *mock_adapters.MockTaskRepository, ctx context.Context, taskID uuid.UUID) {
-				r.EXPECT().Delete(ctx, taskID).Return(errors.New("delete failed"))
+				r.EXPECT().Delete(gomock.Any(), taskID, int64(3)).Return(models.ErrTaskModified)
 			},
-			expectedErr: errors.New("delete failed"),
+			expectedErr: models.ErrTaskModified,
 		},
 	}
 
@@ -292,11 +340,11 @@ func TestTaskAdapter_Delete(t *testing.T) {
 
 			tc.mock(mockRepo, ctx, tc.taskID)
 
-			adapter := NewTaskAdapter(mockRepo)
-			err := adapter.Delete(ctx, tc.taskID)
+			adapter := NewTaskAdapter(mockRepo, inlineTransactor(ctrl))
+			err := adapter.Delete(ctx, tc.taskID, 3)
 
 			if tc.expectedErr != nil {
-				assert.Error(t, err)
+				assert.ErrorIs(t, err, tc.expectedErr)
 			} else {
 				assert.NoError(t, err)
 			}
@@ -311,21 +359,23 @@ func TestTaskAdapter_ToggleDone(t *testing.T) {
 		name        string
 		taskID      uuid.UUID
 		mock        mockBehavior
+		expected    bool
 		expectedErr error
 	}{
 		{
 			name:   "success",
 			taskID: uuid.New(),
 			mock: func(r *mock_adapters.MockTaskRepository, ctx context.Context, taskID uuid.UUID) {
-				r.EXPECT().ToggleDone(ctx, taskID).Return(nil)
+				r.EXPECT().ToggleDone(gomock.Any(), taskID).Return(true, nil)
 			},
+			expected:    true,
 			expectedErr: nil,
 		},
 		{
 			name:   "repository error",
 			taskID: uuid.New(),
 			mock: func(r *mock_adapters.MockTaskRepository, ctx context.Context, taskID uuid.UUID) {
-				r.EXPECT().ToggleDone(ctx, taskID).Return(errors.New("toggle failed"))
+				r.EXPECT().ToggleDone(gomock.Any(), taskID).Return(false, errors.New("toggle failed"))
 			},
 			expectedErr: errors.New("toggle failed"),
 		},
@@ -341,13 +391,168 @@ func TestTaskAdapter_ToggleDone(t *testing.T) {
 
 			tc.mock(mockRepo, ctx, tc.taskID)
 
-			adapter := NewTaskAdapter(mockRepo)
-			err := adapter.ToggleDone(ctx, tc.taskID)
+			adapter := NewTaskAdapter(mockRepo, inlineTransactor(ctrl))
+			isDone, err := adapter.ToggleDone(ctx, tc.taskID)
 
 			if tc.expectedErr != nil {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, isDone)
+			}
+		})
+	}
+}
+
+func TestTaskAdapter_List(t *testing.T) {
+	isDone := true
+	filter := models.TaskFilter{IsDone: &isDone}
+	tasks := []models.TaskShortInfo{{ID: uuid.New(), Title: "Task 1", IsDone: true}}
+
+	testTable := []struct {
+		name          string
+		userID        uuid.UUID
+		mock          func(r *mock_adapters.MockTaskRepository, userID uuid.UUID)
+		expectedTasks []models.TaskShortInfo
+		expectedErr   error
+	}{
+		{
+			name:   "success",
+			userID: uuid.New(),
+			mock: func(r *mock_adapters.MockTaskRepository, userID uuid.UUID) {
+				r.EXPECT().List(gomock.Any(), userID, filter, 1, 10).Return(tasks, nil)
+			},
+			expectedTasks: tasks,
+		},
+		{
+			name:   "repository error",
+			userID: uuid.New(),
+			mock: func(r *mock_adapters.MockTaskRepository, userID uuid.UUID) {
+				r.EXPECT().List(gomock.Any(), userID, filter, 1, 10).Return(nil, errors.New("db error"))
+			},
+			expectedErr: errors.Wrap(errors.New("db error"), "failed to list tasks"),
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mock_adapters.NewMockTaskRepository(ctrl)
+			tc.mock(mockRepo, tc.userID)
+
+			adapter := NewTaskAdapter(mockRepo, inlineTransactor(ctrl))
+			result, err := adapter.List(context.Background(), tc.userID, filter, 1, 10)
+
+			if tc.expectedErr != nil {
+				assert.EqualError(t, err, tc.expectedErr.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectedTasks, result)
+		})
+	}
+}
+
+func TestTaskAdapter_Patch(t *testing.T) {
+	title := "renamed"
+	patch := &models.TaskPatch{Title: &title}
+
+	testTable := []struct {
+		name        string
+		taskID      uuid.UUID
+		mock        func(r *mock_adapters.MockTaskRepository, taskID uuid.UUID)
+		expected    *models.TaskFullInfo
+		expectedErr string
+	}{
+		{
+			name:   "success",
+			taskID: uuid.New(),
+			mock: func(r *mock_adapters.MockTaskRepository, taskID uuid.UUID) {
+				r.EXPECT().Patch(gomock.Any(), taskID, patch).Return(&models.TaskFullInfo{ID: taskID, Title: title}, false, nil)
+			},
+			expected: &models.TaskFullInfo{Title: title},
+		},
+		{
+			name:   "unknown category",
+			taskID: uuid.New(),
+			mock: func(r *mock_adapters.MockTaskRepository, taskID uuid.UUID) {
+				r.EXPECT().Patch(gomock.Any(), taskID, patch).Return(nil, false, models.ErrCategoryNotFound)
+			},
+			expectedErr: "failed to patch task with id",
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mock_adapters.NewMockTaskRepository(ctrl)
+			tc.mock(mockRepo, tc.taskID)
+
+			adapter := NewTaskAdapter(mockRepo, inlineTransactor(ctrl))
+			task, err := adapter.Patch(context.Background(), tc.taskID, patch)
+
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				assert.ErrorIs(t, err, models.ErrCategoryNotFound)
+				assert.Nil(t, task)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.taskID, task.ID)
+				assert.Equal(t, tc.expected.Title, task.Title)
+			}
+		})
+	}
+}
+
+func TestTaskAdapter_Move(t *testing.T) {
+	statusID := uuid.New()
+
+	testTable := []struct {
+		name        string
+		taskID      uuid.UUID
+		mock        func(r *mock_adapters.MockTaskRepository, taskID uuid.UUID)
+		expectedErr string
+	}{
+		{
+			name:   "success",
+			taskID: uuid.New(),
+			mock: func(r *mock_adapters.MockTaskRepository, taskID uuid.UUID) {
+				r.EXPECT().Move(gomock.Any(), taskID, statusID, 2, int64(3)).Return(&models.TaskFullInfo{ID: taskID, StatusID: &statusID}, true, nil)
+			},
+		},
+		{
+			name:   "unknown status",
+			taskID: uuid.New(),
+			mock: func(r *mock_adapters.MockTaskRepository, taskID uuid.UUID) {
+				r.EXPECT().Move(gomock.Any(), taskID, statusID, 2, int64(3)).Return(nil, false, models.ErrStatusNotFound)
+			},
+			expectedErr: "failed to move task with id",
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockRepo := mock_adapters.NewMockTaskRepository(ctrl)
+			tc.mock(mockRepo, tc.taskID)
+
+			adapter := NewTaskAdapter(mockRepo, inlineTransactor(ctrl))
+			task, err := adapter.Move(context.Background(), tc.taskID, statusID, 2, 3)
+
+			if tc.expectedErr != "" {
+				assert.ErrorContains(t, err, tc.expectedErr)
+				assert.ErrorIs(t, err, models.ErrStatusNotFound)
+				assert.Nil(t, task)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.taskID, task.ID)
+				assert.Equal(t, &statusID, task.StatusID)
 			}
 		})
 	}
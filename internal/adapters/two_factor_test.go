@@ -0,0 +1,236 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+	"time"
+	mock_adapters "todolist/internal/adapters/mocks"
+	"todolist/internal/models"
+	auth_utils "todolist/internal/pkg/authUtils"
+	"todolist/internal/pkg/totp"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTOTPUser(t *testing.T, enabled bool) *models.User {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	return &models.User{
+		ID:          uuid.New(),
+		Name:        "testuser",
+		Password:    generateHash(t, "password123"),
+		TOTPSecret:  secret,
+		TOTPEnabled: enabled,
+	}
+}
+
+func currentCode(t *testing.T, secret string) string {
+	code, err := totp.Code(secret, time.Now())
+	require.NoError(t, err)
+	return code
+}
+
+func TestUserAdapter_SignInWithTOTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockIUserRepository(ctrl)
+	mockTokenHandler := mock_adapters.NewMockITokenHandler(ctrl)
+	adapter := NewAuthService(mockRepo, inlineTransactor(ctrl), mockTokenHandler, "test-key")
+
+	user := newTOTPUser(t, true)
+
+	gomock.InOrder(
+		mockRepo.EXPECT().
+			GetUserByName(gomock.Any(), "testuser").
+			Return(user, nil),
+		mockTokenHandler.EXPECT().
+			GenerateChallengeToken(*user, "test-key").
+			Return("challenge-token", nil),
+	)
+
+	result, err := adapter.SignIn(context.Background(), &models.UserAuth{Name: "testuser", Password: "password123"})
+
+	assert.NoError(t, err)
+	assert.True(t, result.ChallengeRequired())
+	assert.Equal(t, "challenge-token", result.ChallengeToken)
+	assert.Empty(t, result.Token)
+}
+
+func TestUserAdapter_SignInSecondFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockIUserRepository(ctrl)
+	mockTokenHandler := mock_adapters.NewMockITokenHandler(ctrl)
+	adapter := NewAuthService(mockRepo, inlineTransactor(ctrl), mockTokenHandler, "test-key")
+
+	user := newTOTPUser(t, true)
+	payload := &auth_utils.Payload{ID: user.ID, Login: user.Name}
+
+	tests := []struct {
+		name          string
+		code          string
+		mockSetup     func()
+		expectedToken string
+		expectedError error
+	}{
+		{
+			name: "valid totp code",
+			code: currentCode(t, user.TOTPSecret),
+			mockSetup: func() {
+				gomock.InOrder(
+					mockTokenHandler.EXPECT().ParseChallengeToken("challenge", "test-key").Return(payload, nil),
+					mockRepo.EXPECT().GetUserByID(gomock.Any(), user.ID).Return(user, nil),
+					mockRepo.EXPECT().UseTOTPStep(gomock.Any(), user.ID, gomock.Any()).Return(true, nil),
+					mockTokenHandler.EXPECT().GenerateToken(*user, "test-key").Return("test-token", nil),
+					mockRepo.EXPECT().RecordActivity(gomock.Any(), user.ID, models.ActivitySignIn).Return(nil),
+				)
+			},
+			expectedToken: "test-token",
+		},
+		{
+			name: "replayed totp code",
+			code: currentCode(t, user.TOTPSecret),
+			mockSetup: func() {
+				gomock.InOrder(
+					mockTokenHandler.EXPECT().ParseChallengeToken("challenge", "test-key").Return(payload, nil),
+					mockRepo.EXPECT().GetUserByID(gomock.Any(), user.ID).Return(user, nil),
+					mockRepo.EXPECT().UseTOTPStep(gomock.Any(), user.ID, gomock.Any()).Return(false, nil),
+				)
+			},
+			expectedError: models.ErrInvalidTOTPCode,
+		},
+		{
+			name: "unused recovery code",
+			code: "ABCD-EFGH",
+			mockSetup: func() {
+				gomock.InOrder(
+					mockTokenHandler.EXPECT().ParseChallengeToken("challenge", "test-key").Return(payload, nil),
+					mockRepo.EXPECT().GetUserByID(gomock.Any(), user.ID).Return(user, nil),
+					mockRepo.EXPECT().UseRecoveryCode(gomock.Any(), user.ID, hashRecoveryCode("abcdefgh")).Return(true, nil),
+					mockTokenHandler.EXPECT().GenerateToken(*user, "test-key").Return("test-token", nil),
+					mockRepo.EXPECT().RecordActivity(gomock.Any(), user.ID, models.ActivitySignIn).Return(nil),
+				)
+			},
+			expectedToken: "test-token",
+		},
+		{
+			name: "wrong code",
+			code: "000000",
+			mockSetup: func() {
+				gomock.InOrder(
+					mockTokenHandler.EXPECT().ParseChallengeToken("challenge", "test-key").Return(payload, nil),
+					mockRepo.EXPECT().GetUserByID(gomock.Any(), user.ID).Return(user, nil),
+					mockRepo.EXPECT().UseRecoveryCode(gomock.Any(), user.ID, gomock.Any()).Return(false, nil),
+				)
+			},
+			expectedError: models.ErrInvalidTOTPCode,
+		},
+		{
+			name: "invalid challenge token",
+			code: "000000",
+			mockSetup: func() {
+				mockTokenHandler.EXPECT().ParseChallengeToken("challenge", "test-key").Return(nil, auth_utils.ErrInvalidToken)
+			},
+			expectedError: auth_utils.ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			token, err := adapter.SignInSecondFactor(context.Background(), "challenge", tt.code)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedToken, token)
+			}
+		})
+	}
+}
+
+func TestUserAdapter_EnrollAndConfirmTOTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockIUserRepository(ctrl)
+	adapter := NewAuthService(mockRepo, inlineTransactor(ctrl), mock_adapters.NewMockITokenHandler(ctrl), "test-key")
+
+	user := &models.User{ID: uuid.New(), Name: "testuser"}
+
+	mockRepo.EXPECT().GetUserByID(gomock.Any(), user.ID).Return(user, nil)
+	mockRepo.EXPECT().
+		SetTOTPSecret(gomock.Any(), user.ID, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, secret string) error {
+			user.TOTPSecret = secret
+			return nil
+		})
+
+	enrollment, err := adapter.EnrollTOTP(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.TOTPSecret, enrollment.Secret)
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+
+	var savedHashes []string
+	mockRepo.EXPECT().GetUserByID(gomock.Any(), user.ID).Return(user, nil).Times(2)
+	mockRepo.EXPECT().UseTOTPStep(gomock.Any(), user.ID, gomock.Any()).Return(true, nil)
+	mockRepo.EXPECT().
+		EnableTOTP(gomock.Any(), user.ID, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ uuid.UUID, hashes []string) error {
+			savedHashes = hashes
+			return nil
+		})
+	mockRepo.EXPECT().RecordActivity(gomock.Any(), user.ID, models.ActivityTwoFactorEnabled).Return(nil)
+
+	_, err = adapter.ConfirmTOTP(context.Background(), user.ID, "000000")
+	assert.ErrorIs(t, err, models.ErrInvalidTOTPCode)
+
+	codes, err := adapter.ConfirmTOTP(context.Background(), user.ID, currentCode(t, user.TOTPSecret))
+	require.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+	require.Len(t, savedHashes, recoveryCodeCount)
+	for i, code := range codes {
+		assert.Equal(t, hashRecoveryCode(code), savedHashes[i], "only hashes are stored")
+	}
+}
+
+func TestUserAdapter_EnrollTOTPAlreadyEnabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockIUserRepository(ctrl)
+	adapter := NewAuthService(mockRepo, inlineTransactor(ctrl), mock_adapters.NewMockITokenHandler(ctrl), "test-key")
+
+	user := newTOTPUser(t, true)
+	mockRepo.EXPECT().GetUserByID(gomock.Any(), user.ID).Return(user, nil)
+
+	_, err := adapter.EnrollTOTP(context.Background(), user.ID)
+	assert.True(t, errors.Is(err, models.ErrTOTPAlreadyEnabled))
+}
+
+func TestUserAdapter_DisableTOTP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockIUserRepository(ctrl)
+	adapter := NewAuthService(mockRepo, inlineTransactor(ctrl), mock_adapters.NewMockITokenHandler(ctrl), "test-key")
+
+	user := newTOTPUser(t, true)
+	gomock.InOrder(
+		mockRepo.EXPECT().GetUserByID(gomock.Any(), user.ID).Return(user, nil),
+		mockRepo.EXPECT().UseTOTPStep(gomock.Any(), user.ID, gomock.Any()).Return(true, nil),
+		mockRepo.EXPECT().DisableTOTP(gomock.Any(), user.ID).Return(nil),
+		mockRepo.EXPECT().RecordActivity(gomock.Any(), user.ID, models.ActivityTwoFactorDisabled).Return(nil),
+	)
+
+	err := adapter.DisableTOTP(context.Background(), user.ID, currentCode(t, user.TOTPSecret))
+	assert.NoError(t, err)
+}
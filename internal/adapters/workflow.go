@@ -0,0 +1,68 @@
+package adapters
+
+import (
+	"context"
+	"todolist/internal/models"
+	"todolist/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type IWorkflowRepository interface {
+	// GetStatuses returns the columns of the user in order, none without a
+	// workflow.
+	GetStatuses(ctx context.Context, userID uuid.UUID) ([]models.TaskStatus, error)
+	// SetStatuses replaces the columns of the user and returns them. It fails
+	// with ErrStatusNotFound when a body refers to a column the user does not
+	// have. The statuses are checked by the adapter.
+	SetStatuses(ctx context.Context, userID uuid.UUID, statuses []models.StatusBody) ([]models.TaskStatus, error)
+	// GetBoard returns the columns of the user with their tasks.
+	GetBoard(ctx context.Context, userID uuid.UUID) ([]models.BoardColumn, error)
+}
+
+type WorkflowAdapter struct {
+	repository IWorkflowRepository
+}
+
+func NewWorkflowAdapter(repository IWorkflowRepository) *WorkflowAdapter {
+	return &WorkflowAdapter{repository: repository}
+}
+
+func (w *WorkflowAdapter) GetStatuses(ctx context.Context, userID uuid.UUID) ([]models.TaskStatus, error) {
+	ctx, span := tracing.Start(ctx, "WorkflowAdapter.GetStatuses")
+	defer span.End()
+
+	statuses, err := w.repository.GetStatuses(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get workflow of user with id: %s", userID)
+	}
+	return statuses, nil
+}
+
+// SetStatuses fails with models.ErrInvalidWorkflow unless the statuses pass
+// models.ValidateStatuses, whoever the caller is.
+func (w *WorkflowAdapter) SetStatuses(ctx context.Context, userID uuid.UUID, statuses []models.StatusBody) ([]models.TaskStatus, error) {
+	ctx, span := tracing.Start(ctx, "WorkflowAdapter.SetStatuses")
+	defer span.End()
+
+	if err := models.ValidateStatuses(statuses); err != nil {
+		return nil, err
+	}
+	result, err := w.repository.SetStatuses(ctx, userID, statuses)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to set workflow of user with id: %s", userID)
+	}
+	return result, nil
+}
+
+func (w *WorkflowAdapter) GetBoard(ctx context.Context, userID uuid.UUID) ([]models.BoardColumn, error) {
+	ctx, span := tracing.Start(ctx, "WorkflowAdapter.GetBoard")
+	defer span.End()
+
+	board, err := w.repository.GetBoard(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get board of user with id: %s", userID)
+	}
+	return board, nil
+}
@@ -0,0 +1,138 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	mock_adapters "todolist/internal/adapters/mocks"
+	"todolist/internal/models"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflowAdapter_SetStatuses(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockIWorkflowRepository(ctrl)
+	adapter := NewWorkflowAdapter(mockRepo)
+
+	userID := uuid.New()
+	bodies := []models.StatusBody{{Name: "todo"}, {ID: uuid.New(), Name: "done"}}
+	statuses := []models.TaskStatus{{ID: uuid.New(), Name: "todo"}, {ID: bodies[1].ID, Name: "done", Position: 1, Terminal: true}}
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		expected      []models.TaskStatus
+		expectedError error
+	}{
+		{
+			name: "successful update",
+			mockSetup: func() {
+				mockRepo.EXPECT().SetStatuses(gomock.Any(), userID, bodies).Return(statuses, nil)
+			},
+			expected: statuses,
+		},
+		{
+			name: "unknown status",
+			mockSetup: func() {
+				mockRepo.EXPECT().SetStatuses(gomock.Any(), userID, bodies).Return(nil, models.ErrStatusNotFound)
+			},
+			expectedError: models.ErrStatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			result, err := adapter.SetStatuses(context.Background(), userID, bodies)
+
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.ErrorContains(t, err, "failed to set workflow of user with id: "+userID.String())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestWorkflowAdapter_SetStatusesValidates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The repository is never reached.
+	adapter := NewWorkflowAdapter(mock_adapters.NewMockIWorkflowRepository(ctrl))
+
+	tooMany := make([]models.StatusBody, models.MaxStatuses+1)
+	for i := range tooMany {
+		tooMany[i] = models.StatusBody{Name: fmt.Sprintf("column %d", i)}
+	}
+	id := uuid.New()
+	for name, statuses := range map[string][]models.StatusBody{
+		"one status":     {{Name: "todo"}},
+		"too many":       tooMany,
+		"empty name":     {{Name: "todo"}, {Name: ""}},
+		"long name":      {{Name: "todo"}, {Name: strings.Repeat("я", models.MaxStatusName+1)}},
+		"duplicate name": {{Name: "todo"}, {Name: "todo"}},
+		"duplicate id":   {{ID: id, Name: "todo"}, {ID: id, Name: "done"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := adapter.SetStatuses(context.Background(), uuid.New(), statuses)
+			assert.ErrorIs(t, err, models.ErrInvalidWorkflow)
+		})
+	}
+}
+
+func TestWorkflowAdapter_GetBoard(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := mock_adapters.NewMockIWorkflowRepository(ctrl)
+	adapter := NewWorkflowAdapter(mockRepo)
+
+	userID := uuid.New()
+	board := []models.BoardColumn{{TaskStatus: models.TaskStatus{ID: uuid.New(), Name: "todo"}, Tasks: []models.TaskShortInfo{{ID: uuid.New(), Title: "task"}}}}
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		expected      []models.BoardColumn
+		expectedError error
+	}{
+		{
+			name: "successful board",
+			mockSetup: func() {
+				mockRepo.EXPECT().GetBoard(gomock.Any(), userID).Return(board, nil)
+			},
+			expected: board,
+		},
+		{
+			name: "repository error",
+			mockSetup: func() {
+				mockRepo.EXPECT().GetBoard(gomock.Any(), userID).Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("failed to get board of user with id: " + userID.String()),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			result, err := adapter.GetBoard(context.Background(), userID)
+
+			if tt.expectedError != nil {
+				assert.Contains(t, err.Error(), tt.expectedError.Error())
+			} else {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
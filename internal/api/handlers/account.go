@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"todolist/internal/middleware"
+	"todolist/internal/models"
+	"todolist/internal/pkg/export"
+	"todolist/internal/pkg/response"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type DeletionSchedule struct {
+	DeletionScheduledAt time.Time `json:"deletion_scheduled_at"`
+}
+
+type AccountProvider interface {
+	ExportData(ctx context.Context, userID uuid.UUID) (*models.AccountData, error)
+	ScheduleDeletion(ctx context.Context, userID uuid.UUID) (time.Time, error)
+	CancelDeletion(ctx context.Context, userID uuid.UUID) error
+}
+
+// @Summary ExportUserData
+// @Security ApiKeyAuth
+// @Tags user
+// @Description Выгрузить все данные пользователя: профиль, задачи, категории и журнал действий. Возвращает zip-архив с JSON файлами
+// @ID export-user-data
+// @Produce  application/zip
+// @Success 200 {file} file
+// @Failure 401,404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/user/export [post]
+func ExportUserData(accountProvider AccountProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
+		if !ok {
+			log.Ctx(r.Context()).Warn().
+				Msg("ExportUserData: missing userID")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("Missing userID"))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		data, err := accountProvider.ExportData(ctx, userID)
+		if err != nil {
+			writeAccountError(w, r, err, "ExportUserData")
+			return
+		}
+
+		// The archive is built in memory first so that a failure can still be
+		// reported as a JSON error instead of a truncated download.
+		exportedAt := time.Now().UTC()
+		var archive bytes.Buffer
+		if err = export.WriteArchive(&archive, data, exportedAt); err != nil {
+			log.Ctx(r.Context()).Error().
+				Err(err).
+				Msg("ExportUserData: failed to build archive")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		filename := fmt.Sprintf("todolist-export-%s.zip", exportedAt.Format("20060102-150405"))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.WriteHeader(http.StatusOK)
+		if _, err = archive.WriteTo(w); err != nil {
+			log.Ctx(r.Context()).Warn().
+				Err(err).
+				Msg("ExportUserData: failed to send archive")
+			return
+		}
+
+		log.Ctx(r.Context()).Info().
+			Msg("ExportUserData: exported user data")
+	}
+}
+
+// @Summary DeleteUser
+// @Security ApiKeyAuth
+// @Tags user
+// @Description Запланировать удаление аккаунта. Аккаунт и все данные удаляются после льготного периода, до этого удаление можно отменить
+// @ID delete-user
+// @Produce  json
+// @Success 202 {object} DeletionSchedule
+// @Failure 401,404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/user [delete]
+func DeleteUser(accountProvider AccountProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
+		if !ok {
+			log.Ctx(r.Context()).Warn().
+				Msg("DeleteUser: failed to delete user")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("Missing userID"))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		scheduledAt, err := accountProvider.ScheduleDeletion(ctx, userID)
+		if err != nil {
+			writeAccountError(w, r, err, "DeleteUser")
+			return
+		}
+
+		log.Ctx(r.Context()).Info().
+			Time("deletion_scheduled_at", scheduledAt).
+			Msg("DeleteUser: scheduled user deletion")
+		render.Status(r, http.StatusAccepted)
+		render.JSON(w, r, DeletionSchedule{DeletionScheduledAt: scheduledAt})
+	}
+}
+
+// @Summary CancelUserDeletion
+// @Security ApiKeyAuth
+// @Tags user
+// @Description Отменить запланированное удаление аккаунта
+// @ID cancel-user-deletion
+// @Produce  json
+// @Success 200
+// @Failure 401,404,409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/user/deletion/cancel [post]
+func CancelUserDeletion(accountProvider AccountProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
+		if !ok {
+			log.Ctx(r.Context()).Warn().
+				Msg("CancelUserDeletion: missing userID")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("Missing userID"))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		err := accountProvider.CancelDeletion(ctx, userID)
+		if err != nil {
+			writeAccountError(w, r, err, "CancelUserDeletion")
+			return
+		}
+
+		log.Ctx(r.Context()).Info().
+			Msg("CancelUserDeletion: cancelled user deletion")
+		render.Status(r, http.StatusOK)
+	}
+}
+
+func writeAccountError(w http.ResponseWriter, r *http.Request, err error, handler string) {
+	switch {
+	case errors.Is(err, models.ErrUserNotFound):
+		log.Ctx(r.Context()).Warn().Err(err).Msgf("%s: user not found", handler)
+		render.Status(r, http.StatusNotFound)
+	case errors.Is(err, models.ErrDeletionNotScheduled):
+		log.Ctx(r.Context()).Warn().Err(err).Msgf("%s: deletion is not scheduled", handler)
+		render.Status(r, http.StatusConflict)
+	default:
+		log.Ctx(r.Context()).Error().Err(err).Msgf("%s: request failed", handler)
+		render.Status(r, http.StatusInternalServerError)
+	}
+	render.JSON(w, r, response.Error(err.Error()))
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"todolist/internal/models"
+	"todolist/internal/pkg/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultPageIndex      = 1
+	defaultRecordsPerPage = 20
+)
+
+type AdminUserResponse struct {
+	ID                    uuid.UUID `json:"id"`
+	Name                  string    `json:"name"`
+	Role                  string    `json:"role"`
+	Disabled              bool      `json:"disabled"`
+	PasswordResetRequired bool      `json:"password_reset_required"`
+	TwoFactorEnabled      bool      `json:"two_factor_enabled"`
+	TaskCount             int64     `json:"task_count"`
+	CategoryCount         int64     `json:"category_count"`
+}
+
+type AdminUsersList struct {
+	Users []AdminUserResponse `json:"users"`
+}
+
+type AdminProvider interface {
+	ListUsers(ctx context.Context, search string, pageIndex, recordsPerPage int) ([]models.UserSummary, error)
+	GetUser(ctx context.Context, userID uuid.UUID) (*models.UserSummary, error)
+	DisableUser(ctx context.Context, userID uuid.UUID) error
+	EnableUser(ctx context.Context, userID uuid.UUID) error
+	ForcePasswordReset(ctx context.Context, userID uuid.UUID) error
+}
+
+// @Summary AdminListUsers
+// @Security ApiKeyAuth
+// @Tags admin
+// @Description Список пользователей с количеством задач и категорий, поиск по части имени. Только для администраторов
+// @ID admin-list-users
+// @Produce  json
+// @Param search           query string false "part of the user name"
+// @Param page_index       query int    false "page number, starts with 1"
+// @Param records_per_page query int    false "page size, 20 by default, at most 100"
+// @Success 200 {object} AdminUsersList
+// @Failure 400,401,403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/admin/users [get]
+func AdminListUsers(adminProvider AdminProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		pagination, err := queryPagination(query)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("AdminListUsers: invalid pagination")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		users, err := adminProvider.ListUsers(ctx, query.Get("search"), pagination.PageIndex, pagination.RecordsPerPage)
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("AdminListUsers: failed to list users")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		list := make([]AdminUserResponse, 0, len(users))
+		for _, user := range users {
+			list = append(list, toAdminUserResponse(user))
+		}
+		render.JSON(w, r, AdminUsersList{Users: list})
+	}
+}
+
+// @Summary AdminGetUser
+// @Security ApiKeyAuth
+// @Tags admin
+// @Description Информация о пользователе с количеством задач и категорий. Только для администраторов
+// @ID admin-get-user
+// @Produce  json
+// @Param id   path      string  true  "User ID (UUID)"
+// @Success 200 {object} AdminUserResponse
+// @Failure 400,401,403,404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/admin/users/{id} [get]
+func AdminGetUser(adminProvider AdminProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := parseUserIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		user, err := adminProvider.GetUser(ctx, userID)
+		if err != nil {
+			writeAdminError(w, r, err, "AdminGetUser")
+			return
+		}
+
+		render.JSON(w, r, toAdminUserResponse(*user))
+	}
+}
+
+// @Summary AdminDisableUser
+// @Security ApiKeyAuth
+// @Tags admin
+// @Description Заблокировать пользователя, он не сможет войти в систему. Только для администраторов
+// @ID admin-disable-user
+// @Produce  json
+// @Param id   path      string  true  "User ID (UUID)"
+// @Success 200
+// @Failure 400,401,403,404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/admin/users/{id}/disable [post]
+func AdminDisableUser(adminProvider AdminProvider, timeout time.Duration) http.HandlerFunc {
+	return adminUserAction(adminProvider.DisableUser, timeout, "AdminDisableUser")
+}
+
+// @Summary AdminEnableUser
+// @Security ApiKeyAuth
+// @Tags admin
+// @Description Разблокировать пользователя. Только для администраторов
+// @ID admin-enable-user
+// @Produce  json
+// @Param id   path      string  true  "User ID (UUID)"
+// @Success 200
+// @Failure 400,401,403,404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/admin/users/{id}/enable [post]
+func AdminEnableUser(adminProvider AdminProvider, timeout time.Duration) http.HandlerFunc {
+	return adminUserAction(adminProvider.EnableUser, timeout, "AdminEnableUser")
+}
+
+// @Summary AdminForcePasswordReset
+// @Security ApiKeyAuth
+// @Tags admin
+// @Description Потребовать смену пароля: пользователь не сможет войти, пока не сменит пароль через /api/v1/user/password. Только для администраторов
+// @ID admin-force-password-reset
+// @Produce  json
+// @Param id   path      string  true  "User ID (UUID)"
+// @Success 200
+// @Failure 400,401,403,404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/admin/users/{id}/password-reset [post]
+func AdminForcePasswordReset(adminProvider AdminProvider, timeout time.Duration) http.HandlerFunc {
+	return adminUserAction(adminProvider.ForcePasswordReset, timeout, "AdminForcePasswordReset")
+}
+
+func adminUserAction(action func(ctx context.Context, userID uuid.UUID) error, timeout time.Duration, handler string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := parseUserIDParam(w, r)
+		if !ok {
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		err := action(ctx, userID)
+		if err != nil {
+			writeAdminError(w, r, err, handler)
+			return
+		}
+
+		log.Ctx(r.Context()).Info().
+			Str("target_user_id", userID.String()).
+			Msgf("%s: done", handler)
+		render.Status(r, http.StatusOK)
+	}
+}
+
+func parseUserIDParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse path parameter")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid UUID"))
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func writeAdminError(w http.ResponseWriter, r *http.Request, err error, handler string) {
+	if errors.Is(err, models.ErrUserNotFound) {
+		log.Ctx(r.Context()).Warn().Err(err).Msgf("%s: user not found", handler)
+		render.Status(r, http.StatusNotFound)
+	} else {
+		log.Ctx(r.Context()).Error().Err(err).Msgf("%s: request failed", handler)
+		render.Status(r, http.StatusInternalServerError)
+	}
+	render.JSON(w, r, response.Error(err.Error()))
+}
+
+func queryInt(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if parsed < 1 {
+		return 0, errors.New("value must be positive")
+	}
+	return parsed, nil
+}
+
+func toAdminUserResponse(user models.UserSummary) AdminUserResponse {
+	return AdminUserResponse{
+		ID:                    user.ID,
+		Name:                  user.Name,
+		Role:                  user.Role,
+		Disabled:              user.Disabled,
+		PasswordResetRequired: user.PasswordResetRequired,
+		TwoFactorEnabled:      user.TOTPEnabled,
+		TaskCount:             user.TaskCount,
+		CategoryCount:         user.CategoryCount,
+	}
+}
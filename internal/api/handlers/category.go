@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"path"
 	"time"
 	"todolist/internal/middleware"
 	"todolist/internal/models"
@@ -22,6 +23,8 @@ type CategoryBody struct {
 type CategoryResponse struct {
 	ID uuid.UUID `json:"id"`
 	CategoryBody
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type CategoriesResponse struct {
@@ -29,8 +32,8 @@ type CategoriesResponse struct {
 }
 
 type CategoriesProvider interface {
-	CreateCategory(ctx context.Context, category *models.CategoryBody) error
-	Delete(ctx context.Context, id uuid.UUID) error
+	CreateCategory(ctx context.Context, category *models.CategoryBody) (*models.Category, error)
+	Delete(ctx context.Context, id, userID uuid.UUID) error
 	GetAll(ctx context.Context, pageIndex, recordsPerPage int, userid uuid.UUID) ([]models.Category, error)
 }
 
@@ -38,11 +41,14 @@ type CategoriesProvider interface {
 // @Security ApiKeyAuth
 // @Tags category
 // @Description Создание новой категории задач
+// @Description Повторный запрос с тем же Idempotency-Key вернет категорию, созданную первым запросом.
 // @ID create-category
 // @Accept  json
 // @Produce  json
 // @Param input body CategoryBody true "category name"
-// @Success 200
+// @Param Idempotency-Key header string false "ключ идемпотентности, до 255 байт"
+// @Success 201 {object} CategoryResponse
+// @Header 201 {string} Location "адрес созданной категории"
 // @Failure 400 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Failure default {object} response.Response
@@ -52,14 +58,14 @@ func CreateCategory(categoryProvider CategoriesProvider, timeout time.Duration)
 
 		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
 		if !ok {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Msg("CreateCategory: missing userID")
 			render.Status(r, http.StatusUnauthorized)
 			render.JSON(w, r, response.Error("Missing userID"))
 			return
 		}
 
-		log.Info().
+		log.Ctx(r.Context()).Info().
 			Str("method", r.Method).
 			Str("path", r.URL.Path).
 			Msg("CreateCategory: started processing request")
@@ -67,16 +73,25 @@ func CreateCategory(categoryProvider CategoriesProvider, timeout time.Duration)
 		var req CategoryBody
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Err(err).
 				Msg("CreateCategory: failed to decode request body")
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response.Error(err.Error()))
 			return
 		}
-		category := models.CategoryBody{Name: req.Name, UserID: userID}
+		key, err := idempotencyKey(r)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().
+				Err(err).
+				Msg("CreateCategory: invalid idempotency key")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+		category := models.CategoryBody{Name: req.Name, UserID: userID, IdempotencyKey: key}
 
-		log.Debug().
+		log.Ctx(r.Context()).Debug().
 			Str("category_name", req.Name).
 			Msg("CreateCategory: received create request")
 
@@ -84,9 +99,9 @@ func CreateCategory(categoryProvider CategoriesProvider, timeout time.Duration)
 		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 
-		err = categoryProvider.CreateCategory(ctx, &category)
+		created, err := categoryProvider.CreateCategory(ctx, &category)
 		if err != nil {
-			log.Error().
+			log.Ctx(r.Context()).Error().
 				Err(err).
 				Str("category_name", req.Name).
 				Msg("CreateCategory: failed to create category")
@@ -95,10 +110,12 @@ func CreateCategory(categoryProvider CategoriesProvider, timeout time.Duration)
 			return
 		}
 
-		log.Info().
+		log.Ctx(r.Context()).Info().
 			Str("category_name", req.Name).
 			Msg("CreateCategory: successfully created new category")
-		render.Status(r, http.StatusOK)
+		w.Header().Set("Location", path.Join(r.URL.Path, created.ID.String()))
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, toCategoryResponse(*created))
 	}
 }
 
@@ -106,31 +123,41 @@ func CreateCategory(categoryProvider CategoriesProvider, timeout time.Duration)
 // @Security ApiKeyAuth
 // @Tags category
 // @Description Удаление категории задачи, при удалении категория пропадет для всех задач
+// @Description Категория другого пользователя не найдется (404).
 // @ID delete-category
 // @Accept  json
 // @Produce  json
 // @Param id   path      string  true  "Category ID (UUID)"
 // @Success 200
-// @Failure 400,404 {object} response.Response
+// @Failure 400,401,404 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Failure default {object} response.Response
 // @Router /api/v1/category/{id} [delete]
 func DeleteCategory(categoryProvider CategoriesProvider, timeout time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		log.Info().
+		log.Ctx(r.Context()).Info().
 			Str("method", r.Method).
 			Str("path", r.URL.Path).
 			Msg("DeleteCategory: started processing request")
 
+		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
+		if !ok {
+			log.Ctx(r.Context()).Warn().
+				Msg("DeleteCategory: failed to get UserID")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("Missing userID"))
+			return
+		}
+
 		id := chi.URLParam(r, "id")
-		log.Debug().
+		log.Ctx(r.Context()).Debug().
 			Str("category_id", id).
 			Msg("DeleteCategory: received category ID")
 
 		uuid, err := uuid.Parse(id)
 		if err != nil {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Str("category_id", id).
 				Err(err).
 				Msg("DeleteCategory: invalid category UUID format")
@@ -143,15 +170,15 @@ func DeleteCategory(categoryProvider CategoriesProvider, timeout time.Duration)
 		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 
-		err = categoryProvider.Delete(ctx, uuid)
+		err = categoryProvider.Delete(ctx, uuid, userID)
 		if err != nil {
 			if errors.Is(err, models.ErrCategoryNotFound) {
-				log.Warn().
+				log.Ctx(r.Context()).Warn().
 					Str("category_id", uuid.String()).
 					Msg("DeleteCategory: category not found")
 				render.Status(r, http.StatusNotFound)
 			} else {
-				log.Error().
+				log.Ctx(r.Context()).Error().
 					Str("category_id", uuid.String()).
 					Err(err).
 					Msg("DeleteCategory: failed to delete category")
@@ -161,7 +188,7 @@ func DeleteCategory(categoryProvider CategoriesProvider, timeout time.Duration)
 			return
 		}
 
-		log.Info().
+		log.Ctx(r.Context()).Info().
 			Str("category_id", uuid.String()).
 			Msg("DeleteCategory: successfully deleted category")
 		render.Status(r, http.StatusOK)
@@ -184,14 +211,14 @@ func DeleteCategory(categoryProvider CategoriesProvider, timeout time.Duration)
 func GetCategories(categoryProvider CategoriesProvider, timeout time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		log.Info().
+		log.Ctx(r.Context()).Info().
 			Str("method", r.Method).
 			Str("path", r.URL.Path).
 			Msg("GetCategories: started processing request")
 
 		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
 		if !ok {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Msg("GetCategories: failed to get UserID")
 			render.Status(r, http.StatusUnauthorized)
 			render.JSON(w, r, response.Error("Missing userID"))
@@ -201,7 +228,7 @@ func GetCategories(categoryProvider CategoriesProvider, timeout time.Duration) h
 		var req Pagination
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Err(err).
 				Msg("GetCategories: failed to decode request body")
 			render.Status(r, http.StatusBadRequest)
@@ -209,7 +236,7 @@ func GetCategories(categoryProvider CategoriesProvider, timeout time.Duration) h
 			return
 		}
 
-		log.Debug().
+		log.Ctx(r.Context()).Debug().
 			Int("page_index", req.PageIndex).
 			Int("records_per_page", req.RecordsPerPage).
 			Msg("GetCategories: received pagination parameters")
@@ -221,7 +248,7 @@ func GetCategories(categoryProvider CategoriesProvider, timeout time.Duration) h
 		var categories []models.Category
 		categories, err = categoryProvider.GetAll(ctx, req.PageIndex, req.RecordsPerPage, userID)
 		if err != nil {
-			log.Error().
+			log.Ctx(r.Context()).Error().
 				Err(err).
 				Int("page_index", req.PageIndex).
 				Int("records_per_page", req.RecordsPerPage).
@@ -231,7 +258,7 @@ func GetCategories(categoryProvider CategoriesProvider, timeout time.Duration) h
 			return
 		}
 
-		log.Info().
+		log.Ctx(r.Context()).Info().
 			Int("count", len(categories)).
 			Msg("GetCategories: successfully fetched categories")
 
@@ -246,6 +273,8 @@ func toCategoryResponse(category models.Category) CategoryResponse {
 		CategoryBody: CategoryBody{
 			Name: category.Name,
 		},
+		CreatedAt: category.CreatedAt,
+		UpdatedAt: category.UpdatedAt,
 	}
 }
 
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"todolist/internal/middleware"
+	"todolist/internal/pkg/response"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// @Summary ListCategories
+// @Security ApiKeyAuth
+// @Tags category
+// @Description Получить список категорий.
+// @Description Если ETag списка совпадает с заголовком If-None-Match, ответ 304 без тела.
+// @ID list-categories
+// @Produce  json
+// @Param page_index       query int false "page number, starts with 1"
+// @Param records_per_page query int false "page size, 20 by default, at most 100"
+// @Param If-None-Match header string false "ETag из предыдущего ответа"
+// @Success 200 {object} CategoriesResponse
+// @Header 200 {string} ETag "версия списка"
+// @Success 304
+// @Failure 400,401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v2/categories [get]
+func ListCategories(categoryProvider CategoriesProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		pagination, err := queryPagination(r.URL.Query())
+		if err != nil {
+			log.Ctx(r.Context()).Warn().
+				Err(err).
+				Msg("ListCategories: invalid pagination")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
+		if !ok {
+			log.Ctx(r.Context()).Warn().
+				Msg("ListCategories: failed to get UserID")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("Missing userID"))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		categories, err := categoryProvider.GetAll(ctx, pagination.PageIndex, pagination.RecordsPerPage, userID)
+		if err != nil {
+			log.Ctx(r.Context()).Error().
+				Err(err).
+				Msg("ListCategories: failed to fetch categories")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		if notModified(w, r, categoryListETag(categories)) {
+			return
+		}
+		render.JSON(w, r, toCategoriesResponse(categories))
+	}
+}
+
+// @Summary CreateCategoryV2
+// @Security ApiKeyAuth
+// @Tags category
+// @Description Создание новой категории задач, как POST /api/v1/category
+// @Description Повторный запрос с тем же Idempotency-Key вернет категорию, созданную первым запросом.
+// @ID create-category-v2
+// @Accept  json
+// @Produce  json
+// @Param input body CategoryBody true "category name"
+// @Param Idempotency-Key header string false "ключ идемпотентности, до 255 байт"
+// @Success 201 {object} CategoryResponse
+// @Header 201 {string} Location "адрес созданной категории"
+// @Failure 400,401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v2/categories [post]
+func CreateCategoryV2(categoryProvider CategoriesProvider, timeout time.Duration) http.HandlerFunc {
+	return CreateCategory(categoryProvider, timeout)
+}
+
+// @Summary DeleteCategoryV2
+// @Security ApiKeyAuth
+// @Tags category
+// @Description Удаление категории задачи, как DELETE /api/v1/category/{id}
+// @ID delete-category-v2
+// @Produce  json
+// @Param id   path      string  true  "Category ID (UUID)"
+// @Success 200
+// @Failure 400,401,404 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v2/categories/{id} [delete]
+func DeleteCategoryV2(categoryProvider CategoriesProvider, timeout time.Duration) http.HandlerFunc {
+	return DeleteCategory(categoryProvider, timeout)
+}
@@ -0,0 +1,787 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"todolist/config"
+	"todolist/internal/adapters"
+	"todolist/internal/api/handlers"
+	"todolist/internal/models"
+	"todolist/internal/pkg/response"
+	"todolist/internal/repository/memory"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer runs the API routes in-process on top of fresh memory storage.
+type testServer struct {
+	*httptest.Server
+	t     *testing.T
+	cfg   *config.Config
+	repos *adapters.Repositories
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	store := memory.NewStore()
+	repos := &adapters.Repositories{
+		Tasks:      memory.NewTaskRepository(store),
+		Categories: memory.NewCategoryRepository(store),
+		Users:      memory.NewUserRepository(store),
+		Identities: memory.NewIdentityRepository(store),
+		Admin:      memory.NewAdminRepository(store),
+		Accounts:   memory.NewAccountRepository(store),
+		Stats:      memory.NewStatsRepository(store),
+		Workflow:   memory.NewWorkflowRepository(store),
+		Transactor: memory.NewTransactor(store),
+	}
+
+	cfg := &config.Config{
+		ServiceConfig: config.ServiceConfig{
+			TaskTimeout:         5 * time.Second,
+			JWTSecret:           "e2e-test-secret",
+			DeletionGracePeriod: time.Hour,
+		},
+		RateLimitConfig: config.RateLimitConfig{Enabled: false},
+	}
+
+	r := chi.NewRouter()
+	handlers.NewHandlers(cfg, repos, nil, r).InitHandlers()
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return &testServer{Server: srv, t: t, cfg: cfg, repos: repos}
+}
+
+// do sends body as JSON, with the token if there is one, and returns the
+// status code and the raw response body.
+func (s *testServer) do(token, method, path string, body interface{}) (int, []byte) {
+	s.t.Helper()
+	status, _, raw := s.send(token, method, path, nil, body)
+	return status, raw
+}
+
+// send is do with extra request headers that also returns the response
+// headers.
+func (s *testServer) send(token, method, path string, header http.Header, body interface{}) (int, http.Header, []byte) {
+	s.t.Helper()
+
+	var reqBody io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		require.NoError(s.t, err)
+		reqBody = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequest(method, s.URL+path, reqBody)
+	require.NoError(s.t, err)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.Client().Do(req)
+	require.NoError(s.t, err)
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	require.NoError(s.t, err)
+	return resp.StatusCode, resp.Header, raw
+}
+
+func (s *testServer) signUp(name, password string) string {
+	s.t.Helper()
+	status, raw := s.do("", http.MethodPost, "/api/v1/sign-up", handlers.UserInfo{Name: name, Password: password})
+	require.Equal(s.t, http.StatusOK, status, string(raw))
+
+	var token handlers.Token
+	decode(s.t, raw, &token)
+	require.NotEmpty(s.t, token.Token)
+	return token.Token
+}
+
+func (s *testServer) createTask(token, title string, categoryIDs ...uuid.UUID) uuid.UUID {
+	s.t.Helper()
+	req := handlers.TaskRequest{TaskBody: handlers.TaskBody{Title: title}, CategoryIds: categoryIDs}
+	status, raw := s.do(token, http.MethodPost, "/api/v1/task/", req)
+	require.Equal(s.t, http.StatusCreated, status, string(raw))
+
+	var task handlers.TaskResponse
+	decode(s.t, raw, &task)
+	require.NotEqual(s.t, uuid.Nil, task.ID)
+	return task.ID
+}
+
+func (s *testServer) listTasks(token string) []handlers.TaskShortResponse {
+	s.t.Helper()
+	status, raw := s.do(token, http.MethodPost, "/api/v1/task/all", handlers.Pagination{PageIndex: 1, RecordsPerPage: 100})
+	require.Equal(s.t, http.StatusOK, status, string(raw))
+
+	var list handlers.TasksList
+	decode(s.t, raw, &list)
+	return list.List
+}
+
+func (s *testServer) createCategory(token, name string) uuid.UUID {
+	s.t.Helper()
+	status, raw := s.do(token, http.MethodPost, "/api/v1/category/", handlers.CategoryBody{Name: name})
+	require.Equal(s.t, http.StatusCreated, status, string(raw))
+
+	var category handlers.CategoryResponse
+	decode(s.t, raw, &category)
+	require.NotEqual(s.t, uuid.Nil, category.ID)
+	return category.ID
+}
+
+func (s *testServer) listCategories(token string) []handlers.CategoryResponse {
+	s.t.Helper()
+	status, raw := s.do(token, http.MethodPost, "/api/v1/category/all", handlers.Pagination{PageIndex: 1, RecordsPerPage: 100})
+	require.Equal(s.t, http.StatusOK, status, string(raw))
+
+	var list handlers.CategoriesResponse
+	decode(s.t, raw, &list)
+	return list.Categories
+}
+
+func decode(t *testing.T, raw []byte, v interface{}) {
+	t.Helper()
+	require.NoError(t, json.Unmarshal(raw, v), string(raw))
+}
+
+// categoryNames maps the IDs of categories to their names.
+func categoryNames(categories []handlers.CategoryResponse) map[uuid.UUID]string {
+	names := make(map[uuid.UUID]string, len(categories))
+	for _, category := range categories {
+		names[category.ID] = category.Name
+	}
+	return names
+}
+
+// assertError checks that the body is a response.Response error with the
+// given message.
+func assertError(t *testing.T, raw []byte, message string) {
+	t.Helper()
+	var resp response.Response
+	decode(t, raw, &resp)
+	assert.Equal(t, response.StatusError, resp.Status)
+	if message != "" {
+		assert.Equal(t, message, resp.Message)
+	}
+}
+
+func TestE2E_SignUpAndSignIn(t *testing.T) {
+	s := newTestServer(t)
+	s.signUp("alice", "password123")
+
+	status, raw := s.do("", http.MethodPost, "/api/v1/sign-up", handlers.UserInfo{Name: "alice", Password: "other"})
+	assert.Equal(t, http.StatusInternalServerError, status, "names are unique")
+	assertError(t, raw, "")
+
+	status, raw = s.do("", http.MethodPost, "/api/v1/sign-in", handlers.UserInfo{Name: "alice", Password: "password123"})
+	require.Equal(t, http.StatusOK, status, string(raw))
+	var token handlers.Token
+	decode(t, raw, &token)
+	assert.NotEmpty(t, token.Token)
+
+	status, raw = s.do("", http.MethodPost, "/api/v1/sign-in", handlers.UserInfo{Name: "nobody", Password: "password123"})
+	assert.Equal(t, http.StatusNotFound, status)
+	assertError(t, raw, "")
+
+	status, raw = s.do("", http.MethodPost, "/api/v1/task/all", handlers.Pagination{PageIndex: 1, RecordsPerPage: 10})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assertError(t, raw, "Error in parsing token")
+
+	status, raw = s.do("not-a-jwt", http.MethodPost, "/api/v1/task/all", handlers.Pagination{PageIndex: 1, RecordsPerPage: 10})
+	assert.Equal(t, http.StatusUnauthorized, status)
+	assertError(t, raw, "")
+}
+
+func TestE2E_TaskAndCategoryCRUD(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp("alice", "password123")
+
+	work := s.createCategory(token, "work")
+	home := s.createCategory(token, "home")
+	assert.Len(t, s.listCategories(token), 2)
+
+	taskID := s.createTask(token, "report", work)
+
+	status, raw := s.do(token, http.MethodGet, "/api/v1/task/"+taskID.String(), nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	var task handlers.TaskResponse
+	decode(t, raw, &task)
+	assert.Equal(t, "report", task.Title)
+	assert.False(t, task.IsDone)
+	assert.Equal(t, map[uuid.UUID]string{work: "work"}, categoryNames(task.Categories))
+
+	edit := handlers.TaskRequest{
+		TaskBody:    handlers.TaskBody{Title: "annual report", Description: "due friday"},
+		CategoryIds: []uuid.UUID{home},
+	}
+	status, raw = s.do(token, http.MethodPatch, "/api/v1/task/"+taskID.String(), edit)
+	require.Equal(t, http.StatusOK, status, string(raw))
+
+	unknown := handlers.TaskRequest{TaskBody: edit.TaskBody, CategoryIds: []uuid.UUID{home, uuid.New()}}
+	status, raw = s.do(token, http.MethodPatch, "/api/v1/task/"+taskID.String(), unknown)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assertError(t, raw, "")
+	status, raw = s.do(token, http.MethodPost, "/api/v1/task", unknown)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assertError(t, raw, "")
+
+	status, raw = s.do(token, http.MethodPost, "/api/v1/task/"+taskID.String()+"/readiness", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	var meta handlers.TaskMeta
+	decode(t, raw, &meta)
+	assert.Equal(t, handlers.TaskMeta{ID: taskID, IsDone: true}, meta)
+
+	status, raw = s.do(token, http.MethodGet, "/api/v1/task/"+taskID.String(), nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	decode(t, raw, &task)
+	assert.Equal(t, "annual report", task.Title)
+	assert.Equal(t, "due friday", task.Description)
+	assert.True(t, task.IsDone)
+	assert.Equal(t, map[uuid.UUID]string{home: "home"}, categoryNames(task.Categories))
+
+	status, raw = s.do(token, http.MethodGet, "/api/v1/task/not-a-uuid", nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assertError(t, raw, "invalid UUID")
+
+	status, raw = s.do(token, http.MethodDelete, "/api/v1/task/"+taskID.String(), nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	assert.Empty(t, s.listTasks(token))
+
+	status, raw = s.do(token, http.MethodDelete, "/api/v1/category/"+work.String(), nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	status, raw = s.do(token, http.MethodDelete, "/api/v1/category/"+work.String(), nil)
+	assert.Equal(t, http.StatusNotFound, status)
+	assertError(t, raw, "")
+	assert.Len(t, s.listCategories(token), 1)
+}
+
+func TestE2E_OwnershipDenials(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp("alice", "password123")
+	bob := s.signUp("bob", "password123")
+
+	work := s.createCategory(alice, "work")
+	taskID := s.createTask(alice, "report", work)
+	taskPath := "/api/v1/task/" + taskID.String()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   interface{}
+		// status is 403 when zero.
+		status  int
+		message string
+	}{
+		{name: "get task", method: http.MethodGet, path: taskPath, message: "Unauthorized access to task"},
+		{name: "edit task", method: http.MethodPatch, path: taskPath, body: handlers.TaskRequest{TaskBody: handlers.TaskBody{Title: "mine"}}, message: "Unauthorized access to task"},
+		{name: "toggle task", method: http.MethodPost, path: taskPath + "/readiness", message: "Unauthorized access to task"},
+		{name: "delete task", method: http.MethodDelete, path: taskPath, message: "Unauthorized access to task"},
+		{name: "move task", method: http.MethodPost, path: taskPath + "/move", body: handlers.MoveTaskRequest{StatusID: uuid.New()}, message: "Unauthorized access to task"},
+		{
+			name:    "task with foreign category",
+			method:  http.MethodPost,
+			path:    "/api/v1/task/",
+			body:    handlers.TaskRequest{TaskBody: handlers.TaskBody{Title: "sneaky"}, CategoryIds: []uuid.UUID{work}},
+			message: "Unauthorized access to categories",
+		},
+		{
+			name:    "delete foreign category",
+			method:  http.MethodDelete,
+			path:    "/api/v1/category/" + work.String(),
+			status:  http.StatusNotFound,
+			message: "failed to delete category: Category not found",
+		},
+		{
+			name:    "delete foreign category through v2",
+			method:  http.MethodDelete,
+			path:    "/api/v2/categories/" + work.String(),
+			status:  http.StatusNotFound,
+			message: "failed to delete category: Category not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := tt.status
+			if expected == 0 {
+				expected = http.StatusForbidden
+			}
+			status, raw := s.do(bob, tt.method, tt.path, tt.body)
+			assert.Equal(t, expected, status)
+			assertError(t, raw, tt.message)
+		})
+	}
+
+	assert.Empty(t, s.listTasks(bob))
+	assert.Empty(t, s.listCategories(bob))
+
+	tasks := s.listTasks(alice)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "report", tasks[0].Title, "the denied requests changed nothing")
+	assert.False(t, tasks[0].IsDone)
+	categories := s.listCategories(alice)
+	require.Len(t, categories, 1)
+	assert.Equal(t, work, categories[0].ID, "the category survives the denied deletes")
+	status, raw := s.do(alice, http.MethodGet, taskPath, nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	var task handlers.TaskResponse
+	decode(t, raw, &task)
+	assert.Equal(t, map[uuid.UUID]string{work: "work"}, categoryNames(task.Categories))
+}
+
+func TestE2E_CreateReturnsResource(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp("alice", "password123")
+
+	header := http.Header{handlers.IdempotencyKeyHeader: {"category-1"}}
+	status, respHeader, raw := s.send(token, http.MethodPost, "/api/v1/category", header, handlers.CategoryBody{Name: "work"})
+	require.Equal(t, http.StatusCreated, status, string(raw))
+	var category handlers.CategoryResponse
+	decode(t, raw, &category)
+	assert.Equal(t, "work", category.Name)
+	assert.Equal(t, "/api/v1/category/"+category.ID.String(), respHeader.Get("Location"))
+
+	status, _, raw = s.send(token, http.MethodPost, "/api/v1/category", header, handlers.CategoryBody{Name: "work"})
+	require.Equal(t, http.StatusCreated, status, string(raw), "a replay is not a name conflict")
+	var replayed handlers.CategoryResponse
+	decode(t, raw, &replayed)
+	assert.Equal(t, category, replayed)
+
+	req := handlers.TaskRequest{TaskBody: handlers.TaskBody{Title: "report"}, CategoryIds: []uuid.UUID{category.ID}}
+	header = http.Header{handlers.IdempotencyKeyHeader: {"task-1"}}
+	status, respHeader, raw = s.send(token, http.MethodPost, "/api/v1/task", header, req)
+	require.Equal(t, http.StatusCreated, status, string(raw))
+	var task handlers.TaskResponse
+	decode(t, raw, &task)
+	assert.Equal(t, "report", task.Title)
+	assert.Equal(t, []handlers.CategoryResponse{category}, task.Categories)
+	location := respHeader.Get("Location")
+	assert.Equal(t, "/api/v1/task/"+task.ID.String(), location)
+
+	status, raw = s.do(token, http.MethodGet, location, nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	var fetched handlers.TaskResponse
+	decode(t, raw, &fetched)
+	assert.Equal(t, task, fetched, "the Location header points at the created task")
+
+	status, _, raw = s.send(token, http.MethodPost, "/api/v1/task", header, req)
+	require.Equal(t, http.StatusCreated, status, string(raw))
+	var replayedTask handlers.TaskResponse
+	decode(t, raw, &replayedTask)
+	assert.Equal(t, task.ID, replayedTask.ID)
+	assert.Len(t, s.listTasks(token), 1, "a replayed key creates nothing")
+
+	header = http.Header{handlers.IdempotencyKeyHeader: {strings.Repeat("k", 256)}}
+	status, _, raw = s.send(token, http.MethodPost, "/api/v1/task", header, req)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assertError(t, raw, "")
+}
+
+func TestE2E_V2(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.signUp("alice", "password123")
+	bob := s.signUp("bob", "password123")
+
+	status, raw := s.do(alice, http.MethodPost, "/api/v2/categories", handlers.CategoryBody{Name: "work"})
+	require.Equal(t, http.StatusCreated, status, string(raw))
+	var work handlers.CategoryResponse
+	decode(t, raw, &work)
+
+	status, raw = s.do(alice, http.MethodGet, "/api/v2/categories?records_per_page=10", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	var categories handlers.CategoriesResponse
+	decode(t, raw, &categories)
+	assert.Equal(t, []handlers.CategoryResponse{work}, categories.Categories)
+
+	create := handlers.TaskRequest{TaskBody: handlers.TaskBody{Title: "report", Description: "quarterly"}, CategoryIds: []uuid.UUID{work.ID}}
+	status, header, raw := s.send(alice, http.MethodPost, "/api/v2/tasks", nil, create)
+	require.Equal(t, http.StatusCreated, status, string(raw))
+	var task handlers.TaskResponse
+	decode(t, raw, &task)
+	location := header.Get("Location")
+	assert.Equal(t, "/api/v2/tasks/"+task.ID.String(), location)
+	s.createTask(alice, "other")
+
+	status, raw = s.do(alice, http.MethodPatch, location, map[string]interface{}{"is_done": true})
+	require.Equal(t, http.StatusOK, status, string(raw))
+	decode(t, raw, &task)
+	assert.True(t, task.IsDone)
+	assert.Equal(t, "quarterly", task.Description, "PATCH keeps the fields it was not given")
+	assert.Equal(t, []handlers.CategoryResponse{work}, task.Categories)
+
+	status, raw = s.do(alice, http.MethodGet, "/api/v2/tasks?done=true&category="+work.ID.String(), nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	var list handlers.TasksList
+	decode(t, raw, &list)
+	assert.Equal(t, []handlers.TaskShortResponse{{TaskMeta: task.TaskMeta, Title: "report", TaskTimestamps: task.TaskTimestamps}}, list.List)
+
+	status, raw = s.do(alice, http.MethodGet, "/api/v2/tasks?page_index=2&records_per_page=1", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	decode(t, raw, &list)
+	require.Len(t, list.List, 1)
+	assert.Equal(t, "report", list.List[0].Title)
+
+	for _, query := range []string{"done=maybe", "category=not-a-uuid", "page_index=0", "records_per_page=x"} {
+		status, raw = s.do(alice, http.MethodGet, "/api/v2/tasks?"+query, nil)
+		assert.Equal(t, http.StatusBadRequest, status, query)
+		assertError(t, raw, "")
+	}
+
+	status, raw = s.do(alice, http.MethodPut, location, handlers.TaskReplaceRequest{TaskRequest: handlers.TaskRequest{TaskBody: handlers.TaskBody{Title: "replaced"}}})
+	require.Equal(t, http.StatusOK, status, string(raw))
+	decode(t, raw, &task)
+	assert.Equal(t, "replaced", task.Title)
+	assert.Empty(t, task.Description, "PUT overwrites every field")
+	assert.False(t, task.IsDone)
+	assert.Empty(t, task.Categories)
+
+	status, raw = s.do(alice, http.MethodPatch, location, map[string]interface{}{"category_ids": []uuid.UUID{uuid.New()}})
+	assert.Equal(t, http.StatusBadRequest, status, "unknown categories")
+	assertError(t, raw, "")
+
+	status, raw = s.do(bob, http.MethodPatch, location, map[string]interface{}{"title": "stolen"})
+	assert.Equal(t, http.StatusForbidden, status)
+	assertError(t, raw, "")
+	status, raw = s.do(bob, http.MethodGet, "/api/v2/tasks", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	decode(t, raw, &list)
+	assert.Empty(t, list.List)
+
+	status, raw = s.do(alice, http.MethodDelete, location, nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	status, raw = s.do(alice, http.MethodDelete, "/api/v2/categories/"+work.ID.String(), nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	assert.Len(t, s.listTasks(alice), 1, "v1 and v2 share the data")
+}
+
+func TestE2E_Timestamps(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp("alice", "password123")
+	first := s.createTask(token, "b")
+	second := s.createTask(token, "a")
+	s.createTask(token, "c")
+
+	for _, id := range []uuid.UUID{first, second} {
+		status, raw := s.do(token, http.MethodPost, "/api/v1/task/"+id.String()+"/readiness", nil)
+		require.Equal(t, http.StatusOK, status, string(raw))
+	}
+	status, raw := s.do(token, http.MethodGet, "/api/v1/task/"+second.String(), nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	var task handlers.TaskResponse
+	decode(t, raw, &task)
+	require.NotNil(t, task.CompletedAt)
+	assert.False(t, task.CreatedAt.IsZero())
+	assert.False(t, task.CompletedAt.Before(task.CreatedAt))
+
+	list := func(query string) []string {
+		t.Helper()
+		status, raw := s.do(token, http.MethodGet, "/api/v2/tasks?"+query, nil)
+		require.Equal(t, http.StatusOK, status, string(raw))
+		var list handlers.TasksList
+		decode(t, raw, &list)
+		result := make([]string, len(list.List))
+		for i, task := range list.List {
+			result[i] = task.Title
+		}
+		return result
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, list(""))
+	assert.Equal(t, []string{"c", "b", "a"}, list("sort=-title"))
+	assert.Equal(t, []string{"b", "a", "c"}, list("sort=created_at"))
+	assert.Equal(t, []string{"a", "b", "c"}, list("sort=-completed_at"), "tasks that are not done come last")
+	from := url.QueryEscape(task.CompletedAt.Format(time.RFC3339Nano))
+	assert.Equal(t, []string{"a"}, list("completed_from="+from))
+	assert.Equal(t, []string{"b"}, list("completed_to="+from))
+
+	for _, query := range []string{"sort=priority", "sort=-", "created_from=yesterday", "completed_to=2024-03-01"} {
+		status, raw = s.do(token, http.MethodGet, "/api/v2/tasks?"+query, nil)
+		assert.Equal(t, http.StatusBadRequest, status, query)
+		assertError(t, raw, "")
+	}
+}
+
+func TestE2E_Stats(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp("alice", "password123")
+	work := s.createCategory(token, "work")
+	done := s.createTask(token, "report", work)
+	s.createTask(token, "review", work)
+	status, raw := s.do(token, http.MethodPost, "/api/v1/task/"+done.String()+"/readiness", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+
+	status, raw = s.do(token, http.MethodGet, "/api/v1/stats", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	var stats handlers.StatsResponse
+	decode(t, raw, &stats)
+	today := time.Now().UTC().Format(time.DateOnly)
+	assert.EqualValues(t, 1, stats.Open)
+	assert.EqualValues(t, 1, stats.Completed)
+	assert.Equal(t, "day", stats.Period)
+	assert.Equal(t, today, stats.To)
+	require.Len(t, stats.Completions, 30, "every day of the range, also without completions")
+	assert.Equal(t, handlers.PeriodCountResponse{Start: today, Count: 1}, stats.Completions[29])
+	assert.Equal(t, stats.From, stats.Completions[0].Start)
+	assert.NotNil(t, stats.AverageTimeToCompleteSeconds)
+	assert.Equal(t, []handlers.CategoryStatsResponse{{ID: work, Name: "work", Open: 1, Completed: 1}}, stats.Categories)
+	assert.Equal(t, 1, stats.CurrentStreakDays)
+
+	status, raw = s.do(token, http.MethodGet, "/api/v1/stats?period=week&from=2024-03-06&to=2024-03-20", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	decode(t, raw, &stats)
+	assert.Equal(t, "2024-03-04", stats.From, "the range covers whole weeks")
+	assert.Equal(t, "2024-03-24", stats.To)
+	assert.Equal(t, []handlers.PeriodCountResponse{{Start: "2024-03-04"}, {Start: "2024-03-11"}, {Start: "2024-03-18"}}, stats.Completions)
+	assert.Nil(t, stats.AverageTimeToCompleteSeconds)
+
+	for _, query := range []string{"period=month", "from=yesterday", "to=2024-13-01", "from=2024-03-02&to=2024-03-01", "from=2020-01-01&to=2024-01-01"} {
+		status, raw = s.do(token, http.MethodGet, "/api/v1/stats?"+query, nil)
+		assert.Equal(t, http.StatusBadRequest, status, query)
+		assertError(t, raw, "")
+	}
+}
+
+func TestE2E_Board(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp("alice", "password123")
+	first := s.createTask(token, "first")
+
+	status, raw := s.do(token, http.MethodGet, "/api/v1/board", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	var board handlers.BoardResponse
+	decode(t, raw, &board)
+	assert.Empty(t, board.Columns)
+
+	for _, statuses := range [][]handlers.StatusRequest{
+		{{Name: "only"}},
+		{{Name: "todo"}, {Name: "todo"}},
+		{{Name: "todo"}, {Name: " "}},
+		{{Name: "todo"}, {Name: strings.Repeat("я", 51)}},
+		{{Name: "todo"}, {ID: uuid.New(), Name: "done"}},
+	} {
+		status, raw = s.do(token, http.MethodPut, "/api/v1/workflow", handlers.WorkflowRequest{Statuses: statuses})
+		assert.Equal(t, http.StatusBadRequest, status, string(raw))
+	}
+
+	status, raw = s.do(token, http.MethodPut, "/api/v1/workflow", handlers.WorkflowRequest{Statuses: []handlers.StatusRequest{{Name: "todo"}, {Name: "doing"}, {Name: "done"}}})
+	require.Equal(t, http.StatusOK, status, string(raw))
+	var workflow handlers.WorkflowResponse
+	decode(t, raw, &workflow)
+	require.Len(t, workflow.Statuses, 3)
+	todo, doing, done := workflow.Statuses[0], workflow.Statuses[1], workflow.Statuses[2]
+	assert.True(t, done.Terminal)
+	assert.Equal(t, 1, doing.Position)
+
+	second := s.createTask(token, "second")
+	path := "/api/v1/task/" + second.String()
+	status, header, raw := s.send(token, http.MethodPost, path+"/move", http.Header{"If-Match": {`"1"`}}, handlers.MoveTaskRequest{StatusID: doing.ID})
+	require.Equal(t, http.StatusOK, status, string(raw))
+	var task handlers.TaskResponse
+	decode(t, raw, &task)
+	assert.Equal(t, &doing.ID, task.StatusID)
+	assert.False(t, task.IsDone)
+	assert.Equal(t, `"2"`, header.Get("ETag"))
+
+	status, _, raw = s.send(token, http.MethodPost, path+"/move", http.Header{"If-Match": {`"1"`}}, handlers.MoveTaskRequest{StatusID: done.ID})
+	assert.Equal(t, http.StatusPreconditionFailed, status, string(raw))
+	status, raw = s.do(token, http.MethodPost, path+"/move", handlers.MoveTaskRequest{StatusID: uuid.New()})
+	assert.Equal(t, http.StatusBadRequest, status, string(raw))
+	status, raw = s.do(token, http.MethodPost, path+"/move", handlers.MoveTaskRequest{StatusID: done.ID, Position: -1})
+	assert.Equal(t, http.StatusBadRequest, status, string(raw))
+
+	status, raw = s.do(token, http.MethodPost, path+"/move", handlers.MoveTaskRequest{StatusID: done.ID})
+	require.Equal(t, http.StatusOK, status, string(raw))
+	decode(t, raw, &task)
+	assert.True(t, task.IsDone, "the terminal column completes the task")
+	assert.NotNil(t, task.CompletedAt)
+
+	status, raw = s.do(token, http.MethodPost, "/api/v1/task/"+first.String()+"/readiness", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+
+	status, raw = s.do(token, http.MethodGet, "/api/v1/board", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	decode(t, raw, &board)
+	require.Len(t, board.Columns, 3)
+	assert.Equal(t, todo, board.Columns[0].StatusResponse)
+	assert.Empty(t, board.Columns[0].Tasks)
+	assert.Empty(t, board.Columns[1].Tasks)
+	require.Len(t, board.Columns[2].Tasks, 2)
+	assert.Equal(t, []uuid.UUID{second, first}, []uuid.UUID{board.Columns[2].Tasks[0].ID, board.Columns[2].Tasks[1].ID}, "readiness appends to the terminal column")
+	assert.Equal(t, &done.ID, board.Columns[2].Tasks[1].StatusID)
+
+	status, raw = s.do(token, http.MethodPost, path+"/readiness", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	var meta handlers.TaskMeta
+	decode(t, raw, &meta)
+	assert.False(t, meta.IsDone)
+	status, raw = s.do(token, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	decode(t, raw, &task)
+	assert.Equal(t, &todo.ID, task.StatusID, "reopening moves the task back to the first column")
+
+	status, raw = s.do(token, http.MethodPut, "/api/v1/workflow", handlers.WorkflowRequest{})
+	require.Equal(t, http.StatusOK, status, string(raw))
+	status, raw = s.do(token, http.MethodGet, "/api/v1/workflow", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	decode(t, raw, &workflow)
+	assert.Empty(t, workflow.Statuses)
+	for _, listed := range s.listTasks(token) {
+		assert.Nil(t, listed.StatusID)
+	}
+}
+
+func TestE2E_ConditionalRequests(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp("alice", "password123")
+	work := s.createCategory(token, "work")
+	taskID := s.createTask(token, "report", work)
+	path := "/api/v1/task/" + taskID.String()
+
+	status, header, raw := s.send(token, http.MethodGet, path, nil, nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	etag := header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	status, _, raw = s.send(token, http.MethodGet, path, http.Header{"If-None-Match": {etag}}, nil)
+	assert.Equal(t, http.StatusNotModified, status)
+	assert.Empty(t, raw)
+
+	status, raw = s.do(token, http.MethodPost, path+"/readiness", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+
+	status, header, raw = s.send(token, http.MethodGet, path, http.Header{"If-None-Match": {etag}}, nil)
+	require.Equal(t, http.StatusOK, status, "the toggle changed the task")
+	assert.NotEqual(t, etag, header.Get("ETag"))
+	stale, etag := etag, header.Get("ETag")
+
+	edit := handlers.TaskRequest{TaskBody: handlers.TaskBody{Title: "renamed"}}
+	status, _, raw = s.send(token, http.MethodPatch, path, http.Header{"If-Match": {stale}}, edit)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	assertError(t, raw, "")
+	status, _, raw = s.send(token, http.MethodPatch, path, http.Header{"If-Match": {`W/` + etag}}, edit)
+	assert.Equal(t, http.StatusPreconditionFailed, status, "If-Match compares strongly")
+	assertError(t, raw, "")
+	status, _, raw = s.send(token, http.MethodPatch, path, http.Header{"If-Match": {etag + ", " + stale}}, edit)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assertError(t, raw, "")
+	status, _, raw = s.send(token, http.MethodPatch, path, http.Header{"If-Match": {etag}}, edit)
+	require.Equal(t, http.StatusOK, status, string(raw))
+
+	status, header, raw = s.send(token, http.MethodPatch, "/api/v2/tasks/"+taskID.String(), http.Header{"If-Match": {"*"}}, map[string]interface{}{"description": "text"})
+	require.Equal(t, http.StatusOK, status, string(raw))
+	etag = header.Get("ETag")
+
+	status, header, raw = s.send(token, http.MethodGet, "/api/v2/tasks", nil, nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	listETag := header.Get("ETag")
+	status, _, _ = s.send(token, http.MethodGet, "/api/v2/tasks", http.Header{"If-None-Match": {`"other", ` + listETag}}, nil)
+	assert.Equal(t, http.StatusNotModified, status)
+	s.createTask(token, "another")
+	status, _, _ = s.send(token, http.MethodGet, "/api/v2/tasks", http.Header{"If-None-Match": {listETag}}, nil)
+	assert.Equal(t, http.StatusOK, status, "a new task changes the list")
+
+	status, header, _ = s.send(token, http.MethodGet, "/api/v2/categories", nil, nil)
+	require.Equal(t, http.StatusOK, status)
+	status, _, _ = s.send(token, http.MethodGet, "/api/v2/categories", http.Header{"If-None-Match": {header.Get("ETag")}}, nil)
+	assert.Equal(t, http.StatusNotModified, status)
+
+	status, _, raw = s.send(token, http.MethodDelete, path, http.Header{"If-Match": {stale}}, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, status)
+	assertError(t, raw, "")
+	status, _, raw = s.send(token, http.MethodDelete, path, http.Header{"If-Match": {etag}}, nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+}
+
+func TestE2E_UserDeletion(t *testing.T) {
+	s := newTestServer(t)
+	token := s.signUp("alice", "password123")
+	s.createTask(token, "report")
+
+	status, raw := s.do(token, http.MethodPost, "/api/v1/user/deletion/cancel", nil)
+	assert.Equal(t, http.StatusConflict, status)
+	assertError(t, raw, "")
+
+	status, raw = s.do(token, http.MethodDelete, "/api/v1/user", nil)
+	require.Equal(t, http.StatusAccepted, status, string(raw))
+	var schedule handlers.DeletionSchedule
+	decode(t, raw, &schedule)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), schedule.DeletionScheduledAt, time.Minute)
+
+	status, raw = s.do(token, http.MethodPost, "/api/v1/user/deletion/cancel", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+
+	status, raw = s.do(token, http.MethodDelete, "/api/v1/user", nil)
+	require.Equal(t, http.StatusAccepted, status, string(raw))
+
+	// The purge job deletes the account once the grace period is over.
+	ctx := context.Background()
+	user, err := s.repos.Users.GetUserByName(ctx, "alice")
+	require.NoError(t, err)
+	require.NoError(t, s.repos.Accounts.ScheduleDeletion(ctx, user.ID, time.Now().Add(-time.Minute)))
+	purge := adapters.NewAccountAdapter(s.repos.Accounts, s.repos.Users, s.repos.Transactor, s.cfg.DeletionGracePeriod)
+	deleted, err := purge.PurgeDueDeletions(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+
+	status, raw = s.do("", http.MethodPost, "/api/v1/sign-in", handlers.UserInfo{Name: "alice", Password: "password123"})
+	assert.Equal(t, http.StatusNotFound, status)
+	assertError(t, raw, "")
+	status, raw = s.do(token, http.MethodPost, "/api/v1/task/all", handlers.Pagination{PageIndex: 1, RecordsPerPage: 100})
+	assert.Equal(t, http.StatusUnauthorized, status, "the token went with the account")
+	assertError(t, raw, "")
+}
+
+func TestE2E_TokensFollowTheAccount(t *testing.T) {
+	s := newTestServer(t)
+	ctx := context.Background()
+	adminToken := s.signUp("alice", "password123")
+	token := s.signUp("bob", "password123")
+	alice, err := s.repos.Users.GetUserByName(ctx, "alice")
+	require.NoError(t, err)
+	bob, err := s.repos.Users.GetUserByName(ctx, "bob")
+	require.NoError(t, err)
+
+	// Roles come from the account, not from the claims of the token.
+	status, _ := s.do(adminToken, http.MethodGet, "/api/v1/admin/users", nil)
+	assert.Equal(t, http.StatusForbidden, status)
+	require.NoError(t, s.repos.Admin.SetUserRole(ctx, alice.ID, models.RoleAdmin))
+	status, raw := s.do(adminToken, http.MethodGet, "/api/v1/admin/users", nil)
+	assert.Equal(t, http.StatusOK, status, string(raw))
+
+	status, raw = s.do(adminToken, http.MethodPost, "/api/v1/admin/users/"+bob.ID.String()+"/disable", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	status, raw = s.do(token, http.MethodPost, "/api/v1/task/all", handlers.Pagination{PageIndex: 1, RecordsPerPage: 10})
+	assert.Equal(t, http.StatusForbidden, status, "a disabled user loses access at once")
+	assertError(t, raw, "")
+
+	status, raw = s.do(adminToken, http.MethodPost, "/api/v1/admin/users/"+bob.ID.String()+"/enable", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	assert.Empty(t, s.listTasks(token))
+
+	status, raw = s.do(adminToken, http.MethodPost, "/api/v1/admin/users/"+bob.ID.String()+"/password-reset", nil)
+	require.Equal(t, http.StatusOK, status, string(raw))
+	status, _ = s.do(token, http.MethodPost, "/api/v1/task/all", handlers.Pagination{PageIndex: 1, RecordsPerPage: 10})
+	assert.Equal(t, http.StatusForbidden, status, "a forced password reset signs the user out")
+
+	require.NoError(t, s.repos.Admin.SetUserRole(ctx, alice.ID, models.RoleUser))
+	status, _ = s.do(adminToken, http.MethodGet, "/api/v1/admin/users", nil)
+	assert.Equal(t, http.StatusForbidden, status, "a demoted admin loses the role at once")
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"todolist/internal/models"
+)
+
+// errSeveralETags rejects an If-Match header listing more than one entity
+// tag: a conditional write compares against a single task version.
+var errSeveralETags = errors.New("If-Match with several entity tags is not supported")
+
+// taskETag is the strong entity tag of a task, its version.
+func taskETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// taskListETag changes whenever a task of the page changes, appears or
+// disappears.
+func taskListETag(tasks []models.TaskShortInfo) string {
+	hash := sha256.New()
+	for _, task := range tasks {
+		fmt.Fprintf(hash, "%s:%d\n", task.ID, task.Version)
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// categoryListETag hashes ids and names: categories are never edited, only
+// created and deleted.
+func categoryListETag(categories []models.Category) string {
+	hash := sha256.New()
+	for _, category := range categories {
+		fmt.Fprintf(hash, "%s:%s\n", category.ID, category.Name)
+	}
+	return `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// notModified sets the ETag header and answers 304 when If-None-Match
+// already names it. Like the RFC it compares weakly, ignoring a W/ prefix.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	for _, candidate := range strings.Split(r.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion returns the task version a write is conditional on, zero
+// without If-Match or with "*". A tag that is not a task version can never
+// match, so it maps to -1 and the write fails with 412.
+func ifMatchVersion(r *http.Request) (int64, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, errSeveralETags
+	}
+
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return -1, nil
+	}
+	version, err := strconv.ParseInt(header[1:len(header)-1], 10, 64)
+	if err != nil || version < 1 {
+		return -1, nil
+	}
+	return version, nil
+}
@@ -2,57 +2,92 @@ package handlers
 
 import (
 	"net/http"
+	"strings"
 	"todolist/config"
 	"todolist/internal/adapters"
 	"todolist/internal/middleware"
+	"todolist/internal/models"
 	auth_utils "todolist/internal/pkg/authUtils"
-	"todolist/internal/repository"
+	"todolist/internal/ratelimit"
+	"todolist/internal/tracing"
 
 	"github.com/go-chi/chi/v5"
 	"gorm.io/gorm"
 )
 
 type Handlers struct {
-	db     *gorm.DB
+	repos  *adapters.Repositories
 	router *chi.Mux
 
-	cfg *config.ServiceConfig
+	cfg     *config.Config
+	limiter *ratelimit.Limiter
 }
 
-func NewHandlers(cfg *config.ServiceConfig, db *gorm.DB, router *chi.Mux) *Handlers {
+// NewHandlers serves the API from repos. The db is only needed by the
+// postgres rate limit backend and is nil with memory storage.
+func NewHandlers(cfg *config.Config, repos *adapters.Repositories, db *gorm.DB, router *chi.Mux) *Handlers {
 	return &Handlers{
-		cfg:    cfg,
-		db:     db,
-		router: router,
+		cfg:     cfg,
+		repos:   repos,
+		router:  router,
+		limiter: newLimiter(cfg.RateLimitConfig, db),
 	}
 }
 
+func newLimiter(cfg config.RateLimitConfig, db *gorm.DB) *ratelimit.Limiter {
+	limits := cfg.Limits
+	if !cfg.Enabled {
+		limits = nil
+	}
+
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if cfg.Backend == "postgres" {
+		store = ratelimit.NewPostgresStore(db)
+	}
+	return ratelimit.NewLimiter(store, limits, cfg.TrustForwardedFor)
+}
+
+// newAuthMiddleware checks the access token and the current state of its
+// user on every request.
+func (h Handlers) newAuthMiddleware() middleware.JwtAuthMiddleware {
+	jwtHandler := auth_utils.NewJWTTokenHandler()
+	users := adapters.NewAuthService(h.repos.Users, h.repos.Transactor, jwtHandler, h.cfg.JWTSecret)
+	return middleware.NewJwtAuthMiddleware(h.cfg.JWTSecret, jwtHandler, users)
+}
+
 func (h Handlers) InitHandlers() {
 	h.initUserHandlers()
 	h.initTaskHandlers()
 	h.initCategoryHandlers()
+	h.initAdminHandlers()
+	h.initStatsHandlers()
+	h.initWorkflowHandlers()
+	h.initV2Handlers()
 
+	if h.cfg.OIDCConfig.Enabled {
+		h.initOIDCHandlers()
+	}
 }
 
 func (h Handlers) initTaskHandlers() {
-	taskRepo := repository.NewGormTaskRepository(h.db)
-	taskUseCase := adapters.NewTaskAdapter(taskRepo)
+	taskUseCase := adapters.NewTaskAdapter(h.repos.Tasks, h.repos.Transactor)
 
 	timeout := h.cfg.TaskTimeout
 
-	userRepo := repository.NewUserRepositoryAdapter(h.db)
 	jwtHandler := auth_utils.NewJWTTokenHandler()
-	userUseCase := adapters.NewAuthService(userRepo, jwtHandler, h.cfg.JWTSecret)
+	userUseCase := adapters.NewAuthService(h.repos.Users, h.repos.Transactor, jwtHandler, h.cfg.JWTSecret)
 
 	ownMiddleware := middleware.NewOwnershipMiddleware(*userUseCase, timeout)
 
-	tokenHandler := auth_utils.NewJWTTokenHandler()
-	authMiddleware := middleware.NewJwtAuthMiddleware(h.cfg.JWTSecret, tokenHandler)
+	authMiddleware := h.newAuthMiddleware()
 
 	h.router.Route("/api/v1/task", func(r chi.Router) {
-		r.With(authMiddleware.MiddlewareFunc).Group(func(r chi.Router) {
+		r.With(tracing.Middleware("JwtAuthMiddleware", authMiddleware.MiddlewareFunc)).Group(func(r chi.Router) {
+			r.With(h.limiter.Limit("task-list")).Post("/all", GetAllTasks(taskUseCase, timeout))
+
+			r.Use(h.limiter.Limit("default"))
 
-			r.With(ownMiddleware.CheckCategoriesMiddleware).Group(func(r chi.Router) {
+			r.With(tracing.Middleware("CheckCategoriesMiddleware", ownMiddleware.CheckCategoriesMiddleware)).Group(func(r chi.Router) {
 				r.Post("/", CreateTask(taskUseCase, timeout))
 			})
 
@@ -65,19 +100,65 @@ func (h Handlers) initTaskHandlers() {
 							next.ServeHTTP(w, r)
 						})
 					},
-					ownMiddleware.CheckTaskMiddleware, // Now {id} is available here
+					tracing.Middleware("CheckTaskMiddleware", ownMiddleware.CheckTaskMiddleware), // Now {id} is available here
 				)
 
 				r.Group(func(r chi.Router) {
-					r.With(ownMiddleware.CheckCategoriesMiddleware).Patch("/", EditTask(taskUseCase, timeout))
+					r.With(tracing.Middleware("CheckCategoriesMiddleware", ownMiddleware.CheckCategoriesMiddleware)).Patch("/", EditTask(taskUseCase, timeout))
 				})
 
 				r.Delete("/", DeleteTask(taskUseCase, timeout))
 				r.Post("/readiness", ToggleReadinessTask(taskUseCase, timeout))
+				r.Post("/move", MoveTask(taskUseCase, timeout))
 				r.Get("/", GetTask(taskUseCase, timeout))
 			})
+		})
+	})
+}
+
+// initV2Handlers serves tasks and categories with plural resource names,
+// GET lists with query parameters and partial PATCH updates. The v1 routes
+// stay as they are.
+func (h Handlers) initV2Handlers() {
+	timeout := h.cfg.TaskTimeout
+
+	taskUseCase := adapters.NewTaskAdapter(h.repos.Tasks, h.repos.Transactor)
+	categoryUseCase := adapters.NewCategoryAdapter(h.repos.Categories, h.repos.Transactor)
+
+	jwtHandler := auth_utils.NewJWTTokenHandler()
+	userUseCase := adapters.NewAuthService(h.repos.Users, h.repos.Transactor, jwtHandler, h.cfg.JWTSecret)
+	ownMiddleware := middleware.NewOwnershipMiddleware(*userUseCase, timeout)
+	checkCategories := tracing.Middleware("CheckCategoriesMiddleware", ownMiddleware.CheckCategoriesMiddleware)
+
+	authMiddleware := h.newAuthMiddleware()
+
+	h.router.Route("/api/v2", func(r chi.Router) {
+		r.Use(tracing.Middleware("JwtAuthMiddleware", authMiddleware.MiddlewareFunc))
+
+		r.Route("/tasks", func(r chi.Router) {
+			r.With(h.limiter.Limit("task-list")).Get("/", ListTasks(taskUseCase, timeout))
+
+			r.Group(func(r chi.Router) {
+				r.Use(h.limiter.Limit("default"))
+
+				r.With(checkCategories).Post("/", CreateTaskV2(taskUseCase, timeout))
+				r.Route("/{id}", func(r chi.Router) {
+					r.Use(tracing.Middleware("CheckTaskMiddleware", ownMiddleware.CheckTaskMiddleware))
+
+					r.Get("/", GetTaskV2(taskUseCase, timeout))
+					r.With(checkCategories).Put("/", ReplaceTask(taskUseCase, timeout))
+					r.With(checkCategories).Patch("/", PatchTask(taskUseCase, timeout))
+					r.Delete("/", DeleteTaskV2(taskUseCase, timeout))
+				})
+			})
+		})
 
-			r.Post("/all", GetAllTasks(taskUseCase, timeout))
+		r.Route("/categories", func(r chi.Router) {
+			r.Use(h.limiter.Limit("default"))
+
+			r.Get("/", ListCategories(categoryUseCase, timeout))
+			r.Post("/", CreateCategoryV2(categoryUseCase, timeout))
+			r.Delete("/{id}", DeleteCategoryV2(categoryUseCase, timeout))
 		})
 	})
 }
@@ -86,18 +167,29 @@ func (h Handlers) initUserHandlers() {
 
 	timeout := h.cfg.TaskTimeout
 
-	userRepo := repository.NewUserRepositoryAdapter(h.db)
 	jwtHandler := auth_utils.NewJWTTokenHandler()
-	userUseCase := adapters.NewAuthService(userRepo, jwtHandler, h.cfg.JWTSecret)
+	userUseCase := adapters.NewAuthService(h.repos.Users, h.repos.Transactor, jwtHandler, h.cfg.JWTSecret)
+
+	accountUseCase := adapters.NewAccountAdapter(h.repos.Accounts, h.repos.Users, h.repos.Transactor, h.cfg.DeletionGracePeriod)
 
-	tokenHandler := auth_utils.NewJWTTokenHandler()
-	authMiddleware := middleware.NewJwtAuthMiddleware(h.cfg.JWTSecret, tokenHandler)
+	authMiddleware := h.newAuthMiddleware()
 
 	h.router.Route("/api/v1", func(r chi.Router) {
-		r.Post("/sign-in", SignIn(userUseCase, timeout))
-		r.Post("/sign-up", SignUp(userUseCase, timeout))
-		r.With(authMiddleware.MiddlewareFunc).Group(func(r chi.Router) {
-			r.Delete("/user", DeleteUser(userUseCase, timeout))
+		r.With(h.limiter.Limit("sign-in")).Group(func(r chi.Router) {
+			r.Post("/sign-in", SignIn(userUseCase, timeout))
+			r.Post("/sign-in/2fa", SignInSecondFactor(userUseCase, timeout))
+			r.Post("/user/password", ChangePassword(userUseCase, timeout))
+		})
+		r.With(h.limiter.Limit("sign-up")).Post("/sign-up", SignUp(userUseCase, timeout))
+		r.With(tracing.Middleware("JwtAuthMiddleware", authMiddleware.MiddlewareFunc)).Group(func(r chi.Router) {
+			r.Use(h.limiter.Limit("default"))
+
+			r.Delete("/user", DeleteUser(accountUseCase, timeout))
+			r.Post("/user/deletion/cancel", CancelUserDeletion(accountUseCase, timeout))
+			r.Post("/user/export", ExportUserData(accountUseCase, timeout))
+			r.Post("/user/2fa/enroll", EnrollTOTP(userUseCase, timeout))
+			r.Post("/user/2fa/confirm", ConfirmTOTP(userUseCase, timeout))
+			r.Post("/user/2fa/disable", DisableTOTP(userUseCase, timeout))
 		})
 	})
 }
@@ -106,16 +198,95 @@ func (h Handlers) initCategoryHandlers() {
 
 	timeout := h.cfg.TaskTimeout
 
-	categoryRepo := repository.NewCategoryRepositoryAdapter(h.db)
-	categoryUseCase := adapters.NewCategoryAdapter(categoryRepo)
-	tokenHandler := auth_utils.NewJWTTokenHandler()
+	categoryUseCase := adapters.NewCategoryAdapter(h.repos.Categories, h.repos.Transactor)
 
-	authMiddleware := middleware.NewJwtAuthMiddleware(h.cfg.JWTSecret, tokenHandler)
+	authMiddleware := h.newAuthMiddleware()
 	h.router.Route("/api/v1/category", func(r chi.Router) {
-		r.With(authMiddleware.MiddlewareFunc).Group(func(r chi.Router) {
+		r.With(
+			tracing.Middleware("JwtAuthMiddleware", authMiddleware.MiddlewareFunc),
+			h.limiter.Limit("default"),
+		).Group(func(r chi.Router) {
 			r.Post("/all", GetCategories(categoryUseCase, timeout))
 			r.Post("/", CreateCategory(categoryUseCase, timeout))
 			r.Delete("/{id}", DeleteCategory(categoryUseCase, timeout))
 		})
 	})
 }
+
+func (h Handlers) initStatsHandlers() {
+
+	timeout := h.cfg.TaskTimeout
+
+	statsUseCase := adapters.NewStatsAdapter(h.repos.Stats)
+
+	authMiddleware := h.newAuthMiddleware()
+	h.router.With(
+		tracing.Middleware("JwtAuthMiddleware", authMiddleware.MiddlewareFunc),
+		h.limiter.Limit("default"),
+	).Get("/api/v1/stats", GetStats(statsUseCase, timeout))
+}
+
+// initWorkflowHandlers serves the board columns of the user; tasks move
+// between them through /api/v1/task/{id}/move.
+func (h Handlers) initWorkflowHandlers() {
+	timeout := h.cfg.TaskTimeout
+
+	workflowUseCase := adapters.NewWorkflowAdapter(h.repos.Workflow)
+
+	authMiddleware := h.newAuthMiddleware()
+	h.router.With(
+		tracing.Middleware("JwtAuthMiddleware", authMiddleware.MiddlewareFunc),
+		h.limiter.Limit("default"),
+	).Group(func(r chi.Router) {
+		r.Get("/api/v1/workflow", GetWorkflow(workflowUseCase, timeout))
+		r.Put("/api/v1/workflow", SetWorkflow(workflowUseCase, timeout))
+		r.Get("/api/v1/board", GetBoard(workflowUseCase, timeout))
+	})
+}
+
+func (h Handlers) initAdminHandlers() {
+
+	timeout := h.cfg.TaskTimeout
+
+	adminUseCase := adapters.NewAdminAdapter(h.repos.Admin)
+
+	authMiddleware := h.newAuthMiddleware()
+	h.router.Route("/api/v1/admin", func(r chi.Router) {
+		r.Use(
+			tracing.Middleware("JwtAuthMiddleware", authMiddleware.MiddlewareFunc),
+			tracing.Middleware("RequireRole", middleware.RequireRole(models.RoleAdmin)),
+			h.limiter.Limit("default"),
+		)
+
+		r.Get("/users", AdminListUsers(adminUseCase, timeout))
+		r.Route("/users/{id}", func(r chi.Router) {
+			r.Get("/", AdminGetUser(adminUseCase, timeout))
+			r.Post("/disable", AdminDisableUser(adminUseCase, timeout))
+			r.Post("/enable", AdminEnableUser(adminUseCase, timeout))
+			r.Post("/password-reset", AdminForcePasswordReset(adminUseCase, timeout))
+		})
+	})
+}
+
+func (h Handlers) initOIDCHandlers() {
+
+	timeout := h.cfg.TaskTimeout
+
+	jwtHandler := auth_utils.NewJWTTokenHandler()
+	oidcUseCase := adapters.NewOIDCAdapter(h.repos.Identities, h.repos.Users, jwtHandler, h.cfg.JWTSecret, adapters.OIDCOptions{
+		IssuerURL:    h.cfg.IssuerURL,
+		ClientID:     h.cfg.ClientID,
+		ClientSecret: h.cfg.ClientSecret,
+		RedirectURL:  h.cfg.RedirectURL,
+		Scopes:       h.cfg.Scopes,
+		StateTTL:     h.cfg.StateTTL,
+	})
+
+	secureCookie := strings.HasPrefix(h.cfg.RedirectURL, "https://")
+
+	h.router.Route("/api/v1/oidc", func(r chi.Router) {
+		r.Use(h.limiter.Limit("sign-in"))
+		r.Get("/login", OIDCLogin(oidcUseCase, timeout, secureCookie))
+		r.Get("/callback", OIDCCallback(oidcUseCase, timeout, secureCookie))
+	})
+}
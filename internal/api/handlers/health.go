@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"todolist/internal/health"
+	"todolist/internal/pkg/response"
+
+	"github.com/go-chi/render"
+	"github.com/rs/zerolog/log"
+)
+
+type ReadinessChecker interface {
+	Check(ctx context.Context) health.Report
+}
+
+// @Summary Liveness
+// @Tags health
+// @Description Процесс жив. Не проверяет зависимости, чтобы оркестратор не перезапускал сервис из-за недоступной базы
+// @ID healthz
+// @Produce  json
+// @Success 200 {object} response.Response
+// @Router /healthz [get]
+func Liveness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, response.OK())
+	}
+}
+
+// @Summary Readiness
+// @Tags health
+// @Description Готовность принимать запросы: доступность Postgres, наличие схемы базы и свободные соединения в пуле. Во время остановки сервиса всегда 503
+// @ID readyz
+// @Produce  json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func Readiness(checker ReadinessChecker, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		report := checker.Check(ctx)
+		if !report.Ready() {
+			log.Ctx(r.Context()).Warn().
+				Interface("checks", report.Checks).
+				Msg("Readiness: service is not ready")
+			render.Status(r, http.StatusServiceUnavailable)
+		}
+		render.JSON(w, r, report)
+	}
+}
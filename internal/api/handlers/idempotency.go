@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"todolist/internal/models"
+)
+
+// IdempotencyKeyHeader lets clients retry a create without duplicating it:
+// the repeated request gets the resource made by the first one.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyKey returns the header value, empty when it is not set.
+func idempotencyKey(r *http.Request) (string, error) {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if len(key) > models.MaxIdempotencyKeyLength {
+		return "", fmt.Errorf("%s is longer than %d bytes", IdempotencyKeyHeader, models.MaxIdempotencyKeyLength)
+	}
+	return key, nil
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+	"todolist/internal/models"
+	"todolist/internal/pkg/response"
+
+	"github.com/go-chi/render"
+	"github.com/rs/zerolog/log"
+)
+
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context) (redirectURL, login string, err error)
+	Exchange(ctx context.Context, login, state, code string) (tokenStr string, err error)
+}
+
+// oidcLoginCookie binds a login to the browser which started it. SameSite=Lax
+// still sends it on the top-level redirect back from the provider.
+const (
+	oidcLoginCookie = "oidc_login"
+	oidcCookiePath  = "/api/v1/oidc"
+)
+
+func setLoginCookie(w http.ResponseWriter, value string, maxAge int, secure bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcLoginCookie,
+		Value:    value,
+		Path:     oidcCookiePath,
+		MaxAge:   maxAge,
+		Secure:   secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// @Summary OIDCLogin
+// @Tags user
+// @Description Начать вход через внешнего OpenID Connect провайдера, перенаправляет на страницу провайдера и сохраняет состояние входа в cookie oidc_login
+// @ID oidc-login
+// @Produce  json
+// @Success 302
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/oidc/login [get]
+func OIDCLogin(oidcProvider OIDCProvider, timeout time.Duration, secureCookie bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Ctx(r.Context()).Info().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Msg("OIDCLogin: started external authentication")
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		redirectURL, login, err := oidcProvider.AuthCodeURL(ctx)
+		if err != nil {
+			log.Ctx(r.Context()).Error().
+				Err(err).
+				Msg("OIDCLogin: failed to build provider redirect")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		// The login expires inside the signed value, the cookie only lives
+		// as long as the browser session.
+		setLoginCookie(w, login, 0, secureCookie)
+		http.Redirect(w, r, redirectURL, http.StatusFound)
+	}
+}
+
+// @Summary OIDCCallback
+// @Tags user
+// @Description Завершить вход через внешнего OpenID Connect провайдера. Требует cookie oidc_login из того же браузера. При первом входе создается новый пользователь
+// @ID oidc-callback
+// @Produce  json
+// @Param state query string true "state from the login redirect"
+// @Param code  query string true "authorization code"
+// @Success 200 {object} Token
+// @Failure 400,401,403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/oidc/callback [get]
+func OIDCCallback(oidcProvider OIDCProvider, timeout time.Duration, secureCookie bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		// Every callback ends the login, so the cookie cannot be replayed.
+		login := ""
+		if cookie, err := r.Cookie(oidcLoginCookie); err == nil {
+			login = cookie.Value
+		}
+		setLoginCookie(w, "", -1, secureCookie)
+
+		if providerErr := query.Get("error"); providerErr != "" {
+			log.Ctx(r.Context()).Warn().
+				Str("error", providerErr).
+				Str("error_description", query.Get("error_description")).
+				Msg("OIDCCallback: provider rejected authentication")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error(providerErr))
+			return
+		}
+
+		state, code := query.Get("state"), query.Get("code")
+		if state == "" || code == "" {
+			log.Ctx(r.Context()).Warn().
+				Msg("OIDCCallback: missing state or code")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("missing state or code"))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		if login == "" {
+			log.Ctx(r.Context()).Warn().
+				Msg("OIDCCallback: missing login cookie")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(models.ErrInvalidLoginState.Error()))
+			return
+		}
+
+		tokenStr, err := oidcProvider.Exchange(ctx, login, state, code)
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidLoginState) {
+				log.Ctx(r.Context()).Warn().
+					Err(err).
+					Msg("OIDCCallback: invalid login state")
+				render.Status(r, http.StatusBadRequest)
+			} else if errors.Is(err, models.ErrExternalAuth) {
+				log.Ctx(r.Context()).Warn().
+					Err(err).
+					Msg("OIDCCallback: provider authentication rejected")
+				render.Status(r, http.StatusUnauthorized)
+			} else if errors.Is(err, models.ErrUserDisabled) {
+				log.Ctx(r.Context()).Warn().
+					Err(err).
+					Msg("OIDCCallback: user is disabled")
+				render.Status(r, http.StatusForbidden)
+			} else {
+				log.Ctx(r.Context()).Error().
+					Err(err).
+					Msg("OIDCCallback: authentication failed")
+				render.Status(r, http.StatusInternalServerError)
+			}
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		log.Ctx(r.Context()).Info().
+			Msg("OIDCCallback: successfully authenticated user")
+		render.JSON(w, r, Token{Token: tokenStr})
+	}
+}
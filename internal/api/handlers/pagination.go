@@ -1,6 +1,40 @@
 package handlers
 
+import (
+	"fmt"
+	"net/url"
+)
+
+// maxRecordsPerPage caps the page size of the lists read from query
+// parameters, so one request cannot read a whole table.
+const maxRecordsPerPage = 100
+
 type Pagination struct {
 	RecordsPerPage int `json:"records_per_page"`
 	PageIndex      int `json:"page_index"`
 }
+
+// queryPagination reads the page_index and records_per_page query parameters
+// of the v2 and admin list endpoints, both optional. They are named like the
+// fields of the v1 request bodies.
+func queryPagination(query url.Values) (Pagination, error) {
+	pageIndex, err := queryInt(query.Get("page_index"), defaultPageIndex)
+	if err != nil {
+		return Pagination{}, fmt.Errorf("invalid page_index: %w", err)
+	}
+	recordsPerPage, err := queryPageSize(query.Get("records_per_page"))
+	if err != nil {
+		return Pagination{}, fmt.Errorf("invalid records_per_page: %w", err)
+	}
+	return Pagination{PageIndex: pageIndex, RecordsPerPage: recordsPerPage}, nil
+}
+
+// queryPageSize reads an optional page size, larger ones are cut down to
+// maxRecordsPerPage.
+func queryPageSize(value string) (int, error) {
+	recordsPerPage, err := queryInt(value, defaultRecordsPerPage)
+	if err != nil {
+		return 0, err
+	}
+	return min(recordsPerPage, maxRecordsPerPage), nil
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+	"todolist/internal/middleware"
+	"todolist/internal/models"
+	"todolist/internal/pkg/response"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// maxStatsPeriods bounds the completion series of one stats request.
+const maxStatsPeriods = 366
+
+type StatsResponse struct {
+	Open      int64 `json:"open"`
+	Completed int64 `json:"completed"`
+	// From and To are the first and the last day of the range, inclusive.
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Period string `json:"period"`
+	// Completions has an entry for every period of the range, also for
+	// the periods without completions.
+	Completions                  []PeriodCountResponse   `json:"completions"`
+	AverageTimeToCompleteSeconds *float64                `json:"average_time_to_complete_seconds"`
+	Categories                   []CategoryStatsResponse `json:"categories"`
+	CurrentStreakDays            int                     `json:"current_streak_days"`
+}
+
+type PeriodCountResponse struct {
+	Start string `json:"start"`
+	Count int64  `json:"count"`
+}
+
+type CategoryStatsResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Open      int64     `json:"open"`
+	Completed int64     `json:"completed"`
+}
+
+type StatsProvider interface {
+	GetStats(ctx context.Context, userID uuid.UUID, query models.StatsQuery) (*models.Stats, error)
+}
+
+// @Summary GetStats
+// @Security ApiKeyAuth
+// @Tags stats
+// @Description Статистика пользователя: число открытых и выполненных задач, выполнения по дням или неделям,
+// @Description среднее время от создания до выполнения, разбивка по категориям и текущая серия дней с выполненными задачами.
+// @Description Дни считаются в UTC, недели начинаются с понедельника. Выполнения и среднее время считаются за диапазон from–to,
+// @Description по умолчанию за последние 30 дней или 12 недель.
+// @ID get-stats
+// @Produce  json
+// @Param from   query string false "первый день диапазона, YYYY-MM-DD"
+// @Param to     query string false "последний день диапазона, YYYY-MM-DD, по умолчанию сегодня"
+// @Param period query string false "day (по умолчанию) или week"
+// @Success 200 {object} StatsResponse
+// @Failure 400,401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/stats [get]
+func GetStats(statsProvider StatsProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Ctx(r.Context()).Trace().Msg("get GetStats request")
+
+		query, err := queryStats(r.URL.Query(), time.Now())
+		if err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("GetStats: invalid query")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
+		if !ok {
+			log.Ctx(r.Context()).Error().Msg("no uuid in context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("unauthorized"))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		stats, err := statsProvider.GetStats(ctx, userID, query)
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("GetStats: failed to get stats")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		render.JSON(w, r, toStatsResponse(stats, query))
+	}
+}
+
+// queryStats reads the range of GetStats. Both ends are widened to whole
+// periods, and the exclusive end of the query is the start of the period
+// after to.
+func queryStats(query url.Values, now time.Time) (models.StatsQuery, error) {
+	period := models.PeriodDay
+	if value := query.Get("period"); value != "" {
+		period = models.StatsPeriod(value)
+		if !period.Valid() {
+			return models.StatsQuery{}, fmt.Errorf("invalid period: %q", value)
+		}
+	}
+
+	to := period.Start(now)
+	if value := query.Get("to"); value != "" {
+		day, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return models.StatsQuery{}, fmt.Errorf("invalid to: %w", err)
+		}
+		to = period.Start(day)
+	}
+	to = period.Next(to)
+
+	from := to.AddDate(0, 0, -30)
+	if period == models.PeriodWeek {
+		from = to.AddDate(0, 0, -12*7)
+	}
+	if value := query.Get("from"); value != "" {
+		day, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return models.StatsQuery{}, fmt.Errorf("invalid from: %w", err)
+		}
+		from = period.Start(day)
+	}
+
+	if !from.Before(to) {
+		return models.StatsQuery{}, errors.New("from must not be after to")
+	}
+	periods := 0
+	for start := from; start.Before(to); start = period.Next(start) {
+		if periods++; periods > maxStatsPeriods {
+			return models.StatsQuery{}, fmt.Errorf("the range exceeds %d periods", maxStatsPeriods)
+		}
+	}
+	return models.StatsQuery{From: from, To: to, Period: period, Now: now}, nil
+}
+
+func toStatsResponse(stats *models.Stats, query models.StatsQuery) StatsResponse {
+	counts := make(map[string]int64, len(stats.Completions))
+	for _, completion := range stats.Completions {
+		counts[completion.Start.Format(time.DateOnly)] = completion.Count
+	}
+	completions := make([]PeriodCountResponse, 0)
+	for start := query.From; start.Before(query.To); start = query.Period.Next(start) {
+		day := start.Format(time.DateOnly)
+		completions = append(completions, PeriodCountResponse{Start: day, Count: counts[day]})
+	}
+
+	var average *float64
+	if stats.AverageTimeToComplete != nil {
+		seconds := stats.AverageTimeToComplete.Seconds()
+		average = &seconds
+	}
+
+	categories := make([]CategoryStatsResponse, 0, len(stats.Categories))
+	for _, c := range stats.Categories {
+		categories = append(categories, CategoryStatsResponse{ID: c.ID, Name: c.Name, Open: c.Open, Completed: c.Completed})
+	}
+
+	return StatsResponse{
+		Open:                         stats.Open,
+		Completed:                    stats.Completed,
+		From:                         query.From.Format(time.DateOnly),
+		To:                           query.To.AddDate(0, 0, -1).Format(time.DateOnly),
+		Period:                       string(query.Period),
+		Completions:                  completions,
+		AverageTimeToCompleteSeconds: average,
+		Categories:                   categories,
+		CurrentStreakDays:            stats.Streak,
+	}
+}
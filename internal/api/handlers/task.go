@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"path"
 	"time"
 	"todolist/internal/middleware"
 	"todolist/internal/models"
@@ -25,6 +27,14 @@ type TaskMeta struct {
 	IsDone bool      `json:"is_done"`
 }
 
+// TaskTimestamps are maintained by the server. completed_at is null while the
+// task is not done.
+type TaskTimestamps struct {
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
+
 type TaskRequest struct {
 	TaskBody
 	CategoryIds []uuid.UUID `json:"category_ids"`
@@ -34,11 +44,17 @@ type TaskResponse struct {
 	TaskMeta
 	TaskBody
 	CategoriesResponse
+	TaskTimestamps
+	// StatusID is the column of the task on the board, null without a
+	// workflow.
+	StatusID *uuid.UUID `json:"status_id"`
 }
 
 type TaskShortResponse struct {
 	TaskMeta
 	Title string `json:"title"`
+	TaskTimestamps
+	StatusID *uuid.UUID `json:"status_id"`
 }
 
 type TasksList struct {
@@ -46,35 +62,42 @@ type TasksList struct {
 }
 
 type TaskProvider interface {
-	CreateTask(ctx context.Context, userId uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) error
+	CreateTask(ctx context.Context, userId uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) (*models.TaskFullInfo, error)
 	Update(ctx context.Context, id uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.TaskFullInfo, error)
 	GetAll(ctx context.Context, userId uuid.UUID, pageIndex, recordsPerPage int) ([]models.TaskShortInfo, error)
-	Delete(ctx context.Context, id uuid.UUID) error
-	ToggleDone(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, userId uuid.UUID, filter models.TaskFilter, pageIndex, recordsPerPage int) ([]models.TaskShortInfo, error)
+	Patch(ctx context.Context, id uuid.UUID, patch *models.TaskPatch) (*models.TaskFullInfo, error)
+	Delete(ctx context.Context, id uuid.UUID, ifVersion int64) error
+	ToggleDone(ctx context.Context, id uuid.UUID) (bool, error)
+	Move(ctx context.Context, id, statusID uuid.UUID, position int, ifVersion int64) (*models.TaskFullInfo, error)
 }
 
 // @Summary CreateTask
 // @Security ApiKeyAuth
 // @Tags task
-// @Description Создать новую задачу
+// @Description Создать новую задачу. Все категории из category_ids должны существовать, иначе 400.
+// @Description Повторный запрос с тем же Idempotency-Key вернет задачу, созданную первым запросом, и не создаст новую.
 // @ID create-task
 // @Accept  json
 // @Produce  json
 // @Param input body TaskRequest true "task info"
-// @Success 200
+// @Param Idempotency-Key header string false "ключ идемпотентности, до 255 байт"
+// @Success 201 {object} TaskResponse
+// @Header 201 {string} Location "адрес созданной задачи"
+// @Header 201 {string} ETag "версия задачи"
 // @Failure 400,401 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Failure default {object} response.Response
 // @Router /api/v1/task [post]
 func CreateTask(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		log.Trace().Msg("get CreateTask request")
+		log.Ctx(r.Context()).Trace().Msg("get CreateTask request")
 
 		var req TaskRequest
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
-			log.Warn().Err(err).Msg("failed to parse request")
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse request")
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response.Error(err.Error()))
 			return
@@ -86,47 +109,61 @@ func CreateTask(taskProvider TaskProvider, timeout time.Duration) http.HandlerFu
 
 		userId, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
 		if !ok {
-			log.Error().Msg("no uuid in context")
+			log.Ctx(r.Context()).Error().Msg("no uuid in context")
 			render.Status(r, http.StatusUnauthorized)
 			render.JSON(w, r, response.Error("unauthorized"))
 			return
 		}
 
-		err = taskProvider.CreateTask(ctx, userId, toModelTaskBody(req), req.CategoryIds)
+		body := toModelTaskBody(req)
+		body.IdempotencyKey, err = idempotencyKey(r)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("invalid idempotency key")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		task, err := taskProvider.CreateTask(ctx, userId, body, req.CategoryIds)
 		if err != nil {
 			log.Err(err).Msg("CreateTask, error from provider")
-			render.Status(r, http.StatusInternalServerError)
+			render.Status(r, taskErrorStatus(err))
 			render.JSON(w, r, response.Error(err.Error()))
 			return
 		}
 
-		render.Status(r, http.StatusOK)
+		w.Header().Set("Location", path.Join(r.URL.Path, task.ID.String()))
+		w.Header().Set("ETag", taskETag(task.Version))
+		render.Status(r, http.StatusCreated)
+		render.JSON(w, r, toTaskResponse(task))
 	}
 }
 
 // @Summary EditTask
 // @Security ApiKeyAuth
 // @Tags task
-// @Description Изменить задачу по указанному id
+// @Description Изменить задачу по указанному id. Все категории из category_ids должны существовать, иначе 400.
+// @Description С заголовком If-Match задача изменится, только если ее ETag не поменялся, иначе 412.
 // @ID edit-task
 // @Accept  json
 // @Produce  json
 // @Param input body TaskRequest true "task info"
 // @Param id   path      string  true  "Task ID (UUID)"
+// @Param If-Match header string false "ETag, полученный вместе с задачей"
 // @Success 200
-// @Failure 400 {object} response.Response
+// @Failure 400,412 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Failure default {object} response.Response
 // @Router /api/v1/task/{id} [patch]
 func EditTask(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		log.Trace().Msg("get EditTask request")
+		log.Ctx(r.Context()).Trace().Msg("get EditTask request")
 
 		id := chi.URLParam(r, "id")
 
 		uuid, err := uuid.Parse(id)
 		if err != nil {
-			log.Warn().Err(err).Msg("failed to parse path parameter")
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse path parameter")
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response.Error("invalid UUID"))
 			return
@@ -135,7 +172,16 @@ func EditTask(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc
 		var req TaskRequest
 		err = render.DecodeJSON(r.Body, &req)
 		if err != nil {
-			log.Warn().Err(err).Msg("failed to parse request")
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse request")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		body := toModelTaskBody(req)
+		body.IfVersion, err = ifMatchVersion(r)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("invalid If-Match")
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response.Error(err.Error()))
 			return
@@ -145,10 +191,10 @@ func EditTask(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc
 		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 
-		err = taskProvider.Update(ctx, uuid, toModelTaskBody(req), req.CategoryIds)
+		err = taskProvider.Update(ctx, uuid, body, req.CategoryIds)
 		if err != nil {
 			log.Err(err).Msg("Update, error from provider")
-			render.Status(r, http.StatusInternalServerError)
+			render.Status(r, taskErrorStatus(err))
 			render.JSON(w, r, response.Error(err.Error()))
 			return
 		}
@@ -160,24 +206,28 @@ func EditTask(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc
 // @Summary GetTask
 // @Security ApiKeyAuth
 // @Tags task
-// @Description Получить полное описание задачи по переданному id
+// @Description Получить полное описание задачи по переданному id.
+// @Description Если ETag задачи совпадает с заголовком If-None-Match, ответ 304 без тела.
 // @ID get-task
 // @Accept  json
 // @Produce  json
 // @Param id   path      string  true  "Task ID (UUID)"
+// @Param If-None-Match header string false "ETag из предыдущего ответа"
 // @Success 200 {object} TaskResponse
+// @Header 200 {string} ETag "версия задачи"
+// @Success 304
 // @Failure 400 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Failure default {object} response.Response
 // @Router /api/v1/task/{id} [get]
 func GetTask(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		log.Trace().Msg("get GetTask request")
+		log.Ctx(r.Context()).Trace().Msg("get GetTask request")
 
 		id := chi.URLParam(r, "id")
 		uuid, err := uuid.Parse(id)
 		if err != nil {
-			log.Warn().Err(err).Msg("failed to parse path parameter")
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse path parameter")
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response.Error("invalid UUID"))
 			return
@@ -195,6 +245,9 @@ func GetTask(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc
 			return
 		}
 
+		if notModified(w, r, taskETag(task.Version)) {
+			return
+		}
 		render.JSON(w, r, toTaskResponse(task))
 	}
 }
@@ -214,12 +267,12 @@ func GetTask(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc
 // @Router /api/v1/task/all [post]
 func GetAllTasks(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		log.Trace().Msg("get GetAllTasks request")
+		log.Ctx(r.Context()).Trace().Msg("get GetAllTasks request")
 
 		var req Pagination
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
-			log.Warn().Err(err).Msg("failed to parse request")
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse request")
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response.Error(err.Error()))
 			return
@@ -231,7 +284,7 @@ func GetAllTasks(taskProvider TaskProvider, timeout time.Duration) http.HandlerF
 
 		userId, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
 		if !ok {
-			log.Error().Msg("no uuid in context")
+			log.Ctx(r.Context()).Error().Msg("no uuid in context")
 			render.Status(r, http.StatusUnauthorized)
 			render.JSON(w, r, response.Error("unauthorized"))
 			return
@@ -253,11 +306,13 @@ func GetAllTasks(taskProvider TaskProvider, timeout time.Duration) http.HandlerF
 // @Security ApiKeyAuth
 // @Tags task
 // @Description Изменить статус готовности задачи. Если задача была готова, то станет неготовой или наоборот.
+// @Description На доске задача переходит в конец последней колонки или, если стала неготовой, первой.
+// @Description В ответе новый статус задачи.
 // @ID toggle-readiness-task
 // @Accept  json
 // @Produce  json
 // @Param id   path      string  true  "Task ID (UUID)"
-// @Success 200
+// @Success 200 {object} TaskMeta
 // @Failure 400 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Failure default {object} response.Response
@@ -267,7 +322,7 @@ func ToggleReadinessTask(taskProvider TaskProvider, timeout time.Duration) http.
 		id := chi.URLParam(r, "id")
 		uuid, err := uuid.Parse(id)
 		if err != nil {
-			log.Warn().Err(err).Msg("failed to parse path parameter")
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse path parameter")
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response.Error("invalid UUID"))
 			return
@@ -277,7 +332,7 @@ func ToggleReadinessTask(taskProvider TaskProvider, timeout time.Duration) http.
 		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 
-		err = taskProvider.ToggleDone(ctx, uuid)
+		isDone, err := taskProvider.ToggleDone(ctx, uuid)
 		if err != nil {
 			log.Err(err).Msg("ToggleDone, error from provider")
 			render.Status(r, http.StatusInternalServerError)
@@ -285,20 +340,22 @@ func ToggleReadinessTask(taskProvider TaskProvider, timeout time.Duration) http.
 			return
 		}
 
-		render.Status(r, http.StatusOK)
+		render.JSON(w, r, TaskMeta{ID: uuid, IsDone: isDone})
 	}
 }
 
 // @Summary DeleteTask
 // @Security ApiKeyAuth
 // @Tags task
-// @Description Удалить задачу по переданному id
+// @Description Удалить задачу по переданному id.
+// @Description С заголовком If-Match задача удалится, только если ее ETag не поменялся, иначе 412.
 // @ID delete-task
 // @Accept  json
 // @Produce  json
 // @Param id   path      string  true  "Task ID (UUID)"
+// @Param If-Match header string false "ETag, полученный вместе с задачей"
 // @Success 200
-// @Failure 400,404 {object} response.Response
+// @Failure 400,404,412 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Failure default {object} response.Response
 // @Router /api/v1/task/{id} [delete]
@@ -307,21 +364,29 @@ func DeleteTask(taskProvider TaskProvider, timeout time.Duration) http.HandlerFu
 		id := chi.URLParam(r, "id")
 		uuid, err := uuid.Parse(id)
 		if err != nil {
-			log.Warn().Err(err).Msg("failed to parse path parameter")
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse path parameter")
 
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response.Error("invalid UUID"))
 			return
 		}
 
+		ifVersion, err := ifMatchVersion(r)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("invalid If-Match")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
 		ctx := r.Context()
 		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 
-		err = taskProvider.Delete(ctx, uuid)
+		err = taskProvider.Delete(ctx, uuid, ifVersion)
 		if err != nil {
 			log.Err(err).Msg("Delete, error from provider")
-			render.Status(r, http.StatusInternalServerError)
+			render.Status(r, taskErrorStatus(err))
 			render.JSON(w, r, response.Error(err.Error()))
 			return
 		}
@@ -340,12 +405,7 @@ func toModelTaskBody(task TaskRequest) *models.TaskBody {
 func toTaskResponse(task *models.TaskFullInfo) *TaskResponse {
 	categoryResponse := make([]CategoryResponse, len(task.Categories))
 	for i, category := range task.Categories {
-		categoryResponse[i] = CategoryResponse{
-			ID: category.ID,
-			CategoryBody: CategoryBody{
-				Name: category.Name,
-			},
-		}
+		categoryResponse[i] = toCategoryResponse(category)
 	}
 
 	return &TaskResponse{
@@ -360,6 +420,12 @@ func toTaskResponse(task *models.TaskFullInfo) *TaskResponse {
 		CategoriesResponse: CategoriesResponse{
 			Categories: categoryResponse,
 		},
+		TaskTimestamps: TaskTimestamps{
+			CreatedAt:   task.CreatedAt,
+			UpdatedAt:   task.UpdatedAt,
+			CompletedAt: task.CompletedAt,
+		},
+		StatusID: task.StatusID,
 	}
 }
 
@@ -372,6 +438,12 @@ func toTaskList(tasks []models.TaskShortInfo) TasksList {
 				ID:     task.ID,
 				IsDone: task.IsDone,
 			},
+			TaskTimestamps: TaskTimestamps{
+				CreatedAt:   task.CreatedAt,
+				UpdatedAt:   task.UpdatedAt,
+				CompletedAt: task.CompletedAt,
+			},
+			StatusID: task.StatusID,
 		})
 	}
 
@@ -379,3 +451,16 @@ func toTaskList(tasks []models.TaskShortInfo) TasksList {
 		List: list,
 	}
 }
+
+// taskErrorStatus answers 400 for category or status IDs that do not exist,
+// 412 for a failed If-Match and 500 for everything else.
+func taskErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrCategoryNotFound), errors.Is(err, models.ErrStatusNotFound):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrTaskModified):
+		return http.StatusPreconditionFailed
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"todolist/internal/middleware"
+	"todolist/internal/models"
+	"todolist/internal/pkg/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// TaskReplaceRequest is the full state of a task written by PUT.
+type TaskReplaceRequest struct {
+	TaskRequest
+	IsDone bool `json:"is_done"`
+}
+
+// TaskPatchRequest changes only the fields present in the body.
+type TaskPatchRequest struct {
+	Title       *string      `json:"title"`
+	Description *string      `json:"description"`
+	IsDone      *bool        `json:"is_done"`
+	CategoryIds *[]uuid.UUID `json:"category_ids"`
+}
+
+// @Summary ListTasks
+// @Security ApiKeyAuth
+// @Tags task
+// @Description Получить список коротких описаний задач с фильтрами по готовности, категории и времени.
+// @Description По умолчанию задачи отсортированы по названию; при сортировке по completed_at невыполненные задачи идут последними.
+// @Description Если ETag списка совпадает с заголовком If-None-Match, ответ 304 без тела.
+// @ID list-tasks
+// @Produce  json
+// @Param page_index       query int    false "page number, starts with 1"
+// @Param records_per_page query int    false "page size, 20 by default, at most 100"
+// @Param done     query bool   false "only done or only not done tasks"
+// @Param category query string false "only tasks of the category (UUID)"
+// @Param sort     query string false "title, created_at, updated_at или completed_at, с минусом впереди — по убыванию"
+// @Param created_from   query string false "создана не раньше (RFC 3339)"
+// @Param created_to     query string false "создана раньше (RFC 3339)"
+// @Param updated_from   query string false "изменена не раньше (RFC 3339)"
+// @Param updated_to     query string false "изменена раньше (RFC 3339)"
+// @Param completed_from query string false "выполнена не раньше (RFC 3339)"
+// @Param completed_to   query string false "выполнена раньше (RFC 3339)"
+// @Param If-None-Match header string false "ETag из предыдущего ответа"
+// @Success 200 {object} TasksList
+// @Header 200 {string} ETag "версия списка"
+// @Success 304
+// @Failure 400,401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v2/tasks [get]
+func ListTasks(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Ctx(r.Context()).Trace().Msg("get ListTasks request")
+
+		query := r.URL.Query()
+		pagination, err := queryPagination(query)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("ListTasks: invalid pagination")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		filter, err := queryTaskFilter(query)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("ListTasks: invalid filter")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		userId, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
+		if !ok {
+			log.Ctx(r.Context()).Error().Msg("no uuid in context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("unauthorized"))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		tasks, err := taskProvider.List(ctx, userId, filter, pagination.PageIndex, pagination.RecordsPerPage)
+		if err != nil {
+			log.Ctx(r.Context()).Err(err).Msg("List, error from provider")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		if notModified(w, r, taskListETag(tasks)) {
+			return
+		}
+		render.JSON(w, r, toTaskList(tasks))
+	}
+}
+
+// queryTaskFilter reads the filter and sort query parameters of ListTasks,
+// all optional. Times are RFC 3339; a sort field prefixed with a minus sorts
+// in descending order.
+func queryTaskFilter(query url.Values) (models.TaskFilter, error) {
+	var filter models.TaskFilter
+	if value := query.Get("done"); value != "" {
+		isDone, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid done: %w", err)
+		}
+		filter.IsDone = &isDone
+	}
+	if value := query.Get("category"); value != "" {
+		categoryID, err := uuid.Parse(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid category: %w", err)
+		}
+		filter.CategoryID = &categoryID
+	}
+
+	ranges := []struct {
+		name string
+		r    *models.TimeRange
+	}{
+		{"created", &filter.Created},
+		{"updated", &filter.Updated},
+		{"completed", &filter.Completed},
+	}
+	for _, field := range ranges {
+		var err error
+		if field.r.From, err = queryTime(query, field.name+"_from"); err != nil {
+			return filter, err
+		}
+		if field.r.To, err = queryTime(query, field.name+"_to"); err != nil {
+			return filter, err
+		}
+	}
+
+	if value := query.Get("sort"); value != "" {
+		sortBy, descending := strings.CutPrefix(value, "-")
+		filter.Sort, filter.Descending = models.TaskSort(sortBy), descending
+		if !filter.Sort.Valid() {
+			return filter, fmt.Errorf("invalid sort: %q", value)
+		}
+	}
+	return filter, nil
+}
+
+// queryTime returns nil for a missing parameter.
+func queryTime(query url.Values, name string) (*time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return &t, nil
+}
+
+// @Summary ReplaceTask
+// @Security ApiKeyAuth
+// @Tags task
+// @Description Заменить задачу целиком: отсутствующие в запросе поля получат пустые значения, а category_ids — пустой список.
+// @Description Все категории из category_ids должны существовать, иначе 400.
+// @Description С заголовком If-Match задача изменится, только если ее ETag не поменялся, иначе 412.
+// @ID replace-task
+// @Accept  json
+// @Produce  json
+// @Param input body TaskReplaceRequest true "task info"
+// @Param id   path      string  true  "Task ID (UUID)"
+// @Param If-Match header string false "ETag, полученный вместе с задачей"
+// @Success 200 {object} TaskResponse
+// @Header 200 {string} ETag "новая версия задачи"
+// @Failure 400,401,403,412 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v2/tasks/{id} [put]
+func ReplaceTask(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Ctx(r.Context()).Trace().Msg("get ReplaceTask request")
+
+		var req TaskReplaceRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse request")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		categoryIDs := req.CategoryIds
+		if categoryIDs == nil {
+			categoryIDs = []uuid.UUID{}
+		}
+		patchTask(w, r, taskProvider, timeout, &models.TaskPatch{
+			Title:       &req.Title,
+			Description: &req.Description,
+			IsDone:      &req.IsDone,
+			CategoryIDs: categoryIDs,
+		})
+	}
+}
+
+// @Summary PatchTask
+// @Security ApiKeyAuth
+// @Tags task
+// @Description Изменить только переданные поля задачи, остальные сохранят свои значения.
+// @Description Все категории из category_ids должны существовать, иначе 400.
+// @Description С заголовком If-Match задача изменится, только если ее ETag не поменялся, иначе 412.
+// @ID patch-task
+// @Accept  json
+// @Produce  json
+// @Param input body TaskPatchRequest true "changed fields"
+// @Param id   path      string  true  "Task ID (UUID)"
+// @Param If-Match header string false "ETag, полученный вместе с задачей"
+// @Success 200 {object} TaskResponse
+// @Header 200 {string} ETag "новая версия задачи"
+// @Failure 400,401,403,412 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v2/tasks/{id} [patch]
+func PatchTask(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Ctx(r.Context()).Trace().Msg("get PatchTask request")
+
+		var req TaskPatchRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse request")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		patch := &models.TaskPatch{
+			Title:       req.Title,
+			Description: req.Description,
+			IsDone:      req.IsDone,
+		}
+		if req.CategoryIds != nil {
+			patch.CategoryIDs = *req.CategoryIds
+		}
+		patchTask(w, r, taskProvider, timeout, patch)
+	}
+}
+
+func patchTask(w http.ResponseWriter, r *http.Request, taskProvider TaskProvider, timeout time.Duration, patch *models.TaskPatch) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse path parameter")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error("invalid UUID"))
+		return
+	}
+	patch.IfVersion, err = ifMatchVersion(r)
+	if err != nil {
+		log.Ctx(r.Context()).Warn().Err(err).Msg("invalid If-Match")
+		render.Status(r, http.StatusBadRequest)
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	ctx := r.Context()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	task, err := taskProvider.Patch(ctx, id, patch)
+	if err != nil {
+		log.Ctx(r.Context()).Err(err).Msg("Patch, error from provider")
+		render.Status(r, taskErrorStatus(err))
+		render.JSON(w, r, response.Error(err.Error()))
+		return
+	}
+
+	w.Header().Set("ETag", taskETag(task.Version))
+	render.JSON(w, r, toTaskResponse(task))
+}
+
+// @Summary CreateTaskV2
+// @Security ApiKeyAuth
+// @Tags task
+// @Description Создать новую задачу, как POST /api/v1/task. Все категории из category_ids должны существовать, иначе 400.
+// @Description Повторный запрос с тем же Idempotency-Key вернет задачу, созданную первым запросом, и не создаст новую.
+// @ID create-task-v2
+// @Accept  json
+// @Produce  json
+// @Param input body TaskRequest true "task info"
+// @Param Idempotency-Key header string false "ключ идемпотентности, до 255 байт"
+// @Success 201 {object} TaskResponse
+// @Header 201 {string} Location "адрес созданной задачи"
+// @Header 201 {string} ETag "версия задачи"
+// @Failure 400,401,403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v2/tasks [post]
+func CreateTaskV2(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc {
+	return CreateTask(taskProvider, timeout)
+}
+
+// @Summary GetTaskV2
+// @Security ApiKeyAuth
+// @Tags task
+// @Description Получить полное описание задачи по переданному id.
+// @Description Если ETag задачи совпадает с заголовком If-None-Match, ответ 304 без тела.
+// @ID get-task-v2
+// @Produce  json
+// @Param id   path      string  true  "Task ID (UUID)"
+// @Param If-None-Match header string false "ETag из предыдущего ответа"
+// @Success 200 {object} TaskResponse
+// @Header 200 {string} ETag "версия задачи"
+// @Success 304
+// @Failure 400,401,403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v2/tasks/{id} [get]
+func GetTaskV2(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc {
+	return GetTask(taskProvider, timeout)
+}
+
+// @Summary DeleteTaskV2
+// @Security ApiKeyAuth
+// @Tags task
+// @Description Удалить задачу по переданному id.
+// @Description С заголовком If-Match задача удалится, только если ее ETag не поменялся, иначе 412.
+// @ID delete-task-v2
+// @Produce  json
+// @Param id   path      string  true  "Task ID (UUID)"
+// @Param If-Match header string false "ETag, полученный вместе с задачей"
+// @Success 200
+// @Failure 400,401,403,412 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v2/tasks/{id} [delete]
+func DeleteTaskV2(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc {
+	return DeleteTask(taskProvider, timeout)
+}
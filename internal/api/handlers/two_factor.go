@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+	"todolist/internal/middleware"
+	"todolist/internal/models"
+	auth_utils "todolist/internal/pkg/authUtils"
+	"todolist/internal/pkg/response"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type SecondFactorRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+type TOTPCode struct {
+	Code string `json:"code"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorProvider interface {
+	SignInSecondFactor(ctx context.Context, challengeToken string, code string) (tokenStr string, err error)
+	EnrollTOTP(ctx context.Context, userID uuid.UUID) (*models.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, userID uuid.UUID, code string) (recoveryCodes []string, err error)
+	DisableTOTP(ctx context.Context, userID uuid.UUID, code string) error
+}
+
+// @Summary SignInSecondFactor
+// @Tags user
+// @Description Второй шаг входа: код из приложения-аутентификатора или один из кодов восстановления
+// @ID sign-in-2fa
+// @Accept  json
+// @Produce  json
+// @Param input body SecondFactorRequest true "challenge token from sign-in and code"
+// @Success 200 {object} Token
+// @Failure 400,401,403 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/sign-in/2fa [post]
+func SignInSecondFactor(twoFactorProvider TwoFactorProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SecondFactorRequest
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().
+				Err(err).
+				Msg("SignInSecondFactor: failed to decode request body")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		tokenStr, err := twoFactorProvider.SignInSecondFactor(ctx, req.ChallengeToken, req.Code)
+		if err != nil {
+			if errors.Is(err, auth_utils.ErrInvalidToken) || errors.Is(err, models.ErrInvalidTOTPCode) {
+				log.Ctx(r.Context()).Warn().
+					Err(err).
+					Msg("SignInSecondFactor: invalid challenge or code")
+				render.Status(r, http.StatusUnauthorized)
+			} else if errors.Is(err, models.ErrUserDisabled) {
+				log.Ctx(r.Context()).Warn().
+					Err(err).
+					Msg("SignInSecondFactor: user is disabled")
+				render.Status(r, http.StatusForbidden)
+			} else {
+				log.Ctx(r.Context()).Error().
+					Err(err).
+					Msg("SignInSecondFactor: authentication failed")
+				render.Status(r, http.StatusInternalServerError)
+			}
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		log.Ctx(r.Context()).Info().
+			Msg("SignInSecondFactor: successfully authenticated user")
+		render.JSON(w, r, Token{Token: tokenStr})
+	}
+}
+
+// @Summary EnrollTOTP
+// @Security ApiKeyAuth
+// @Tags user
+// @Description Создать секрет для приложения-аутентификатора. Двухфакторная аутентификация включится после подтверждения кодом
+// @ID enroll-totp
+// @Produce  json
+// @Success 200 {object} TOTPEnrollmentResponse
+// @Failure 401,409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/user/2fa/enroll [post]
+func EnrollTOTP(twoFactorProvider TwoFactorProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
+		if !ok {
+			log.Ctx(r.Context()).Warn().
+				Msg("EnrollTOTP: missing userID")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("Missing userID"))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		enrollment, err := twoFactorProvider.EnrollTOTP(ctx, userID)
+		if err != nil {
+			writeTwoFactorError(w, r, err, "EnrollTOTP")
+			return
+		}
+
+		log.Ctx(r.Context()).Info().
+			Msg("EnrollTOTP: generated totp secret")
+		render.JSON(w, r, TOTPEnrollmentResponse{Secret: enrollment.Secret, URI: enrollment.URI})
+	}
+}
+
+// @Summary ConfirmTOTP
+// @Security ApiKeyAuth
+// @Tags user
+// @Description Подтвердить подключение аутентификатора кодом из него. Возвращает одноразовые коды восстановления, они показываются только один раз
+// @ID confirm-totp
+// @Accept  json
+// @Produce  json
+// @Param input body TOTPCode true "code from the authenticator app"
+// @Success 200 {object} RecoveryCodes
+// @Failure 400,401,409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/user/2fa/confirm [post]
+func ConfirmTOTP(twoFactorProvider TwoFactorProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
+		if !ok {
+			log.Ctx(r.Context()).Warn().
+				Msg("ConfirmTOTP: missing userID")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("Missing userID"))
+			return
+		}
+
+		var req TOTPCode
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().
+				Err(err).
+				Msg("ConfirmTOTP: failed to decode request body")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		codes, err := twoFactorProvider.ConfirmTOTP(ctx, userID, req.Code)
+		if err != nil {
+			writeTwoFactorError(w, r, err, "ConfirmTOTP")
+			return
+		}
+
+		log.Ctx(r.Context()).Info().
+			Msg("ConfirmTOTP: two-factor authentication enabled")
+		render.JSON(w, r, RecoveryCodes{RecoveryCodes: codes})
+	}
+}
+
+// @Summary DisableTOTP
+// @Security ApiKeyAuth
+// @Tags user
+// @Description Отключить двухфакторную аутентификацию, нужен текущий код или код восстановления
+// @ID disable-totp
+// @Accept  json
+// @Produce  json
+// @Param input body TOTPCode true "code from the authenticator app or recovery code"
+// @Success 200
+// @Failure 400,401,409 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/user/2fa/disable [post]
+func DisableTOTP(twoFactorProvider TwoFactorProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
+		if !ok {
+			log.Ctx(r.Context()).Warn().
+				Msg("DisableTOTP: missing userID")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("Missing userID"))
+			return
+		}
+
+		var req TOTPCode
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().
+				Err(err).
+				Msg("DisableTOTP: failed to decode request body")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		err = twoFactorProvider.DisableTOTP(ctx, userID, req.Code)
+		if err != nil {
+			writeTwoFactorError(w, r, err, "DisableTOTP")
+			return
+		}
+
+		log.Ctx(r.Context()).Info().
+			Msg("DisableTOTP: two-factor authentication disabled")
+		render.Status(r, http.StatusOK)
+	}
+}
+
+func writeTwoFactorError(w http.ResponseWriter, r *http.Request, err error, handler string) {
+	switch {
+	case errors.Is(err, models.ErrInvalidTOTPCode):
+		log.Ctx(r.Context()).Warn().Err(err).Msgf("%s: invalid code", handler)
+		render.Status(r, http.StatusBadRequest)
+	case errors.Is(err, models.ErrTOTPAlreadyEnabled), errors.Is(err, models.ErrTOTPNotEnrolled):
+		log.Ctx(r.Context()).Warn().Err(err).Msgf("%s: conflicting two-factor state", handler)
+		render.Status(r, http.StatusConflict)
+	default:
+		log.Ctx(r.Context()).Error().Err(err).Msgf("%s: request failed", handler)
+		render.Status(r, http.StatusInternalServerError)
+	}
+	render.JSON(w, r, response.Error(err.Error()))
+}
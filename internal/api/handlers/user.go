@@ -5,7 +5,6 @@ import (
 	"errors"
 	"net/http"
 	"time"
-	"todolist/internal/middleware"
 	"todolist/internal/models"
 	auth_utils "todolist/internal/pkg/authUtils"
 	"todolist/internal/pkg/response"
@@ -26,16 +25,28 @@ type UserInfo struct {
 	Password string `json:"password"`
 }
 
+type PasswordChange struct {
+	UserInfo
+	NewPassword string `json:"new_password"`
+}
+
 type Token struct {
 	Token string `json:"token"`
 }
 
+// TwoFactorChallenge is returned by sign-in instead of Token when the user has
+// two-factor authentication enabled.
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
 type AuthProvider interface {
-	SignIn(ctx context.Context, candidate *models.UserAuth) (tokenStr string, err error)
+	SignIn(ctx context.Context, candidate *models.UserAuth) (*models.SignInResult, error)
 	SignUp(ctx context.Context, candidate *models.UserAuth) error
 	CheckTaskOwnership(ctx context.Context, userID uuid.UUID, taskID uuid.UUID) (bool, error)
 	CheckCategoriesOwnership(ctx context.Context, userID uuid.UUID, categories []uuid.UUID) (bool, error)
-	DeleteUser(ctx context.Context, userID uuid.UUID) error
+	ChangePassword(ctx context.Context, candidate *models.UserAuth, newPassword string) error
 }
 
 func FromUserInfo(userDTO UserInfo) *models.UserAuth {
@@ -47,20 +58,21 @@ func FromUserInfo(userDTO UserInfo) *models.UserAuth {
 
 // @Summary SignIn
 // @Tags user
-// @Description Войти в систему
+// @Description Войти в систему. Если у пользователя включена двухфакторная аутентификация, вместо токена возвращается challenge_token для /api/v1/sign-in/2fa
 // @ID sign-in
 // @Accept  json
 // @Produce  json
 // @Param input body UserInfo true "user's name and password"
 // @Success 200 {object} Token
-// @Failure 400,404 {object} response.Response
+// @Success 202 {object} TwoFactorChallenge
+// @Failure 400,403,404 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Failure default {object} response.Response
 // @Router /api/v1/sign-in [post]
 func SignIn(authProvider AuthProvider, timeout time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
-		log.Info().
+		log.Ctx(r.Context()).Info().
 			Str("method", r.Method).
 			Str("path", r.URL.Path).
 			Msg("SignIn: started authentication process")
@@ -68,7 +80,7 @@ func SignIn(authProvider AuthProvider, timeout time.Duration) http.HandlerFunc {
 		var req UserInfo
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Err(err).
 				Msg("SignIn: failed to decode request body")
 			render.Status(r, http.StatusBadRequest)
@@ -76,7 +88,7 @@ func SignIn(authProvider AuthProvider, timeout time.Duration) http.HandlerFunc {
 			return
 		}
 
-		log.Debug().
+		log.Ctx(r.Context()).Debug().
 			Str("username", req.Name).
 			Msg("SignIn: processing authentication request")
 
@@ -84,22 +96,28 @@ func SignIn(authProvider AuthProvider, timeout time.Duration) http.HandlerFunc {
 		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 
-		tokenStr, err := authProvider.SignIn(ctx, FromUserInfo(req))
+		result, err := authProvider.SignIn(ctx, FromUserInfo(req))
 		if err != nil {
 			if errors.Is(err, auth_utils.ErrInvalidToken) {
-				log.Warn().
+				log.Ctx(r.Context()).Warn().
 					Str("username", req.Name).
 					Err(err).
 					Msg("SignIn: invalid credentials provided")
 				render.Status(r, http.StatusBadRequest)
 			} else if errors.Is(err, models.ErrUserNotFound) {
-				log.Warn().
+				log.Ctx(r.Context()).Warn().
 					Str("username", req.Name).
 					Err(err).
 					Msg("SignIn: user not found")
 				render.Status(r, http.StatusNotFound)
+			} else if errors.Is(err, models.ErrUserDisabled) || errors.Is(err, models.ErrPasswordReset) {
+				log.Ctx(r.Context()).Warn().
+					Str("username", req.Name).
+					Err(err).
+					Msg("SignIn: sign in is not allowed")
+				render.Status(r, http.StatusForbidden)
 			} else {
-				log.Error().
+				log.Ctx(r.Context()).Error().
 					Str("username", req.Name).
 					Err(err).
 					Msg("SignIn: authentication failed")
@@ -109,10 +127,19 @@ func SignIn(authProvider AuthProvider, timeout time.Duration) http.HandlerFunc {
 			return
 		}
 
-		log.Info().
+		if result.ChallengeRequired() {
+			log.Ctx(r.Context()).Info().
+				Str("username", req.Name).
+				Msg("SignIn: password accepted, second factor required")
+			render.Status(r, http.StatusAccepted)
+			render.JSON(w, r, TwoFactorChallenge{TwoFactorRequired: true, ChallengeToken: result.ChallengeToken})
+			return
+		}
+
+		log.Ctx(r.Context()).Info().
 			Str("username", req.Name).
 			Msg("SignIn: successfully authenticated user")
-		render.JSON(w, r, Token{Token: tokenStr})
+		render.JSON(w, r, Token{Token: result.Token})
 	}
 }
 
@@ -133,7 +160,7 @@ func SignUp(authProvider AuthProvider, timeout time.Duration) http.HandlerFunc {
 		var req UserInfo
 		err := render.DecodeJSON(r.Body, &req)
 		if err != nil {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Err(err).
 				Str("phase", "request_parsing").
 				Msg("failed to decode signup request body")
@@ -148,7 +175,7 @@ func SignUp(authProvider AuthProvider, timeout time.Duration) http.HandlerFunc {
 
 		err = authProvider.SignUp(ctx, FromUserInfo(req))
 		if err != nil {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Err(err).
 				Str("phase", "request_parsing").
 				Msg("failed to decode signup request body")
@@ -156,26 +183,26 @@ func SignUp(authProvider AuthProvider, timeout time.Duration) http.HandlerFunc {
 			render.JSON(w, r, response.Error(err.Error()))
 			return
 		}
-		log.Debug().
+		log.Ctx(r.Context()).Debug().
 			Str("username", req.Name).
 			Msg("parsed signup request")
 
-		tokenStr, err := authProvider.SignIn(ctx, FromUserInfo(req))
+		result, err := authProvider.SignIn(ctx, FromUserInfo(req))
 		if err != nil {
 			if errors.Is(err, auth_utils.ErrInvalidToken) {
-				log.Warn().
+				log.Ctx(r.Context()).Warn().
 					Str("username", req.Name).
 					Err(err).
 					Msg("SignUp: invalid credentials provided")
 				render.Status(r, http.StatusBadRequest)
 			} else if errors.Is(err, models.ErrUserNotFound) {
-				log.Warn().
+				log.Ctx(r.Context()).Warn().
 					Str("username", req.Name).
 					Err(err).
 					Msg("SignUp: user not found")
 				render.Status(r, http.StatusNotFound)
 			} else {
-				log.Error().
+				log.Ctx(r.Context()).Error().
 					Str("username", req.Name).
 					Err(err).
 					Msg("SignUp: authentication failed")
@@ -185,34 +212,35 @@ func SignUp(authProvider AuthProvider, timeout time.Duration) http.HandlerFunc {
 			return
 		}
 
-		log.Info().
+		log.Ctx(r.Context()).Info().
 			Str("username", req.Name).
 			Msg("SignUp: successfully authenticated user")
-		render.JSON(w, r, Token{Token: tokenStr})
+		render.JSON(w, r, Token{Token: result.Token})
 	}
 }
 
-// @Summary DeleteUser
-// @Security ApiKeyAuth
+// @Summary ChangePassword
 // @Tags user
-// @Description delete user
-// @ID delete-user
+// @Description Сменить пароль, нужен текущий пароль. Работает и когда администратор потребовал смену пароля
+// @ID change-password
 // @Accept  json
 // @Produce  json
+// @Param input body PasswordChange true "user's name, current and new password"
 // @Success 200
-// @Failure 400 {object} response.Response
+// @Failure 400,403,404 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Failure default {object} response.Response
-// @Router /api/v1/user [delete]
-func DeleteUser(authProvider AuthProvider, timeout time.Duration) http.HandlerFunc {
+// @Router /api/v1/user/password [post]
+func ChangePassword(authProvider AuthProvider, timeout time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-
-		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
-		if !ok {
-			log.Warn().
-				Msg("DeleteUser: failed to delete user")
-			render.Status(r, http.StatusUnauthorized)
-			render.JSON(w, r, response.Error("Missing userID"))
+		var req PasswordChange
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().
+				Err(err).
+				Msg("ChangePassword: failed to decode request body")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
 			return
 		}
 
@@ -220,18 +248,34 @@ func DeleteUser(authProvider AuthProvider, timeout time.Duration) http.HandlerFu
 		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 
-		err := authProvider.DeleteUser(ctx, userID)
+		err = authProvider.ChangePassword(ctx, FromUserInfo(req.UserInfo), req.NewPassword)
 		if err != nil {
-			log.Error().
-				Err(err).
-				Msg("DeleteUser: failed to delete user")
-			render.Status(r, http.StatusInternalServerError)
+			if errors.Is(err, models.ErrUserNotFound) {
+				log.Ctx(r.Context()).Warn().
+					Str("username", req.Name).
+					Err(err).
+					Msg("ChangePassword: user not found")
+				render.Status(r, http.StatusNotFound)
+			} else if errors.Is(err, models.ErrUserDisabled) {
+				log.Ctx(r.Context()).Warn().
+					Str("username", req.Name).
+					Err(err).
+					Msg("ChangePassword: user is disabled")
+				render.Status(r, http.StatusForbidden)
+			} else {
+				log.Ctx(r.Context()).Error().
+					Str("username", req.Name).
+					Err(err).
+					Msg("ChangePassword: failed to change password")
+				render.Status(r, http.StatusInternalServerError)
+			}
 			render.JSON(w, r, response.Error(err.Error()))
 			return
 		}
 
-		log.Info().
-			Msg("DeleteUser: successfully deleted user")
+		log.Ctx(r.Context()).Info().
+			Str("username", req.Name).
+			Msg("ChangePassword: password changed")
 		render.Status(r, http.StatusOK)
 	}
 }
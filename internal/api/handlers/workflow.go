@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"todolist/internal/middleware"
+	"todolist/internal/models"
+	"todolist/internal/pkg/response"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type StatusRequest struct {
+	// ID is omitted for a new column.
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+type WorkflowRequest struct {
+	Statuses []StatusRequest `json:"statuses"`
+}
+
+type StatusResponse struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Position int       `json:"position"`
+	// Terminal marks the last column, the one holding the done tasks.
+	Terminal bool `json:"terminal"`
+}
+
+type WorkflowResponse struct {
+	Statuses []StatusResponse `json:"statuses"`
+}
+
+type BoardColumnResponse struct {
+	StatusResponse
+	Tasks []TaskShortResponse `json:"tasks"`
+}
+
+type BoardResponse struct {
+	Columns []BoardColumnResponse `json:"columns"`
+}
+
+type MoveTaskRequest struct {
+	StatusID uuid.UUID `json:"status_id"`
+	// Position counts from 0 inside the column; past the end means last.
+	Position int `json:"position"`
+}
+
+type WorkflowProvider interface {
+	GetStatuses(ctx context.Context, userID uuid.UUID) ([]models.TaskStatus, error)
+	SetStatuses(ctx context.Context, userID uuid.UUID, statuses []models.StatusBody) ([]models.TaskStatus, error)
+	GetBoard(ctx context.Context, userID uuid.UUID) ([]models.BoardColumn, error)
+}
+
+// @Summary GetWorkflow
+// @Security ApiKeyAuth
+// @Tags workflow
+// @Description Колонки доски пользователя по порядку. Последняя колонка завершающая: в ней ровно выполненные задачи.
+// @Description Пустой список, если доска не настроена.
+// @ID get-workflow
+// @Produce  json
+// @Success 200 {object} WorkflowResponse
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/workflow [get]
+func GetWorkflow(workflowProvider WorkflowProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Ctx(r.Context()).Trace().Msg("get GetWorkflow request")
+
+		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
+		if !ok {
+			log.Ctx(r.Context()).Error().Msg("no uuid in context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("unauthorized"))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		statuses, err := workflowProvider.GetStatuses(ctx, userID)
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("GetWorkflow: failed to get statuses")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		render.JSON(w, r, toWorkflowResponse(statuses))
+	}
+}
+
+// @Summary SetWorkflow
+// @Security ApiKeyAuth
+// @Tags workflow
+// @Description Заменить колонки доски. Колонка с id сохраняет свои задачи под новым именем и на новом месте,
+// @Description колонка без id создается, колонки, которых нет в списке, удаляются.
+// @Description Колонок от 2 до 20 с уникальными непустыми именами до 50 символов; пустой список отключает доску.
+// @Description Задачи без колонки и задачи, чья колонка не соответствует is_done, переходят в конец первой
+// @Description или, если выполнены, последней колонки.
+// @ID set-workflow
+// @Accept  json
+// @Produce  json
+// @Param input body WorkflowRequest true "колонки по порядку"
+// @Success 200 {object} WorkflowResponse
+// @Failure 400,401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/workflow [put]
+func SetWorkflow(workflowProvider WorkflowProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Ctx(r.Context()).Trace().Msg("get SetWorkflow request")
+
+		var req WorkflowRequest
+		if err := render.DecodeJSON(r.Body, &req); err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse request")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+		statuses, err := toStatusBodies(req)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("SetWorkflow: invalid workflow")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
+		if !ok {
+			log.Ctx(r.Context()).Error().Msg("no uuid in context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("unauthorized"))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		result, err := workflowProvider.SetStatuses(ctx, userID, statuses)
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("SetWorkflow: failed to set statuses")
+			status := http.StatusInternalServerError
+			if errors.Is(err, models.ErrStatusNotFound) || errors.Is(err, models.ErrInvalidWorkflow) {
+				status = http.StatusBadRequest
+			}
+			render.Status(r, status)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		render.JSON(w, r, toWorkflowResponse(result))
+	}
+}
+
+// @Summary GetBoard
+// @Security ApiKeyAuth
+// @Tags workflow
+// @Description Доска пользователя: колонки по порядку, в каждой задачи в порядке position.
+// @Description Пустой список, если доска не настроена.
+// @ID get-board
+// @Produce  json
+// @Success 200 {object} BoardResponse
+// @Failure 401 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/board [get]
+func GetBoard(workflowProvider WorkflowProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Ctx(r.Context()).Trace().Msg("get GetBoard request")
+
+		userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID)
+		if !ok {
+			log.Ctx(r.Context()).Error().Msg("no uuid in context")
+			render.Status(r, http.StatusUnauthorized)
+			render.JSON(w, r, response.Error("unauthorized"))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		board, err := workflowProvider.GetBoard(ctx, userID)
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("GetBoard: failed to get board")
+			render.Status(r, http.StatusInternalServerError)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		columns := make([]BoardColumnResponse, 0, len(board))
+		for _, column := range board {
+			columns = append(columns, BoardColumnResponse{
+				StatusResponse: toStatusResponse(column.TaskStatus),
+				Tasks:          toTaskList(column.Tasks).List,
+			})
+		}
+		render.JSON(w, r, BoardResponse{Columns: columns})
+	}
+}
+
+// @Summary MoveTask
+// @Security ApiKeyAuth
+// @Tags task
+// @Description Переместить задачу в колонку status_id на место position, считая с 0; большее значение ставит ее в конец.
+// @Description Перемещение в последнюю колонку выполняет задачу, перемещение из нее снимает отметку о выполнении.
+// @Description С заголовком If-Match задача переместится, только если ее ETag не поменялся, иначе 412.
+// @ID move-task
+// @Accept  json
+// @Produce  json
+// @Param input body MoveTaskRequest true "колонка и место в ней"
+// @Param id   path      string  true  "Task ID (UUID)"
+// @Param If-Match header string false "ETag, полученный вместе с задачей"
+// @Success 200 {object} TaskResponse
+// @Header 200 {string} ETag "новая версия задачи"
+// @Failure 400,401,403,412 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Failure default {object} response.Response
+// @Router /api/v1/task/{id}/move [post]
+func MoveTask(taskProvider TaskProvider, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Ctx(r.Context()).Trace().Msg("get MoveTask request")
+
+		id, err := uuid.Parse(chi.URLParam(r, "id"))
+		if err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse path parameter")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("invalid UUID"))
+			return
+		}
+
+		var req MoveTaskRequest
+		if err = render.DecodeJSON(r.Body, &req); err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to parse request")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+		if req.StatusID == uuid.Nil || req.Position < 0 {
+			log.Ctx(r.Context()).Warn().Msg("MoveTask: invalid target")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("status_id is required and position must not be negative"))
+			return
+		}
+
+		ifVersion, err := ifMatchVersion(r)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("invalid If-Match")
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		ctx := r.Context()
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		task, err := taskProvider.Move(ctx, id, req.StatusID, req.Position, ifVersion)
+		if err != nil {
+			log.Ctx(r.Context()).Err(err).Msg("Move, error from provider")
+			render.Status(r, taskErrorStatus(err))
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+
+		w.Header().Set("ETag", taskETag(task.Version))
+		render.JSON(w, r, toTaskResponse(task))
+	}
+}
+
+// toStatusBodies trims the names of the workflow of SetWorkflow and checks
+// it with models.ValidateStatuses, the check the adapter makes as well.
+func toStatusBodies(req WorkflowRequest) ([]models.StatusBody, error) {
+	statuses := make([]models.StatusBody, 0, len(req.Statuses))
+	for _, status := range req.Statuses {
+		statuses = append(statuses, models.StatusBody{ID: status.ID, Name: strings.TrimSpace(status.Name)})
+	}
+	if err := models.ValidateStatuses(statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+func toStatusResponse(status models.TaskStatus) StatusResponse {
+	return StatusResponse{ID: status.ID, Name: status.Name, Position: status.Position, Terminal: status.Terminal}
+}
+
+func toWorkflowResponse(statuses []models.TaskStatus) WorkflowResponse {
+	responses := make([]StatusResponse, 0, len(statuses))
+	for _, status := range statuses {
+		responses = append(responses, toStatusResponse(status))
+	}
+	return WorkflowResponse{Statuses: responses}
+}
@@ -0,0 +1,101 @@
+// Package database opens the Postgres connection pool, with an optional read
+// replica for list queries, or the SQLite database file.
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+	"todolist/config"
+
+	"github.com/avast/retry-go"
+	"github.com/glebarez/sqlite"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replicaResolver names the resolver that ReadReplica routes to. It matches
+// no table, so queries go to the replica only when asked to.
+const replicaResolver = "replica"
+
+// Open connects to the primary, retrying while Postgres starts up, and
+// applies the pool settings. With a replica host configured, queries using
+// ReadReplica are sent there; everything else stays on the primary, so
+// reads right after a write never see replication lag.
+func Open(cfg config.PostgresConfig) (*gorm.DB, error) {
+	var db *gorm.DB
+	err := retry.Do(
+		func() error {
+			var err error
+			db, err = gorm.Open(postgres.Open(cfg.String()), &gorm.Config{})
+			if err != nil {
+				log.Warn().Err(err).Msg("DB connection failed, retrying")
+			}
+			return err
+		},
+		retry.Attempts(5),
+		retry.Delay(1*time.Second),
+		retry.MaxDelay(10*time.Second),
+		retry.DelayType(retry.BackOffDelay),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to postgres")
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get connection pool")
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if cfg.ReplicaHost != "" {
+		resolver := dbresolver.Register(dbresolver.Config{
+			Replicas: []gorm.Dialector{postgres.Open(cfg.ReplicaString())},
+		}, replicaResolver).
+			SetMaxOpenConns(cfg.MaxOpenConns).
+			SetMaxIdleConns(cfg.MaxIdleConns).
+			SetConnMaxLifetime(cfg.ConnMaxLifetime).
+			SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+		if err = db.Use(resolver); err != nil {
+			return nil, errors.Wrap(err, "failed to register read replica")
+		}
+	}
+
+	return db, nil
+}
+
+// OpenSQLite opens the database file, creating it when missing. Every
+// connection enforces foreign keys, which SQLite leaves off by default, and
+// uses the write-ahead log so readers do not block the writer. Transactions
+// take the write lock up front: waiting on it honours BusyTimeout, while
+// upgrading a read transaction later fails at once when another write runs.
+// Timestamps are written in UTC: SQLite stores them as text, which only
+// sorts and compares correctly with a single offset.
+func OpenSQLite(cfg config.SQLiteConfig) (*gorm.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", cfg.BusyTimeout.Milliseconds()))
+	params.Set("_txlock", "immediate")
+
+	db, err := gorm.Open(sqlite.Open(cfg.Path+"?"+params.Encode()), &gorm.Config{
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open sqlite database %s", cfg.Path)
+	}
+	return db, nil
+}
+
+// ReadReplica sends a read query to the replica when one is configured. Use
+// it for lists that tolerate a little replication lag.
+func ReadReplica() clause.Expression {
+	return dbresolver.Use(replicaResolver)
+}
@@ -0,0 +1,132 @@
+// Package health implements the readiness checks used by /readyz.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusFail        = "fail"
+)
+
+type CheckResult struct {
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Ready reports whether every check passed.
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// SchemaStatus reports how many migrations the database is missing.
+type SchemaStatus interface {
+	Pending(ctx context.Context) (int, error)
+}
+
+type Checker struct {
+	db       *gorm.DB
+	schema   SchemaStatus
+	draining atomic.Bool
+}
+
+// NewChecker checks the database and its schema. Without a db, as with
+// memory storage, only the shutdown state is reported.
+func NewChecker(db *gorm.DB, schema SchemaStatus) *Checker {
+	return &Checker{db: db, schema: schema}
+}
+
+// StartDraining makes the service report itself unready from now on, so that
+// load balancers stop sending traffic while in-flight requests finish.
+func (c *Checker) StartDraining() {
+	c.draining.Store(true)
+}
+
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult)}
+	if c.draining.Load() {
+		report.Checks["shutdown"] = CheckResult{Status: StatusFail, Detail: "server is shutting down"}
+		report.Status = StatusUnavailable
+		return report
+	}
+
+	if c.db == nil {
+		return report
+	}
+
+	// The ping check is named after the database: postgres or sqlite.
+	report.Checks[c.db.Dialector.Name()] = result(c.checkPing(ctx))
+	report.Checks["schema"] = result(c.checkSchema(ctx))
+	poolDetail, poolErr := c.checkPool()
+	pool := result(poolErr)
+	if poolErr == nil {
+		pool.Detail = poolDetail
+	}
+	report.Checks["pool"] = pool
+
+	for _, check := range report.Checks {
+		if check.Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+func (c *Checker) checkPing(ctx context.Context) error {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return errors.Wrap(err, "failed to get sql.DB")
+	}
+	return errors.Wrap(sqlDB.PingContext(ctx), "ping failed")
+}
+
+// checkSchema fails until every migration is applied, since the code expects
+// the latest schema.
+func (c *Checker) checkSchema(ctx context.Context) error {
+	pending, err := c.schema.Pending(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to read schema version")
+	}
+	if pending > 0 {
+		return errors.Errorf("%d migrations pending, run migrate up", pending)
+	}
+	return nil
+}
+
+// checkPool fails when every allowed connection is in use, so requests would
+// queue for a connection.
+func (c *Checker) checkPool() (string, error) {
+	sqlDB, err := c.db.DB()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get sql.DB")
+	}
+
+	stats := sqlDB.Stats()
+	if stats.MaxOpenConnections == 0 {
+		return fmt.Sprintf("%d connections in use, no limit", stats.InUse), nil
+	}
+	detail := fmt.Sprintf("%d/%d connections in use", stats.InUse, stats.MaxOpenConnections)
+	if stats.InUse >= stats.MaxOpenConnections {
+		return "", errors.Errorf("pool saturated: %s, %d waits so far", detail, stats.WaitCount)
+	}
+	return detail, nil
+}
+
+func result(err error) CheckResult {
+	if err != nil {
+		return CheckResult{Status: StatusFail, Detail: err.Error()}
+	}
+	return CheckResult{Status: StatusOK}
+}
@@ -0,0 +1,27 @@
+package health
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_DrainingIsNotReady(t *testing.T) {
+	// A draining checker answers without touching the database.
+	checker := NewChecker(nil, nil)
+	checker.StartDraining()
+
+	report := checker.Check(context.Background())
+
+	assert.False(t, report.Ready())
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, StatusFail, report.Checks["shutdown"].Status)
+}
+
+func TestChecker_WithoutDatabase(t *testing.T) {
+	report := NewChecker(nil, nil).Check(context.Background())
+
+	assert.True(t, report.Ready())
+	assert.Empty(t, report.Checks)
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type DeletionPurger interface {
+	PurgeDueDeletions(ctx context.Context) (int, error)
+}
+
+// RunDeletionPurge deletes the accounts whose deletion grace period is over,
+// once at start and then every interval, until ctx is cancelled.
+func RunDeletionPurge(ctx context.Context, purger DeletionPurger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := purger.PurgeDueDeletions(ctx)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Int("deleted", deleted).
+				Msg("RunDeletionPurge: failed to delete scheduled accounts")
+		} else if deleted > 0 {
+			log.Info().
+				Int("deleted", deleted).
+				Msg("RunDeletionPurge: deleted scheduled accounts")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
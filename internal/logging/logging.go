@@ -0,0 +1,44 @@
+// Package logging configures zerolog and provides the request-scoped logger
+// handlers reach through log.Ctx.
+package logging
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Formats accepted by Setup.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Setup replaces the global logger. Every line goes through Redact before it
+// is written, whatever the format.
+func Setup(level, format string) error {
+	parsedLevel, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil {
+		return errors.Wrapf(err, "invalid log level %q", level)
+	}
+
+	var out io.Writer
+	switch strings.ToLower(format) {
+	case FormatJSON, "":
+		out = os.Stdout
+	case FormatConsole:
+		out = zerolog.ConsoleWriter{Out: os.Stdout}
+	default:
+		return errors.Errorf("unknown log format %q", format)
+	}
+
+	zerolog.SetGlobalLevel(parsedLevel)
+	log.Logger = zerolog.New(NewRedactingWriter(out)).With().Timestamp().Logger()
+	// Outside a request log.Ctx falls back to the global logger.
+	zerolog.DefaultContextLogger = &log.Logger
+	return nil
+}
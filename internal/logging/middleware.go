@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength keeps a client from stuffing arbitrary data into every
+// log line through the header.
+const maxRequestIDLength = 128
+
+type contextKey string
+
+const (
+	requestIDKey     contextKey = "requestID"
+	requestLoggerKey contextKey = "requestLogger"
+)
+
+// RequestID takes the request ID from the X-Request-ID header or generates
+// one, and echoes it in the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// RequestLogger puts a logger carrying the request ID, method and path into
+// the context for log.Ctx, and writes one access log line per request with
+// route, status, latency and user ID. It builds on the logger already in the
+// context, so trace IDs added by tracing.HTTPMiddleware are kept.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		logger := zerolog.Ctx(r.Context()).With().
+			Str("request_id", RequestIDFromContext(r.Context())).
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Logger()
+
+		// WithContext stores a copy; that copy is shared by pointer with the
+		// handlers below, so fields they add with SetUserID also end up in
+		// the access log line.
+		ctx := logger.WithContext(r.Context())
+		reqLogger := zerolog.Ctx(ctx)
+		ctx = context.WithValue(ctx, requestLoggerKey, reqLogger)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		var event *zerolog.Event
+		switch {
+		case status >= http.StatusInternalServerError:
+			event = reqLogger.Error()
+		case status >= http.StatusBadRequest:
+			event = reqLogger.Warn()
+		default:
+			event = reqLogger.Info()
+		}
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			event = event.Str("route", rctx.RoutePattern())
+		}
+		event.
+			Int("status", status).
+			Int("bytes", ww.BytesWritten()).
+			Dur("latency", time.Since(start)).
+			Msg("request completed")
+	})
+}
+
+// SetUserID adds the authenticated user to the request logger, both for the
+// lines logged from here on and for the access log line.
+func SetUserID(ctx context.Context, userID string) {
+	logger, ok := ctx.Value(requestLoggerKey).(*zerolog.Logger)
+	if !ok {
+		return
+	}
+	logger.UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Str("user_id", userID)
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
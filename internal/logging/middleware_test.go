@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "incoming id is kept", incoming: "req-42", keep: true},
+		{name: "missing id is generated"},
+		{name: "oversized id is replaced", incoming: strings.Repeat("a", maxRequestIDLength+1)},
+		{name: "id with spaces is replaced", incoming: "two words"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestIDFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, seen, rec.Header().Get(RequestIDHeader))
+			if tt.keep {
+				assert.Equal(t, tt.incoming, seen)
+			} else {
+				_, err := uuid.Parse(seen)
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	var logs bytes.Buffer
+	globalLogger := log.Logger
+	log.Logger = zerolog.New(&logs)
+	defer func() { log.Logger = globalLogger }()
+	zerolog.DefaultContextLogger = &log.Logger
+
+	r := chi.NewRouter()
+	r.Use(RequestID, RequestLogger)
+	r.Get("/api/v1/task/{id}", func(w http.ResponseWriter, r *http.Request) {
+		SetUserID(r.Context(), "user-1")
+		log.Ctx(r.Context()).Info().Msg("handling")
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/task/7", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var handlerLine, accessLine map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &handlerLine))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &accessLine))
+
+	assert.Equal(t, "req-42", handlerLine["request_id"])
+	assert.Equal(t, "user-1", handlerLine["user_id"])
+
+	assert.Equal(t, "request completed", accessLine["message"])
+	assert.Equal(t, "warn", accessLine["level"])
+	assert.Equal(t, "req-42", accessLine["request_id"])
+	assert.Equal(t, "user-1", accessLine["user_id"])
+	assert.Equal(t, "/api/v1/task/{id}", accessLine["route"])
+	assert.Equal(t, float64(http.StatusNotFound), accessLine["status"])
+	assert.Contains(t, accessLine, "latency")
+}
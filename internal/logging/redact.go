@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"io"
+	"regexp"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// sensitiveField matches JSON string fields holding credentials, both as
+	// log fields and, with escaped quotes, inside logged request or error text.
+	sensitiveField = regexp.MustCompile(`(?i)(\\?"(?:password|new_password|password_hash|token|challenge_token|access_token|refresh_token|id_token|secret|client_secret|authorization|code_verifier)\\?"\s*:\s*)(\\?")(?:[^"\\]|\\[^"])*\\?"`)
+	// bearerToken and jwtToken catch tokens in free text, e.g. error messages.
+	bearerToken = regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+	jwtToken    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+)
+
+// Redact masks passwords, secrets and tokens in a log line.
+func Redact(line []byte) []byte {
+	line = sensitiveField.ReplaceAll(line, []byte(`${1}${2}`+redacted+`${2}`))
+	line = bearerToken.ReplaceAll(line, []byte(`${1}`+redacted))
+	line = jwtToken.ReplaceAll(line, []byte(redacted))
+	return line
+}
+
+type redactingWriter struct {
+	out io.Writer
+}
+
+// NewRedactingWriter returns a writer that passes every log line through
+// Redact. zerolog writes one complete line per Write call.
+func NewRedactingWriter(out io.Writer) io.Writer {
+	return redactingWriter{out: out}
+}
+
+func (w redactingWriter) Write(p []byte) (int, error) {
+	if _, err := w.out.Write(Redact(p)); err != nil {
+		return 0, err
+	}
+	// Report the original length, zerolog treats a short write as an error.
+	return len(p), nil
+}
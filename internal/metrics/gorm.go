@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const startedAtKey = "metrics:started_at"
+
+// GormPlugin records the duration of every GORM operation.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "metrics"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	errs := []error{
+		cb.Create().Before("gorm:create").Register("metrics:before_create", start),
+		cb.Create().After("gorm:create").Register("metrics:after_create", observe("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", start),
+		cb.Query().After("gorm:query").Register("metrics:after_query", observe("query")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", start),
+		cb.Update().After("gorm:update").Register("metrics:after_update", observe("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", start),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", observe("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", start),
+		cb.Row().After("gorm:row").Register("metrics:after_row", observe("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", start),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", observe("raw")),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return errors.Wrap(err, "failed to register metrics callbacks")
+		}
+	}
+	return nil
+}
+
+// RegisterDBStats exposes the connection pool statistics of db.
+func RegisterDBStats(db *gorm.DB, name string) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return errors.Wrap(err, "failed to get sql.DB from gorm")
+	}
+	return prometheus.Register(collectors.NewDBStatsCollector(sqlDB, name))
+}
+
+func start(db *gorm.DB) {
+	db.InstanceSet(startedAtKey, time.Now())
+}
+
+func observe(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startedAtKey)
+		if !ok {
+			return
+		}
+		startedAt, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		dbQueryDuration.WithLabelValues(operation, db.Statement.Table).Observe(time.Since(startedAt).Seconds())
+	}
+}
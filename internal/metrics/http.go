@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute labels requests no route matched, so that scanning for
+// random paths cannot blow up the label cardinality.
+const unmatchedRoute = "unmatched"
+
+// HTTPMiddleware records request count and latency. The route label is the
+// chi route pattern, e.g. /api/v1/task/{id}/, not the raw path.
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				route = pattern
+			}
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		labels := []string{r.Method, route, strconv.Itoa(status)}
+
+		httpRequests.WithLabelValues(labels...).Inc()
+		httpDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
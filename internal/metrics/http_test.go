@@ -0,0 +1,28 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPMiddleware_UsesRoutePattern(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(HTTPMiddleware)
+	r.Get("/api/v1/task/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	before := testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/api/v1/task/{id}", "418"))
+	for _, id := range []string{"1", "2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/task/"+id, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/no/such/path", nil))
+
+	assert.Equal(t, before+2, testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/api/v1/task/{id}", "418")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")))
+}
@@ -2,36 +2,49 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
+	"todolist/internal/logging"
+	"todolist/internal/models"
 	auth_utils "todolist/internal/pkg/authUtils"
 	"todolist/internal/pkg/response"
 
 	"github.com/go-chi/render"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
 var (
-	UserIDContextKey string = "contextKeyID{}"
+	UserIDContextKey   string = "contextKeyID{}"
+	UserRoleContextKey string = "contextKeyRole{}"
 )
 
-func NewJwtAuthMiddleware(secretSrc string, tokenHandlerSrc auth_utils.ITokenHandler) JwtAuthMiddleware {
+// UserAuthorizer returns the current state of the user a token was issued
+// to, or an error when the user may no longer use the API.
+type UserAuthorizer interface {
+	Authorize(ctx context.Context, userID uuid.UUID) (*models.User, error)
+}
+
+func NewJwtAuthMiddleware(secretSrc string, tokenHandlerSrc auth_utils.ITokenHandler, usersSrc UserAuthorizer) JwtAuthMiddleware {
 	return JwtAuthMiddleware{
 		secret:       secretSrc,
 		tokenHandler: tokenHandlerSrc,
+		users:        usersSrc,
 	}
 }
 
 type JwtAuthMiddleware struct {
 	secret       string
 	tokenHandler auth_utils.ITokenHandler
+	users        UserAuthorizer
 }
 
 func (m *JwtAuthMiddleware) MiddlewareFunc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := r.Header.Get("Authorization")
 		if token == "" {
-			log.Info().Msg("user with no token came")
+			log.Ctx(r.Context()).Info().Msg("user with no token came")
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response.Error("Error in parsing token"))
 			return
@@ -41,20 +54,75 @@ func (m *JwtAuthMiddleware) MiddlewareFunc(next http.Handler) http.Handler {
 		payload, err := m.tokenHandler.ParseToken(token, m.secret)
 		if err != nil {
 			if err == auth_utils.ErrParsingToken {
-				log.Info().Msg("user with invalid jwt came")
+				log.Ctx(r.Context()).Info().Msg("user with invalid jwt came")
 				render.Status(r, http.StatusBadRequest)
 				render.JSON(w, r, response.Error(err.Error()))
 			} else {
-				log.Info().Msg("user with invalid jwt came")
+				log.Ctx(r.Context()).Info().Msg("user with invalid jwt came")
 				render.Status(r, http.StatusUnauthorized)
 				render.JSON(w, r, response.Error(err.Error()))
 			}
 			return
 		}
-		ctx := context.WithValue(r.Context(), UserIDContextKey, payload.ID)
+		logging.SetUserID(r.Context(), payload.ID.String())
+
+		// The claims are as old as the token; the role and the account
+		// state come from the database.
+		user, err := m.users.Authorize(r.Context(), payload.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrUserNotFound):
+				log.Ctx(r.Context()).Info().Err(err).Msg("token of a deleted user came")
+				render.Status(r, http.StatusUnauthorized)
+			case errors.Is(err, models.ErrUserDisabled), errors.Is(err, models.ErrPasswordReset):
+				log.Ctx(r.Context()).Info().Err(err).Msg("token of a locked user came")
+				render.Status(r, http.StatusForbidden)
+			default:
+				log.Ctx(r.Context()).Error().Err(err).Msg("failed to authorize user")
+				render.Status(r, http.StatusInternalServerError)
+			}
+			render.JSON(w, r, response.Error(err.Error()))
+			return
+		}
+		role := user.Role
+		if role == "" {
+			role = models.RoleUser
+		}
 
-		log.Info().Msgf("user with id %v successfully authorized", payload.ID)
+		ctx := context.WithValue(r.Context(), UserIDContextKey, user.ID)
+		ctx = context.WithValue(ctx, UserRoleContextKey, role)
+
+		log.Ctx(r.Context()).Info().Msgf("user with id %v successfully authorized", payload.ID)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// RequireRole lets through only users authorized by JwtAuthMiddleware with
+// the given role.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userRole, ok := r.Context().Value(UserRoleContextKey).(string)
+			if !ok {
+				log.Ctx(r.Context()).Warn().Msg("RequireRole: missing role in context")
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, response.Error("Missing role"))
+				return
+			}
+
+			if userRole != role {
+				log.Ctx(r.Context()).Warn().
+					Interface("userID", r.Context().Value(UserIDContextKey)).
+					Str("role", userRole).
+					Str("required_role", role).
+					Msg("RequireRole: access denied")
+				render.Status(r, http.StatusForbidden)
+				render.JSON(w, r, response.Error("Insufficient role"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"time"
 	"todolist/internal/adapters"
+	"todolist/internal/metrics"
 	"todolist/internal/pkg/response"
 
 	"github.com/go-chi/chi/v5"
@@ -40,7 +41,7 @@ func NewOwnershipMiddleware(service adapters.UserAdapter, timeout time.Duration)
 
 func (m *OwnershipMiddleware) CheckCategoriesMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Info().
+		log.Ctx(r.Context()).Info().
 			Str("method", r.Method).
 			Str("path", r.URL.Path).
 			Msg("CheckCategoriesMiddleware: started processing")
@@ -48,7 +49,7 @@ func (m *OwnershipMiddleware) CheckCategoriesMiddleware(next http.Handler) http.
 		// saving data for further handlers
 		bodyBytes, err := io.ReadAll(r.Body)
 		if err != nil {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Err(err).
 				Msg("CheckCategoriesMiddleware: failed to read request body")
 			render.Status(r, http.StatusBadRequest)
@@ -59,21 +60,21 @@ func (m *OwnershipMiddleware) CheckCategoriesMiddleware(next http.Handler) http.
 
 		userID, ok := r.Context().Value(UserIDContextKey).(uuid.UUID)
 		if !ok {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Msg("CheckCategoriesMiddleware: missing userID in context")
 			render.Status(r, http.StatusUnauthorized)
 			render.JSON(w, r, response.Error("Missing userID"))
 			return
 		}
 
-		log.Debug().
+		log.Ctx(r.Context()).Debug().
 			Str("userID", userID.String()).
 			Msg("CheckCategoriesMiddleware: processing request")
 
 		var req TaskRequest
 		err = json.NewDecoder(bytes.NewReader(bodyBytes)).Decode(&req)
 		if err != nil {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Err(err).
 				Msg("CheckCategoriesMiddleware: failed to decode request body")
 			render.Status(r, http.StatusBadRequest)
@@ -81,7 +82,7 @@ func (m *OwnershipMiddleware) CheckCategoriesMiddleware(next http.Handler) http.
 			return
 		}
 
-		log.Debug().
+		log.Ctx(r.Context()).Debug().
 			Int("num_categories", len(req.CategoryIds)).
 			Msg("CheckCategoriesMiddleware: checking category ownership")
 
@@ -91,7 +92,7 @@ func (m *OwnershipMiddleware) CheckCategoriesMiddleware(next http.Handler) http.
 
 		areCategoriesOwned, err := m.userService.CheckCategoriesOwnership(ctx, userID, req.CategoryIds)
 		if err != nil {
-			log.Error().
+			log.Ctx(r.Context()).Error().
 				Err(err).
 				Str("userID", userID.String()).
 				Msg("CheckCategoriesMiddleware: failed to verify category ownership")
@@ -101,16 +102,17 @@ func (m *OwnershipMiddleware) CheckCategoriesMiddleware(next http.Handler) http.
 		}
 
 		if !areCategoriesOwned {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Str("userID", userID.String()).
 				Int("num_categories", len(req.CategoryIds)).
 				Msg("CheckCategoriesMiddleware: unauthorized category access attempt")
+			metrics.OwnershipDenials.WithLabelValues(metrics.ResourceCategory).Inc()
 			render.Status(r, http.StatusForbidden)
 			render.JSON(w, r, response.Error("Unauthorized access to categories"))
 			return
 		}
 
-		log.Info().
+		log.Ctx(r.Context()).Info().
 			Str("userID", userID.String()).
 			Msg("CheckCategoriesMiddleware: successful ownership verification")
 
@@ -122,27 +124,27 @@ func (m *OwnershipMiddleware) CheckCategoriesMiddleware(next http.Handler) http.
 
 func (m *OwnershipMiddleware) CheckTaskMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Info().
+		log.Ctx(r.Context()).Info().
 			Str("method", r.Method).
 			Str("path", r.URL.Path).
 			Msg("CheckTaskMiddleware: started processing")
 
 		taskID := chi.URLParam(r, "id")
 		if taskID == "" {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Msg("CheckTaskMiddleware: empty task ID provided")
 			render.Status(r, http.StatusBadRequest)
 			render.JSON(w, r, response.Error("Task ID required"))
 			return
 		}
 
-		log.Debug().
+		log.Ctx(r.Context()).Debug().
 			Str("taskID", taskID).
 			Msg("CheckTaskMiddleware: processing task")
 
 		taskUUID, err := uuid.Parse(taskID)
 		if err != nil {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Str("taskID", taskID).
 				Err(err).
 				Msg("CheckTaskMiddleware: invalid task UUID format")
@@ -153,14 +155,14 @@ func (m *OwnershipMiddleware) CheckTaskMiddleware(next http.Handler) http.Handle
 
 		userID, ok := r.Context().Value(UserIDContextKey).(uuid.UUID)
 		if !ok {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Msg("CheckTaskMiddleware: missing userID in context")
 			render.Status(r, http.StatusUnauthorized)
 			render.JSON(w, r, response.Error("Missing userID"))
 			return
 		}
 
-		log.Debug().
+		log.Ctx(r.Context()).Debug().
 			Str("userID", userID.String()).
 			Str("taskID", taskUUID.String()).
 			Msg("CheckTaskMiddleware: verifying ownership")
@@ -171,7 +173,7 @@ func (m *OwnershipMiddleware) CheckTaskMiddleware(next http.Handler) http.Handle
 
 		isTaskOwned, err := m.userService.CheckTaskOwnership(ctx, userID, taskUUID)
 		if err != nil {
-			log.Error().
+			log.Ctx(r.Context()).Error().
 				Err(err).
 				Str("userID", userID.String()).
 				Str("taskID", taskUUID.String()).
@@ -182,16 +184,17 @@ func (m *OwnershipMiddleware) CheckTaskMiddleware(next http.Handler) http.Handle
 		}
 
 		if !isTaskOwned {
-			log.Warn().
+			log.Ctx(r.Context()).Warn().
 				Str("userID", userID.String()).
 				Str("taskID", taskUUID.String()).
 				Msg("CheckTaskMiddleware: unauthorized task access attempt")
+			metrics.OwnershipDenials.WithLabelValues(metrics.ResourceTask).Inc()
 			render.Status(r, http.StatusForbidden)
 			render.JSON(w, r, response.Error("Unauthorized access to task"))
 			return
 		}
 
-		log.Info().
+		log.Ctx(r.Context()).Info().
 			Str("userID", userID.String()).
 			Str("taskID", taskUUID.String()).
 			Msg("CheckTaskMiddleware: ownership verified successfully")
@@ -0,0 +1,59 @@
+package migrations_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"todolist/internal/migrations"
+	"todolist/internal/pkg/dbtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrator_ConcurrentUp(t *testing.T) {
+	db := dbtest.Postgres(t)
+	ctx := context.Background()
+
+	migrator, err := migrations.NewMigrator(db)
+	require.NoError(t, err)
+	for {
+		reverted, err := migrator.Down(ctx)
+		require.NoError(t, err)
+		if reverted == nil {
+			break
+		}
+	}
+
+	// Replicas started together each run serve --migrate.
+	const replicas = 4
+	applied := make([]int, replicas)
+	errs := make([]error, replicas)
+	var wg sync.WaitGroup
+	for i := 0; i < replicas; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			migrator, err := migrations.NewMigrator(db)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			done, err := migrator.Up(ctx)
+			applied[i], errs[i] = len(done), err
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for i := 0; i < replicas; i++ {
+		assert.NoError(t, errs[i])
+		total += applied[i]
+	}
+	pending, err := migrator.Pending(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, pending)
+	states, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(states), total, "every migration is applied exactly once")
+}
@@ -0,0 +1,289 @@
+// Package migrations applies the schema changes embedded in sql/. Every
+// supported dialect has a directory of its own, sql/postgres and sql/sqlite,
+// holding the same versions. Each version has an NNNN_name.up.sql and an
+// NNNN_name.down.sql file; applied versions are recorded in
+// schema_migrations.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*/*.sql
+var files embed.FS
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type State struct {
+	Migration
+	// AppliedAt is nil for pending migrations.
+	AppliedAt *time.Time
+}
+
+type schemaMigration struct {
+	Version   int       `gorm:"primaryKey"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Load returns the embedded migrations of a dialect, as named by the gorm
+// dialector, ordered by version.
+func Load(dialect string) ([]Migration, error) {
+	names, err := fs.Glob(files, path.Join("sql", dialect, "*.sql"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list migrations")
+	}
+	if len(names) == 0 {
+		return nil, errors.Errorf("no migrations for the %s dialect", dialect)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, name := range names {
+		base := path.Base(name)
+		stem, direction, ok := strings.Cut(strings.TrimSuffix(base, ".sql"), ".")
+		versionText, title, found := strings.Cut(stem, "_")
+		version, convErr := strconv.Atoi(versionText)
+		if !ok || !found || convErr != nil || (direction != "up" && direction != "down") {
+			return nil, errors.Errorf("invalid migration file name %s, expected NNNN_name.up.sql or NNNN_name.down.sql", base)
+		}
+
+		content, err := files.ReadFile(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read migration %s", base)
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: title}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, errors.Errorf("migration %04d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// lockID keys the Postgres advisory lock held while migrating. Any number
+// no other code of the application locks on will do.
+const lockID = 7_315_402_986
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	now        func() time.Time
+}
+
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	migrations, err := Load(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, now: time.Now}, nil
+}
+
+// Up applies every pending migration, each in its own transaction, and
+// returns the applied ones.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		var err error
+		done, err = m.up(db)
+		return err
+	})
+	return done, err
+}
+
+func (m *Migrator) up(db *gorm.DB) ([]Migration, error) {
+	applied, err := m.prepare(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Up).Error; err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: m.now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return done, errors.Wrapf(err, "failed to apply migration %04d_%s", migration.Version, migration.Name)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the latest applied migration and returns it, or nil when
+// nothing is applied.
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var reverted *Migration
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		var err error
+		reverted, err = m.down(db)
+		return err
+	})
+	return reverted, err
+}
+
+func (m *Migrator) down(db *gorm.DB) (*Migration, error) {
+	applied, err := m.prepare(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(migration.Down).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, "version = ?", migration.Version).Error
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to revert migration %04d_%s", migration.Version, migration.Name)
+		}
+		return &migration, nil
+	}
+	return nil, nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]State, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]State, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		state := State{Migration: migration}
+		if at, ok := applied[migration.Version]; ok {
+			state.AppliedAt = &at
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// Pending returns how many migrations are not applied yet.
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+// prepare creates schema_migrations if needed and returns the applied
+// versions. Databases created by the former db_init/init.sql have the tables
+// of the first migration but no schema_migrations; that migration is recorded
+// as applied for them. Whatever a later version of the script added on top is
+// created again by the following migrations, which tolerate finding it.
+func (m *Migrator) prepare(db *gorm.DB) (map[int]time.Time, error) {
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		if err := db.Migrator().CreateTable(&schemaMigration{}); err != nil {
+			return nil, errors.Wrap(err, "failed to create schema_migrations")
+		}
+		if len(m.migrations) > 0 && db.Migrator().HasTable("users") {
+			first := m.migrations[0]
+			err := db.Create(&schemaMigration{Version: first.Version, Name: first.Name, AppliedAt: m.now().UTC()}).Error
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to record the existing schema")
+			}
+		}
+	}
+	return m.applied(db)
+}
+
+// applied returns the applied versions with their dates without changing the
+// database, so it is safe for status and readiness checks.
+func (m *Migrator) applied(db *gorm.DB) (map[int]time.Time, error) {
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return map[int]time.Time{}, nil
+	}
+
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, errors.Wrap(err, "failed to read schema_migrations")
+	}
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+// locked runs fn with the migration lock held for all of it, so instances
+// started together with serve --migrate apply the migrations one at a time
+// and the later ones find nothing pending. On Postgres the lock is a session
+// advisory lock, and fn gets the connection holding it. SQLite serves a
+// single instance; its transactions are serialized by the file lock.
+func (m *Migrator) locked(ctx context.Context, fn func(db *gorm.DB) error) error {
+	db := m.db.WithContext(ctx)
+	if db.Dialector.Name() != "postgres" {
+		return fn(db)
+	}
+
+	return db.Connection(func(conn *gorm.DB) (err error) {
+		if err = conn.Exec("SELECT pg_advisory_lock(?)", lockID).Error; err != nil {
+			return errors.Wrap(err, "failed to take the migration lock")
+		}
+		defer func() {
+			// The connection goes back to the pool afterwards, so the lock
+			// is released even when ctx is done.
+			unlock := conn.WithContext(context.WithoutCancel(ctx)).Exec("SELECT pg_advisory_unlock(?)", lockID).Error
+			if unlock != nil && err == nil {
+				err = errors.Wrap(unlock, "failed to release the migration lock")
+			}
+		}()
+		return fn(conn)
+	})
+}
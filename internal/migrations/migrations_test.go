@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+	"todolist/config"
+	"todolist/internal/database"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	for _, dialect := range []string{"postgres", "sqlite"} {
+		t.Run(dialect, func(t *testing.T) {
+			migrations, err := Load(dialect)
+			require.NoError(t, err)
+			require.NotEmpty(t, migrations)
+
+			for i, m := range migrations {
+				assert.Equal(t, i+1, m.Version, "versions have no gaps")
+				assert.NotEmpty(t, m.Name)
+				assert.NotEmpty(t, m.Up)
+				assert.NotEmpty(t, m.Down)
+			}
+			assert.Equal(t, "init", migrations[0].Name)
+			assert.Contains(t, migrations[0].Up, "CREATE TABLE users")
+		})
+	}
+}
+
+func TestLoad_DialectsMatch(t *testing.T) {
+	postgres, err := Load("postgres")
+	require.NoError(t, err)
+	sqlite, err := Load("sqlite")
+	require.NoError(t, err)
+
+	require.Len(t, sqlite, len(postgres), "every dialect has every version")
+	for i := range postgres {
+		assert.Equal(t, postgres[i].Name, sqlite[i].Name)
+	}
+}
+
+func TestLoad_UnknownDialect(t *testing.T) {
+	_, err := Load("mysql")
+	assert.Error(t, err)
+}
+
+func TestMigrator_BaselineDatabase(t *testing.T) {
+	db, err := database.OpenSQLite(config.SQLiteConfig{Path: filepath.Join(t.TempDir(), "test.db"), BusyTimeout: 5 * time.Second})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	ctx := context.Background()
+
+	migrator, err := NewMigrator(db)
+	require.NoError(t, err)
+	// A database made by the original init.sql: the first migration's tables
+	// without schema_migrations.
+	require.NoError(t, db.Exec(migrator.migrations[0].Up).Error)
+
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, len(migrator.migrations)-1, "the first migration is only recorded")
+	assert.Equal(t, 2, applied[0].Version)
+	for _, column := range []string{"totp_secret", "role", "disabled", "deletion_scheduled_at"} {
+		assert.True(t, db.Migrator().HasColumn("users", column), column)
+	}
+
+	for range migrator.migrations {
+		reverted, err := migrator.Down(ctx)
+		require.NoError(t, err)
+		require.NotNil(t, reverted)
+	}
+	reverted, err := migrator.Down(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, reverted)
+	assert.False(t, db.Migrator().HasTable("users"))
+}
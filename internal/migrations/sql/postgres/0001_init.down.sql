@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS task_category;
+DROP TABLE IF EXISTS task;
+DROP TABLE IF EXISTS category;
+DROP TABLE IF EXISTS users;
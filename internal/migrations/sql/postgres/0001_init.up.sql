@@ -1,3 +1,8 @@
+-- The schema of the original db_init/init.sql. Databases created by that
+-- script are recorded as having this version applied, see Migrator.prepare,
+-- so it must stay exactly what the script created; later changes go into
+-- the following versions.
+
 CREATE EXTENSION IF NOT EXISTS "pgcrypto";
 
 CREATE TABLE users
@@ -42,4 +47,4 @@ ALTER TABLE task_category
     ADD FOREIGN KEY (category_id) REFERENCES category (id_category) ON DELETE CASCADE;
 
 ALTER TABLE task
-    ADD FOREIGN KEY (user_id) REFERENCES users (id_user) ON DELETE CASCADE;
\ No newline at end of file
+    ADD FOREIGN KEY (user_id) REFERENCES users (id_user) ON DELETE CASCADE;
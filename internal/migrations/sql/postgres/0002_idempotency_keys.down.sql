@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS task_user_id_idempotency_key_idx;
+DROP INDEX IF EXISTS category_user_id_idempotency_key_idx;
+
+ALTER TABLE task
+    DROP COLUMN idempotency_key;
+ALTER TABLE category
+    DROP COLUMN idempotency_key;
@@ -0,0 +1,10 @@
+-- Keys of the Idempotency-Key header: a retried create finds the row made by
+-- the first attempt. NULLs never collide, so rows created without a key are
+-- not affected by the unique indexes.
+ALTER TABLE task
+    ADD COLUMN idempotency_key varchar(255);
+ALTER TABLE category
+    ADD COLUMN idempotency_key varchar(255);
+
+CREATE UNIQUE INDEX task_user_id_idempotency_key_idx ON task (user_id, idempotency_key);
+CREATE UNIQUE INDEX category_user_id_idempotency_key_idx ON category (user_id, idempotency_key);
@@ -0,0 +1,18 @@
+-- Timestamps maintained by the repositories. Existing rows get the time of
+-- the migration, and done tasks count as completed then, so completed_at is
+-- set exactly when is_done is.
+ALTER TABLE users
+    ADD COLUMN created_at timestamptz NOT NULL DEFAULT now(),
+    ADD COLUMN updated_at timestamptz NOT NULL DEFAULT now();
+ALTER TABLE category
+    ADD COLUMN created_at timestamptz NOT NULL DEFAULT now(),
+    ADD COLUMN updated_at timestamptz NOT NULL DEFAULT now();
+ALTER TABLE task
+    ADD COLUMN created_at   timestamptz NOT NULL DEFAULT now(),
+    ADD COLUMN updated_at   timestamptz NOT NULL DEFAULT now(),
+    ADD COLUMN completed_at timestamptz;
+
+UPDATE task SET completed_at = now() WHERE is_done;
+
+CREATE INDEX task_user_id_created_at_idx ON task (user_id, created_at);
+CREATE INDEX task_user_id_completed_at_idx ON task (user_id, completed_at);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS task_status_id_position_idx;
+
+ALTER TABLE task
+    DROP COLUMN status_id,
+    DROP COLUMN position;
+
+DROP TABLE task_status;
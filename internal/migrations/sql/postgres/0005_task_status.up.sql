@@ -0,0 +1,21 @@
+-- The columns of the kanban board of a user, ordered by position. The last
+-- one is terminal: a task is in it exactly when it is done. Users without
+-- statuses have no board and their tasks no status.
+CREATE TABLE task_status
+(
+    id_status  UUID PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id    UUID        NOT NULL REFERENCES users (id_user) ON DELETE CASCADE,
+    name       varchar(50) NOT NULL,
+    position   integer     NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX task_status_user_id_position_idx ON task_status (user_id, position);
+
+-- position orders the tasks inside the column of their status.
+ALTER TABLE task
+    ADD COLUMN status_id UUID REFERENCES task_status (id_status) ON DELETE SET NULL,
+    ADD COLUMN position  integer NOT NULL DEFAULT 0;
+
+CREATE INDEX task_status_id_position_idx ON task (status_id, position);
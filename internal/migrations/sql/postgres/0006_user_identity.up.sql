@@ -0,0 +1,14 @@
+-- Logins through an OpenID Connect provider, one row per provider account.
+-- Versions 0006 to 0010 add what db_init/init.sql created after 0001 had
+-- been cut from it. A database made by a later version of the script is
+-- recorded at version 1 like any other, so these use IF NOT EXISTS to skip
+-- what it already has.
+CREATE TABLE IF NOT EXISTS user_identity
+(
+    id_identity UUID PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id     UUID         NOT NULL REFERENCES users (id_user) ON DELETE CASCADE,
+    issuer      varchar(256) NOT NULL,
+    subject     varchar(256) NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS user_identity_issuer_subject_idx ON user_identity (issuer, subject);
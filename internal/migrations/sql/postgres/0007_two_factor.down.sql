@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS recovery_code;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS totp_secret,
+    DROP COLUMN IF EXISTS totp_enabled;
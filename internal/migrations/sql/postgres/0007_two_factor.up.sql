@@ -0,0 +1,14 @@
+-- TOTP second factor and its single-use recovery codes.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS totp_secret  varchar(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS totp_enabled boolean     NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS recovery_code
+(
+    id_recovery_code UUID PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id          UUID        NOT NULL REFERENCES users (id_user) ON DELETE CASCADE,
+    code_hash        varchar(64) NOT NULL,
+    used             boolean     NOT NULL DEFAULT false
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS recovery_code_user_id_code_hash_idx ON recovery_code (user_id, code_hash);
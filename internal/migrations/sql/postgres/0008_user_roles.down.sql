@@ -0,0 +1,4 @@
+ALTER TABLE users
+    DROP COLUMN IF EXISTS role,
+    DROP COLUMN IF EXISTS disabled,
+    DROP COLUMN IF EXISTS password_reset_required;
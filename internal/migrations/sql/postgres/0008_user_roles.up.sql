@@ -0,0 +1,5 @@
+-- Roles and the account states an admin can set.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role                    varchar(16) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin')),
+    ADD COLUMN IF NOT EXISTS disabled                boolean     NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS password_reset_required boolean     NOT NULL DEFAULT false;
@@ -0,0 +1,14 @@
+-- Deferred account deletion and the activity included in data exports.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS deletion_scheduled_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS activity_log
+(
+    id_activity UUID PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id     UUID        NOT NULL REFERENCES users (id_user) ON DELETE CASCADE,
+    event       varchar(64) NOT NULL,
+    created_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS activity_log_user_id_created_at_idx ON activity_log (user_id, created_at);
+CREATE INDEX IF NOT EXISTS users_deletion_scheduled_at_idx ON users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
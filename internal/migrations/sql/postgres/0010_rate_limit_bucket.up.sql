@@ -0,0 +1,11 @@
+-- Token buckets of the postgres rate limiter backend. Losing them on a crash
+-- only resets the limits, so the table skips the WAL.
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_bucket
+(
+    bucket_key varchar(256) PRIMARY KEY,
+    tokens     double precision NOT NULL,
+    allowed    boolean          NOT NULL,
+    updated_at timestamptz      NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS rate_limit_bucket_updated_at_idx ON rate_limit_bucket (updated_at);
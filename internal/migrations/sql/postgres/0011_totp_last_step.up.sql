@@ -0,0 +1,3 @@
+-- The time step of the last accepted TOTP code, so every code is used once.
+ALTER TABLE users
+    ADD COLUMN totp_last_step bigint NOT NULL DEFAULT 0;
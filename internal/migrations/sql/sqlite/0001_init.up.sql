@@ -0,0 +1,38 @@
+-- SQLite has no uuid type and no gen_random_uuid(): ids are text in the
+-- canonical form and the repositories generate them before inserting.
+-- Foreign keys are only enforced with PRAGMA foreign_keys=ON, which
+-- database.OpenSQLite sets on every connection.
+
+CREATE TABLE users
+(
+    id_user       text PRIMARY KEY NOT NULL,
+    user_name     varchar(50) UNIQUE NOT NULL,
+    password_hash varchar(256)       NOT NULL
+);
+
+CREATE TABLE task
+(
+    id_task     text PRIMARY KEY NOT NULL,
+    user_id     text         NOT NULL REFERENCES users (id_user) ON DELETE CASCADE,
+    title       varchar(128) NOT NULL,
+    description varchar(1000),
+    is_done     boolean      NOT NULL DEFAULT false
+);
+
+CREATE TABLE category
+(
+    id_category text PRIMARY KEY NOT NULL,
+    user_id     text        NOT NULL REFERENCES users (id_user) ON DELETE CASCADE,
+    name        varchar(50) NOT NULL
+);
+
+CREATE TABLE task_category
+(
+    task_id     text NOT NULL REFERENCES task (id_task) ON DELETE CASCADE,
+    category_id text NOT NULL REFERENCES category (id_category) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, category_id)
+);
+
+CREATE INDEX task_user_id_idx ON task (user_id);
+CREATE INDEX category_user_id_idx ON category (user_id);
+CREATE UNIQUE INDEX category_user_id_name_idx ON category (user_id, name);
@@ -0,0 +1,4 @@
+-- Incremented by every write to a task, including changes of its category
+-- links. The API exposes it as the ETag of the task.
+ALTER TABLE task
+    ADD COLUMN version integer NOT NULL DEFAULT 1;
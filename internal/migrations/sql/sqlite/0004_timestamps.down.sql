@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS task_user_id_created_at_idx;
+DROP INDEX IF EXISTS task_user_id_completed_at_idx;
+
+ALTER TABLE task DROP COLUMN created_at;
+ALTER TABLE task DROP COLUMN updated_at;
+ALTER TABLE task DROP COLUMN completed_at;
+ALTER TABLE category DROP COLUMN created_at;
+ALTER TABLE category DROP COLUMN updated_at;
+ALTER TABLE users DROP COLUMN created_at;
+ALTER TABLE users DROP COLUMN updated_at;
@@ -0,0 +1,24 @@
+-- Timestamps maintained by the repositories, see the postgres migration.
+-- SQLite only adds columns with constant defaults, so existing rows are
+-- backfilled afterwards, in UTC and the text format GORM writes.
+ALTER TABLE users ADD COLUMN created_at datetime NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE users ADD COLUMN updated_at datetime NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE category ADD COLUMN created_at datetime NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE category ADD COLUMN updated_at datetime NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE task ADD COLUMN created_at datetime NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE task ADD COLUMN updated_at datetime NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
+ALTER TABLE task ADD COLUMN completed_at datetime;
+
+UPDATE users
+SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now');
+UPDATE category
+SET created_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'),
+    updated_at = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now');
+UPDATE task
+SET created_at   = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'),
+    updated_at   = strftime('%Y-%m-%d %H:%M:%f+00:00', 'now'),
+    completed_at = CASE WHEN is_done THEN strftime('%Y-%m-%d %H:%M:%f+00:00', 'now') END;
+
+CREATE INDEX task_user_id_created_at_idx ON task (user_id, created_at);
+CREATE INDEX task_user_id_completed_at_idx ON task (user_id, completed_at);
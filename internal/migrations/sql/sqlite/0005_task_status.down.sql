@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS task_status_id_position_idx;
+
+ALTER TABLE task DROP COLUMN status_id;
+ALTER TABLE task DROP COLUMN position;
+
+DROP TABLE task_status;
@@ -0,0 +1,17 @@
+-- The columns of the kanban board of a user, see the postgres migration.
+CREATE TABLE task_status
+(
+    id_status  text PRIMARY KEY NOT NULL,
+    user_id    text        NOT NULL REFERENCES users (id_user) ON DELETE CASCADE,
+    name       varchar(50) NOT NULL,
+    position   integer     NOT NULL,
+    created_at datetime    NOT NULL DEFAULT '1970-01-01 00:00:00+00:00',
+    updated_at datetime    NOT NULL DEFAULT '1970-01-01 00:00:00+00:00'
+);
+
+CREATE INDEX task_status_user_id_position_idx ON task_status (user_id, position);
+
+ALTER TABLE task ADD COLUMN status_id text REFERENCES task_status (id_status) ON DELETE SET NULL;
+ALTER TABLE task ADD COLUMN position integer NOT NULL DEFAULT 0;
+
+CREATE INDEX task_status_id_position_idx ON task (status_id, position);
@@ -0,0 +1,10 @@
+-- Logins through an OpenID Connect provider, one row per provider account.
+CREATE TABLE user_identity
+(
+    id_identity text PRIMARY KEY NOT NULL,
+    user_id     text         NOT NULL REFERENCES users (id_user) ON DELETE CASCADE,
+    issuer      varchar(256) NOT NULL,
+    subject     varchar(256) NOT NULL
+);
+
+CREATE UNIQUE INDEX user_identity_issuer_subject_idx ON user_identity (issuer, subject);
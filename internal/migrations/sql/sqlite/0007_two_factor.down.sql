@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS recovery_code;
+
+ALTER TABLE users DROP COLUMN totp_secret;
+ALTER TABLE users DROP COLUMN totp_enabled;
@@ -0,0 +1,13 @@
+-- TOTP second factor and its single-use recovery codes.
+ALTER TABLE users ADD COLUMN totp_secret varchar(64) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN totp_enabled boolean NOT NULL DEFAULT false;
+
+CREATE TABLE recovery_code
+(
+    id_recovery_code text PRIMARY KEY NOT NULL,
+    user_id          text        NOT NULL REFERENCES users (id_user) ON DELETE CASCADE,
+    code_hash        varchar(64) NOT NULL,
+    used             boolean     NOT NULL DEFAULT false
+);
+
+CREATE UNIQUE INDEX recovery_code_user_id_code_hash_idx ON recovery_code (user_id, code_hash);
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN role;
+ALTER TABLE users DROP COLUMN disabled;
+ALTER TABLE users DROP COLUMN password_reset_required;
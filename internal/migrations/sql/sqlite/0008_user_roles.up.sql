@@ -0,0 +1,4 @@
+-- Roles and the account states an admin can set.
+ALTER TABLE users ADD COLUMN role varchar(16) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+ALTER TABLE users ADD COLUMN disabled boolean NOT NULL DEFAULT false;
+ALTER TABLE users ADD COLUMN password_reset_required boolean NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS activity_log;
+DROP INDEX IF EXISTS users_deletion_scheduled_at_idx;
+
+ALTER TABLE users DROP COLUMN deletion_scheduled_at;
@@ -0,0 +1,13 @@
+-- Deferred account deletion and the activity included in data exports.
+ALTER TABLE users ADD COLUMN deletion_scheduled_at datetime;
+
+CREATE TABLE activity_log
+(
+    id_activity text PRIMARY KEY NOT NULL,
+    user_id     text        NOT NULL REFERENCES users (id_user) ON DELETE CASCADE,
+    event       varchar(64) NOT NULL,
+    created_at  datetime    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX activity_log_user_id_created_at_idx ON activity_log (user_id, created_at);
+CREATE INDEX users_deletion_scheduled_at_idx ON users (deletion_scheduled_at) WHERE deletion_scheduled_at IS NOT NULL;
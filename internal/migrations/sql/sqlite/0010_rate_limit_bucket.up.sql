@@ -0,0 +1,3 @@
+-- The postgres rate limiter backend needs Postgres storage, so SQLite gets
+-- no rate_limit_bucket table; the version only keeps the dialects in step.
+SELECT 1;
@@ -0,0 +1,2 @@
+-- The time step of the last accepted TOTP code, so every code is used once.
+ALTER TABLE users ADD COLUMN totp_last_step integer NOT NULL DEFAULT 0;
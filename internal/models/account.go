@@ -0,0 +1,43 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrDeletionNotScheduled = errors.New("account deletion is not scheduled")
+
+// Events recorded in the account activity log.
+const (
+	ActivitySignIn            = "sign_in"
+	ActivityPasswordChanged   = "password_changed"
+	ActivityTwoFactorEnabled  = "two_factor_enabled"
+	ActivityTwoFactorDisabled = "two_factor_disabled"
+	ActivityDataExported      = "data_exported"
+	ActivityDataImported      = "data_imported"
+	ActivityDeletionScheduled = "deletion_scheduled"
+	ActivityDeletionCancelled = "deletion_cancelled"
+)
+
+type Activity struct {
+	Event     string
+	CreatedAt time.Time
+}
+
+// AccountData is everything stored about a user, as handed out by the
+// personal data export.
+type AccountData struct {
+	User       User
+	Tasks      []TaskFullInfo
+	Categories []Category
+	// Statuses is the workflow of the user in board order, empty without one.
+	Statuses []TaskStatus
+	Activity []Activity
+}
+
+// ImportResult counts what an import added. Categories whose name the user
+// already has are reused rather than added.
+type ImportResult struct {
+	Categories int
+	Tasks      int
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -9,12 +10,17 @@ import (
 var ErrCategoryNotFound = errors.New("Category not found")
 
 type Category struct {
-	ID     uuid.UUID
-	Name   string
-	UserID uuid.UUID
+	ID        uuid.UUID
+	Name      string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 type CategoryBody struct {
 	Name   string
 	UserID uuid.UUID
+	// IdempotencyKey makes a repeated create return the first category, see
+	// TaskBody.
+	IdempotencyKey string
 }
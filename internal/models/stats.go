@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatsPeriod is the bucket size of the completion series.
+type StatsPeriod string
+
+const (
+	PeriodDay  StatsPeriod = "day"
+	PeriodWeek StatsPeriod = "week"
+)
+
+// Valid reports whether p is one of the known periods.
+func (p StatsPeriod) Valid() bool {
+	return p == PeriodDay || p == PeriodWeek
+}
+
+// Start returns the start of the period that contains t: midnight UTC, on
+// Monday for weeks.
+func (p StatsPeriod) Start(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if p == PeriodWeek {
+		day = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
+
+// Next returns the start of the period after the one starting at start.
+func (p StatsPeriod) Next(start time.Time) time.Time {
+	if p == PeriodWeek {
+		return start.AddDate(0, 0, 7)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// StatsQuery selects the completions the statistics cover: those from From,
+// inclusive, to To, exclusive. Now is the moment the streak is counted up to.
+type StatsQuery struct {
+	From   time.Time
+	To     time.Time
+	Period StatsPeriod
+	Now    time.Time
+}
+
+// Stats summarises the tasks of a user. Open, Completed and Categories
+// count every task; Completions and AverageTimeToComplete only the tasks
+// completed in the queried range.
+type Stats struct {
+	Open      int64
+	Completed int64
+	// Completions holds the periods with at least one completion, oldest
+	// first.
+	Completions []PeriodCount
+	// AverageTimeToComplete is the mean time from creation to completion,
+	// nil without completions.
+	AverageTimeToComplete *time.Duration
+	Categories            []CategoryStats
+	// Streak is the number of consecutive days with a completion, ending
+	// today, or yesterday while nothing was completed yet today.
+	Streak int
+}
+
+type PeriodCount struct {
+	Start time.Time
+	Count int64
+}
+
+type CategoryStats struct {
+	ID        uuid.UUID
+	Name      string
+	Open      int64
+	Completed int64
+}
+
+// StreakFrom counts the streak from the days with completions, given newest
+// first; it reads only as many days as the streak is long, plus one.
+func StreakFrom(now time.Time, days func() (time.Time, bool)) int {
+	expected := PeriodDay.Start(now)
+	streak := 0
+	for {
+		day, ok := days()
+		if !ok {
+			return streak
+		}
+		if streak == 0 && day.Equal(expected.AddDate(0, 0, -1)) {
+			expected = day
+		}
+		if day.After(expected) {
+			continue
+		}
+		if !day.Equal(expected) {
+			return streak
+		}
+		streak++
+		expected = expected.AddDate(0, 0, -1)
+	}
+}
@@ -1,16 +1,40 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrTaskModified fails a conditional write when the task no longer has the
+// version the client expected.
+var ErrTaskModified = errors.New("task was modified")
+
+// MaxIdempotencyKeyLength bounds the Idempotency-Key header of the create
+// endpoints.
+const MaxIdempotencyKeyLength = 255
 
 type TaskBody struct {
 	Title       string
 	Description string
+	// IdempotencyKey is only read when creating: a second create of the same
+	// user with the same key returns the first task instead of a new one.
+	IdempotencyKey string
+	// IfVersion is only read when updating: unless zero, the update fails
+	// with ErrTaskModified when the task has another version.
+	IfVersion int64
 }
 
 type TaskShortInfo struct {
-	ID     uuid.UUID
-	IsDone bool
-	Title  string
+	ID          uuid.UUID
+	IsDone      bool
+	Title       string
+	Version     int64
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt *time.Time
+	StatusID    *uuid.UUID
 }
 
 type TaskFullInfo struct {
@@ -19,4 +43,77 @@ type TaskFullInfo struct {
 	Description string
 	IsDone      bool
 	Categories  []Category
+	// Version grows with every change of the task.
+	Version   int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// CompletedAt is set while the task is done.
+	CompletedAt *time.Time
+	// StatusID is the board column of the task, nil while its user has no
+	// workflow. Position orders the tasks of the column.
+	StatusID *uuid.UUID
+	Position int
+}
+
+// TaskSort names the field a task list is ordered by.
+type TaskSort string
+
+const (
+	SortByTitle     TaskSort = "title"
+	SortByCreated   TaskSort = "created_at"
+	SortByUpdated   TaskSort = "updated_at"
+	SortByCompleted TaskSort = "completed_at"
+)
+
+// Valid reports whether s is one of the known sort fields.
+func (s TaskSort) Valid() bool {
+	switch s {
+	case SortByTitle, SortByCreated, SortByUpdated, SortByCompleted:
+		return true
+	}
+	return false
+}
+
+// TimeRange matches times from From, inclusive, up to To, exclusive. A nil
+// bound leaves that side open.
+type TimeRange struct {
+	From *time.Time
+	To   *time.Time
+}
+
+// IsZero reports whether the range matches every time.
+func (r TimeRange) IsZero() bool {
+	return r.From == nil && r.To == nil
+}
+
+// Contains reports whether t lies in the range.
+func (r TimeRange) Contains(t time.Time) bool {
+	return (r.From == nil || !t.Before(*r.From)) && (r.To == nil || t.Before(*r.To))
+}
+
+// TaskFilter narrows and orders a task list; nil fields and zero ranges
+// match every task.
+type TaskFilter struct {
+	IsDone     *bool
+	CategoryID *uuid.UUID
+	Created    TimeRange
+	Updated    TimeRange
+	// Completed only matches done tasks unless it is zero.
+	Completed TimeRange
+	// Sort defaults to the title. Tasks that are not done come last when
+	// sorting by completion, in either direction.
+	Sort       TaskSort
+	Descending bool
+}
+
+// TaskPatch is a partial update: nil fields keep their current value.
+type TaskPatch struct {
+	Title       *string
+	Description *string
+	IsDone      *bool
+	// CategoryIDs replaces the links when not nil, an empty slice removes
+	// them all.
+	CategoryIDs []uuid.UUID
+	// IfVersion makes the patch conditional, see TaskBody.IfVersion.
+	IfVersion int64
 }
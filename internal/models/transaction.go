@@ -0,0 +1,15 @@
+package models
+
+import "context"
+
+// Transactor runs units of work. Every repository call made with the context
+// passed to fn joins one transaction, committed when fn returns nil and
+// rolled back when it returns an error. A unit of work started inside another
+// one is nested in it: its failure only undoes its own changes.
+type Transactor interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	// AfterCommit runs fn once the outermost unit of work running in ctx
+	// commits, and never if it or the nested unit fn was registered in rolls
+	// back. Outside a unit of work fn runs right away.
+	AfterCommit(ctx context.Context, fn func())
+}
@@ -2,19 +2,77 @@ package models
 
 import (
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 )
 
-var ErrUserNotFound = errors.New("user not found")
+var (
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidLoginState  = errors.New("invalid or expired login state")
+	ErrExternalAuth       = errors.New("external authentication failed")
+	ErrInvalidTOTPCode    = errors.New("invalid two-factor code")
+	ErrTOTPNotEnrolled    = errors.New("two-factor authentication is not enrolled")
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrUserDisabled       = errors.New("user is disabled")
+	ErrPasswordReset      = errors.New("password reset required")
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
 
 type User struct {
-	ID       uuid.UUID
-	Name     string
-	Password string
+	ID                    uuid.UUID
+	Name                  string
+	Password              string
+	Role                  string
+	Disabled              bool
+	PasswordResetRequired bool
+	TOTPSecret            string
+	TOTPEnabled           bool
+	TOTPLastStep          int64
+	DeletionScheduledAt   *time.Time
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+}
+
+// UserSummary is the administrative view of an account.
+type UserSummary struct {
+	ID                    uuid.UUID
+	Name                  string
+	Role                  string
+	Disabled              bool
+	PasswordResetRequired bool
+	TOTPEnabled           bool
+	TaskCount             int64
+	CategoryCount         int64
 }
 
 type UserAuth struct {
 	Name     string
 	Password string
 }
+
+// ExternalIdentity identifies a user at an external OpenID Connect provider.
+type ExternalIdentity struct {
+	Issuer  string
+	Subject string
+}
+
+// SignInResult holds either the access token or, for users with two-factor
+// authentication enabled, the challenge token for the second step.
+type SignInResult struct {
+	Token          string
+	ChallengeToken string
+}
+
+func (r SignInResult) ChallengeRequired() bool {
+	return r.ChallengeToken != ""
+}
+
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
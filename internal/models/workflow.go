@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrStatusNotFound  = errors.New("status not found")
+	ErrInvalidWorkflow = errors.New("invalid workflow")
+)
+
+const (
+	// MaxStatuses bounds the number of columns of a workflow.
+	MaxStatuses = 20
+	// MaxStatusName is the length of task_status.name, in characters.
+	MaxStatusName = 50
+)
+
+// TaskStatus is a column of the kanban board of a user. The columns are
+// ordered by Position, from 0; the last one is terminal: a task is in it
+// exactly when it is done.
+type TaskStatus struct {
+	ID       uuid.UUID
+	Name     string
+	Position int
+	Terminal bool
+}
+
+// StatusBody is a column of a new workflow. A zero ID adds a column, the ID
+// of an existing one keeps it, with its tasks, under the new name.
+type StatusBody struct {
+	ID   uuid.UUID
+	Name string
+}
+
+// BoardColumn is a status with its tasks in board order.
+type BoardColumn struct {
+	TaskStatus
+	Tasks []TaskShortInfo
+}
+
+// ValidateStatuses checks a new workflow: none or 2 to MaxStatuses columns,
+// with distinct ids and distinct names of 1 to MaxStatusName characters. The
+// error wraps ErrInvalidWorkflow.
+func ValidateStatuses(statuses []StatusBody) error {
+	if len(statuses) == 1 || len(statuses) > MaxStatuses {
+		return fmt.Errorf("%w: a workflow has from 2 to %d statuses, or none", ErrInvalidWorkflow, MaxStatuses)
+	}
+
+	ids := make(map[uuid.UUID]bool, len(statuses))
+	names := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		if status.Name == "" || utf8.RuneCountInString(status.Name) > MaxStatusName {
+			return fmt.Errorf("%w: status names have from 1 to %d characters", ErrInvalidWorkflow, MaxStatusName)
+		}
+		if names[status.Name] {
+			return fmt.Errorf("%w: duplicate status name: %q", ErrInvalidWorkflow, status.Name)
+		}
+		names[status.Name] = true
+		if status.ID != uuid.Nil {
+			if ids[status.ID] {
+				return fmt.Errorf("%w: duplicate status id: %s", ErrInvalidWorkflow, status.ID)
+			}
+			ids[status.ID] = true
+		}
+	}
+	return nil
+}
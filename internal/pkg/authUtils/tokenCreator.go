@@ -16,12 +16,15 @@ import (
 type Payload struct {
 	Login string
 	ID    uuid.UUID
+	Role  string
 }
 
 type ITokenHandler interface {
 	GenerateToken(credentials models.User, key string) (string, error)
 	ValidateToken(tokenString string, key string) error
 	ParseToken(tokenString string, key string) (*Payload, error)
+	GenerateChallengeToken(credentials models.User, key string) (string, error)
+	ParseChallengeToken(tokenString string, key string) (*Payload, error)
 }
 
 var (
@@ -29,6 +32,13 @@ var (
 	ErrParsingToken = errors.New("error parsing token")
 )
 
+const (
+	purposeClaim          = "purpose"
+	purposeSecondFactor   = "second_factor"
+	challengeTokenTimeout = 5 * time.Minute
+	tokenTimeout          = 24 * time.Hour
+)
+
 type JWTTokenHandler struct {
 }
 
@@ -40,9 +50,10 @@ func (hasher JWTTokenHandler) GenerateToken(credentials models.User, key string)
 	token := jwt.NewWithClaims(
 		jwt.SigningMethodHS256,
 		jwt.MapClaims{
-			"exprires": time.Now().Add(time.Hour * 24),
-			"name":     credentials.Name,
-			"ID":       credentials.ID,
+			"exp":  time.Now().Add(tokenTimeout).Unix(),
+			"name": credentials.Name,
+			"ID":   credentials.ID,
+			"role": credentials.Role,
 		})
 	tokenString, err := token.SignedString([]byte(key))
 	if err != nil {
@@ -77,25 +88,90 @@ func (hasher JWTTokenHandler) ParseToken(tokenString string, key string) (*Paylo
 		return nil, errors.Wrap(err, "failed to parse token")
 	}
 
+	// challenge tokens only prove the password and must not grant access
+	if _, isChallenge := claims[purposeClaim]; isChallenge {
+		return nil, ErrInvalidToken
+	}
+	// the parser checks exp only when it is there; tokens issued without
+	// one would never expire
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, errors.Wrap(ErrInvalidToken, "token has no expiry")
+	}
+
 	name, ok := claims["name"].(string)
 	if !ok {
-		return nil, errors.Wrapf(err, "failed to parse the name of user")
+		return nil, errors.Wrap(ErrInvalidToken, "token has no user name")
 	}
 
-	userID, err := uuid.Parse(claims["ID"].(string))
-
+	rawID, ok := claims["ID"].(string)
+	if !ok {
+		return nil, errors.Wrapf(ErrInvalidToken, "token of user %v has no ID", name)
+	}
+	userID, err := uuid.Parse(rawID)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to parse the ID of user with name %v", claims["name"])
+		return nil, errors.Wrapf(err, "failed to parse the ID of user with name %v", name)
+	}
+
+	// tokens issued before roles existed belong to regular users
+	role, ok := claims["role"].(string)
+	if !ok || role == "" {
+		role = models.RoleUser
 	}
 
 	payload := &Payload{
 		Login: name,
 		ID:    userID,
+		Role:  role,
 	}
 
 	return payload, nil
 }
 
+// GenerateChallengeToken issues a short-lived token proving that the user
+// passed the password check and now has to provide the second factor.
+func (hasher JWTTokenHandler) GenerateChallengeToken(credentials models.User, key string) (string, error) {
+	token := jwt.NewWithClaims(
+		jwt.SigningMethodHS256,
+		jwt.MapClaims{
+			"exp":        time.Now().Add(challengeTokenTimeout).Unix(),
+			"name":       credentials.Name,
+			"ID":         credentials.ID,
+			purposeClaim: purposeSecondFactor,
+		})
+	tokenString, err := token.SignedString([]byte(key))
+	if err != nil {
+		return "", fmt.Errorf("creating challenge token err: %w", err)
+	}
+
+	return tokenString, nil
+}
+
+func (hasher JWTTokenHandler) ParseChallengeToken(tokenString string, key string) (*Payload, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(key), nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, err.Error())
+	}
+
+	if claims[purposeClaim] != purposeSecondFactor {
+		return nil, ErrInvalidToken
+	}
+
+	name, _ := claims["name"].(string)
+	rawID, _ := claims["ID"].(string)
+	userID, err := uuid.Parse(rawID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse the ID of user with name %v", name)
+	}
+
+	return &Payload{
+		Login: name,
+		ID:    userID,
+	}, nil
+}
+
 func ExtractTokenFromReq(r *http.Request) string {
 
 	token := r.Header.Get("Authorization")
@@ -0,0 +1,45 @@
+package auth_utils
+
+import (
+	"testing"
+	"time"
+	"todolist/internal/models"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKey = "test-key"
+
+func signClaims(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testKey))
+	require.NoError(t, err)
+	return token
+}
+
+func TestJWTTokenHandler_ParseToken(t *testing.T) {
+	handler := NewJWTTokenHandler()
+	user := models.User{ID: uuid.New(), Name: "alice", Role: models.RoleAdmin}
+
+	token, err := handler.GenerateToken(user, testKey)
+	require.NoError(t, err)
+	payload, err := handler.ParseToken(token, testKey)
+	require.NoError(t, err)
+	assert.Equal(t, &Payload{Login: "alice", ID: user.ID, Role: models.RoleAdmin}, payload)
+
+	exp := time.Now().Add(time.Hour).Unix()
+	for name, claims := range map[string]jwt.MapClaims{
+		"no name":         {"exp": exp, "ID": user.ID.String()},
+		"no ID":           {"exp": exp, "name": "alice"},
+		"ID not a string": {"exp": exp, "name": "alice", "ID": 42},
+	} {
+		t.Run(name, func(t *testing.T) {
+			payload, err := handler.ParseToken(signClaims(t, claims), testKey)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+			assert.Nil(t, payload)
+		})
+	}
+}
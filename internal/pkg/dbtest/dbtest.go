@@ -0,0 +1,127 @@
+// Package dbtest gives integration tests a migrated database of their own on
+// every supported SQL backend. SQLite always runs, in a temporary file;
+// Postgres is the server configured by the usual POSTGRES_* variables and is
+// skipped when POSTGRES_HOST is not set.
+package dbtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"todolist/config"
+	"todolist/internal/database"
+	"todolist/internal/migrations"
+
+	"github.com/caarlos0/env/v11"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Run calls test once per backend, each in a subtest named after it with a
+// fresh database.
+func Run(t *testing.T, test func(t *testing.T, db *gorm.DB)) {
+	t.Helper()
+	for _, backend := range []struct {
+		name string
+		open func(t testing.TB) *gorm.DB
+	}{
+		{config.StoragePostgres, Postgres},
+		{config.StorageSQLite, SQLite},
+	} {
+		t.Run(backend.name, func(t *testing.T) {
+			test(t, backend.open(t))
+		})
+	}
+}
+
+// Postgres creates an empty schema, applies the migrations to it and returns
+// a connection pool whose search_path points there. The schema is dropped
+// when the test ends, so every test starts from a clean database and nothing
+// touches the tables of the configured database itself.
+func Postgres(t testing.TB) *gorm.DB {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("integration test skipped in short mode")
+	}
+	if os.Getenv("POSTGRES_HOST") == "" {
+		t.Skip("POSTGRES_HOST is not set")
+	}
+
+	var cfg config.PostgresConfig
+	if err := env.ParseWithOptions(&cfg, env.Options{Prefix: "POSTGRES_"}); err != nil {
+		t.Fatalf("failed to read postgres config: %v", err)
+	}
+
+	schema := "test_" + randomSuffix(t)
+	db, err := gorm.Open(postgres.Open(cfg.String()+" search_path="+schema), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get connection pool: %v", err)
+	}
+
+	if err = db.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		sqlDB.Close()
+		t.Fatalf("failed to create schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() {
+		if err := db.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Errorf("failed to drop schema %s: %v", schema, err)
+		}
+		sqlDB.Close()
+	})
+
+	migrate(t, db)
+	return db
+}
+
+// SQLite opens a migrated database in a file of the test's temporary
+// directory, with the settings database.OpenSQLite uses in production.
+func SQLite(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := database.OpenSQLite(config.SQLiteConfig{
+		Path:        filepath.Join(t.TempDir(), "test.db"),
+		BusyTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	db.Logger = logger.Default.LogMode(logger.Silent)
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get connection pool: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrate(t, db)
+	return db
+}
+
+func migrate(t testing.TB, db *gorm.DB) {
+	t.Helper()
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err = migrator.Up(context.Background()); err != nil {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+}
+
+func randomSuffix(t testing.TB) string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatalf("failed to generate schema name: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,233 @@
+// Package export defines the personal data archive: a zip file with one JSON
+// document per kind of data.
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"time"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const FormatVersion = 1
+
+const (
+	ManifestFile   = "manifest.json"
+	ProfileFile    = "profile.json"
+	TasksFile      = "tasks.json"
+	CategoriesFile = "categories.json"
+	WorkflowFile   = "workflow.json"
+	ActivityFile   = "activity.json"
+)
+
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	ExportedAt    time.Time `json:"exported_at"`
+}
+
+type Profile struct {
+	ID                  uuid.UUID  `json:"id"`
+	Name                string     `json:"name"`
+	Role                string     `json:"role"`
+	TwoFactorEnabled    bool       `json:"two_factor_enabled"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Task has no completed_at while it is not done, and no status_id while its
+// user has no workflow. Archives written before the timestamps were added
+// have none of them.
+type Task struct {
+	ID          uuid.UUID   `json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	IsDone      bool        `json:"is_done"`
+	CategoryIDs []uuid.UUID `json:"category_ids"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+	StatusID    *uuid.UUID  `json:"status_id,omitempty"`
+	Position    int         `json:"position"`
+}
+
+type Category struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Status is a column of the workflow; the last one is terminal.
+type Status struct {
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	Position int       `json:"position"`
+}
+
+type Activity struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WriteArchive writes the account data as a zip archive. Credentials are
+// never part of it.
+func WriteArchive(w io.Writer, data *models.AccountData, exportedAt time.Time) error {
+	tasks := make([]Task, 0, len(data.Tasks))
+	for _, task := range data.Tasks {
+		categoryIDs := make([]uuid.UUID, 0, len(task.Categories))
+		for _, cat := range task.Categories {
+			categoryIDs = append(categoryIDs, cat.ID)
+		}
+		tasks = append(tasks, Task{
+			ID:          task.ID,
+			Title:       task.Title,
+			Description: task.Description,
+			IsDone:      task.IsDone,
+			CategoryIDs: categoryIDs,
+			CreatedAt:   task.CreatedAt,
+			UpdatedAt:   task.UpdatedAt,
+			CompletedAt: task.CompletedAt,
+			StatusID:    task.StatusID,
+			Position:    task.Position,
+		})
+	}
+
+	categories := make([]Category, 0, len(data.Categories))
+	for _, cat := range data.Categories {
+		categories = append(categories, Category{ID: cat.ID, Name: cat.Name, CreatedAt: cat.CreatedAt, UpdatedAt: cat.UpdatedAt})
+	}
+
+	statuses := make([]Status, 0, len(data.Statuses))
+	for _, status := range data.Statuses {
+		statuses = append(statuses, Status{ID: status.ID, Name: status.Name, Position: status.Position})
+	}
+
+	activity := make([]Activity, 0, len(data.Activity))
+	for _, entry := range data.Activity {
+		activity = append(activity, Activity{Event: entry.Event, CreatedAt: entry.CreatedAt})
+	}
+
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{ManifestFile, Manifest{FormatVersion: FormatVersion, ExportedAt: exportedAt}},
+		{ProfileFile, Profile{
+			ID:                  data.User.ID,
+			Name:                data.User.Name,
+			Role:                data.User.Role,
+			TwoFactorEnabled:    data.User.TOTPEnabled,
+			DeletionScheduledAt: data.User.DeletionScheduledAt,
+			CreatedAt:           data.User.CreatedAt,
+			UpdatedAt:           data.User.UpdatedAt,
+		}},
+		{TasksFile, tasks},
+		{CategoriesFile, categories},
+		{WorkflowFile, statuses},
+		{ActivityFile, activity},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: exportedAt,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "failed to add %s to archive", file.name)
+		}
+
+		encoder := json.NewEncoder(fw)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(file.content); err != nil {
+			return errors.Wrapf(err, "failed to write %s", file.name)
+		}
+	}
+
+	return errors.Wrap(zw.Close(), "failed to finish archive")
+}
+
+// ReadArchive reads the tasks, categories and workflow back from an archive
+// written by WriteArchive, for importing them into an account. Profile and
+// activity are not read, they belong to the exported account. Archives
+// written before workflows existed have no workflow file.
+func ReadArchive(r io.ReaderAt, size int64) (*models.AccountData, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open archive")
+	}
+
+	var manifest Manifest
+	if err = readFile(zr, ManifestFile, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, errors.Errorf("unsupported archive format version %d, expected %d", manifest.FormatVersion, FormatVersion)
+	}
+
+	var tasks []Task
+	if err = readFile(zr, TasksFile, &tasks); err != nil {
+		return nil, err
+	}
+	var categories []Category
+	if err = readFile(zr, CategoriesFile, &categories); err != nil {
+		return nil, err
+	}
+	var statuses []Status
+	if _, statErr := fs.Stat(zr, WorkflowFile); statErr == nil {
+		if err = readFile(zr, WorkflowFile, &statuses); err != nil {
+			return nil, err
+		}
+	}
+
+	data := &models.AccountData{
+		Tasks:      make([]models.TaskFullInfo, 0, len(tasks)),
+		Categories: make([]models.Category, 0, len(categories)),
+		Statuses:   make([]models.TaskStatus, 0, len(statuses)),
+	}
+	for _, cat := range categories {
+		data.Categories = append(data.Categories, models.Category{ID: cat.ID, Name: cat.Name, CreatedAt: cat.CreatedAt, UpdatedAt: cat.UpdatedAt})
+	}
+	for _, task := range tasks {
+		taskCategories := make([]models.Category, 0, len(task.CategoryIDs))
+		for _, id := range task.CategoryIDs {
+			taskCategories = append(taskCategories, models.Category{ID: id})
+		}
+		data.Tasks = append(data.Tasks, models.TaskFullInfo{
+			ID:          task.ID,
+			Title:       task.Title,
+			Description: task.Description,
+			IsDone:      task.IsDone,
+			Categories:  taskCategories,
+			CreatedAt:   task.CreatedAt,
+			UpdatedAt:   task.UpdatedAt,
+			CompletedAt: task.CompletedAt,
+			StatusID:    task.StatusID,
+			Position:    task.Position,
+		})
+	}
+	for _, status := range statuses {
+		data.Statuses = append(data.Statuses, models.TaskStatus{ID: status.ID, Name: status.Name, Position: status.Position})
+	}
+	return data, nil
+}
+
+func readFile(zr *zip.Reader, name string, v interface{}) error {
+	f, err := zr.Open(name)
+	if err != nil {
+		return errors.Wrapf(err, "archive has no %s", name)
+	}
+	defer f.Close()
+
+	if err = json.NewDecoder(f).Decode(v); err != nil {
+		return errors.Wrapf(err, "failed to read %s", name)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteArchive(t *testing.T) {
+	category := models.Category{ID: uuid.New(), Name: "home"}
+	data := &models.AccountData{
+		User:       models.User{ID: uuid.New(), Name: "alice", Password: "hash", TOTPSecret: "secret", Role: models.RoleUser},
+		Tasks:      []models.TaskFullInfo{{ID: uuid.New(), Title: "buy milk", Categories: []models.Category{category}}},
+		Categories: []models.Category{category},
+		Activity:   []models.Activity{{Event: models.ActivitySignIn, CreatedAt: time.Now().UTC()}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteArchive(&buf, data, time.Now().UTC()))
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	contents := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		var b bytes.Buffer
+		_, err = b.ReadFrom(rc)
+		require.NoError(t, err)
+		rc.Close()
+		contents[f.Name] = b.Bytes()
+	}
+
+	for _, name := range []string{ManifestFile, ProfileFile, TasksFile, CategoriesFile, WorkflowFile, ActivityFile} {
+		assert.Contains(t, contents, name)
+	}
+	assert.NotContains(t, string(contents[ProfileFile]), "hash")
+	assert.NotContains(t, string(contents[ProfileFile]), "secret")
+
+	var tasks []Task
+	require.NoError(t, json.Unmarshal(contents[TasksFile], &tasks))
+	require.Len(t, tasks, 1)
+	assert.Equal(t, []uuid.UUID{category.ID}, tasks[0].CategoryIDs)
+}
+
+func TestReadArchive(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	completed := created.Add(48 * time.Hour)
+	category := models.Category{ID: uuid.New(), Name: "home", CreatedAt: created, UpdatedAt: created}
+	todo := models.TaskStatus{ID: uuid.New(), Name: "todo", Position: 0}
+	done := models.TaskStatus{ID: uuid.New(), Name: "done", Position: 1, Terminal: true}
+	data := &models.AccountData{
+		User: models.User{ID: uuid.New(), Name: "alice"},
+		Tasks: []models.TaskFullInfo{{
+			ID:          uuid.New(),
+			Title:       "buy milk",
+			IsDone:      true,
+			Categories:  []models.Category{category},
+			CreatedAt:   created,
+			UpdatedAt:   completed,
+			CompletedAt: &completed,
+			StatusID:    &done.ID,
+			Position:    3,
+		}},
+		Categories: []models.Category{category},
+		Statuses:   []models.TaskStatus{todo, done},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteArchive(&buf, data, time.Now().UTC()))
+
+	read, err := ReadArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, []models.Category{{ID: category.ID, Name: "home", CreatedAt: created, UpdatedAt: created}}, read.Categories)
+	require.Len(t, read.Tasks, 1)
+	assert.Equal(t, "buy milk", read.Tasks[0].Title)
+	assert.True(t, read.Tasks[0].IsDone)
+	assert.True(t, created.Equal(read.Tasks[0].CreatedAt))
+	require.NotNil(t, read.Tasks[0].CompletedAt)
+	assert.True(t, completed.Equal(*read.Tasks[0].CompletedAt), "the completion time survives the round trip")
+	assert.Equal(t, &done.ID, read.Tasks[0].StatusID)
+	assert.Equal(t, 3, read.Tasks[0].Position)
+	assert.Equal(t, []models.TaskStatus{{ID: todo.ID, Name: "todo"}, {ID: done.ID, Name: "done", Position: 1}}, read.Statuses)
+	assert.Equal(t, []models.Category{{ID: category.ID}}, read.Tasks[0].Categories)
+
+	_, err = ReadArchive(bytes.NewReader([]byte("not a zip")), 9)
+	assert.Error(t, err)
+}
+
+func TestReadArchive_WithoutWorkflow(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		ManifestFile:   `{"format_version": 1}`,
+		TasksFile:      `[{"title": "buy milk", "is_done": true}]`,
+		CategoriesFile: `[]`,
+	} {
+		fw, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	read, err := ReadArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err, "archives from before workflows still import")
+	require.Len(t, read.Tasks, 1)
+	assert.Nil(t, read.Tasks[0].CompletedAt)
+	assert.Nil(t, read.Tasks[0].StatusID)
+	assert.Empty(t, read.Statuses)
+}
@@ -0,0 +1,100 @@
+// Package totp implements time-based one-time passwords (RFC 6238) with the
+// parameters every authenticator app supports: SHA1, 6 digits, 30 seconds.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // RFC 6238 default, required by authenticator apps
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	Digits = 6
+	Period = 30 * time.Second
+
+	secretSize = 20
+	// skew is the number of periods accepted before and after the current
+	// one to tolerate clock drift between server and device.
+	skew = 1
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random base32 encoded shared secret.
+func GenerateSecret() (string, error) {
+	buf := make([]byte, secretSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", errors.Wrap(err, "failed to generate totp secret")
+	}
+	return encoding.EncodeToString(buf), nil
+}
+
+// URI builds the otpauth:// URI authenticator apps read from QR codes.
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(int(Period.Seconds())))
+
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// Code returns the one-time password for the period containing t.
+func Code(secret string, t time.Time) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", errors.Wrap(err, "invalid totp secret")
+	}
+	return hotp(key, uint64(t.Unix()/int64(Period.Seconds()))), nil
+}
+
+// Verify reports whether code is valid for secret at time t and returns the
+// time step it belongs to. A code stays valid for several steps, so callers
+// have to reject steps at or below the last one they accepted.
+func Verify(code, secret string, t time.Time) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+
+	counter := t.Unix() / int64(Period.Seconds())
+	for i := int64(-skew); i <= skew; i++ {
+		expected := hotp(key, uint64(counter+i))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter + i, true
+		}
+	}
+	return 0, false
+}
+
+// hotp implements RFC 4226.
+func hotp(key []byte, counter uint64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", Digits, value%1000000)
+}
@@ -0,0 +1,61 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test vectors from RFC 6238 appendix B (SHA1), truncated to 6 digits.
+func TestCode_RFC6238(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := Code(secret, time.Unix(tt.unix, 0))
+		require.NoError(t, err)
+		assert.Equal(t, tt.code, code, "time %d", tt.unix)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	code, err := Code(secret, now)
+	require.NoError(t, err)
+	step := now.Unix() / int64(Period.Seconds())
+
+	accepted, ok := Verify(code, secret, now)
+	assert.True(t, ok)
+	assert.Equal(t, step, accepted)
+	accepted, ok = Verify(code, secret, now.Add(Period))
+	assert.True(t, ok, "previous period is accepted")
+	assert.Equal(t, step, accepted, "the step is the one of the code, not of the clock")
+
+	_, ok = Verify(code, secret, now.Add(3*Period))
+	assert.False(t, ok, "old code is rejected")
+	_, ok = Verify("12345", secret, now)
+	assert.False(t, ok, "short code is rejected")
+	_, ok = Verify(code, "not base32!", now)
+	assert.False(t, ok, "broken secret is rejected")
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Plan&Do", "alice", "JBSWY3DPEHPK3PXP")
+	assert.Equal(t, "otpauth://totp/Plan&Do:alice?algorithm=SHA1&digits=6&issuer=Plan%26Do&period=30&secret=JBSWY3DPEHPK3PXP", uri)
+}
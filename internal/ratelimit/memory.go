@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepEvery is how many Take calls pass between removals of idle buckets.
+const sweepEvery = 1024
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket is full again and can be dropped, since a
+	// fresh bucket behaves the same.
+	full time.Time
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.calls++
+	if s.calls%sweepEvery == 0 {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit.burst(), updated: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(limit.burst(), b.tokens+now.Sub(b.updated).Seconds()*limit.rate())
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	res := result(limit, b.tokens, allowed)
+	b.full = now.Add(res.Reset)
+	return res, nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"todolist/internal/middleware"
+	"todolist/internal/pkg/response"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	HeaderLimit      = "RateLimit-Limit"
+	HeaderRemaining  = "RateLimit-Remaining"
+	HeaderReset      = "RateLimit-Reset"
+	HeaderRetryAfter = "Retry-After"
+)
+
+// Limiter hands out middlewares that apply the named limits from config.
+type Limiter struct {
+	store  Store
+	limits map[string]Limit
+	// trustForwardedFor takes the client IP from X-Forwarded-For, only safe
+	// behind a proxy that overwrites the header.
+	trustForwardedFor bool
+}
+
+func NewLimiter(store Store, limits map[string]Limit, trustForwardedFor bool) *Limiter {
+	return &Limiter{
+		store:             store,
+		limits:            limits,
+		trustForwardedFor: trustForwardedFor,
+	}
+}
+
+// Limit applies the limit called name per authenticated user, or per client
+// IP when the route is anonymous. Routes with a limit missing from config are
+// not limited. When the store fails the request is let through: an outage of
+// the limiter should not take the API down with it.
+func (l *Limiter) Limit(name string) func(http.Handler) http.Handler {
+	limit, ok := l.limits[name]
+	if !ok {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := name + ":" + l.clientKey(r)
+
+			res, err := l.store.Take(r.Context(), key, limit)
+			if err != nil {
+				log.Ctx(r.Context()).Error().Err(err).Str("limit", name).Msg("rate limiter failed, request let through")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(HeaderLimit, strconv.Itoa(res.Limit))
+			w.Header().Set(HeaderRemaining, strconv.Itoa(res.Remaining))
+			w.Header().Set(HeaderReset, ceilSeconds(res.Reset))
+
+			if !res.Allowed {
+				log.Ctx(r.Context()).Warn().Str("limit", name).Msg("rate limit exceeded")
+				w.Header().Set(HeaderRetryAfter, ceilSeconds(res.RetryAfter))
+				render.Status(r, http.StatusTooManyRequests)
+				render.JSON(w, r, response.Error("Rate limit exceeded"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (l *Limiter) clientKey(r *http.Request) string {
+	if userID, ok := r.Context().Value(middleware.UserIDContextKey).(uuid.UUID); ok {
+		return "user:" + userID.String()
+	}
+	return "ip:" + l.clientIP(r)
+}
+
+func (l *Limiter) clientIP(r *http.Request) string {
+	if l.trustForwardedFor {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			if ip := net.ParseIP(strings.TrimSpace(first)); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
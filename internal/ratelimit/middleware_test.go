@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todolist/internal/middleware"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit) (Result, error) {
+	return Result{}, errors.New("db is down")
+}
+
+func serve(handler http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestLimiter_Limit(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	limiter := NewLimiter(NewMemoryStore(), map[string]Limit{"sign-up": {Requests: 1, Period: time.Minute}}, false)
+	handler := limiter.Limit("sign-up")(ok)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/sign-up", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+
+	w := serve(handler, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get(HeaderLimit))
+	assert.Equal(t, "0", w.Header().Get(HeaderRemaining))
+	assert.Equal(t, "60", w.Header().Get(HeaderReset))
+
+	w = serve(handler, r)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get(HeaderRetryAfter))
+
+	other := r.Clone(r.Context())
+	other.RemoteAddr = "10.0.0.2:1234"
+	assert.Equal(t, http.StatusOK, serve(handler, other).Code, "other clients keep their own bucket")
+
+	user := r.WithContext(context.WithValue(r.Context(), middleware.UserIDContextKey, uuid.New()))
+	assert.Equal(t, http.StatusOK, serve(handler, user).Code, "authenticated users are keyed by ID, not IP")
+}
+
+func TestLimiter_ForwardedFor(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	limits := map[string]Limit{"sign-in": {Requests: 1, Period: time.Minute}}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/sign-in", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	second := r.Clone(r.Context())
+	r.Header.Set("X-Forwarded-For", "203.0.113.1, 10.0.0.1")
+	second.Header.Set("X-Forwarded-For", "203.0.113.2, 10.0.0.1")
+
+	trusted := NewLimiter(NewMemoryStore(), limits, true).Limit("sign-in")(ok)
+	assert.Equal(t, http.StatusOK, serve(trusted, r).Code)
+	assert.Equal(t, http.StatusOK, serve(trusted, second).Code)
+
+	untrusted := NewLimiter(NewMemoryStore(), limits, false).Limit("sign-in")(ok)
+	assert.Equal(t, http.StatusOK, serve(untrusted, r).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(untrusted, second).Code, "a spoofed header does not get a new bucket")
+}
+
+func TestLimiter_Passthrough(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+
+	unconfigured := NewLimiter(NewMemoryStore(), nil, false).Limit("sign-in")(ok)
+	w := serve(unconfigured, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(HeaderLimit))
+
+	failing := NewLimiter(failingStore{}, map[string]Limit{"sign-in": {Requests: 1, Period: time.Minute}}, false).Limit("sign-in")(ok)
+	assert.Equal(t, http.StatusOK, serve(failing, r).Code, "a store failure lets the request through")
+}
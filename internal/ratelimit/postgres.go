@@ -0,0 +1,72 @@
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// purgeEvery is how many Take calls pass between removals of stale rows.
+const purgeEvery = 1024
+
+// purgeAfter is how long a bucket must be untouched before its row is
+// removed; every configured limit refills well within it.
+const purgeAfter = 24 * time.Hour
+
+// takeQuery refills the bucket by the time passed since the last request and
+// takes a token from it in one statement, so concurrent requests hitting
+// different replicas cannot both spend the last token.
+const takeQuery = `
+INSERT INTO rate_limit_bucket AS b (bucket_key, tokens, allowed, updated_at)
+VALUES (@key, @burst - 1, true, now())
+ON CONFLICT (bucket_key) DO UPDATE SET
+    tokens     = CASE WHEN ` + refilled + ` >= 1 THEN ` + refilled + ` - 1 ELSE ` + refilled + ` END,
+    allowed    = ` + refilled + ` >= 1,
+    updated_at = now()
+RETURNING b.tokens, b.allowed`
+
+const refilled = `LEAST(@burst, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at) * @rate)`
+
+type PostgresStore struct {
+	db    *gorm.DB
+	calls atomic.Int64
+}
+
+func NewPostgresStore(db *gorm.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	if s.calls.Add(1)%purgeEvery == 0 {
+		go s.purge(context.WithoutCancel(ctx))
+	}
+
+	var row struct {
+		Tokens  float64
+		Allowed bool
+	}
+	err := s.db.WithContext(ctx).
+		Raw(takeQuery, map[string]interface{}{
+			"key":   key,
+			"burst": limit.burst(),
+			"rate":  limit.rate(),
+		}).
+		Scan(&row).Error
+	if err != nil {
+		return Result{}, errors.Wrapf(err, "failed to take token for %s", key)
+	}
+	return result(limit, row.Tokens, row.Allowed), nil
+}
+
+func (s *PostgresStore) purge(ctx context.Context) {
+	err := s.db.WithContext(ctx).
+		Exec("DELETE FROM rate_limit_bucket WHERE updated_at < now() - make_interval(secs => ?)", purgeAfter.Seconds()).
+		Error
+	if err != nil {
+		log.Warn().Err(err).Msg("PostgresStore: failed to purge stale rate limit buckets")
+	}
+}
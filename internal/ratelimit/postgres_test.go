@@ -0,0 +1,63 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todolist/internal/pkg/dbtest"
+	"todolist/internal/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// The Postgres store keeps its buckets in an UNLOGGED table SQLite has no
+// equivalent of, so it runs against Postgres only. The test package is
+// external because dbtest reads the config, which imports ratelimit.
+func TestPostgresStore_Take(t *testing.T) {
+	db := dbtest.Postgres(t)
+	store := ratelimit.NewPostgresStore(db)
+	limit := ratelimit.Limit{Requests: 2, Period: 10 * time.Second}
+	ctx := context.Background()
+
+	res, err := store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Limit)
+	assert.Equal(t, 1, res.Remaining)
+
+	res, err = store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res, err = store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed, "burst is spent")
+	assert.Positive(t, res.RetryAfter)
+
+	res, err = store.Take(ctx, "b", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "keys have separate buckets")
+
+	// The store refills by the database clock, so the bucket is aged
+	// instead of waiting.
+	age(t, db, "a", 5*time.Second)
+	res, err = store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed, "a token is refilled after period/requests")
+	assert.Equal(t, 0, res.Remaining)
+
+	age(t, db, "a", time.Hour)
+	res, err = store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining, "the refill stops at the burst")
+}
+
+func age(t *testing.T, db *gorm.DB, key string, by time.Duration) {
+	t.Helper()
+	err := db.Exec("UPDATE rate_limit_bucket SET updated_at = updated_at - make_interval(secs => ?) WHERE bucket_key = ?", by.Seconds(), key).Error
+	require.NoError(t, err)
+}
@@ -0,0 +1,82 @@
+// Package ratelimit implements token-bucket rate limiting with an in-memory
+// store for a single instance and a Postgres store shared by all replicas.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Limit allows Requests per Period, with bursts of up to Requests.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit parses limits written as "10/1m", i.e. 10 requests per minute.
+func ParseLimit(s string) (Limit, error) {
+	count, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, errors.Errorf("invalid rate limit %q, expected requests/period like 10/1m", s)
+	}
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests < 1 {
+		return Limit{}, errors.Errorf("invalid request count in rate limit %q", s)
+	}
+	duration, err := time.ParseDuration(period)
+	if err != nil || duration <= 0 {
+		return Limit{}, errors.Errorf("invalid period in rate limit %q", s)
+	}
+	return Limit{Requests: requests, Period: duration}, nil
+}
+
+func (l Limit) burst() float64 {
+	return float64(l.Requests)
+}
+
+// rate is the refill speed in tokens per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, zero when
+	// this one was.
+	RetryAfter time.Duration
+}
+
+// Store takes a token from the bucket of the key.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// result describes a bucket holding tokens after the request was handled.
+func result(limit Limit, tokens float64, allowed bool) Result {
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     seconds((limit.burst() - tokens) / limit.rate()),
+	}
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / limit.rate())
+	}
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(s * float64(time.Second))
+}
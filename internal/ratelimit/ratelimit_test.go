@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("10/1m")
+	require.NoError(t, err)
+	assert.Equal(t, Limit{Requests: 10, Period: time.Minute}, limit)
+
+	for _, invalid := range []string{"", "10", "0/1m", "x/1m", "10/x", "10/-1s"} {
+		_, err = ParseLimit(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 2, Period: 10 * time.Second}
+	ctx := context.Background()
+
+	res, err := store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+	assert.Equal(t, 5*time.Second, res.Reset)
+
+	res, _ = store.Take(ctx, "a", limit)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	res, _ = store.Take(ctx, "a", limit)
+	assert.False(t, res.Allowed, "burst is spent")
+	assert.Equal(t, 5*time.Second, res.RetryAfter)
+
+	res, _ = store.Take(ctx, "b", limit)
+	assert.True(t, res.Allowed, "keys have separate buckets")
+
+	now = now.Add(5 * time.Second)
+	res, _ = store.Take(ctx, "a", limit)
+	assert.True(t, res.Allowed, "a token is refilled after period/requests")
+}
+
+func TestMemoryStore_SweepsFullBuckets(t *testing.T) {
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 1, Period: time.Second}
+
+	_, _ = store.Take(context.Background(), "idle", limit)
+	now = now.Add(time.Minute)
+	for i := 0; i < sweepEvery; i++ {
+		_, _ = store.Take(context.Background(), "busy", limit)
+	}
+
+	assert.NotContains(t, store.buckets, "idle")
+	assert.Contains(t, store.buckets, "busy")
+}
@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type ActivityLog struct {
+	ID        uuid.UUID `gorm:"primaryKey;column:id_activity;type:uuid"`
+	UserID    uuid.UUID `gorm:"column:user_id;type:uuid;not null"`
+	Event     string    `gorm:"column:event;type:varchar(64);not null"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+}
+
+func (ActivityLog) TableName() string {
+	return "activity_log"
+}
+
+func (a *ActivityLog) BeforeCreate(*gorm.DB) error {
+	assignID(&a.ID)
+	return nil
+}
+
+func (repo *UserRepositoryAdapter) RecordActivity(ctx context.Context, userID uuid.UUID, event string) error {
+	tx := conn(ctx, repo.db).Create(&ActivityLog{UserID: userID, Event: event})
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "error recording activity")
+	}
+
+	return nil
+}
+
+type AccountRepositoryAdapter struct {
+	db *gorm.DB
+}
+
+func NewAccountRepositoryAdapter(srcDB *gorm.DB) *AccountRepositoryAdapter {
+	return &AccountRepositoryAdapter{
+		db: srcDB,
+	}
+}
+
+// GetAccountData loads the user together with everything they own.
+func (repo *AccountRepositoryAdapter) GetAccountData(ctx context.Context, userID uuid.UUID) (*models.AccountData, error) {
+	var userDA User
+	tx := conn(ctx, repo.db).First(&userDA, "id_user = ?", userID)
+	if tx.Error != nil {
+		if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			return nil, models.ErrUserNotFound
+		}
+		return nil, errors.Wrap(tx.Error, "error getting user by ID")
+	}
+
+	var tasks []Task
+	err := conn(ctx, repo.db).
+		Preload("Categories").
+		Where("user_id = ?", userID).
+		Order("title ASC").
+		Find(&tasks).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting tasks")
+	}
+
+	var categories []Category
+	err = conn(ctx, repo.db).
+		Where("user_id = ?", userID).
+		Order("name ASC").
+		Find(&categories).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting categories")
+	}
+
+	statuses, err := statusesOf(conn(ctx, repo.db), userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting workflow")
+	}
+
+	var activity []ActivityLog
+	err = conn(ctx, repo.db).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&activity).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting activity log")
+	}
+
+	data := &models.AccountData{
+		User:       FromDaUser(userDA),
+		Tasks:      make([]models.TaskFullInfo, 0, len(tasks)),
+		Categories: make([]models.Category, 0, len(categories)),
+		Statuses:   toStatusModels(statuses),
+		Activity:   make([]models.Activity, 0, len(activity)),
+	}
+	for _, task := range tasks {
+		taskCategories := make([]models.Category, 0, len(task.Categories))
+		for _, cat := range task.Categories {
+			taskCategories = append(taskCategories, models.Category{ID: cat.ID, Name: cat.Name, UserID: cat.UserID})
+		}
+		data.Tasks = append(data.Tasks, models.TaskFullInfo{
+			ID:          task.ID,
+			Title:       task.Title,
+			Description: task.Description,
+			IsDone:      task.IsDone,
+			Categories:  taskCategories,
+			CreatedAt:   task.CreatedAt,
+			UpdatedAt:   task.UpdatedAt,
+			CompletedAt: task.CompletedAt,
+			StatusID:    task.StatusID,
+			Position:    task.Position,
+		})
+	}
+	for _, cat := range categories {
+		data.Categories = append(data.Categories, *toCategoryModel(cat))
+	}
+	for _, entry := range activity {
+		data.Activity = append(data.Activity, models.Activity{Event: entry.Event, CreatedAt: entry.CreatedAt})
+	}
+
+	return data, nil
+}
+
+// ScheduleDeletion stores the time in UTC: SQLite compares timestamps as
+// text, which only orders them right within one zone.
+func (repo *AccountRepositoryAdapter) ScheduleDeletion(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	tx := conn(ctx, repo.db).
+		Model(&User{}).
+		Where("id_user = ?", userID).
+		Update("deletion_scheduled_at", at.UTC())
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "error scheduling user deletion")
+	}
+
+	if tx.RowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (repo *AccountRepositoryAdapter) CancelDeletion(ctx context.Context, userID uuid.UUID) error {
+	tx := conn(ctx, repo.db).
+		Model(&User{}).
+		Where("id_user = ? AND deletion_scheduled_at IS NOT NULL", userID).
+		Update("deletion_scheduled_at", nil)
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "error cancelling user deletion")
+	}
+
+	if tx.RowsAffected == 0 {
+		return models.ErrDeletionNotScheduled
+	}
+
+	return nil
+}
+
+// ListDueDeletions returns the users whose grace period ended before now.
+func (repo *AccountRepositoryAdapter) ListDueDeletions(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := conn(ctx, repo.db).
+		Model(&User{}).
+		Where("deletion_scheduled_at <= ?", now.UTC()).
+		Pluck("id_user", &ids).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing scheduled deletions")
+	}
+
+	return ids, nil
+}
+
+// DeleteIfDue deletes the user when the deletion is still scheduled at or
+// before now and reports whether it did. The check and the delete are one
+// statement, so a deletion cancelled after ListDueDeletions is respected.
+func (repo *AccountRepositoryAdapter) DeleteIfDue(ctx context.Context, userID uuid.UUID, now time.Time) (bool, error) {
+	tx := conn(ctx, repo.db).
+		Where("id_user = ? AND deletion_scheduled_at IS NOT NULL AND deletion_scheduled_at <= ?", userID, now.UTC()).
+		Delete(&User{})
+	if tx.Error != nil {
+		return false, errors.Wrap(tx.Error, "error deleting user")
+	}
+
+	return tx.RowsAffected > 0, nil
+}
+
+// ImportData adds the tasks and categories of an export to the user in one
+// transaction. Categories are matched by name, so importing into an account
+// that already has a category of the same name links the tasks to it. The
+// archived workflow is restored, with the tasks in their columns, only for a
+// user without one, see importWorkflow.
+func (repo *AccountRepositoryAdapter) ImportData(ctx context.Context, userID uuid.UUID, data *models.AccountData) (*models.ImportResult, error) {
+	result := &models.ImportResult{}
+	err := conn(ctx, repo.db).Transaction(func(tx *gorm.DB) error {
+		var existing []Category
+		if err := tx.Where("user_id = ?", userID).Find(&existing).Error; err != nil {
+			return errors.Wrap(err, "error getting categories")
+		}
+		byName := make(map[string]Category, len(existing))
+		for _, cat := range existing {
+			byName[cat.Name] = cat
+		}
+
+		// Archive category IDs point to the imported or reused categories.
+		byArchiveID := make(map[uuid.UUID]Category, len(data.Categories))
+		for _, archived := range data.Categories {
+			cat, ok := byName[archived.Name]
+			if !ok {
+				cat = Category{UserID: userID, Name: archived.Name}
+				if err := tx.Create(&cat).Error; err != nil {
+					return errors.Wrapf(err, "error creating category %s", archived.Name)
+				}
+				byName[cat.Name] = cat
+				result.Categories++
+			}
+			byArchiveID[archived.ID] = cat
+		}
+
+		byArchiveStatus, err := importWorkflow(tx, userID, data.Statuses)
+		if err != nil {
+			return err
+		}
+
+		for _, archived := range data.Tasks {
+			// Times are stored in UTC, like NowFunc returns them. A zero
+			// CreatedAt, from an archive without it, means now.
+			task := Task{
+				UserID:      userID,
+				Title:       archived.Title,
+				Description: archived.Description,
+				IsDone:      archived.IsDone,
+				CreatedAt:   archived.CreatedAt.UTC(),
+			}
+			if task.IsDone {
+				// Older archives do not say when, so the task counts as
+				// completed now.
+				completedAt := tx.NowFunc()
+				if archived.CompletedAt != nil {
+					completedAt = archived.CompletedAt.UTC()
+				}
+				task.CompletedAt = &completedAt
+			}
+			for _, archivedCat := range archived.Categories {
+				cat, ok := byArchiveID[archivedCat.ID]
+				if !ok {
+					return errors.Errorf("task %s refers to unknown category %s", archived.Title, archivedCat.ID)
+				}
+				task.Categories = append(task.Categories, cat)
+			}
+			if archived.StatusID != nil {
+				if statusID, ok := byArchiveStatus[*archived.StatusID]; ok {
+					task.StatusID = &statusID
+					task.Position = archived.Position
+				}
+			}
+			if err := tx.Omit("Categories.*").Create(&task).Error; err != nil {
+				return errors.Wrapf(err, "error creating task %s", archived.Title)
+			}
+			result.Tasks++
+		}
+		// The other imported tasks join the board of the user, if there is
+		// one.
+		if err := syncBoard(tx, userID); err != nil {
+			return errors.Wrap(err, "error placing tasks on the board")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// importWorkflow recreates the archived workflow for a user without one and
+// maps the archived status IDs to the new ones. A user who already has a
+// workflow keeps it; the archived one is ignored and the imported tasks are
+// placed by their flag.
+func importWorkflow(tx *gorm.DB, userID uuid.UUID, archived []models.TaskStatus) (map[uuid.UUID]uuid.UUID, error) {
+	byArchiveID := make(map[uuid.UUID]uuid.UUID, len(archived))
+	if len(archived) == 0 {
+		return byArchiveID, nil
+	}
+	existing, err := statusesOf(tx, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting workflow")
+	}
+	if len(existing) > 0 {
+		return byArchiveID, nil
+	}
+
+	ordered := append([]models.TaskStatus(nil), archived...)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Position < ordered[j].Position })
+	for i, status := range ordered {
+		created := TaskStatus{UserID: userID, Name: status.Name, Position: i}
+		if err = tx.Create(&created).Error; err != nil {
+			return nil, errors.Wrapf(err, "error creating status %s", status.Name)
+		}
+		byArchiveID[status.ID] = created.ID
+	}
+	return byArchiveID, nil
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todolist/internal/models"
+	"todolist/internal/pkg/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestAccountRepositoryAdapter_GetAccountData(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewAccountRepositoryAdapter(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		work := createCategory(t, db, userID, "work")
+		createCategory(t, db, userID, "home")
+		createTask(t, db, userID, "report", work)
+		createTask(t, db, userID, "groceries")
+		require.NoError(t, NewUserRepositoryAdapter(db).RecordActivity(ctx, userID, models.ActivitySignIn))
+		createTask(t, db, createUser(t, db, "bob"), "bob's task")
+
+		data, err := repo.GetAccountData(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, "alice", data.User.Name)
+		require.Len(t, data.Tasks, 2)
+		assert.Equal(t, "groceries", data.Tasks[0].Title)
+		assert.Equal(t, "report", data.Tasks[1].Title)
+		assert.Equal(t, []models.Category{{ID: work, Name: "work", UserID: userID}}, data.Tasks[1].Categories)
+		assert.False(t, data.Tasks[1].CreatedAt.IsZero())
+		assert.False(t, data.Tasks[1].UpdatedAt.IsZero())
+		require.Len(t, data.Categories, 2)
+		assert.Equal(t, "home", data.Categories[0].Name)
+		assert.False(t, data.Categories[0].CreatedAt.IsZero())
+		assert.False(t, data.User.CreatedAt.IsZero(), "the profile has its timestamps")
+		require.Len(t, data.Activity, 1)
+		assert.Equal(t, models.ActivitySignIn, data.Activity[0].Event)
+
+		_, err = repo.GetAccountData(ctx, uuid.New())
+		assert.ErrorIs(t, err, models.ErrUserNotFound)
+	})
+}
+
+func TestAccountRepositoryAdapter_Deletion(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewAccountRepositoryAdapter(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		bob := createUser(t, db, "bob")
+		now := time.Now()
+
+		assert.ErrorIs(t, repo.CancelDeletion(ctx, alice), models.ErrDeletionNotScheduled)
+
+		require.NoError(t, repo.ScheduleDeletion(ctx, alice, now.Add(-time.Hour)))
+		require.NoError(t, repo.ScheduleDeletion(ctx, bob, now.Add(time.Hour)))
+
+		due, err := repo.ListDueDeletions(ctx, now)
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{alice}, due)
+
+		require.NoError(t, repo.CancelDeletion(ctx, alice))
+		due, err = repo.ListDueDeletions(ctx, now.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, []uuid.UUID{bob}, due)
+
+		// A listed account whose deletion was cancelled or moved is kept.
+		isDeleted, err := repo.DeleteIfDue(ctx, alice, now)
+		require.NoError(t, err)
+		assert.False(t, isDeleted, "cancelled")
+		isDeleted, err = repo.DeleteIfDue(ctx, bob, now)
+		require.NoError(t, err)
+		assert.False(t, isDeleted, "not due yet")
+		isDeleted, err = repo.DeleteIfDue(ctx, bob, now.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.True(t, isDeleted)
+		assert.Zero(t, count(t, db, "users", "id_user = ?", bob))
+		isDeleted, err = repo.DeleteIfDue(ctx, bob, now.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.False(t, isDeleted, "already gone")
+
+		assert.ErrorIs(t, repo.ScheduleDeletion(ctx, uuid.New(), now), models.ErrUserNotFound)
+	})
+}
+
+func TestAccountRepositoryAdapter_ImportData(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewAccountRepositoryAdapter(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		existing := createCategory(t, db, userID, "work")
+
+		archivedWork := models.Category{ID: uuid.New(), Name: "work"}
+		archivedHome := models.Category{ID: uuid.New(), Name: "home"}
+		created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+		completed := created.Add(48 * time.Hour)
+		data := &models.AccountData{
+			Categories: []models.Category{archivedWork, archivedHome},
+			Tasks: []models.TaskFullInfo{
+				{Title: "report", IsDone: true, Categories: []models.Category{archivedWork, archivedHome}, CreatedAt: created, CompletedAt: &completed},
+				{Title: "groceries", IsDone: true},
+			},
+		}
+
+		result, err := repo.ImportData(ctx, userID, data)
+		require.NoError(t, err)
+		assert.Equal(t, &models.ImportResult{Categories: 1, Tasks: 2}, result)
+
+		account, err := repo.GetAccountData(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, account.Tasks, 2)
+		report := account.Tasks[1]
+		assert.True(t, report.IsDone)
+		assert.True(t, created.Equal(report.CreatedAt), "the archived creation time is kept")
+		require.NotNil(t, report.CompletedAt)
+		assert.True(t, completed.Equal(*report.CompletedAt), "the archived completion time is kept")
+		groceries := account.Tasks[0]
+		require.NotNil(t, groceries.CompletedAt)
+		assert.WithinDuration(t, time.Now(), *groceries.CompletedAt, time.Minute, "without a time in the archive it is now")
+		require.Len(t, report.Categories, 2)
+		names := map[string]uuid.UUID{}
+		for _, cat := range report.Categories {
+			names[cat.Name] = cat.ID
+		}
+		assert.Equal(t, existing, names["work"], "categories are matched by name")
+
+		// A broken archive leaves nothing behind.
+		broken := &models.AccountData{
+			Tasks: []models.TaskFullInfo{
+				{Title: "first"},
+				{Title: "second", Categories: []models.Category{{ID: uuid.New(), Name: "missing"}}},
+			},
+		}
+		_, err = repo.ImportData(ctx, userID, broken)
+		assert.Error(t, err)
+		assert.Zero(t, count(t, db, "task", "user_id = ? AND title = ?", userID, "first"))
+	})
+}
+
+func TestAccountRepositoryAdapter_ImportWorkflow(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewAccountRepositoryAdapter(db)
+		workflow := NewWorkflowRepositoryAdapter(db)
+		tasks := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		first := createTask(t, db, alice, "first")
+		createTask(t, db, alice, "second")
+		statuses, err := workflow.SetStatuses(ctx, alice, []models.StatusBody{{Name: "todo"}, {Name: "doing"}, {Name: "done"}})
+		require.NoError(t, err)
+		_, _, err = tasks.Move(ctx, first, statuses[1].ID, 0, 0)
+		require.NoError(t, err)
+
+		data, err := repo.GetAccountData(ctx, alice)
+		require.NoError(t, err)
+		require.Len(t, data.Statuses, 3)
+		assert.Equal(t, "doing", data.Statuses[1].Name)
+
+		// columnsOf returns the column names with the task titles of a board.
+		columnsOf := func(userID uuid.UUID) map[string][]string {
+			t.Helper()
+			board, err := workflow.GetBoard(ctx, userID)
+			require.NoError(t, err)
+			result := make(map[string][]string, len(board))
+			for _, column := range board {
+				result[column.Name] = titles(column.Tasks)
+			}
+			return result
+		}
+
+		bob := createUser(t, db, "bob")
+		_, err = repo.ImportData(ctx, bob, data)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{"todo": {"second"}, "doing": {"first"}, "done": {}}, columnsOf(bob), "the board is restored")
+
+		carol := createUser(t, db, "carol")
+		_, err = workflow.SetStatuses(ctx, carol, []models.StatusBody{{Name: "open"}, {Name: "closed"}})
+		require.NoError(t, err)
+		_, err = repo.ImportData(ctx, carol, data)
+		require.NoError(t, err)
+		assert.Equal(t, map[string][]string{"open": {"first", "second"}, "closed": {}}, columnsOf(carol), "an existing board is kept")
+	})
+}
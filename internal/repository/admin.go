@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type AdminRepositoryAdapter struct {
+	db *gorm.DB
+}
+
+func NewAdminRepositoryAdapter(srcDB *gorm.DB) *AdminRepositoryAdapter {
+	return &AdminRepositoryAdapter{
+		db: srcDB,
+	}
+}
+
+type userSummary struct {
+	ID                    uuid.UUID `gorm:"column:id_user"`
+	Name                  string    `gorm:"column:user_name"`
+	Role                  string    `gorm:"column:role"`
+	Disabled              bool      `gorm:"column:disabled"`
+	PasswordResetRequired bool      `gorm:"column:password_reset_required"`
+	TOTPEnabled           bool      `gorm:"column:totp_enabled"`
+	TaskCount             int64     `gorm:"column:task_count"`
+	CategoryCount         int64     `gorm:"column:category_count"`
+}
+
+func (s userSummary) toModel() models.UserSummary {
+	return models.UserSummary{
+		ID:                    s.ID,
+		Name:                  s.Name,
+		Role:                  s.Role,
+		Disabled:              s.Disabled,
+		PasswordResetRequired: s.PasswordResetRequired,
+		TOTPEnabled:           s.TOTPEnabled,
+		TaskCount:             s.TaskCount,
+		CategoryCount:         s.CategoryCount,
+	}
+}
+
+func (repo *AdminRepositoryAdapter) summaries(ctx context.Context) *gorm.DB {
+	return conn(ctx, repo.db).
+		Table("users AS u").
+		Select(`u.id_user, u.user_name, u.role, u.disabled, u.password_reset_required, u.totp_enabled,
+			(SELECT COUNT(*) FROM task t WHERE t.user_id = u.id_user) AS task_count,
+			(SELECT COUNT(*) FROM category c WHERE c.user_id = u.id_user) AS category_count`)
+}
+
+func (repo *AdminRepositoryAdapter) ListUsers(ctx context.Context, search string, pageIndex, recordsPerPage int) ([]models.UserSummary, error) {
+	var rows []userSummary
+	offset := (pageIndex - 1) * recordsPerPage
+
+	query := repo.summaries(ctx)
+	if search != "" {
+		query = query.Where(`LOWER(u.user_name) LIKE ? ESCAPE '\'`, "%"+escapeLike(strings.ToLower(search))+"%")
+	}
+
+	err := query.
+		Order("u.user_name ASC").
+		Limit(recordsPerPage).
+		Offset(offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing users")
+	}
+
+	result := make([]models.UserSummary, len(rows))
+	for i, row := range rows {
+		result[i] = row.toModel()
+	}
+	return result, nil
+}
+
+// likeEscaper makes the wildcards of a search term match literally in a LIKE
+// pattern with ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func escapeLike(term string) string {
+	return likeEscaper.Replace(term)
+}
+
+func (repo *AdminRepositoryAdapter) GetUserSummary(ctx context.Context, userID uuid.UUID) (*models.UserSummary, error) {
+	var rows []userSummary
+
+	err := repo.summaries(ctx).
+		Where("u.id_user = ?", userID).
+		Limit(1).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting user summary")
+	}
+
+	if len(rows) == 0 {
+		return nil, models.ErrUserNotFound
+	}
+
+	summary := rows[0].toModel()
+	return &summary, nil
+}
+
+func (repo *AdminRepositoryAdapter) SetUserDisabled(ctx context.Context, userID uuid.UUID, disabled bool) error {
+	return repo.updateUser(ctx, userID, "disabled", disabled)
+}
+
+func (repo *AdminRepositoryAdapter) RequirePasswordReset(ctx context.Context, userID uuid.UUID) error {
+	return repo.updateUser(ctx, userID, "password_reset_required", true)
+}
+
+func (repo *AdminRepositoryAdapter) SetUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	return repo.updateUser(ctx, userID, "role", role)
+}
+
+func (repo *AdminRepositoryAdapter) updateUser(ctx context.Context, userID uuid.UUID, column string, value interface{}) error {
+	tx := conn(ctx, repo.db).
+		Model(&User{}).
+		Where("id_user = ?", userID).
+		Update(column, value)
+	if tx.Error != nil {
+		return errors.Wrapf(tx.Error, "error updating user %s", column)
+	}
+
+	if tx.RowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
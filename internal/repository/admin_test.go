@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"todolist/internal/models"
+	"todolist/internal/pkg/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestAdminRepositoryAdapter_ListUsers(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewAdminRepositoryAdapter(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "Alice")
+		createUser(t, db, "bob")
+		createUser(t, db, "carol")
+		createCategory(t, db, alice, "work")
+		createTask(t, db, alice, "report")
+		createTask(t, db, alice, "groceries")
+
+		names := func(users []models.UserSummary) []string {
+			result := make([]string, len(users))
+			for i, user := range users {
+				result[i] = user.Name
+			}
+			return result
+		}
+
+		tests := []struct {
+			name           string
+			search         string
+			pageIndex      int
+			recordsPerPage int
+			expected       []string
+		}{
+			{name: "first page", pageIndex: 1, recordsPerPage: 2, expected: []string{"Alice", "bob"}},
+			{name: "last page", pageIndex: 2, recordsPerPage: 2, expected: []string{"carol"}},
+			{name: "past the end", pageIndex: 3, recordsPerPage: 2, expected: []string{}},
+			{name: "case-insensitive search", search: "ALI", pageIndex: 1, recordsPerPage: 10, expected: []string{"Alice"}},
+			{name: "percent is literal", search: "%", pageIndex: 1, recordsPerPage: 10, expected: []string{}},
+			{name: "underscore is literal", search: "b_b", pageIndex: 1, recordsPerPage: 10, expected: []string{}},
+			{name: "search without match", search: "dave", pageIndex: 1, recordsPerPage: 10, expected: []string{}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				users, err := repo.ListUsers(ctx, tt.search, tt.pageIndex, tt.recordsPerPage)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, names(users))
+			})
+		}
+
+		summary, err := repo.GetUserSummary(ctx, alice)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, summary.TaskCount)
+		assert.EqualValues(t, 1, summary.CategoryCount)
+
+		_, err = repo.GetUserSummary(ctx, uuid.New())
+		assert.ErrorIs(t, err, models.ErrUserNotFound)
+	})
+}
+
+func TestAdminRepositoryAdapter_UpdateUser(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewAdminRepositoryAdapter(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		require.NoError(t, repo.SetUserDisabled(ctx, userID, true))
+		require.NoError(t, repo.RequirePasswordReset(ctx, userID))
+		require.NoError(t, repo.SetUserRole(ctx, userID, models.RoleAdmin))
+
+		summary, err := repo.GetUserSummary(ctx, userID)
+		require.NoError(t, err)
+		assert.True(t, summary.Disabled)
+		assert.True(t, summary.PasswordResetRequired)
+		assert.Equal(t, models.RoleAdmin, summary.Role)
+
+		assert.Error(t, repo.SetUserRole(ctx, userID, "root"), "the schema only allows known roles")
+		assert.ErrorIs(t, repo.SetUserDisabled(ctx, uuid.New(), true), models.ErrUserNotFound)
+	})
+}
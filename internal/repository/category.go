@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"time"
+	"todolist/internal/database"
 	"todolist/internal/models"
 
 	"github.com/google/uuid"
@@ -19,39 +21,102 @@ func NewCategoryRepositoryAdapter(srcDB *gorm.DB) *CategoryRepositoryAdapter {
 }
 
 type Category struct {
-	ID     uuid.UUID `gorm:"column:id_category;type:uuid;default:gen_random_uuid();primaryKey"`
+	ID     uuid.UUID `gorm:"column:id_category;type:uuid;primaryKey"`
 	UserID uuid.UUID `gorm:"column:user_id;type:uuid;not null"`
 	Name   string    `gorm:"column:name;type:varchar(50);not null"`
+	// IdempotencyKey is NULL for categories created without a key.
+	IdempotencyKey *string   `gorm:"column:idempotency_key"`
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"column:updated_at;autoUpdateTime"`
 }
 
 func (Category) TableName() string {
 	return "category"
 }
 
-func (c *CategoryRepositoryAdapter) CreateCategory(ctx context.Context, body *models.CategoryBody) error {
+func (c *Category) BeforeCreate(*gorm.DB) error {
+	assignID(&c.ID)
+	return nil
+}
+
+// CreateCategory returns the new category. With an idempotency key that the
+// user already created a category with, it returns that category instead.
+func (c *CategoryRepositoryAdapter) CreateCategory(ctx context.Context, body *models.CategoryBody) (*models.Category, error) {
+	if existing, err := c.findByIdempotencyKey(ctx, body.UserID, body.IdempotencyKey); existing != nil || err != nil {
+		return existing, err
+	}
+
 	category := Category{
-		Name:   body.Name,
-		UserID: body.UserID,
+		Name:           body.Name,
+		UserID:         body.UserID,
+		IdempotencyKey: idempotencyKey(body.IdempotencyKey),
 	}
-	result := c.db.WithContext(ctx).Create(&category)
-	if result.Error != nil {
-		return result.Error
+	// The savepoint keeps an ambient Postgres transaction usable after a
+	// unique violation, for the lookup below.
+	err := conn(ctx, c.db).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&category).Error
+	})
+	if err != nil {
+		// A concurrent request with the same key may have created it first.
+		if existing, findErr := c.findByIdempotencyKey(ctx, body.UserID, body.IdempotencyKey); existing != nil && findErr == nil {
+			return existing, nil
+		}
+		return nil, err
 	}
 
-	return nil
+	return toCategoryModel(category), nil
 }
 
-func (c *CategoryRepositoryAdapter) Delete(ctx context.Context, id uuid.UUID) error {
-	result := c.db.WithContext(ctx).Where("id_category = ?", id).Delete(&Category{})
-	if result.Error != nil {
-		return result.Error
+func toCategoryModel(category Category) *models.Category {
+	return &models.Category{
+		ID:        category.ID,
+		Name:      category.Name,
+		UserID:    category.UserID,
+		CreatedAt: category.CreatedAt,
+		UpdatedAt: category.UpdatedAt,
 	}
+}
 
-	if result.RowsAffected == 0 {
-		return models.ErrCategoryNotFound
+// findByIdempotencyKey returns nil without an error when no category of the
+// user has the key.
+func (c *CategoryRepositoryAdapter) findByIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) (*models.Category, error) {
+	if key == "" {
+		return nil, nil
+	}
+	var categories []Category
+	err := conn(ctx, c.db).
+		Where("user_id = ? AND idempotency_key = ?", userID, key).
+		Limit(1).
+		Find(&categories).Error
+	if err != nil || len(categories) == 0 {
+		return nil, err
 	}
+	return toCategoryModel(categories[0]), nil
+}
 
-	return nil
+// Delete removes the category of the user; the category of another user is
+// not found. It also counts as a change of the tasks that lose the category,
+// so their versions are incremented.
+func (c *CategoryRepositoryAdapter) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	return conn(ctx, c.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Task{}).
+			Where("id_task IN (?)", tx.Model(&taskCategory{}).Select("task_id").Where("category_id = ?", id)).
+			Update("version", gorm.Expr("version + 1")).Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Where("id_category = ? AND user_id = ?", id, userID).Delete(&Category{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return models.ErrCategoryNotFound
+		}
+
+		return nil
+	})
 }
 
 func (c *CategoryRepositoryAdapter) GetAll(ctx context.Context, pageIndex, recordsPerPage int, userID uuid.UUID) ([]models.Category, error) {
@@ -60,7 +125,8 @@ func (c *CategoryRepositoryAdapter) GetAll(ctx context.Context, pageIndex, recor
 
 	offset := (pageIndex - 1) * recordsPerPage
 
-	result := c.db.WithContext(ctx).
+	result := conn(ctx, c.db).
+		Clauses(database.ReadReplica()).
 		Where("user_id = ?", userID).
 		Offset(offset).
 		Limit(recordsPerPage).
@@ -72,8 +138,10 @@ func (c *CategoryRepositoryAdapter) GetAll(ctx context.Context, pageIndex, recor
 
 	for _, cat := range categories {
 		modelCategories = append(modelCategories, models.Category{
-			ID:   cat.ID,
-			Name: cat.Name,
+			ID:        cat.ID,
+			Name:      cat.Name,
+			CreatedAt: cat.CreatedAt,
+			UpdatedAt: cat.UpdatedAt,
 		})
 	}
 
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"todolist/internal/models"
+	"todolist/internal/pkg/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestCategoryRepositoryAdapter_CreateUniquePerUser(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewCategoryRepositoryAdapter(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		bob := createUser(t, db, "bob")
+
+		created, err := repo.CreateCategory(ctx, &models.CategoryBody{Name: "work", UserID: alice})
+		require.NoError(t, err)
+		assert.Equal(t, "work", created.Name)
+		assert.NotEqual(t, uuid.Nil, created.ID)
+
+		_, err = repo.CreateCategory(ctx, &models.CategoryBody{Name: "work", UserID: alice})
+		assert.Error(t, err, "names are unique per user")
+		_, err = repo.CreateCategory(ctx, &models.CategoryBody{Name: "work", UserID: bob})
+		assert.NoError(t, err)
+		_, err = repo.CreateCategory(ctx, &models.CategoryBody{Name: "work", UserID: uuid.New()})
+		assert.Error(t, err, "the user must exist")
+	})
+}
+
+func TestCategoryRepositoryAdapter_CreateIdempotent(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewCategoryRepositoryAdapter(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		bob := createUser(t, db, "bob")
+
+		first, err := repo.CreateCategory(ctx, &models.CategoryBody{Name: "work", UserID: alice, IdempotencyKey: "k1"})
+		require.NoError(t, err)
+		again, err := repo.CreateCategory(ctx, &models.CategoryBody{Name: "work", UserID: alice, IdempotencyKey: "k1"})
+		require.NoError(t, err)
+		assert.Equal(t, first, again, "a replayed key returns the original category")
+		assert.EqualValues(t, 1, count(t, db, "category", "user_id = ?", alice))
+
+		other, err := repo.CreateCategory(ctx, &models.CategoryBody{Name: "work", UserID: bob, IdempotencyKey: "k1"})
+		require.NoError(t, err)
+		assert.NotEqual(t, first.ID, other.ID, "keys are scoped to the user")
+
+		_, err = repo.CreateCategory(ctx, &models.CategoryBody{Name: "home", UserID: alice})
+		require.NoError(t, err)
+		_, err = repo.CreateCategory(ctx, &models.CategoryBody{Name: "misc", UserID: alice})
+		require.NoError(t, err, "requests without a key never collide")
+	})
+}
+
+func TestCategoryRepositoryAdapter_GetAllPagination(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewCategoryRepositoryAdapter(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		bob := createUser(t, db, "bob")
+		for i := 0; i < 5; i++ {
+			createCategory(t, db, alice, fmt.Sprintf("category %d", i))
+		}
+		createCategory(t, db, bob, "bob's category")
+
+		tests := []struct {
+			name           string
+			pageIndex      int
+			recordsPerPage int
+			expectedCount  int
+		}{
+			{name: "full page", pageIndex: 1, recordsPerPage: 2, expectedCount: 2},
+			{name: "partial last page", pageIndex: 3, recordsPerPage: 2, expectedCount: 1},
+			{name: "past the end", pageIndex: 4, recordsPerPage: 2, expectedCount: 0},
+			{name: "page larger than the list", pageIndex: 1, recordsPerPage: 100, expectedCount: 5},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				categories, err := repo.GetAll(ctx, tt.pageIndex, tt.recordsPerPage, alice)
+				require.NoError(t, err)
+				assert.Len(t, categories, tt.expectedCount)
+				for _, category := range categories {
+					assert.NotEqual(t, "bob's category", category.Name)
+				}
+			})
+		}
+
+		// Pages never overlap, so walking them yields every category once.
+		seen := make(map[uuid.UUID]bool)
+		for page := 1; page <= 3; page++ {
+			categories, err := repo.GetAll(ctx, page, 2, alice)
+			require.NoError(t, err)
+			for _, category := range categories {
+				assert.False(t, seen[category.ID], "category %s on two pages", category.Name)
+				seen[category.ID] = true
+			}
+		}
+		assert.Len(t, seen, 5)
+	})
+}
+
+func TestCategoryRepositoryAdapter_DeleteKeepsTasks(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewCategoryRepositoryAdapter(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		work := createCategory(t, db, userID, "work")
+		taskID := createTask(t, db, userID, "report", work)
+
+		bob := createUser(t, db, "bob")
+		assert.ErrorIs(t, repo.Delete(ctx, work, bob), models.ErrCategoryNotFound, "the category of another user")
+		assert.EqualValues(t, 1, count(t, db, "category", "id_category = ?", work))
+
+		require.NoError(t, repo.Delete(ctx, work, userID))
+		assert.Zero(t, count(t, db, "category", "id_category = ?", work))
+		assert.Zero(t, count(t, db, "task_category", "category_id = ?", work))
+		assert.EqualValues(t, 1, count(t, db, "task", "id_task = ?", taskID), "the task stays")
+
+		assert.ErrorIs(t, repo.Delete(ctx, work, userID), models.ErrCategoryNotFound)
+	})
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type UserIdentity struct {
+	ID      uuid.UUID `gorm:"primaryKey;column:id_identity;type:uuid"`
+	UserID  uuid.UUID `gorm:"column:user_id;type:uuid;not null"`
+	Issuer  string    `gorm:"column:issuer;not null"`
+	Subject string    `gorm:"column:subject;not null"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identity"
+}
+
+func (i *UserIdentity) BeforeCreate(*gorm.DB) error {
+	assignID(&i.ID)
+	return nil
+}
+
+type IdentityRepositoryAdapter struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepositoryAdapter(srcDB *gorm.DB) *IdentityRepositoryAdapter {
+	return &IdentityRepositoryAdapter{
+		db: srcDB,
+	}
+}
+
+func (repo *IdentityRepositoryAdapter) GetUserByIdentity(ctx context.Context, identity models.ExternalIdentity) (*models.User, error) {
+	var userDA User
+
+	tx := conn(ctx, repo.db).
+		Joins("JOIN user_identity ON user_identity.user_id = users.id_user").
+		Where("user_identity.issuer = ? AND user_identity.subject = ?", identity.Issuer, identity.Subject).
+		First(&userDA)
+	if tx.Error != nil {
+		if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			return nil, models.ErrUserNotFound
+		}
+		return nil, errors.Wrap(tx.Error, "error getting user by external identity")
+	}
+
+	user := FromDaUser(userDA)
+	return &user, nil
+}
+
+func (repo *IdentityRepositoryAdapter) CreateUserWithIdentity(ctx context.Context, user *models.UserAuth, identity models.ExternalIdentity) (*models.User, error) {
+	userDA := ToDaUser(*user)
+
+	err := conn(ctx, repo.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&userDA).Error; err != nil {
+			return errors.Wrap(err, "error creating user")
+		}
+
+		identityDA := UserIdentity{
+			UserID:  userDA.ID,
+			Issuer:  identity.Issuer,
+			Subject: identity.Subject,
+		}
+		if err := tx.Create(&identityDA).Error; err != nil {
+			return errors.Wrap(err, "error linking external identity")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created := FromDaUser(userDA)
+	return &created, nil
+}
@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type AccountRepository struct {
+	store *Store
+}
+
+func NewAccountRepository(store *Store) *AccountRepository {
+	return &AccountRepository{store: store}
+}
+
+// GetAccountData loads the user together with everything they own.
+func (repo *AccountRepository) GetAccountData(ctx context.Context, userID uuid.UUID) (*models.AccountData, error) {
+	defer repo.store.rlock(ctx)()
+
+	user, ok := repo.store.users[userID]
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+
+	data := &models.AccountData{User: *copyUser(user)}
+	for _, t := range repo.store.userTasks(userID) {
+		data.Tasks = append(data.Tasks, models.TaskFullInfo{
+			ID:          t.id,
+			Title:       t.title,
+			Description: t.description,
+			IsDone:      t.isDone,
+			Categories:  repo.store.taskCategories(t),
+			CreatedAt:   t.createdAt,
+			UpdatedAt:   t.updatedAt,
+			CompletedAt: copyTime(t.completedAt),
+			StatusID:    t.status(),
+			Position:    t.position,
+		})
+	}
+
+	categories := repo.store.userCategories(userID)
+	sort.Slice(categories, func(i, j int) bool { return categories[i].name < categories[j].name })
+	for _, c := range categories {
+		data.Categories = append(data.Categories, c.info())
+	}
+
+	data.Statuses = statusInfos(repo.store.userStatuses(userID))
+	for _, entry := range repo.store.activity {
+		if entry.userID == userID {
+			data.Activity = append(data.Activity, entry.Activity)
+		}
+	}
+	return data, nil
+}
+
+func (repo *AccountRepository) ScheduleDeletion(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	defer repo.store.lock(ctx)()
+
+	user, ok := repo.store.users[userID]
+	if !ok {
+		return models.ErrUserNotFound
+	}
+	user.DeletionScheduledAt = &at
+	user.UpdatedAt = repo.store.now()
+	return nil
+}
+
+func (repo *AccountRepository) CancelDeletion(ctx context.Context, userID uuid.UUID) error {
+	defer repo.store.lock(ctx)()
+
+	user, ok := repo.store.users[userID]
+	if !ok || user.DeletionScheduledAt == nil {
+		return models.ErrDeletionNotScheduled
+	}
+	user.DeletionScheduledAt = nil
+	user.UpdatedAt = repo.store.now()
+	return nil
+}
+
+// ListDueDeletions returns the users whose grace period ended before now.
+func (repo *AccountRepository) ListDueDeletions(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	defer repo.store.rlock(ctx)()
+
+	var ids []uuid.UUID
+	for _, user := range repo.store.users {
+		if user.DeletionScheduledAt != nil && !user.DeletionScheduledAt.After(now) {
+			ids = append(ids, user.ID)
+		}
+	}
+	return ids, nil
+}
+
+// DeleteIfDue deletes the user when the deletion is still scheduled at or
+// before now and reports whether it did.
+func (repo *AccountRepository) DeleteIfDue(ctx context.Context, userID uuid.UUID, now time.Time) (bool, error) {
+	defer repo.store.lock(ctx)()
+
+	user, ok := repo.store.users[userID]
+	if !ok || user.DeletionScheduledAt == nil || user.DeletionScheduledAt.After(now) {
+		return false, nil
+	}
+	repo.store.deleteUser(userID)
+	return true, nil
+}
+
+// ImportData adds the tasks and categories of an export to the user.
+// Categories are matched by name, and the archived workflow is restored only
+// for a user without one. The archive is checked before anything is added,
+// so a broken one leaves the account as it was.
+func (repo *AccountRepository) ImportData(ctx context.Context, userID uuid.UUID, data *models.AccountData) (*models.ImportResult, error) {
+	defer repo.store.lock(ctx)()
+
+	if err := repo.store.userExists(userID); err != nil {
+		return nil, err
+	}
+
+	archived := make(map[uuid.UUID]string, len(data.Categories))
+	for _, c := range data.Categories {
+		archived[c.ID] = c.Name
+	}
+	for _, t := range data.Tasks {
+		for _, c := range t.Categories {
+			if _, ok := archived[c.ID]; !ok {
+				return nil, errors.Errorf("task %s refers to unknown category %s", t.Title, c.ID)
+			}
+		}
+	}
+
+	result := &models.ImportResult{}
+	byName := make(map[string]uuid.UUID)
+	for _, c := range repo.store.userCategories(userID) {
+		byName[c.name] = c.id
+	}
+	byArchiveID := make(map[uuid.UUID]uuid.UUID, len(data.Categories))
+	for _, c := range data.Categories {
+		id, ok := byName[c.Name]
+		if !ok {
+			created, err := repo.store.addCategory(userID, c.Name)
+			if err != nil {
+				return nil, err
+			}
+			id = created.id
+			byName[c.Name] = id
+			result.Categories++
+		}
+		byArchiveID[c.ID] = id
+	}
+
+	now := repo.store.now()
+	byArchiveStatus := make(map[uuid.UUID]uuid.UUID, len(data.Statuses))
+	if len(repo.store.userStatuses(userID)) == 0 {
+		ordered := append([]models.TaskStatus(nil), data.Statuses...)
+		sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Position < ordered[j].Position })
+		for i, archivedStatus := range ordered {
+			st := &status{id: uuid.New(), userID: userID, name: archivedStatus.Name, position: i, createdAt: now, updatedAt: now}
+			repo.store.statuses[st.id] = st
+			byArchiveStatus[archivedStatus.ID] = st.id
+		}
+	}
+	for _, archivedTask := range data.Tasks {
+		t := &task{
+			id:          uuid.New(),
+			userID:      userID,
+			title:       archivedTask.Title,
+			description: archivedTask.Description,
+			version:     1,
+			createdAt:   now,
+			updatedAt:   now,
+		}
+		if !archivedTask.CreatedAt.IsZero() {
+			t.createdAt = archivedTask.CreatedAt
+		}
+		// Older archives do not say when, so the task counts as completed now.
+		completedAt := now
+		if archivedTask.CompletedAt != nil {
+			completedAt = *archivedTask.CompletedAt
+		}
+		t.setDone(archivedTask.IsDone, completedAt)
+		for _, c := range archivedTask.Categories {
+			if id := byArchiveID[c.ID]; !contains(t.categories, id) {
+				t.categories = append(t.categories, id)
+			}
+		}
+		if archivedTask.StatusID != nil {
+			if id, ok := byArchiveStatus[*archivedTask.StatusID]; ok {
+				t.statusID = id
+				t.position = archivedTask.Position
+			}
+		}
+		repo.store.tasks[t.id] = t
+		result.Tasks++
+	}
+	repo.store.syncBoard(userID)
+	return result, nil
+}
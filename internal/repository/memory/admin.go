@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type AdminRepository struct {
+	store *Store
+}
+
+func NewAdminRepository(store *Store) *AdminRepository {
+	return &AdminRepository{store: store}
+}
+
+func (repo *AdminRepository) ListUsers(ctx context.Context, search string, pageIndex, recordsPerPage int) ([]models.UserSummary, error) {
+	defer repo.store.rlock(ctx)()
+
+	search = strings.ToLower(search)
+	var users []*models.User
+	for _, user := range repo.store.users {
+		if strings.Contains(strings.ToLower(user.Name), search) {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Name < users[j].Name })
+
+	start, end := page(len(users), pageIndex, recordsPerPage)
+	result := make([]models.UserSummary, 0, end-start)
+	for _, user := range users[start:end] {
+		result = append(result, repo.summary(user))
+	}
+	return result, nil
+}
+
+func (repo *AdminRepository) GetUserSummary(ctx context.Context, userID uuid.UUID) (*models.UserSummary, error) {
+	defer repo.store.rlock(ctx)()
+
+	user, ok := repo.store.users[userID]
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+	summary := repo.summary(user)
+	return &summary, nil
+}
+
+func (repo *AdminRepository) SetUserDisabled(ctx context.Context, userID uuid.UUID, disabled bool) error {
+	return repo.update(ctx, userID, func(user *models.User) { user.Disabled = disabled })
+}
+
+func (repo *AdminRepository) RequirePasswordReset(ctx context.Context, userID uuid.UUID) error {
+	return repo.update(ctx, userID, func(user *models.User) { user.PasswordResetRequired = true })
+}
+
+// SetUserRole accepts the roles the schema allows.
+func (repo *AdminRepository) SetUserRole(ctx context.Context, userID uuid.UUID, role string) error {
+	if role != models.RoleUser && role != models.RoleAdmin {
+		return errors.Errorf("error updating user role: unknown role %q", role)
+	}
+	return repo.update(ctx, userID, func(user *models.User) { user.Role = role })
+}
+
+func (repo *AdminRepository) update(ctx context.Context, userID uuid.UUID, change func(user *models.User)) error {
+	defer repo.store.lock(ctx)()
+
+	user, ok := repo.store.users[userID]
+	if !ok {
+		return models.ErrUserNotFound
+	}
+	change(user)
+	user.UpdatedAt = repo.store.now()
+	return nil
+}
+
+func (repo *AdminRepository) summary(user *models.User) models.UserSummary {
+	summary := models.UserSummary{
+		ID:                    user.ID,
+		Name:                  user.Name,
+		Role:                  user.Role,
+		Disabled:              user.Disabled,
+		PasswordResetRequired: user.PasswordResetRequired,
+		TOTPEnabled:           user.TOTPEnabled,
+	}
+	for _, t := range repo.store.tasks {
+		if t.userID == user.ID {
+			summary.TaskCount++
+		}
+	}
+	for _, c := range repo.store.categories {
+		if c.userID == user.ID {
+			summary.CategoryCount++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type CategoryRepository struct {
+	store *Store
+}
+
+func NewCategoryRepository(store *Store) *CategoryRepository {
+	return &CategoryRepository{store: store}
+}
+
+// CreateCategory refuses a second category of the same name for a user and
+// returns the existing category for a known idempotency key.
+func (c *CategoryRepository) CreateCategory(ctx context.Context, body *models.CategoryBody) (*models.Category, error) {
+	defer c.store.lock(ctx)()
+
+	if err := c.store.userExists(body.UserID); err != nil {
+		return nil, err
+	}
+	if body.IdempotencyKey != "" {
+		for _, existing := range c.store.categories {
+			if existing.userID == body.UserID && existing.idempotencyKey == body.IdempotencyKey {
+				info := existing.info()
+				return &info, nil
+			}
+		}
+	}
+	created, err := c.store.addCategory(body.UserID, body.Name)
+	if err != nil {
+		return nil, err
+	}
+	created.idempotencyKey = body.IdempotencyKey
+	info := created.info()
+	return &info, nil
+}
+
+func (c *CategoryRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	defer c.store.lock(ctx)()
+
+	if cat, ok := c.store.categories[id]; !ok || cat.userID != userID {
+		return models.ErrCategoryNotFound
+	}
+	c.store.deleteCategory(id)
+	return nil
+}
+
+func (c *CategoryRepository) GetAll(ctx context.Context, pageIndex, recordsPerPage int, userID uuid.UUID) ([]models.Category, error) {
+	defer c.store.rlock(ctx)()
+
+	categories := c.store.userCategories(userID)
+	start, end := page(len(categories), pageIndex, recordsPerPage)
+
+	var result []models.Category
+	for _, cat := range categories[start:end] {
+		result = append(result, models.Category{ID: cat.id, Name: cat.name, CreatedAt: cat.createdAt, UpdatedAt: cat.updatedAt})
+	}
+	return result, nil
+}
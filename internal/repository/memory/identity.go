@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"todolist/internal/models"
+
+	"github.com/pkg/errors"
+)
+
+type IdentityRepository struct {
+	store *Store
+}
+
+func NewIdentityRepository(store *Store) *IdentityRepository {
+	return &IdentityRepository{store: store}
+}
+
+func (repo *IdentityRepository) GetUserByIdentity(ctx context.Context, ext models.ExternalIdentity) (*models.User, error) {
+	defer repo.store.rlock(ctx)()
+
+	for _, linked := range repo.store.identities {
+		if linked.ExternalIdentity == ext {
+			return copyUser(repo.store.users[linked.userID]), nil
+		}
+	}
+	return nil, models.ErrUserNotFound
+}
+
+func (repo *IdentityRepository) CreateUserWithIdentity(ctx context.Context, user *models.UserAuth, ext models.ExternalIdentity) (*models.User, error) {
+	defer repo.store.lock(ctx)()
+
+	for _, linked := range repo.store.identities {
+		if linked.ExternalIdentity == ext {
+			return nil, errors.New("error linking external identity: identity is already linked")
+		}
+	}
+	created, err := repo.store.createUser(*user)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating user")
+	}
+	repo.store.identities = append(repo.store.identities, identity{userID: created.ID, ExternalIdentity: ext})
+	return copyUser(created), nil
+}
@@ -0,0 +1,698 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// repositories is the set of repositories the tests use, all on one store.
+type repositories struct {
+	Tasks      *TaskRepository
+	Categories *CategoryRepository
+	Users      *UserRepository
+	Admin      *AdminRepository
+	Accounts   *AccountRepository
+	Workflow   *WorkflowRepository
+	Transactor *Transactor
+}
+
+func newRepositories() *repositories {
+	store := NewStore()
+	return &repositories{
+		Tasks:      NewTaskRepository(store),
+		Categories: NewCategoryRepository(store),
+		Users:      NewUserRepository(store),
+		Admin:      NewAdminRepository(store),
+		Accounts:   NewAccountRepository(store),
+		Workflow:   NewWorkflowRepository(store),
+		Transactor: NewTransactor(store),
+	}
+}
+
+func newUser(t *testing.T, repos *repositories, name string) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	require.NoError(t, repos.Users.CreateUser(ctx, &models.UserAuth{Name: name, Password: "hash"}))
+	user, err := repos.Users.GetUserByName(ctx, name)
+	require.NoError(t, err)
+	return user.ID
+}
+
+func newCategory(t *testing.T, repos *repositories, userID uuid.UUID, name string) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	category, err := repos.Categories.CreateCategory(ctx, &models.CategoryBody{Name: name, UserID: userID})
+	require.NoError(t, err)
+	return category.ID
+}
+
+func newTask(t *testing.T, repos *repositories, userID uuid.UUID, title string, categoryIDs ...uuid.UUID) uuid.UUID {
+	t.Helper()
+	ctx := context.Background()
+	task, _, err := repos.Tasks.CreateTask(ctx, userID, &models.TaskBody{Title: title}, categoryIDs)
+	require.NoError(t, err)
+	return task.ID
+}
+
+func TestTaskRepository_GetAllPagination(t *testing.T) {
+	repos := newRepositories()
+	alice := newUser(t, repos, "alice")
+	for _, title := range []string{"e", "c", "a", "d", "b"} {
+		newTask(t, repos, alice, title)
+	}
+	newTask(t, repos, newUser(t, repos, "bob"), "bob's task")
+
+	tests := []struct {
+		name           string
+		pageIndex      int
+		recordsPerPage int
+		expected       []string
+	}{
+		{name: "first page", pageIndex: 1, recordsPerPage: 2, expected: []string{"a", "b"}},
+		{name: "partial last page", pageIndex: 3, recordsPerPage: 2, expected: []string{"e"}},
+		{name: "past the end", pageIndex: 4, recordsPerPage: 2, expected: []string{}},
+		{name: "zero page size", pageIndex: 1, recordsPerPage: 0, expected: []string{}},
+		{name: "page index below one", pageIndex: 0, recordsPerPage: 2, expected: []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := repos.Tasks.GetAll(context.Background(), alice, tt.pageIndex, tt.recordsPerPage)
+			require.NoError(t, err)
+			titles := make([]string, len(tasks))
+			for i, task := range tasks {
+				titles[i] = task.Title
+			}
+			assert.Equal(t, tt.expected, titles)
+		})
+	}
+}
+
+func TestTaskRepository_UpdateAndToggle(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+	work := newCategory(t, repos, alice, "work")
+	home := newCategory(t, repos, alice, "home")
+	_, _, err := repos.Tasks.CreateTask(ctx, alice, &models.TaskBody{Title: "report"}, []uuid.UUID{work, uuid.New()})
+	assert.ErrorIs(t, err, models.ErrCategoryNotFound)
+	taskID := newTask(t, repos, alice, "report", work, work)
+
+	task, err := repos.Tasks.GetByID(ctx, taskID)
+	require.NoError(t, err)
+	require.Len(t, task.Categories, 1, "duplicate IDs are linked once")
+	assert.Equal(t, work, task.Categories[0].ID)
+	assert.Equal(t, "work", task.Categories[0].Name)
+
+	require.NoError(t, repos.Tasks.Update(ctx, taskID, &models.TaskBody{Title: "renamed"}, nil))
+	isDone, err := repos.Tasks.ToggleDone(ctx, taskID)
+	require.NoError(t, err)
+	assert.True(t, isDone)
+	task, err = repos.Tasks.GetByID(ctx, taskID)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", task.Title)
+	assert.True(t, task.IsDone)
+	assert.Len(t, task.Categories, 1, "nil categories keep the links")
+
+	require.NoError(t, repos.Tasks.Update(ctx, taskID, &models.TaskBody{Title: "renamed"}, []uuid.UUID{home}))
+	task, err = repos.Tasks.GetByID(ctx, taskID)
+	require.NoError(t, err)
+	assert.Equal(t, home, task.Categories[0].ID)
+
+	assert.ErrorIs(t, repos.Tasks.Update(ctx, taskID, &models.TaskBody{Title: "unknown"}, []uuid.UUID{uuid.New()}), models.ErrCategoryNotFound)
+	task, err = repos.Tasks.GetByID(ctx, taskID)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", task.Title, "a failed update changes nothing")
+
+	assert.ErrorIs(t, repos.Tasks.Update(ctx, uuid.New(), &models.TaskBody{}, nil), gorm.ErrRecordNotFound)
+	_, err = repos.Tasks.ToggleDone(ctx, uuid.New())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	_, _, err = repos.Tasks.CreateTask(ctx, uuid.New(), &models.TaskBody{Title: "orphan"}, nil)
+	assert.Error(t, err, "the user must exist")
+}
+
+func TestTaskRepository_ListAndPatch(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+	work := newCategory(t, repos, alice, "work")
+	newTask(t, repos, alice, "a", work)
+	b := newTask(t, repos, alice, "b")
+
+	done := true
+	task, _, err := repos.Tasks.Patch(ctx, b, &models.TaskPatch{IsDone: &done, CategoryIDs: []uuid.UUID{work}})
+	require.NoError(t, err)
+	assert.True(t, task.IsDone)
+	assert.Equal(t, "b", task.Title, "absent fields keep their value")
+	assert.Len(t, task.Categories, 1)
+
+	tasks, err := repos.Tasks.List(ctx, alice, models.TaskFilter{IsDone: &done, CategoryID: &work}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, b, tasks[0].ID)
+	tasks, err = repos.Tasks.List(ctx, alice, models.TaskFilter{CategoryID: &work}, 1, 10)
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	title := "renamed"
+	_, _, err = repos.Tasks.Patch(ctx, b, &models.TaskPatch{Title: &title, CategoryIDs: []uuid.UUID{uuid.New()}})
+	assert.ErrorIs(t, err, models.ErrCategoryNotFound)
+	task, err = repos.Tasks.GetByID(ctx, b)
+	require.NoError(t, err)
+	assert.Equal(t, "b", task.Title, "a failed patch changes nothing")
+	_, _, err = repos.Tasks.Patch(ctx, uuid.New(), &models.TaskPatch{})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestTaskRepository_Versions(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+	work := newCategory(t, repos, alice, "work")
+	taskID := newTask(t, repos, alice, "report", work)
+
+	require.NoError(t, repos.Tasks.Update(ctx, taskID, &models.TaskBody{Title: "renamed", IfVersion: 1}, nil))
+	assert.ErrorIs(t, repos.Tasks.Update(ctx, taskID, &models.TaskBody{Title: "stale", IfVersion: 1}, nil), models.ErrTaskModified)
+	_, err := repos.Tasks.ToggleDone(ctx, taskID)
+	require.NoError(t, err)
+	title := "patched"
+	_, _, err = repos.Tasks.Patch(ctx, taskID, &models.TaskPatch{Title: &title, IfVersion: 2})
+	assert.ErrorIs(t, err, models.ErrTaskModified)
+	require.NoError(t, repos.Categories.Delete(ctx, work, alice))
+
+	task, err := repos.Tasks.GetByID(ctx, taskID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, task.Version, "update, toggle and the lost category")
+	assert.ErrorIs(t, repos.Tasks.Delete(ctx, taskID, 3), models.ErrTaskModified)
+	require.NoError(t, repos.Tasks.Delete(ctx, taskID, 4))
+	_, err = repos.Tasks.GetByID(ctx, taskID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestTaskRepository_Timestamps(t *testing.T) {
+	store := NewStore()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+	repos := &repositories{
+		Tasks:      NewTaskRepository(store),
+		Categories: NewCategoryRepository(store),
+		Users:      NewUserRepository(store),
+	}
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+	work := newCategory(t, repos, alice, "work")
+	a := newTask(t, repos, alice, "a", work)
+	now = now.Add(time.Hour)
+	bCreated := now
+	b := newTask(t, repos, alice, "b")
+
+	task, err := repos.Tasks.GetByID(ctx, a)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-time.Hour), task.CreatedAt)
+	assert.Equal(t, task.CreatedAt, task.UpdatedAt)
+	assert.Equal(t, task.CreatedAt, task.Categories[0].CreatedAt)
+	assert.Nil(t, task.CompletedAt)
+
+	now = now.Add(time.Hour)
+	_, err = repos.Tasks.ToggleDone(ctx, a)
+	require.NoError(t, err)
+	task, err = repos.Tasks.GetByID(ctx, a)
+	require.NoError(t, err)
+	assert.Equal(t, now, task.UpdatedAt)
+	require.NotNil(t, task.CompletedAt)
+	assert.Equal(t, now, *task.CompletedAt)
+
+	now = now.Add(time.Hour)
+	done := true
+	task, _, err = repos.Tasks.Patch(ctx, a, &models.TaskPatch{IsDone: &done})
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-time.Hour), *task.CompletedAt, "a done task keeps its completion time")
+	_, _, err = repos.Tasks.Patch(ctx, b, &models.TaskPatch{IsDone: &done})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		filter   models.TaskFilter
+		expected []uuid.UUID
+	}{
+		{name: "created descending", filter: models.TaskFilter{Sort: models.SortByCreated, Descending: true}, expected: []uuid.UUID{b, a}},
+		{name: "completed descending", filter: models.TaskFilter{Sort: models.SortByCompleted, Descending: true}, expected: []uuid.UUID{b, a}},
+		{name: "completed from", filter: models.TaskFilter{Completed: models.TimeRange{From: &now}}, expected: []uuid.UUID{b}},
+		{name: "created to", filter: models.TaskFilter{Created: models.TimeRange{To: &bCreated}}, expected: []uuid.UUID{a}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := repos.Tasks.List(ctx, alice, tt.filter, 1, 10)
+			require.NoError(t, err)
+			ids := make([]uuid.UUID, len(tasks))
+			for i, task := range tasks {
+				ids[i] = task.ID
+			}
+			assert.Equal(t, tt.expected, ids)
+		})
+	}
+
+	_, err = repos.Tasks.ToggleDone(ctx, a)
+	require.NoError(t, err)
+	task, err = repos.Tasks.GetByID(ctx, a)
+	require.NoError(t, err)
+	assert.Nil(t, task.CompletedAt, "undoing the task clears completed_at")
+}
+
+func TestCreateIdempotent(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+	bob := newUser(t, repos, "bob")
+
+	first, _, err := repos.Tasks.CreateTask(ctx, alice, &models.TaskBody{Title: "report", IdempotencyKey: "k1"}, nil)
+	require.NoError(t, err)
+	again, _, err := repos.Tasks.CreateTask(ctx, alice, &models.TaskBody{Title: "changed", IdempotencyKey: "k1"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, first, again, "a replayed key returns the original task")
+	other, _, err := repos.Tasks.CreateTask(ctx, bob, &models.TaskBody{Title: "report", IdempotencyKey: "k1"}, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, other.ID, "keys are scoped to the user")
+
+	category, err := repos.Categories.CreateCategory(ctx, &models.CategoryBody{Name: "work", UserID: alice, IdempotencyKey: "k1"})
+	require.NoError(t, err)
+	replayed, err := repos.Categories.CreateCategory(ctx, &models.CategoryBody{Name: "work", UserID: alice, IdempotencyKey: "k1"})
+	require.NoError(t, err)
+	assert.Equal(t, category, replayed)
+
+	summary, err := repos.Admin.GetUserSummary(ctx, alice)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, summary.TaskCount)
+	assert.EqualValues(t, 1, summary.CategoryCount)
+}
+
+func TestStatsRepository(t *testing.T) {
+	store := NewStore()
+	var now time.Time
+	store.now = func() time.Time { return now }
+	repos := &repositories{
+		Tasks:      NewTaskRepository(store),
+		Categories: NewCategoryRepository(store),
+		Users:      NewUserRepository(store),
+	}
+	stats := NewStatsRepository(store)
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+	work := newCategory(t, repos, alice, "work")
+	home := newCategory(t, repos, alice, "home")
+
+	day := func(d, hour int) time.Time { return time.Date(2024, 3, d, hour, 0, 0, 0, time.UTC) }
+	task := func(created, completed time.Time, categoryIDs ...uuid.UUID) {
+		now = created
+		id := newTask(t, repos, alice, "task", categoryIDs...)
+		if !completed.IsZero() {
+			now = completed
+			_, err := repos.Tasks.ToggleDone(ctx, id)
+			require.NoError(t, err)
+		}
+	}
+	task(day(1, 10), day(6, 10), work)
+	task(day(4, 10), day(5, 10), work, home)
+	task(day(2, 10), day(3, 9))
+	task(day(2, 10), time.Time{}, work)
+
+	result, err := stats.GetStats(ctx, alice, models.StatsQuery{From: day(1, 0), To: day(7, 0), Period: models.PeriodDay, Now: day(6, 15)})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, result.Open)
+	assert.EqualValues(t, 3, result.Completed)
+	assert.Equal(t, []models.PeriodCount{{Start: day(3, 0), Count: 1}, {Start: day(5, 0), Count: 1}, {Start: day(6, 0), Count: 1}}, result.Completions)
+	require.NotNil(t, result.AverageTimeToComplete)
+	assert.Equal(t, (120+24+23)*time.Hour/3, *result.AverageTimeToComplete)
+	assert.Equal(t, []models.CategoryStats{
+		{ID: home, Name: "home", Completed: 1},
+		{ID: work, Name: "work", Open: 1, Completed: 2},
+	}, result.Categories)
+	assert.Equal(t, 2, result.Streak)
+
+	result, err = stats.GetStats(ctx, alice, models.StatsQuery{From: time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC), To: day(11, 0), Period: models.PeriodWeek, Now: day(9, 0)})
+	require.NoError(t, err)
+	assert.Equal(t, []models.PeriodCount{{Start: time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC), Count: 1}, {Start: day(4, 0), Count: 2}}, result.Completions)
+	assert.Zero(t, result.Streak)
+}
+
+func TestWorkflowRepository(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+	boardTitles := func() [][]string {
+		t.Helper()
+		board, err := repos.Workflow.GetBoard(ctx, alice)
+		require.NoError(t, err)
+		result := make([][]string, len(board))
+		for i, column := range board {
+			result[i] = []string{}
+			for _, task := range column.Tasks {
+				result[i] = append(result[i], task.Title)
+			}
+		}
+		return result
+	}
+
+	first := newTask(t, repos, alice, "first")
+	statuses, err := repos.Workflow.SetStatuses(ctx, alice, []models.StatusBody{{Name: "todo"}, {Name: "doing"}, {Name: "done"}})
+	require.NoError(t, err)
+	doing, done := statuses[1].ID, statuses[2].ID
+	second := newTask(t, repos, alice, "second")
+	assert.Equal(t, [][]string{{"first", "second"}, {}, {}}, boardTitles())
+
+	_, err = repos.Tasks.ToggleDone(ctx, first)
+	require.NoError(t, err)
+	moved, _, err := repos.Tasks.Move(ctx, second, done, 0, 0)
+	require.NoError(t, err)
+	assert.True(t, moved.IsDone)
+	assert.Equal(t, &done, moved.StatusID)
+	assert.Equal(t, [][]string{{}, {}, {"second", "first"}}, boardTitles())
+
+	_, _, err = repos.Tasks.Move(ctx, first, doing, 0, 1)
+	assert.ErrorIs(t, err, models.ErrTaskModified)
+	_, _, err = repos.Tasks.Move(ctx, first, uuid.New(), 0, 0)
+	assert.ErrorIs(t, err, models.ErrStatusNotFound)
+
+	_, err = repos.Workflow.SetStatuses(ctx, alice, []models.StatusBody{{ID: statuses[0].ID, Name: "todo"}, {ID: doing, Name: "doing"}, {ID: done, Name: "finished"}})
+	require.NoError(t, err)
+	task, err := repos.Tasks.GetByID(ctx, first)
+	require.NoError(t, err)
+	firstVersion := task.Version
+	assert.Equal(t, [][]string{{}, {}, {"second", "first"}}, boardTitles(), "renaming columns moves no task")
+
+	statuses, err = repos.Workflow.SetStatuses(ctx, alice, []models.StatusBody{{ID: done, Name: "done"}, {Name: "archive"}})
+	require.NoError(t, err)
+	assert.Equal(t, done, statuses[0].ID)
+	assert.Equal(t, [][]string{{}, {"first", "second"}}, boardTitles(), "done tasks follow the terminal column")
+	task, err = repos.Tasks.GetByID(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, firstVersion+1, task.Version, "a moved task gets a new version")
+	firstVersion = task.Version
+
+	_, err = repos.Workflow.SetStatuses(ctx, alice, []models.StatusBody{{ID: doing, Name: "doing"}})
+	assert.ErrorIs(t, err, models.ErrStatusNotFound)
+
+	_, err = repos.Workflow.SetStatuses(ctx, alice, nil)
+	require.NoError(t, err)
+	task, err = repos.Tasks.GetByID(ctx, first)
+	require.NoError(t, err)
+	assert.Nil(t, task.StatusID)
+	assert.True(t, task.IsDone)
+	assert.Equal(t, firstVersion+1, task.Version, "deleting the column changes its tasks")
+}
+
+func TestCategoryRepository(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+	bob := newUser(t, repos, "bob")
+	work := newCategory(t, repos, alice, "work")
+	taskID := newTask(t, repos, alice, "report", work)
+
+	_, err := repos.Categories.CreateCategory(ctx, &models.CategoryBody{Name: "work", UserID: alice})
+	assert.Error(t, err, "names are unique per user")
+	_, err = repos.Categories.CreateCategory(ctx, &models.CategoryBody{Name: "work", UserID: bob})
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, repos.Categories.Delete(ctx, work, bob), models.ErrCategoryNotFound, "the category of another user")
+	require.NoError(t, repos.Categories.Delete(ctx, work, alice))
+	task, err := repos.Tasks.GetByID(ctx, taskID)
+	require.NoError(t, err)
+	assert.Empty(t, task.Categories, "deleting a category removes its links, not the task")
+	assert.ErrorIs(t, repos.Categories.Delete(ctx, work, alice), models.ErrCategoryNotFound)
+}
+
+func TestUserRepository_Ownership(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+	bob := newUser(t, repos, "bob")
+	aliceCategory := newCategory(t, repos, alice, "work")
+	bobCategory := newCategory(t, repos, bob, "work")
+	taskID := newTask(t, repos, alice, "report")
+
+	owned, err := repos.Users.CheckTaskOwnership(ctx, alice, taskID)
+	require.NoError(t, err)
+	assert.True(t, owned)
+	owned, err = repos.Users.CheckTaskOwnership(ctx, bob, taskID)
+	require.NoError(t, err)
+	assert.False(t, owned)
+
+	owned, err = repos.Users.CheckCategoriesOwnership(ctx, alice, []uuid.UUID{aliceCategory, uuid.New()})
+	require.NoError(t, err)
+	assert.True(t, owned, "unknown IDs belong to nobody else")
+	owned, err = repos.Users.CheckCategoriesOwnership(ctx, alice, []uuid.UUID{aliceCategory, bobCategory})
+	require.NoError(t, err)
+	assert.False(t, owned)
+}
+
+func TestUserRepository_UseTOTPStep(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+
+	for _, tt := range []struct {
+		step     int64
+		expected bool
+	}{{step: 100, expected: true}, {step: 100, expected: false}, {step: 99, expected: false}, {step: 101, expected: true}} {
+		used, err := repos.Users.UseTOTPStep(ctx, alice, tt.step)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, used, "step %d", tt.step)
+	}
+	used, err := repos.Users.UseTOTPStep(ctx, uuid.New(), 1)
+	require.NoError(t, err)
+	assert.False(t, used, "unknown user")
+}
+
+func TestUserRepository_DeleteUserCascades(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+	bob := newUser(t, repos, "bob")
+	newTask(t, repos, alice, "report", newCategory(t, repos, alice, "work"))
+	bobTask := newTask(t, repos, bob, "bob's task")
+	require.NoError(t, repos.Users.RecordActivity(ctx, alice, models.ActivitySignIn))
+	require.NoError(t, repos.Users.EnableTOTP(ctx, alice, []string{"code"}))
+
+	require.NoError(t, repos.Users.DeleteUser(ctx, alice))
+	assert.ErrorIs(t, repos.Users.DeleteUser(ctx, alice), models.ErrUserNotFound)
+
+	summary, err := repos.Admin.GetUserSummary(ctx, bob)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, summary.TaskCount)
+	_, err = repos.Tasks.GetByID(ctx, bobTask)
+	assert.NoError(t, err, "other users keep their data")
+
+	// The name is free again and the new account starts empty.
+	again := newUser(t, repos, "alice")
+	data, err := repos.Accounts.GetAccountData(ctx, again)
+	require.NoError(t, err)
+	assert.Empty(t, data.Tasks)
+	assert.Empty(t, data.Categories)
+	assert.Empty(t, data.Activity)
+	used, err := repos.Users.UseRecoveryCode(ctx, again, "code")
+	require.NoError(t, err)
+	assert.False(t, used)
+}
+
+func TestAccountRepository_ImportDataIsAtomic(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+	existing := newCategory(t, repos, alice, "work")
+
+	archivedWork := models.Category{ID: uuid.New(), Name: "work"}
+	archivedHome := models.Category{ID: uuid.New(), Name: "home"}
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	completed := created.Add(48 * time.Hour)
+	result, err := repos.Accounts.ImportData(ctx, alice, &models.AccountData{
+		Categories: []models.Category{archivedWork, archivedHome},
+		Tasks: []models.TaskFullInfo{
+			{Title: "report", Categories: []models.Category{archivedWork}, IsDone: true, CreatedAt: created, CompletedAt: &completed},
+			{Title: "garden", Categories: []models.Category{archivedHome}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &models.ImportResult{Categories: 1, Tasks: 2}, result)
+
+	data, err := repos.Accounts.GetAccountData(ctx, alice)
+	require.NoError(t, err)
+	require.Len(t, data.Tasks, 2)
+	assert.Equal(t, existing, data.Tasks[1].Categories[0].ID, "categories are matched by name")
+	assert.Equal(t, created, data.Tasks[1].CreatedAt, "the archived creation time is kept")
+	assert.Equal(t, &completed, data.Tasks[1].CompletedAt, "the archived completion time is kept")
+	assert.False(t, data.User.CreatedAt.IsZero())
+
+	_, err = repos.Accounts.ImportData(ctx, alice, &models.AccountData{
+		Tasks: []models.TaskFullInfo{
+			{Title: "first"},
+			{Title: "second", Categories: []models.Category{{ID: uuid.New()}}},
+		},
+	})
+	assert.Error(t, err)
+	data, err = repos.Accounts.GetAccountData(ctx, alice)
+	require.NoError(t, err)
+	assert.Len(t, data.Tasks, 2, "a broken archive adds nothing")
+}
+
+func TestAccountRepository_ImportWorkflow(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+	first := newTask(t, repos, alice, "first")
+	newTask(t, repos, alice, "second")
+	statuses, err := repos.Workflow.SetStatuses(ctx, alice, []models.StatusBody{{Name: "todo"}, {Name: "doing"}, {Name: "done"}})
+	require.NoError(t, err)
+	_, _, err = repos.Tasks.Move(ctx, first, statuses[1].ID, 0, 0)
+	require.NoError(t, err)
+
+	data, err := repos.Accounts.GetAccountData(ctx, alice)
+	require.NoError(t, err)
+	require.Len(t, data.Statuses, 3)
+
+	bob := newUser(t, repos, "bob")
+	_, err = repos.Accounts.ImportData(ctx, bob, data)
+	require.NoError(t, err)
+	board, err := repos.Workflow.GetBoard(ctx, bob)
+	require.NoError(t, err)
+	require.Len(t, board, 3)
+	assert.Equal(t, []string{"todo", "doing", "done"}, []string{board[0].Name, board[1].Name, board[2].Name})
+	require.Len(t, board[1].Tasks, 1)
+	assert.Equal(t, "first", board[1].Tasks[0].Title, "the board is restored")
+	require.Len(t, board[0].Tasks, 1)
+	assert.Equal(t, "second", board[0].Tasks[0].Title)
+}
+
+func TestStore_ConcurrentUse(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _, err := repos.Tasks.CreateTask(ctx, alice, &models.TaskBody{Title: fmt.Sprintf("task %02d", i)}, nil)
+			assert.NoError(t, err)
+			_, err = repos.Categories.CreateCategory(ctx, &models.CategoryBody{Name: fmt.Sprintf("category %02d", i), UserID: alice})
+			assert.NoError(t, err)
+			_, err = repos.Tasks.GetAll(ctx, alice, 1, 10)
+			assert.NoError(t, err)
+			_, err = repos.Admin.ListUsers(ctx, "", 1, 10)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	summary, err := repos.Admin.GetUserSummary(ctx, alice)
+	require.NoError(t, err)
+	assert.EqualValues(t, 20, summary.TaskCount)
+	assert.EqualValues(t, 20, summary.CategoryCount)
+}
+
+func TestTransactor(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+	newTask(t, repos, alice, "kept")
+
+	err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, _, err := repos.Tasks.CreateTask(ctx, alice, &models.TaskBody{Title: "outer"}, nil)
+		require.NoError(t, err)
+		inner := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			_, err := repos.Categories.CreateCategory(ctx, &models.CategoryBody{Name: "work", UserID: alice})
+			require.NoError(t, err)
+			return errors.New("inner failure")
+		})
+		assert.Error(t, inner)
+		return nil
+	})
+	require.NoError(t, err)
+	summary, err := repos.Admin.GetUserSummary(ctx, alice)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, summary.TaskCount)
+	assert.EqualValues(t, 0, summary.CategoryCount, "the failed inner unit only undoes its own changes")
+
+	err = repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		require.NoError(t, repos.Users.DeleteUser(ctx, alice))
+		return errors.New("failure")
+	})
+	assert.Error(t, err)
+	tasks, err := repos.Tasks.GetAll(ctx, alice, 1, -1)
+	require.NoError(t, err)
+	assert.Len(t, tasks, 2, "the deletion and its cascades are rolled back")
+}
+
+func TestTransactor_AfterCommit(t *testing.T) {
+	transactor := NewTransactor(NewStore())
+	ctx := context.Background()
+	var ran []string
+
+	transactor.AfterCommit(ctx, func() { ran = append(ran, "outside") })
+	assert.Equal(t, []string{"outside"}, ran, "without a unit of work it runs right away")
+
+	ran = nil
+	err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		transactor.AfterCommit(ctx, func() { ran = append(ran, "outer") })
+		inner := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			transactor.AfterCommit(ctx, func() { ran = append(ran, "failed inner") })
+			return errors.New("inner failure")
+		})
+		assert.Error(t, inner)
+		require.NoError(t, transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			transactor.AfterCommit(ctx, func() { ran = append(ran, "inner") })
+			return nil
+		}))
+		assert.Empty(t, ran, "nothing runs before the outermost unit commits")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"outer", "inner"}, ran)
+
+	ran = nil
+	err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		transactor.AfterCommit(ctx, func() { ran = append(ran, "rolled back") })
+		return errors.New("failure")
+	})
+	assert.Error(t, err)
+	assert.Empty(t, ran)
+}
+
+func TestTransactor_RollbackKeepsConcurrentWrites(t *testing.T) {
+	repos := newRepositories()
+	ctx := context.Background()
+	alice := newUser(t, repos, "alice")
+
+	started := make(chan struct{})
+	written := make(chan error, 1)
+	err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+		_, _, err := repos.Tasks.CreateTask(ctx, alice, &models.TaskBody{Title: "rolled back"}, nil)
+		require.NoError(t, err)
+		go func() {
+			close(started)
+			_, err := repos.Categories.CreateCategory(context.Background(), &models.CategoryBody{Name: "work", UserID: alice})
+			written <- err
+		}()
+		<-started
+		time.Sleep(10 * time.Millisecond)
+		return errors.New("failure")
+	})
+	assert.Error(t, err)
+	require.NoError(t, <-written)
+
+	summary, err := repos.Admin.GetUserSummary(ctx, alice)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, summary.TaskCount)
+	assert.EqualValues(t, 1, summary.CategoryCount, "a write made by another caller is not undone")
+}
@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type StatsRepository struct {
+	store *Store
+}
+
+func NewStatsRepository(store *Store) *StatsRepository {
+	return &StatsRepository{store: store}
+}
+
+// GetStats computes in one pass over the tasks of the user what the SQL
+// repository asks the database for.
+func (repo *StatsRepository) GetStats(ctx context.Context, userID uuid.UUID, query models.StatsQuery) (*models.Stats, error) {
+	defer repo.store.rlock(ctx)()
+
+	stats := &models.Stats{}
+	inRange := models.TimeRange{From: &query.From, To: &query.To}
+	perPeriod := make(map[time.Time]int64)
+	perCategory := make(map[uuid.UUID]*models.CategoryStats)
+	for _, c := range repo.store.userCategories(userID) {
+		perCategory[c.id] = &models.CategoryStats{ID: c.id, Name: c.name}
+	}
+	// Like the join in SQL, a category counts every task linked to it.
+	for _, t := range repo.store.tasks {
+		for _, id := range t.categories {
+			c, ok := perCategory[id]
+			if !ok {
+				continue
+			}
+			if t.isDone {
+				c.Completed++
+			} else {
+				c.Open++
+			}
+		}
+	}
+	days := make(map[time.Time]bool)
+	var completed int64
+	var total time.Duration
+
+	for _, t := range repo.store.userTasks(userID) {
+		if !t.isDone {
+			stats.Open++
+			continue
+		}
+		stats.Completed++
+		days[models.PeriodDay.Start(*t.completedAt)] = true
+		if inRange.Contains(*t.completedAt) {
+			perPeriod[query.Period.Start(*t.completedAt)]++
+			completed++
+			total += t.completedAt.Sub(t.createdAt)
+		}
+	}
+
+	for start, count := range perPeriod {
+		stats.Completions = append(stats.Completions, models.PeriodCount{Start: start, Count: count})
+	}
+	sort.Slice(stats.Completions, func(i, j int) bool {
+		return stats.Completions[i].Start.Before(stats.Completions[j].Start)
+	})
+	if completed > 0 {
+		average := total / time.Duration(completed)
+		stats.AverageTimeToComplete = &average
+	}
+
+	for _, c := range perCategory {
+		stats.Categories = append(stats.Categories, *c)
+	}
+	sort.Slice(stats.Categories, func(i, j int) bool {
+		a, b := stats.Categories[i], stats.Categories[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID.String() < b.ID.String()
+	})
+
+	newestFirst := make([]time.Time, 0, len(days))
+	for day := range days {
+		newestFirst = append(newestFirst, day)
+	}
+	sort.Slice(newestFirst, func(i, j int) bool { return newestFirst[i].After(newestFirst[j]) })
+	stats.Streak = models.StreakFrom(query.Now, func() (time.Time, bool) {
+		if len(newestFirst) == 0 {
+			return time.Time{}, false
+		}
+		day := newestFirst[0]
+		newestFirst = newestFirst[1:]
+		return day, true
+	})
+	return stats, nil
+}
@@ -0,0 +1,429 @@
+// Package memory keeps every repository in process memory. It backs the demo
+// mode, which runs without a database, and handler tests. The semantics
+// follow the Postgres repositories, including the cascades of the schema's
+// foreign keys; all data is lost when the process exits.
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type task struct {
+	id          uuid.UUID
+	userID      uuid.UUID
+	title       string
+	description string
+	isDone      bool
+	categories  []uuid.UUID
+	// idempotencyKey is empty for tasks created without a key.
+	idempotencyKey string
+	version        int64
+	createdAt      time.Time
+	updatedAt      time.Time
+	// completedAt is nil while the task is not done.
+	completedAt *time.Time
+	// statusID is uuid.Nil while the user has no workflow.
+	statusID uuid.UUID
+	position int
+}
+
+type category struct {
+	id     uuid.UUID
+	userID uuid.UUID
+	name   string
+	// seq keeps the listing in insertion order, like a heap scan would.
+	seq            int64
+	idempotencyKey string
+	createdAt      time.Time
+	updatedAt      time.Time
+}
+
+type status struct {
+	id        uuid.UUID
+	userID    uuid.UUID
+	name      string
+	position  int
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+type identity struct {
+	userID uuid.UUID
+	models.ExternalIdentity
+}
+
+type recoveryCode struct {
+	userID   uuid.UUID
+	codeHash string
+	used     bool
+}
+
+type activity struct {
+	userID uuid.UUID
+	models.Activity
+}
+
+// Store holds the data shared by the repositories built on it.
+// A single lock guards it, so every repository call is atomic. A unit of work
+// holds the lock until it ends; the calls made inside it do not lock again.
+type Store struct {
+	mu  sync.RWMutex
+	seq int64
+	now func() time.Time
+	data
+}
+
+// data is everything a unit of work can roll back.
+type data struct {
+	users         map[uuid.UUID]*models.User
+	tasks         map[uuid.UUID]*task
+	categories    map[uuid.UUID]*category
+	statuses      map[uuid.UUID]*status
+	identities    []identity
+	recoveryCodes []recoveryCode
+	activity      []activity
+}
+
+func NewStore() *Store {
+	return &Store{
+		now: time.Now,
+		data: data{
+			users:      make(map[uuid.UUID]*models.User),
+			tasks:      make(map[uuid.UUID]*task),
+			categories: make(map[uuid.UUID]*category),
+			statuses:   make(map[uuid.UUID]*status),
+		},
+	}
+}
+
+// lock takes the write lock for a repository call and returns its release.
+// Inside a unit of work of this store the lock is already held.
+func (s *Store) lock(ctx context.Context) func() {
+	if s.inTransaction(ctx) {
+		return func() {}
+	}
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+// rlock is lock for the calls that only read.
+func (s *Store) rlock(ctx context.Context) func() {
+	if s.inTransaction(ctx) {
+		return func() {}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+func (s *Store) inTransaction(ctx context.Context) bool {
+	return ctx.Value(txKey{}) == s
+}
+
+// snapshot returns a deep copy of the data. The caller holds the lock.
+//
+// Every unit of work copies the whole store, not only what it touches, so it
+// costs time and memory in proportion to all the data held. That is cheap for
+// the demo mode and the tests this store is meant for; tracking the touched
+// rows instead would spread undo bookkeeping over every repository method.
+func (s *Store) snapshot() data {
+	saved := data{
+		users:         make(map[uuid.UUID]*models.User, len(s.users)),
+		tasks:         make(map[uuid.UUID]*task, len(s.tasks)),
+		categories:    make(map[uuid.UUID]*category, len(s.categories)),
+		statuses:      make(map[uuid.UUID]*status, len(s.statuses)),
+		identities:    append([]identity(nil), s.identities...),
+		recoveryCodes: append([]recoveryCode(nil), s.recoveryCodes...),
+		activity:      append([]activity(nil), s.activity...),
+	}
+	for id, user := range s.users {
+		saved.users[id] = copyUser(user)
+	}
+	for id, t := range s.tasks {
+		copied := *t
+		copied.categories = append([]uuid.UUID(nil), t.categories...)
+		saved.tasks[id] = &copied
+	}
+	for id, c := range s.categories {
+		copied := *c
+		saved.categories[id] = &copied
+	}
+	for id, st := range s.statuses {
+		copied := *st
+		saved.statuses[id] = &copied
+	}
+	return saved
+}
+
+// restore puts a snapshot back. The caller holds the lock.
+func (s *Store) restore(saved data) {
+	s.data = saved
+}
+
+// userExists stands in for the foreign keys to users.
+func (s *Store) userExists(userID uuid.UUID) error {
+	if _, ok := s.users[userID]; !ok {
+		return errors.Errorf("user %s does not exist", userID)
+	}
+	return nil
+}
+
+func (s *Store) nameTaken(name string) bool {
+	for _, user := range s.users {
+		if user.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) createUser(auth models.UserAuth) (*models.User, error) {
+	if s.nameTaken(auth.Name) {
+		return nil, errors.Errorf("user name %s is already taken", auth.Name)
+	}
+	now := s.now()
+	user := &models.User{ID: uuid.New(), Name: auth.Name, Password: auth.Password, Role: models.RoleUser, CreatedAt: now, UpdatedAt: now}
+	s.users[user.ID] = user
+	return user, nil
+}
+
+// deleteUser removes the user with everything that references them.
+func (s *Store) deleteUser(userID uuid.UUID) {
+	delete(s.users, userID)
+	for id, t := range s.tasks {
+		if t.userID == userID {
+			delete(s.tasks, id)
+		}
+	}
+	for id, c := range s.categories {
+		if c.userID == userID {
+			s.deleteCategory(id)
+		}
+	}
+	for id, st := range s.statuses {
+		if st.userID == userID {
+			delete(s.statuses, id)
+		}
+	}
+	s.identities = filter(s.identities, func(i identity) bool { return i.userID != userID })
+	s.recoveryCodes = filter(s.recoveryCodes, func(c recoveryCode) bool { return c.userID != userID })
+	s.activity = filter(s.activity, func(a activity) bool { return a.userID != userID })
+}
+
+// deleteCategory removes the category and its task links, which changes
+// the version of the linked tasks.
+func (s *Store) deleteCategory(id uuid.UUID) {
+	delete(s.categories, id)
+	for _, t := range s.tasks {
+		if contains(t.categories, id) {
+			t.categories = filter(t.categories, func(categoryID uuid.UUID) bool { return categoryID != id })
+			t.version++
+			t.updatedAt = s.now()
+		}
+	}
+}
+
+func (s *Store) addCategory(userID uuid.UUID, name string) (*category, error) {
+	for _, c := range s.categories {
+		if c.userID == userID && c.name == name {
+			return nil, errors.Errorf("category %s already exists", name)
+		}
+	}
+	s.seq++
+	now := s.now()
+	c := &category{id: uuid.New(), userID: userID, name: name, seq: s.seq, createdAt: now, updatedAt: now}
+	s.categories[c.id] = c
+	return c, nil
+}
+
+// resolveCategories drops duplicate IDs and, like the SQL repositories,
+// fails with ErrCategoryNotFound when any of them does not exist.
+func (s *Store) resolveCategories(ids []uuid.UUID) ([]uuid.UUID, error) {
+	result := make([]uuid.UUID, 0, len(ids))
+	var missing []uuid.UUID
+	for _, id := range ids {
+		if contains(result, id) || contains(missing, id) {
+			continue
+		}
+		if _, ok := s.categories[id]; ok {
+			result = append(result, id)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, errors.Wrapf(models.ErrCategoryNotFound, "unknown categories %v", missing)
+	}
+	return result, nil
+}
+
+func (s *Store) taskInfo(t *task) *models.TaskFullInfo {
+	return &models.TaskFullInfo{
+		ID:          t.id,
+		Title:       t.title,
+		Description: t.description,
+		IsDone:      t.isDone,
+		Categories:  s.taskCategories(t),
+		Version:     t.version,
+		CreatedAt:   t.createdAt,
+		UpdatedAt:   t.updatedAt,
+		CompletedAt: copyTime(t.completedAt),
+		StatusID:    t.status(),
+		Position:    t.position,
+	}
+}
+
+func (t *task) shortInfo() models.TaskShortInfo {
+	return models.TaskShortInfo{
+		ID:          t.id,
+		Title:       t.title,
+		IsDone:      t.isDone,
+		Version:     t.version,
+		CreatedAt:   t.createdAt,
+		UpdatedAt:   t.updatedAt,
+		CompletedAt: copyTime(t.completedAt),
+		StatusID:    t.status(),
+	}
+}
+
+// status returns the column of the task, nil when it is on no board.
+func (t *task) status() *uuid.UUID {
+	if t.statusID == uuid.Nil {
+		return nil
+	}
+	id := t.statusID
+	return &id
+}
+
+// setDone changes the flag and keeps completedAt in step with it.
+func (t *task) setDone(done bool, now time.Time) {
+	if done == t.isDone {
+		return
+	}
+	t.isDone = done
+	t.completedAt = nil
+	if done {
+		t.completedAt = &now
+	}
+}
+
+func (c *category) info() models.Category {
+	return models.Category{ID: c.id, Name: c.name, UserID: c.userID, CreatedAt: c.createdAt, UpdatedAt: c.updatedAt}
+}
+
+func (s *Store) taskCategories(t *task) []models.Category {
+	result := make([]models.Category, 0, len(t.categories))
+	for _, id := range t.categories {
+		result = append(result, s.categories[id].info())
+	}
+	return result
+}
+
+func (s *Store) userTasks(userID uuid.UUID) []*task {
+	var result []*task
+	for _, t := range s.tasks {
+		if t.userID == userID {
+			result = append(result, t)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return lessTasks(result[i], result[j], models.TaskFilter{}) })
+	return result
+}
+
+func (s *Store) userCategories(userID uuid.UUID) []*category {
+	var result []*category
+	for _, c := range s.categories {
+		if c.userID == userID {
+			result = append(result, c)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].seq < result[j].seq })
+	return result
+}
+
+// checkVersion is the in-memory counterpart of the conditional writes of the
+// SQL repositories: a non-zero ifVersion must match the task.
+func checkVersion(t *task, ifVersion int64) error {
+	if ifVersion != 0 && t.version != ifVersion {
+		return models.ErrTaskModified
+	}
+	return nil
+}
+
+// page returns the bounds of a page the way LIMIT and OFFSET treat them: a
+// negative size means no limit, offsets below zero are ignored.
+func page(total, pageIndex, recordsPerPage int) (int, int) {
+	start := (pageIndex - 1) * recordsPerPage
+	if start < 0 {
+		start = 0
+	}
+	if start > total {
+		start = total
+	}
+	end := total
+	if recordsPerPage >= 0 && start+recordsPerPage < end {
+		end = start + recordsPerPage
+	}
+	return start, end
+}
+
+// lessTasks orders tasks the way the SQL repositories sort a list.
+func lessTasks(a, b *task, taskFilter models.TaskFilter) bool {
+	if taskFilter.Sort == models.SortByCompleted && (a.completedAt == nil) != (b.completedAt == nil) {
+		return b.completedAt == nil
+	}
+	var cmp int
+	switch taskFilter.Sort {
+	case models.SortByCreated:
+		cmp = a.createdAt.Compare(b.createdAt)
+	case models.SortByUpdated:
+		cmp = a.updatedAt.Compare(b.updatedAt)
+	case models.SortByCompleted:
+		if a.completedAt != nil && b.completedAt != nil {
+			cmp = a.completedAt.Compare(*b.completedAt)
+		}
+	default:
+		cmp = strings.Compare(a.title, b.title)
+	}
+	if taskFilter.Descending {
+		cmp = -cmp
+	}
+	if cmp != 0 {
+		return cmp < 0
+	}
+	return a.id.String() < b.id.String()
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
+
+func filter[T any](items []T, keep func(T) bool) []T {
+	result := items[:0]
+	for _, item := range items {
+		if keep(item) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func contains(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type TaskRepository struct {
+	store *Store
+}
+
+func NewTaskRepository(store *Store) *TaskRepository {
+	return &TaskRepository{store: store}
+}
+
+// CreateTask fails when a category does not exist and returns the existing
+// task for a known idempotency key; like the SQL repositories it leaves
+// ownership checks to the caller.
+func (r *TaskRepository) CreateTask(ctx context.Context, userId uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) (*models.TaskFullInfo, bool, error) {
+	defer r.store.lock(ctx)()
+
+	if err := r.store.userExists(userId); err != nil {
+		return nil, false, err
+	}
+	if body.IdempotencyKey != "" {
+		for _, existing := range r.store.tasks {
+			if existing.userID == userId && existing.idempotencyKey == body.IdempotencyKey {
+				return r.store.taskInfo(existing), false, nil
+			}
+		}
+	}
+	categories, err := r.store.resolveCategories(categoryIDs)
+	if err != nil {
+		return nil, false, err
+	}
+	now := r.store.now()
+	t := &task{
+		id:             uuid.New(),
+		userID:         userId,
+		title:          body.Title,
+		description:    body.Description,
+		categories:     categories,
+		idempotencyKey: body.IdempotencyKey,
+		version:        1,
+		createdAt:      now,
+		updatedAt:      now,
+	}
+	r.store.tasks[t.id] = t
+	r.store.placeTask(t)
+	return r.store.taskInfo(t), true, nil
+}
+
+// Update keeps the category links when categoryIDs is nil.
+func (r *TaskRepository) Update(ctx context.Context, id uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) error {
+	defer r.store.lock(ctx)()
+
+	t, ok := r.store.tasks[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if err := checkVersion(t, body.IfVersion); err != nil {
+		return err
+	}
+	categories := t.categories
+	if categoryIDs != nil {
+		var err error
+		if categories, err = r.store.resolveCategories(categoryIDs); err != nil {
+			return err
+		}
+	}
+	t.title = body.Title
+	t.description = body.Description
+	t.categories = categories
+	t.version++
+	t.updatedAt = r.store.now()
+	return nil
+}
+
+// Patch writes only the fields set in patch.
+func (r *TaskRepository) Patch(ctx context.Context, id uuid.UUID, patch *models.TaskPatch) (*models.TaskFullInfo, bool, error) {
+	defer r.store.lock(ctx)()
+
+	t, ok := r.store.tasks[id]
+	if !ok {
+		return nil, false, gorm.ErrRecordNotFound
+	}
+	if err := checkVersion(t, patch.IfVersion); err != nil {
+		return nil, false, err
+	}
+	categories := t.categories
+	if patch.CategoryIDs != nil {
+		var err error
+		if categories, err = r.store.resolveCategories(patch.CategoryIDs); err != nil {
+			return nil, false, err
+		}
+	}
+	if patch.Title != nil {
+		t.title = *patch.Title
+	}
+	if patch.Description != nil {
+		t.description = *patch.Description
+	}
+	now := r.store.now()
+	completed := false
+	if patch.IsDone != nil && *patch.IsDone != t.isDone {
+		completed = *patch.IsDone
+		t.setDone(*patch.IsDone, now)
+		r.store.placeTask(t)
+	}
+	t.categories = categories
+	t.version++
+	t.updatedAt = now
+	return r.store.taskInfo(t), completed, nil
+}
+
+func (r *TaskRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.TaskFullInfo, error) {
+	defer r.store.rlock(ctx)()
+
+	t, ok := r.store.tasks[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.store.taskInfo(t), nil
+}
+
+func (r *TaskRepository) GetAll(ctx context.Context, userId uuid.UUID, pageIndex, recordsPerPage int) ([]models.TaskShortInfo, error) {
+	return r.List(ctx, userId, models.TaskFilter{}, pageIndex, recordsPerPage)
+}
+
+func (r *TaskRepository) List(ctx context.Context, userId uuid.UUID, taskFilter models.TaskFilter, pageIndex, recordsPerPage int) ([]models.TaskShortInfo, error) {
+	defer r.store.rlock(ctx)()
+
+	tasks := filter(r.store.userTasks(userId), func(t *task) bool {
+		if taskFilter.IsDone != nil && t.isDone != *taskFilter.IsDone {
+			return false
+		}
+		if taskFilter.CategoryID != nil && !contains(t.categories, *taskFilter.CategoryID) {
+			return false
+		}
+		if !taskFilter.Completed.IsZero() && (t.completedAt == nil || !taskFilter.Completed.Contains(*t.completedAt)) {
+			return false
+		}
+		return taskFilter.Created.Contains(t.createdAt) && taskFilter.Updated.Contains(t.updatedAt)
+	})
+	sort.SliceStable(tasks, func(i, j int) bool { return lessTasks(tasks[i], tasks[j], taskFilter) })
+	start, end := page(len(tasks), pageIndex, recordsPerPage)
+
+	result := make([]models.TaskShortInfo, 0, end-start)
+	for _, t := range tasks[start:end] {
+		result = append(result, t.shortInfo())
+	}
+	return result, nil
+}
+
+func (r *TaskRepository) Delete(ctx context.Context, id uuid.UUID, ifVersion int64) error {
+	defer r.store.lock(ctx)()
+
+	t, ok := r.store.tasks[id]
+	if !ok {
+		return nil
+	}
+	if err := checkVersion(t, ifVersion); err != nil {
+		return err
+	}
+	delete(r.store.tasks, id)
+	return nil
+}
+
+func (r *TaskRepository) ToggleDone(ctx context.Context, id uuid.UUID) (bool, error) {
+	defer r.store.lock(ctx)()
+
+	t, ok := r.store.tasks[id]
+	if !ok {
+		return false, gorm.ErrRecordNotFound
+	}
+	now := r.store.now()
+	t.setDone(!t.isDone, now)
+	r.store.placeTask(t)
+	t.version++
+	t.updatedAt = now
+	return t.isDone, nil
+}
+
+// Move mirrors the SQL repository: the tasks from position on shift down by
+// one to make room for the task, and the flag follows the terminal column.
+func (r *TaskRepository) Move(ctx context.Context, id, statusID uuid.UUID, position int, ifVersion int64) (*models.TaskFullInfo, bool, error) {
+	defer r.store.lock(ctx)()
+
+	t, ok := r.store.tasks[id]
+	if !ok {
+		return nil, false, gorm.ErrRecordNotFound
+	}
+	statuses := r.store.userStatuses(t.userID)
+	target := -1
+	for i, st := range statuses {
+		if st.id == statusID {
+			target = i
+		}
+	}
+	if target < 0 {
+		return nil, false, errors.Wrapf(models.ErrStatusNotFound, "unknown status %s", statusID)
+	}
+	if err := checkVersion(t, ifVersion); err != nil {
+		return nil, false, err
+	}
+
+	now := r.store.now()
+	isDone := target == len(statuses)-1
+	completed := isDone && !t.isDone
+	t.setDone(isDone, now)
+	t.version++
+	t.updatedAt = now
+
+	column := filter(r.store.columnTasks(statusID), func(other *task) bool { return other != t })
+	index := max(position, 0)
+	if index >= len(column) {
+		r.store.appendToColumn(statusID, t)
+		return r.store.taskInfo(t), completed, nil
+	}
+	slot := column[index].position
+	for _, other := range column {
+		if other.position >= slot {
+			other.position++
+		}
+	}
+	t.statusID = statusID
+	t.position = slot
+	return r.store.taskInfo(t), completed, nil
+}
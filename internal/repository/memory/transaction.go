@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+)
+
+// txKey marks the context of a unit of work with the store it locked.
+type txKey struct{}
+
+// afterCommitKey holds the functions queued by AfterCommit for the outermost
+// unit of work.
+type afterCommitKey struct{}
+
+// Transactor runs the units of work of the memory store. A unit of work holds
+// the store's write lock from start to end, so no other call can write in
+// between, and a failed one is rolled back by restoring a snapshot taken when
+// it began without undoing anyone else's writes. The snapshot copies the
+// whole store, see Store.snapshot.
+type Transactor struct {
+	store *Store
+}
+
+func NewTransactor(store *Store) *Transactor {
+	return &Transactor{store: store}
+}
+
+func (t *Transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if t.store.inTransaction(ctx) {
+		// A nested unit of work already holds the lock, like a savepoint
+		// inside the outer transaction.
+		return t.run(ctx, fn)
+	}
+
+	queued := new([]func())
+	ctx = context.WithValue(ctx, afterCommitKey{}, queued)
+	err := func() error {
+		t.store.mu.Lock()
+		defer t.store.mu.Unlock()
+		return t.run(context.WithValue(ctx, txKey{}, t.store), fn)
+	}()
+	if err != nil {
+		return err
+	}
+	for _, f := range *queued {
+		f()
+	}
+	return nil
+}
+
+// run calls fn with the lock held and rolls its writes and what it queued
+// with AfterCommit back when it fails.
+func (t *Transactor) run(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	queued := ctx.Value(afterCommitKey{}).(*[]func())
+	mark := len(*queued)
+	saved := t.store.snapshot()
+	defer func() {
+		if p := recover(); p != nil {
+			t.store.restore(saved)
+			*queued = (*queued)[:mark]
+			panic(p)
+		}
+	}()
+	if err = fn(ctx); err != nil {
+		t.store.restore(saved)
+		*queued = (*queued)[:mark]
+	}
+	return err
+}
+
+func (t *Transactor) AfterCommit(ctx context.Context, fn func()) {
+	if queued, ok := ctx.Value(afterCommitKey{}).(*[]func()); ok {
+		*queued = append(*queued, fn)
+		return
+	}
+	fn()
+}
@@ -0,0 +1,182 @@
+package memory
+
+import (
+	"context"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+)
+
+type UserRepository struct {
+	store *Store
+}
+
+func NewUserRepository(store *Store) *UserRepository {
+	return &UserRepository{store: store}
+}
+
+func (repo *UserRepository) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	defer repo.store.rlock(ctx)()
+
+	user, ok := repo.store.users[id]
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+	return copyUser(user), nil
+}
+
+func (repo *UserRepository) GetUserByName(ctx context.Context, name string) (*models.User, error) {
+	defer repo.store.rlock(ctx)()
+
+	for _, user := range repo.store.users {
+		if user.Name == name {
+			return copyUser(user), nil
+		}
+	}
+	return nil, models.ErrUserNotFound
+}
+
+func (repo *UserRepository) CreateUser(ctx context.Context, user *models.UserAuth) error {
+	defer repo.store.lock(ctx)()
+
+	_, err := repo.store.createUser(*user)
+	return err
+}
+
+// UpdatePassword stores a new password hash and clears a pending forced reset.
+func (repo *UserRepository) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	return repo.update(ctx, userID, func(user *models.User) {
+		user.Password = passwordHash
+		user.PasswordResetRequired = false
+	})
+}
+
+func (repo *UserRepository) CheckTaskOwnership(ctx context.Context, userID, taskID uuid.UUID) (bool, error) {
+	defer repo.store.rlock(ctx)()
+
+	t, ok := repo.store.tasks[taskID]
+	return ok && t.userID == userID, nil
+}
+
+// CheckCategoriesOwnership fails only for categories of other users; unknown
+// IDs pass, as in the Postgres repository.
+func (repo *UserRepository) CheckCategoriesOwnership(ctx context.Context, userID uuid.UUID, categories []uuid.UUID) (bool, error) {
+	defer repo.store.rlock(ctx)()
+
+	for _, id := range categories {
+		if c, ok := repo.store.categories[id]; ok && c.userID != userID {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (repo *UserRepository) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	defer repo.store.lock(ctx)()
+
+	if _, ok := repo.store.users[userID]; !ok {
+		return models.ErrUserNotFound
+	}
+	repo.store.deleteUser(userID)
+	return nil
+}
+
+func (repo *UserRepository) SetTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	return repo.update(ctx, userID, func(user *models.User) {
+		user.TOTPSecret = secret
+	})
+}
+
+// EnableTOTP replaces the recovery codes of the user.
+func (repo *UserRepository) EnableTOTP(ctx context.Context, userID uuid.UUID, recoveryCodeHashes []string) error {
+	defer repo.store.lock(ctx)()
+
+	user, ok := repo.store.users[userID]
+	if !ok {
+		return models.ErrUserNotFound
+	}
+	user.TOTPEnabled = true
+	user.UpdatedAt = repo.store.now()
+	repo.store.recoveryCodes = filter(repo.store.recoveryCodes, func(c recoveryCode) bool { return c.userID != userID })
+	for _, hash := range recoveryCodeHashes {
+		repo.store.recoveryCodes = append(repo.store.recoveryCodes, recoveryCode{userID: userID, codeHash: hash})
+	}
+	return nil
+}
+
+func (repo *UserRepository) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	defer repo.store.lock(ctx)()
+
+	user, ok := repo.store.users[userID]
+	if !ok {
+		return models.ErrUserNotFound
+	}
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.UpdatedAt = repo.store.now()
+	repo.store.recoveryCodes = filter(repo.store.recoveryCodes, func(c recoveryCode) bool { return c.userID != userID })
+	return nil
+}
+
+// UseTOTPStep records step as the last accepted TOTP time step and reports
+// whether it is later than the one recorded before.
+func (repo *UserRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	defer repo.store.lock(ctx)()
+
+	user, ok := repo.store.users[userID]
+	if !ok || user.TOTPLastStep >= step {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	user.UpdatedAt = repo.store.now()
+	return true, nil
+}
+
+// UseRecoveryCode marks the matching unused code as used and reports whether
+// there was one.
+func (repo *UserRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	defer repo.store.lock(ctx)()
+
+	for i, code := range repo.store.recoveryCodes {
+		if code.userID == userID && code.codeHash == codeHash && !code.used {
+			repo.store.recoveryCodes[i].used = true
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (repo *UserRepository) RecordActivity(ctx context.Context, userID uuid.UUID, event string) error {
+	defer repo.store.lock(ctx)()
+
+	if err := repo.store.userExists(userID); err != nil {
+		return err
+	}
+	repo.store.activity = append(repo.store.activity, activity{
+		userID:   userID,
+		Activity: models.Activity{Event: event, CreatedAt: repo.store.now()},
+	})
+	return nil
+}
+
+func (repo *UserRepository) update(ctx context.Context, userID uuid.UUID, change func(user *models.User)) error {
+	defer repo.store.lock(ctx)()
+
+	user, ok := repo.store.users[userID]
+	if !ok {
+		return models.ErrUserNotFound
+	}
+	change(user)
+	user.UpdatedAt = repo.store.now()
+	return nil
+}
+
+// copyUser hands out a copy, so callers never write to the store unlocked.
+func copyUser(user *models.User) *models.User {
+	result := *user
+	if user.DeletionScheduledAt != nil {
+		at := *user.DeletionScheduledAt
+		result.DeletionScheduledAt = &at
+	}
+	return &result
+}
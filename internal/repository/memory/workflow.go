@@ -0,0 +1,211 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type WorkflowRepository struct {
+	store *Store
+}
+
+func NewWorkflowRepository(store *Store) *WorkflowRepository {
+	return &WorkflowRepository{store: store}
+}
+
+func (r *WorkflowRepository) GetStatuses(ctx context.Context, userID uuid.UUID) ([]models.TaskStatus, error) {
+	defer r.store.rlock(ctx)()
+
+	return statusInfos(r.store.userStatuses(userID)), nil
+}
+
+// SetStatuses follows the SQL repository: omitted columns are deleted, the
+// tasks that are then in no column or in the wrong one are put back, and
+// every task that changed its column or position gets a new version.
+func (r *WorkflowRepository) SetStatuses(ctx context.Context, userID uuid.UUID, statuses []models.StatusBody) ([]models.TaskStatus, error) {
+	defer r.store.lock(ctx)()
+
+	if err := r.store.userExists(userID); err != nil {
+		return nil, err
+	}
+	removed := make(map[uuid.UUID]bool)
+	for _, st := range r.store.userStatuses(userID) {
+		removed[st.id] = true
+	}
+	for _, body := range statuses {
+		if body.ID != uuid.Nil && !removed[body.ID] {
+			return nil, errors.Wrapf(models.ErrStatusNotFound, "unknown status %s", body.ID)
+		}
+	}
+
+	before := r.store.placements(userID)
+	now := r.store.now()
+	for i, body := range statuses {
+		if body.ID == uuid.Nil {
+			st := &status{id: uuid.New(), userID: userID, name: body.Name, position: i, createdAt: now, updatedAt: now}
+			r.store.statuses[st.id] = st
+			continue
+		}
+		delete(removed, body.ID)
+		st := r.store.statuses[body.ID]
+		st.name = body.Name
+		st.position = i
+		st.updatedAt = now
+	}
+	for id := range removed {
+		r.store.deleteStatus(id)
+	}
+	r.store.syncBoard(userID)
+	for t, was := range before {
+		if t.statusID != was.statusID || t.position != was.position {
+			t.version++
+			t.updatedAt = now
+		}
+	}
+	return statusInfos(r.store.userStatuses(userID)), nil
+}
+
+func (r *WorkflowRepository) GetBoard(ctx context.Context, userID uuid.UUID) ([]models.BoardColumn, error) {
+	defer r.store.rlock(ctx)()
+
+	statuses := statusInfos(r.store.userStatuses(userID))
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+	board := make([]models.BoardColumn, len(statuses))
+	for i, st := range statuses {
+		board[i] = models.BoardColumn{TaskStatus: st, Tasks: []models.TaskShortInfo{}}
+		for _, t := range r.store.columnTasks(st.ID) {
+			board[i].Tasks = append(board[i].Tasks, t.shortInfo())
+		}
+	}
+	return board, nil
+}
+
+func statusInfos(statuses []*status) []models.TaskStatus {
+	result := make([]models.TaskStatus, len(statuses))
+	for i, st := range statuses {
+		result[i] = models.TaskStatus{ID: st.id, Name: st.name, Position: i, Terminal: i == len(statuses)-1}
+	}
+	return result
+}
+
+// userStatuses returns the workflow of the user in board order.
+func (s *Store) userStatuses(userID uuid.UUID) []*status {
+	var result []*status
+	for _, st := range s.statuses {
+		if st.userID == userID {
+			result = append(result, st)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].position < result[j].position })
+	return result
+}
+
+// columnTasks returns the tasks of the column in board order.
+func (s *Store) columnTasks(statusID uuid.UUID) []*task {
+	var result []*task
+	for _, t := range s.tasks {
+		if t.statusID == statusID {
+			result = append(result, t)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].position != result[j].position {
+			return result[i].position < result[j].position
+		}
+		return result[i].id.String() < result[j].id.String()
+	})
+	return result
+}
+
+// placement is where a task is on the board.
+type placement struct {
+	statusID uuid.UUID
+	position int
+}
+
+// placements returns the placement of every task of the user.
+func (s *Store) placements(userID uuid.UUID) map[*task]placement {
+	result := make(map[*task]placement)
+	for _, t := range s.tasks {
+		if t.userID == userID {
+			result[t] = placement{statusID: t.statusID, position: t.position}
+		}
+	}
+	return result
+}
+
+// deleteStatus removes the column; like the foreign key, it takes its tasks
+// off the board without changing their version.
+func (s *Store) deleteStatus(id uuid.UUID) {
+	delete(s.statuses, id)
+	for _, t := range s.tasks {
+		if t.statusID == id {
+			t.statusID = uuid.Nil
+		}
+	}
+}
+
+// columnFor returns the column a task belongs in: the terminal one when it
+// is done, the first one otherwise.
+func columnFor(statuses []*status, isDone bool) uuid.UUID {
+	if isDone {
+		return statuses[len(statuses)-1].id
+	}
+	return statuses[0].id
+}
+
+// placeTask moves the task to the end of the column its flag asks for,
+// unless its user has no workflow.
+func (s *Store) placeTask(t *task) {
+	if statuses := s.userStatuses(t.userID); len(statuses) > 0 {
+		s.appendToColumn(columnFor(statuses, t.isDone), t)
+	}
+}
+
+// syncBoard moves every task of the user that is not in the column its flag
+// asks for to the end of that column, oldest first.
+func (s *Store) syncBoard(userID uuid.UUID) {
+	statuses := s.userStatuses(userID)
+	if len(statuses) == 0 {
+		return
+	}
+	terminal := columnFor(statuses, true)
+	var misplaced []*task
+	for _, t := range s.tasks {
+		if t.userID == userID && (t.statusID == uuid.Nil || t.isDone != (t.statusID == terminal)) {
+			misplaced = append(misplaced, t)
+		}
+	}
+	sort.Slice(misplaced, func(i, j int) bool {
+		if cmp := misplaced[i].createdAt.Compare(misplaced[j].createdAt); cmp != 0 {
+			return cmp < 0
+		}
+		return misplaced[i].id.String() < misplaced[j].id.String()
+	})
+	for _, isDone := range []bool{false, true} {
+		for _, t := range misplaced {
+			if t.isDone == isDone {
+				s.appendToColumn(columnFor(statuses, isDone), t)
+			}
+		}
+	}
+}
+
+// appendToColumn puts the task after the other tasks of the column without
+// changing its version.
+func (s *Store) appendToColumn(statusID uuid.UUID, t *task) {
+	next := 0
+	for _, other := range s.tasks {
+		if other != t && other.statusID == statusID && other.position >= next {
+			next = other.position + 1
+		}
+	}
+	t.statusID = statusID
+	t.position = next
+}
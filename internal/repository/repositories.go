@@ -0,0 +1,21 @@
+package repository
+
+import "github.com/google/uuid"
+
+// assignID gives a new row its UUID unless the caller chose one. The ids are
+// generated here rather than by a column default, since SQLite has no
+// gen_random_uuid().
+func assignID(id *uuid.UUID) {
+	if *id == uuid.Nil {
+		*id = uuid.New()
+	}
+}
+
+// idempotencyKey maps the absent key to NULL, which the unique indexes on
+// (user_id, idempotency_key) ignore.
+func idempotencyKey(key string) *string {
+	if key == "" {
+		return nil
+	}
+	return &key
+}
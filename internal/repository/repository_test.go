@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// The tests in this package run against every SQL backend with a fresh
+// database each: SQLite always, Postgres when POSTGRES_* is configured; see
+// dbtest.Run.
+
+func createUser(t *testing.T, db *gorm.DB, name string) uuid.UUID {
+	t.Helper()
+	repo := NewUserRepositoryAdapter(db)
+	require.NoError(t, repo.CreateUser(context.Background(), &models.UserAuth{Name: name, Password: "hash"}))
+	user, err := repo.GetUserByName(context.Background(), name)
+	require.NoError(t, err)
+	return user.ID
+}
+
+func createCategory(t *testing.T, db *gorm.DB, userID uuid.UUID, name string) uuid.UUID {
+	t.Helper()
+	category := Category{UserID: userID, Name: name}
+	require.NoError(t, db.Create(&category).Error)
+	return category.ID
+}
+
+func createTask(t *testing.T, db *gorm.DB, userID uuid.UUID, title string, categoryIDs ...uuid.UUID) uuid.UUID {
+	t.Helper()
+	repo := NewGormTaskRepository(db)
+	task, _, err := repo.CreateTask(context.Background(), userID, &models.TaskBody{Title: title}, categoryIDs)
+	require.NoError(t, err)
+	return task.ID
+}
+
+func titles(tasks []models.TaskShortInfo) []string {
+	result := make([]string, len(tasks))
+	for i, task := range tasks {
+		result[i] = task.Title
+	}
+	return result
+}
+
+// withoutTimestamps clears the timestamps of categories, so they compare
+// equal to literals.
+func withoutTimestamps(categories []models.Category) []models.Category {
+	result := make([]models.Category, len(categories))
+	for i, category := range categories {
+		category.CreatedAt, category.UpdatedAt = time.Time{}, time.Time{}
+		result[i] = category
+	}
+	return result
+}
+
+func count(t *testing.T, db *gorm.DB, table string, where string, args ...interface{}) int64 {
+	t.Helper()
+	var n int64
+	require.NoError(t, db.Table(table).Where(where, args...).Count(&n).Error)
+	return n
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"todolist/internal/database"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type StatsRepositoryAdapter struct {
+	db *gorm.DB
+}
+
+func NewStatsRepositoryAdapter(srcDB *gorm.DB) *StatsRepositoryAdapter {
+	return &StatsRepositoryAdapter{
+		db: srcDB,
+	}
+}
+
+// statsDialect holds the date arithmetic the statistics need, which Postgres
+// and SQLite spell differently. The day and week expressions yield the UTC
+// date of the start of the period as YYYY-MM-DD.
+type statsDialect struct {
+	day             string
+	week            string
+	secondsToFinish string
+}
+
+var statsDialects = map[string]statsDialect{
+	"postgres": {
+		day:             "to_char(completed_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')",
+		week:            "to_char(date_trunc('week', completed_at AT TIME ZONE 'UTC'), 'YYYY-MM-DD')",
+		secondsToFinish: "EXTRACT(EPOCH FROM completed_at - created_at)",
+	},
+	// SQLite keeps the timestamps as UTC text, see database.OpenSQLite.
+	"sqlite": {
+		day:             "date(completed_at)",
+		week:            "date(completed_at, '-6 days', 'weekday 1')",
+		secondsToFinish: "(julianday(completed_at) - julianday(created_at)) * 86400",
+	},
+}
+
+func (repo *StatsRepositoryAdapter) dialect() (statsDialect, error) {
+	dialect, ok := statsDialects[repo.db.Dialector.Name()]
+	if !ok {
+		return statsDialect{}, errors.Errorf("statistics are not supported on %s", repo.db.Dialector.Name())
+	}
+	return dialect, nil
+}
+
+// read starts a query; the statistics tolerate replication lag.
+func (repo *StatsRepositoryAdapter) read(ctx context.Context) *gorm.DB {
+	return conn(ctx, repo.db).Clauses(database.ReadReplica())
+}
+
+type categoryStats struct {
+	ID        uuid.UUID `gorm:"column:id_category"`
+	Name      string    `gorm:"column:name"`
+	Open      int64     `gorm:"column:open"`
+	Completed int64     `gorm:"column:completed"`
+}
+
+// GetStats computes every figure with an aggregate query; only the streak
+// reads one row per day, and no more days than it is long.
+func (repo *StatsRepositoryAdapter) GetStats(ctx context.Context, userID uuid.UUID, query models.StatsQuery) (*models.Stats, error) {
+	dialect, err := repo.dialect()
+	if err != nil {
+		return nil, err
+	}
+	from, to := query.From.UTC(), query.To.UTC()
+	stats := &models.Stats{}
+
+	var counts struct {
+		Open      int64
+		Completed int64
+	}
+	err = repo.read(ctx).Model(&Task{}).
+		Select("COALESCE(SUM(CASE WHEN is_done THEN 0 ELSE 1 END), 0) AS open, COALESCE(SUM(CASE WHEN is_done THEN 1 ELSE 0 END), 0) AS completed").
+		Where("user_id = ?", userID).
+		Scan(&counts).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "error counting tasks")
+	}
+	stats.Open, stats.Completed = counts.Open, counts.Completed
+
+	period := dialect.day
+	if query.Period == models.PeriodWeek {
+		period = dialect.week
+	}
+	var completions []struct {
+		Start string
+		Count int64
+	}
+	err = repo.read(ctx).Model(&Task{}).
+		Select(period+" AS start, COUNT(*) AS count").
+		Where("user_id = ? AND completed_at >= ? AND completed_at < ?", userID, from, to).
+		Group("start").
+		Order("start").
+		Scan(&completions).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "error counting completions")
+	}
+	for _, row := range completions {
+		start, err := time.Parse(time.DateOnly, row.Start)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unexpected period start %q", row.Start)
+		}
+		stats.Completions = append(stats.Completions, models.PeriodCount{Start: start, Count: row.Count})
+	}
+
+	var average struct {
+		Seconds *float64
+	}
+	err = repo.read(ctx).Model(&Task{}).
+		Select("AVG("+dialect.secondsToFinish+") AS seconds").
+		Where("user_id = ? AND completed_at >= ? AND completed_at < ?", userID, from, to).
+		Scan(&average).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "error averaging completion times")
+	}
+	if average.Seconds != nil {
+		duration := time.Duration(*average.Seconds * float64(time.Second))
+		stats.AverageTimeToComplete = &duration
+	}
+
+	var categories []categoryStats
+	err = repo.read(ctx).Table("category AS c").
+		Select(`c.id_category, c.name,
+			COALESCE(SUM(CASE WHEN t.id_task IS NOT NULL AND NOT t.is_done THEN 1 ELSE 0 END), 0) AS open,
+			COALESCE(SUM(CASE WHEN t.is_done THEN 1 ELSE 0 END), 0) AS completed`).
+		Joins("LEFT JOIN task_category tc ON tc.category_id = c.id_category").
+		Joins("LEFT JOIN task t ON t.id_task = tc.task_id").
+		Where("c.user_id = ?", userID).
+		Group("c.id_category, c.name").
+		Order("c.name, c.id_category").
+		Scan(&categories).Error
+	if err != nil {
+		return nil, errors.Wrap(err, "error counting tasks by category")
+	}
+	for _, c := range categories {
+		stats.Categories = append(stats.Categories, models.CategoryStats(c))
+	}
+
+	if stats.Streak, err = repo.streak(ctx, dialect, userID, query.Now); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// streak walks the days with completions from the newest and stops at the
+// first gap.
+func (repo *StatsRepositoryAdapter) streak(ctx context.Context, dialect statsDialect, userID uuid.UUID, now time.Time) (int, error) {
+	rows, err := repo.read(ctx).Model(&Task{}).
+		Distinct(dialect.day+" AS day").
+		Where("user_id = ? AND completed_at IS NOT NULL", userID).
+		Order("day DESC").
+		Rows()
+	if err != nil {
+		return 0, errors.Wrap(err, "error reading completion days")
+	}
+	defer rows.Close()
+
+	var scanErr error
+	streak := models.StreakFrom(now, func() (time.Time, bool) {
+		if !rows.Next() {
+			return time.Time{}, false
+		}
+		var day string
+		if scanErr = rows.Scan(&day); scanErr != nil {
+			return time.Time{}, false
+		}
+		parsed, err := time.Parse(time.DateOnly, day)
+		if err != nil {
+			scanErr = errors.Wrapf(err, "unexpected completion day %q", day)
+			return time.Time{}, false
+		}
+		return parsed, true
+	})
+	if scanErr != nil {
+		return 0, scanErr
+	}
+	if err = rows.Err(); err != nil {
+		return 0, errors.Wrap(err, "error reading completion days")
+	}
+	return streak, nil
+}
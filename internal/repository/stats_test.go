@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todolist/internal/models"
+	"todolist/internal/pkg/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestStatsRepository_GetStats(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewStatsRepositoryAdapter(db)
+		tasks := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		work := createCategory(t, db, alice, "work")
+		home := createCategory(t, db, alice, "home")
+		unused := createCategory(t, db, alice, "unused")
+
+		day := func(d, hour int) time.Time { return time.Date(2024, 3, d, hour, 0, 0, 0, time.UTC) }
+		// task creates a task and, when completed is not zero, completes it
+		// then.
+		task := func(created, completed time.Time, categoryIDs ...uuid.UUID) {
+			id := createTask(t, db, alice, "task", categoryIDs...)
+			columns := map[string]interface{}{"created_at": created}
+			if !completed.IsZero() {
+				_, err := tasks.ToggleDone(ctx, id)
+				require.NoError(t, err)
+				columns["completed_at"] = completed
+			}
+			require.NoError(t, db.Model(&Task{}).Where("id_task = ?", id).UpdateColumns(columns).Error)
+		}
+		task(day(1, 10), day(6, 10), work)
+		task(day(4, 10), day(5, 10), work, home)
+		task(day(2, 10), day(3, 9))
+		task(day(2, 10), time.Time{}, work)
+		task(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC))
+		bob := createUser(t, db, "bob")
+		createTask(t, db, bob, "other")
+
+		stats, err := repo.GetStats(ctx, alice, models.StatsQuery{From: day(1, 0), To: day(7, 0), Period: models.PeriodDay, Now: day(6, 15)})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, stats.Open)
+		assert.EqualValues(t, 4, stats.Completed)
+		assert.Equal(t, []models.PeriodCount{{Start: day(3, 0), Count: 1}, {Start: day(5, 0), Count: 1}, {Start: day(6, 0), Count: 1}}, stats.Completions)
+		require.NotNil(t, stats.AverageTimeToComplete)
+		assert.InDelta(t, (120+24+23)*time.Hour/3, *stats.AverageTimeToComplete, float64(time.Second))
+		assert.Equal(t, []models.CategoryStats{
+			{ID: home, Name: "home", Completed: 1},
+			{ID: unused, Name: "unused"},
+			{ID: work, Name: "work", Open: 1, Completed: 2},
+		}, stats.Categories)
+		assert.Equal(t, 2, stats.Streak, "the 4th has no completion")
+
+		stats, err = repo.GetStats(ctx, alice, models.StatsQuery{From: time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC), To: day(11, 0), Period: models.PeriodWeek, Now: day(7, 12)})
+		require.NoError(t, err)
+		assert.Equal(t, []models.PeriodCount{{Start: time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC), Count: 1}, {Start: day(4, 0), Count: 2}}, stats.Completions, "weeks start on Monday")
+		assert.Equal(t, 2, stats.Streak, "a streak ending yesterday still counts")
+
+		stats, err = repo.GetStats(ctx, alice, models.StatsQuery{From: day(10, 0), To: day(11, 0), Period: models.PeriodDay, Now: day(8, 0)})
+		require.NoError(t, err)
+		assert.Empty(t, stats.Completions)
+		assert.Nil(t, stats.AverageTimeToComplete)
+		assert.Zero(t, stats.Streak)
+
+		stats, err = repo.GetStats(ctx, uuid.New(), models.StatsQuery{From: day(1, 0), To: day(7, 0), Period: models.PeriodDay, Now: day(6, 15)})
+		require.NoError(t, err)
+		assert.Zero(t, stats.Open)
+		assert.Empty(t, stats.Categories)
+	})
+}
@@ -2,26 +2,55 @@ package repository
 
 import (
 	"context"
+	"time"
 
+	"todolist/internal/database"
 	"todolist/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
 type Task struct {
-	ID          uuid.UUID  `gorm:"column:id_task;type:uuid;default:gen_random_uuid();primaryKey"`
+	ID          uuid.UUID  `gorm:"column:id_task;type:uuid;primaryKey"`
 	UserID      uuid.UUID  `gorm:"column:user_id;type:uuid;not null"`
 	Title       string     `gorm:"type:varchar(128);not null"`
 	Description string     `gorm:"type:varchar(1000)"`
 	IsDone      bool       `gorm:"column:is_done;default:false"`
 	Categories  []Category `gorm:"many2many:task_category;joinForeignKey:TaskID;JoinReferences:CategoryID"`
+	// IdempotencyKey is NULL for tasks created without a key.
+	IdempotencyKey *string   `gorm:"column:idempotency_key"`
+	Version        int64     `gorm:"column:version;default:1"`
+	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"column:updated_at;autoUpdateTime"`
+	// CompletedAt is NULL while the task is not done.
+	CompletedAt *time.Time `gorm:"column:completed_at"`
+	// StatusID is NULL while the user has no workflow. Position orders the
+	// tasks of a column.
+	StatusID *uuid.UUID `gorm:"column:status_id;type:uuid"`
+	Position int        `gorm:"column:position;default:0"`
 }
 
 func (Task) TableName() string {
 	return "task"
 }
 
+func (t *Task) BeforeCreate(*gorm.DB) error {
+	assignID(&t.ID)
+	return nil
+}
+
+// taskCategory is a row of the join table behind Task.Categories.
+type taskCategory struct {
+	TaskID     uuid.UUID `gorm:"column:task_id;type:uuid;primaryKey"`
+	CategoryID uuid.UUID `gorm:"column:category_id;type:uuid;primaryKey"`
+}
+
+func (taskCategory) TableName() string {
+	return "task_category"
+}
+
 type GormTaskRepository struct {
 	db *gorm.DB
 }
@@ -30,68 +59,238 @@ func NewGormTaskRepository(db *gorm.DB) *GormTaskRepository {
 	return &GormTaskRepository{db: db}
 }
 
-func (r *GormTaskRepository) CreateTask(ctx context.Context, userId uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		task := Task{
-			UserID:      userId,
-			Title:       body.Title,
-			Description: body.Description,
-			IsDone:      false,
-		}
+// CreateTask returns the new task. With an idempotency key that the user
+// already created a task with, it returns that task and creates nothing.
+func (r *GormTaskRepository) CreateTask(ctx context.Context, userId uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) (*models.TaskFullInfo, bool, error) {
+	if existing, err := r.findByIdempotencyKey(ctx, userId, body.IdempotencyKey); existing != nil || err != nil {
+		return existing, false, err
+	}
 
-		if err := tx.Create(&task).Error; err != nil {
+	task := Task{
+		UserID:         userId,
+		Title:          body.Title,
+		Description:    body.Description,
+		IsDone:         false,
+		IdempotencyKey: idempotencyKey(body.IdempotencyKey),
+	}
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		categoryIDs, err := resolveCategories(tx, categoryIDs)
+		if err != nil {
 			return err
 		}
-
-		if len(categoryIDs) > 0 {
-			var categories []Category
-			if err := tx.
-				Where("id_category IN ?", categoryIDs).
-				Find(&categories).Error; err != nil {
-				return err
-			}
-			if err := tx.Model(&task).Association("Categories").Replace(&categories); err != nil {
-				return err
-			}
+		if err = tx.Omit("Categories").Create(&task).Error; err != nil {
+			return err
 		}
-
-		return nil
+		if err = linkCategories(tx, task.ID, categoryIDs); err != nil {
+			return err
+		}
+		return placeTask(tx, task.ID, userId, false)
 	})
+	if err != nil {
+		// A concurrent request with the same key may have created it first.
+		if existing, findErr := r.findByIdempotencyKey(ctx, userId, body.IdempotencyKey); existing != nil && findErr == nil {
+			return existing, false, nil
+		}
+		return nil, false, err
+	}
+
+	created, err := r.GetByID(ctx, task.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	return created, true, nil
 }
 
+// findByIdempotencyKey returns nil without an error when no task of the user
+// has the key.
+func (r *GormTaskRepository) findByIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) (*models.TaskFullInfo, error) {
+	if key == "" {
+		return nil, nil
+	}
+	var ids []uuid.UUID
+	err := conn(ctx, r.db).
+		Model(&Task{}).
+		Where("user_id = ? AND idempotency_key = ?", userID, key).
+		Pluck("id_task", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return r.GetByID(ctx, ids[0])
+}
+
+// Update writes only the title and description, so it never undoes a
+// concurrent ToggleDone, and keeps the category links when categoryIDs is nil.
 func (r *GormTaskRepository) Update(ctx context.Context, id uuid.UUID, body *models.TaskBody, categoryIDs []uuid.UUID) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		var task Task
-		if err := tx.First(&task, "id_task = ?", id).Error; err != nil {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"title": body.Title, "description": body.Description}
+		if err := writeTask(tx, id, body.IfVersion, updates); err != nil {
 			return err
 		}
 
-		task.Title = body.Title
-		task.Description = body.Description
+		if categoryIDs == nil {
+			return nil
+		}
+		categoryIDs, err := resolveCategories(tx, categoryIDs)
+		if err != nil {
+			return err
+		}
+		if err = tx.Where("task_id = ?", id).Delete(&taskCategory{}).Error; err != nil {
+			return err
+		}
+		return linkCategories(tx, id, categoryIDs)
+	})
+}
 
-		if err := tx.Save(&task).Error; err != nil {
+// Patch writes only the fields set in patch and returns the updated task and
+// whether it became done. Like ToggleDone it moves a task whose flag changes
+// between the first and the terminal column of the board.
+func (r *GormTaskRepository) Patch(ctx context.Context, id uuid.UUID, patch *models.TaskPatch) (*models.TaskFullInfo, bool, error) {
+	completed := false
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{}
+		if patch.Title != nil {
+			updates["title"] = *patch.Title
+		}
+		if patch.Description != nil {
+			updates["description"] = *patch.Description
+		}
+		if err := writeTask(tx, id, patch.IfVersion, updates); err != nil {
 			return err
 		}
 
-		if categoryIDs != nil {
-			var categories []Category
-			if err := tx.
-				Where("id_category IN ?", categoryIDs).
-				Find(&categories).Error; err != nil {
-				return err
+		if patch.IsDone != nil {
+			var completedAt *time.Time
+			if *patch.IsDone {
+				now := tx.NowFunc()
+				completedAt = &now
 			}
-			if err := tx.Model(&task).Association("Categories").Replace(&categories); err != nil {
-				return err
+			result := tx.Model(&Task{}).
+				Where("id_task = ? AND is_done <> ?", id, *patch.IsDone).
+				Updates(map[string]interface{}{"is_done": *patch.IsDone, "completed_at": completedAt})
+			if result.Error != nil {
+				return result.Error
+			}
+			completed = *patch.IsDone && result.RowsAffected > 0
+			if result.RowsAffected > 0 {
+				var task Task
+				if err := tx.Select("user_id").First(&task, "id_task = ?", id).Error; err != nil {
+					return err
+				}
+				if err := placeTask(tx, id, task.UserID, *patch.IsDone); err != nil {
+					return err
+				}
 			}
 		}
 
-		return nil
+		if patch.CategoryIDs == nil {
+			return nil
+		}
+		categoryIDs, err := resolveCategories(tx, patch.CategoryIDs)
+		if err != nil {
+			return err
+		}
+		if err = tx.Where("task_id = ?", id).Delete(&taskCategory{}).Error; err != nil {
+			return err
+		}
+		return linkCategories(tx, id, categoryIDs)
 	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	task, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	return task, completed, nil
+}
+
+// writeTask applies updates together with a version increment. A non-zero
+// ifVersion makes the write conditional: it fails with ErrTaskModified when
+// the task has another version.
+func writeTask(tx *gorm.DB, id uuid.UUID, ifVersion int64, updates map[string]interface{}) error {
+	write := make(map[string]interface{}, len(updates)+1)
+	for column, value := range updates {
+		write[column] = value
+	}
+	write["version"] = gorm.Expr("version + 1")
+
+	query := tx.Model(&Task{}).Where("id_task = ?", id)
+	if ifVersion != 0 {
+		query = query.Where("version = ?", ifVersion)
+	}
+	result := query.Updates(write)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return missingOrModified(tx, id)
+	}
+	return nil
+}
+
+// missingOrModified explains why a conditional write matched no row.
+func missingOrModified(tx *gorm.DB, id uuid.UUID) error {
+	var found int64
+	if err := tx.Model(&Task{}).Where("id_task = ?", id).Count(&found).Error; err != nil {
+		return err
+	}
+	if found == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return models.ErrTaskModified
+}
+
+// resolveCategories drops duplicate IDs and fails with ErrCategoryNotFound
+// when any of them does not exist.
+func resolveCategories(tx *gorm.DB, categoryIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if len(categoryIDs) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[uuid.UUID]bool, len(categoryIDs))
+	unique := make([]uuid.UUID, 0, len(categoryIDs))
+	for _, id := range categoryIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	var found []uuid.UUID
+	if err := tx.Model(&Category{}).Where("id_category IN ?", unique).Pluck("id_category", &found).Error; err != nil {
+		return nil, err
+	}
+	if len(found) == len(unique) {
+		return unique, nil
+	}
+	for _, id := range found {
+		delete(seen, id)
+	}
+	missing := make([]uuid.UUID, 0, len(seen))
+	for _, id := range unique {
+		if seen[id] {
+			missing = append(missing, id)
+		}
+	}
+	return nil, errors.Wrapf(models.ErrCategoryNotFound, "unknown categories %v", missing)
+}
+
+// linkCategories inserts the links of a task in one statement.
+func linkCategories(tx *gorm.DB, taskID uuid.UUID, categoryIDs []uuid.UUID) error {
+	if len(categoryIDs) == 0 {
+		return nil
+	}
+	links := make([]taskCategory, len(categoryIDs))
+	for i, categoryID := range categoryIDs {
+		links[i] = taskCategory{TaskID: taskID, CategoryID: categoryID}
+	}
+	return tx.Create(&links).Error
 }
 
 func (r *GormTaskRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.TaskFullInfo, error) {
 	var task Task
-	if err := r.db.WithContext(ctx).
+	if err := conn(ctx, r.db).
 		Preload("Categories").
 		First(&task, "id_task = ?", id).Error; err != nil {
 		return nil, err
@@ -100,8 +299,10 @@ func (r *GormTaskRepository) GetByID(ctx context.Context, id uuid.UUID) (*models
 	categoryNames := make([]models.Category, len(task.Categories))
 	for i, cat := range task.Categories {
 		categoryNames[i] = models.Category{
-			ID:   cat.ID,
-			Name: cat.Name,
+			ID:        cat.ID,
+			Name:      cat.Name,
+			CreatedAt: cat.CreatedAt,
+			UpdatedAt: cat.UpdatedAt,
 		}
 	}
 
@@ -111,16 +312,39 @@ func (r *GormTaskRepository) GetByID(ctx context.Context, id uuid.UUID) (*models
 		Description: task.Description,
 		IsDone:      task.IsDone,
 		Categories:  categoryNames,
+		Version:     task.Version,
+		CreatedAt:   task.CreatedAt,
+		UpdatedAt:   task.UpdatedAt,
+		CompletedAt: task.CompletedAt,
+		StatusID:    task.StatusID,
+		Position:    task.Position,
 	}, nil
 }
 
 func (r *GormTaskRepository) GetAll(ctx context.Context, userId uuid.UUID, pageIndex, recordsPerPage int) ([]models.TaskShortInfo, error) {
+	return r.List(ctx, userId, models.TaskFilter{}, pageIndex, recordsPerPage)
+}
+
+// List is GetAll narrowed by filter.
+func (r *GormTaskRepository) List(ctx context.Context, userId uuid.UUID, filter models.TaskFilter, pageIndex, recordsPerPage int) ([]models.TaskShortInfo, error) {
 	var tasks []Task
 	offset := (pageIndex - 1) * recordsPerPage
 
-	err := r.db.WithContext(ctx).
-		Where("user_id = ?", userId).
-		Order("title ASC").
+	query := conn(ctx, r.db).
+		Clauses(database.ReadReplica()).
+		Where("user_id = ?", userId)
+	if filter.IsDone != nil {
+		query = query.Where("is_done = ?", *filter.IsDone)
+	}
+	if filter.CategoryID != nil {
+		query = query.Where("EXISTS (SELECT 1 FROM task_category WHERE task_category.task_id = task.id_task AND task_category.category_id = ?)", *filter.CategoryID)
+	}
+	query = whereInRange(query, "created_at", filter.Created)
+	query = whereInRange(query, "updated_at", filter.Updated)
+	query = whereInRange(query, "completed_at", filter.Completed)
+
+	err := query.
+		Order(taskOrder(filter)).
 		Limit(recordsPerPage).
 		Offset(offset).
 		Find(&tasks).Error
@@ -131,34 +355,193 @@ func (r *GormTaskRepository) GetAll(ctx context.Context, userId uuid.UUID, pageI
 
 	result := make([]models.TaskShortInfo, len(tasks))
 	for i, task := range tasks {
-		result[i] = models.TaskShortInfo{
-			ID:     task.ID,
-			Title:  task.Title,
-			IsDone: task.IsDone,
-		}
+		result[i] = toTaskShortInfo(task)
 	}
 
 	return result, nil
 }
 
-func (r *GormTaskRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	if err := r.db.WithContext(ctx).Delete(&Task{}, "id_task = ?", id).Error; err != nil {
-		return err
+func toTaskShortInfo(task Task) models.TaskShortInfo {
+	return models.TaskShortInfo{
+		ID:          task.ID,
+		Title:       task.Title,
+		IsDone:      task.IsDone,
+		Version:     task.Version,
+		CreatedAt:   task.CreatedAt,
+		UpdatedAt:   task.UpdatedAt,
+		CompletedAt: task.CompletedAt,
+		StatusID:    task.StatusID,
 	}
-	return nil
 }
 
-func (r *GormTaskRepository) ToggleDone(ctx context.Context, id uuid.UUID) error {
-	var task Task
-	if err := r.db.WithContext(ctx).First(&task, "id_task = ?", id).Error; err != nil {
-		return err
+// whereInRange narrows query to rows whose column lies in r. The bounds are
+// passed in UTC, the zone the timestamps are written in, since SQLite
+// compares them as text.
+func whereInRange(query *gorm.DB, column string, r models.TimeRange) *gorm.DB {
+	if r.From != nil {
+		query = query.Where(column+" >= ?", r.From.UTC())
+	}
+	if r.To != nil {
+		query = query.Where(column+" < ?", r.To.UTC())
 	}
+	return query
+}
 
-	task.IsDone = !task.IsDone
+// taskOrder is the ORDER BY of List. The ID breaks ties, so pages stay
+// stable, and tasks without completed_at come last in both directions.
+func taskOrder(filter models.TaskFilter) string {
+	column := "title"
+	switch filter.Sort {
+	case models.SortByCreated, models.SortByUpdated, models.SortByCompleted:
+		column = string(filter.Sort)
+	}
+	order := column + " ASC"
+	if filter.Descending {
+		order = column + " DESC"
+	}
+	if filter.Sort == models.SortByCompleted {
+		order = "completed_at IS NULL, " + order
+	}
+	return order + ", id_task ASC"
+}
 
-	if err := r.db.WithContext(ctx).Save(&task).Error; err != nil {
-		return err
+// Delete ignores tasks that do not exist. A non-zero ifVersion makes it
+// conditional like the writes of Update.
+func (r *GormTaskRepository) Delete(ctx context.Context, id uuid.UUID, ifVersion int64) error {
+	return conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("id_task = ?", id)
+		if ifVersion != 0 {
+			query = query.Where("version = ?", ifVersion)
+		}
+		result := query.Delete(&Task{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 && ifVersion != 0 {
+			if err := missingOrModified(tx, id); !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ToggleDone flips the flag in a single statement, so concurrent toggles
+// never lose an update, and returns the new state. completed_at follows the
+// flag: it is set when the task becomes done and cleared otherwise, and so
+// does the column of the task on the board.
+func (r *GormTaskRepository) ToggleDone(ctx context.Context, id uuid.UUID) (bool, error) {
+	var toggled struct {
+		IsDone bool
+		UserID uuid.UUID
+	}
+	now := r.db.NowFunc()
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Raw(`UPDATE task
+				SET is_done = NOT is_done,
+					completed_at = CASE WHEN is_done THEN NULL ELSE ? END,
+					updated_at = ?,
+					version = version + 1
+				WHERE id_task = ?
+				RETURNING is_done, user_id`, now, now, id).
+			Scan(&toggled)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return placeTask(tx, id, toggled.UserID, toggled.IsDone)
+	})
+	if err != nil {
+		return false, err
 	}
 
-	return nil
+	return toggled.IsDone, nil
+}
+
+// Move puts the task at position, counted from 0, in the column of statusID,
+// or at its end when position is past the other tasks. The tasks from that
+// position on shift down by one in a single statement; positions may have
+// gaps, only their order counts. Moving into the terminal column completes
+// the task, moving out of it reopens it. A non-zero ifVersion makes the move
+// conditional like the writes of Update.
+func (r *GormTaskRepository) Move(ctx context.Context, id, statusID uuid.UUID, position int, ifVersion int64) (*models.TaskFullInfo, bool, error) {
+	completed := false
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var task Task
+		if err := tx.Select("user_id", "is_done").First(&task, "id_task = ?", id).Error; err != nil {
+			return err
+		}
+		statuses, err := statusesOf(tx, task.UserID)
+		if err != nil {
+			return err
+		}
+		target := -1
+		for i, status := range statuses {
+			if status.ID == statusID {
+				target = i
+			}
+		}
+		if target < 0 {
+			return errors.Wrapf(models.ErrStatusNotFound, "unknown status %s", statusID)
+		}
+
+		slot, err := columnSlot(tx, statusID, id, position)
+		if err != nil {
+			return err
+		}
+
+		isDone := target == len(statuses)-1
+		updates := map[string]interface{}{"status_id": statusID, "position": slot}
+		if isDone != task.IsDone {
+			var completedAt *time.Time
+			if isDone {
+				now := tx.NowFunc()
+				completedAt = &now
+			}
+			updates["is_done"] = isDone
+			updates["completed_at"] = completedAt
+		}
+		if err = writeTask(tx, id, ifVersion, updates); err != nil {
+			return err
+		}
+		completed = isDone && !task.IsDone
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	task, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, false, err
+	}
+	return task, completed, nil
+}
+
+// columnSlot frees the place of the task at index in the column of statusID,
+// not counting the task itself, and returns the position to write. Past the
+// last task it is the end of the column; otherwise the task at index and
+// those after it shift down by one.
+func columnSlot(tx *gorm.DB, statusID, taskID uuid.UUID, index int) (int, error) {
+	var at []int
+	err := tx.Model(&Task{}).
+		Where("status_id = ? AND id_task <> ?", statusID, taskID).
+		Order("position ASC, id_task ASC").
+		Offset(max(index, 0)).
+		Limit(1).
+		Pluck("position", &at).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(at) == 0 {
+		return nextPosition(tx, statusID, taskID)
+	}
+
+	err = tx.Model(&Task{}).
+		Where("status_id = ? AND id_task <> ? AND position >= ?", statusID, taskID, at[0]).
+		UpdateColumn("position", gorm.Expr("position + 1")).Error
+	return at[0], err
 }
@@ -0,0 +1,494 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"todolist/internal/models"
+	"todolist/internal/pkg/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestGormTaskRepository_CreateAndGet(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		work := createCategory(t, db, userID, "work")
+		home := createCategory(t, db, userID, "home")
+
+		created, _, err := repo.CreateTask(ctx, userID, &models.TaskBody{Title: "report", Description: "quarterly"}, []uuid.UUID{work, home})
+		require.NoError(t, err)
+
+		info, err := repo.GetByID(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, info, created, "CreateTask returns the stored task")
+		assert.Equal(t, "report", info.Title)
+		assert.Equal(t, "quarterly", info.Description)
+		assert.False(t, info.IsDone)
+		assert.ElementsMatch(t, []models.Category{{ID: work, Name: "work"}, {ID: home, Name: "home"}}, withoutTimestamps(info.Categories))
+
+		_, err = repo.GetByID(ctx, uuid.New())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestGormTaskRepository_Update(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		work := createCategory(t, db, userID, "work")
+		home := createCategory(t, db, userID, "home")
+		taskID := createTask(t, db, userID, "report", work)
+
+		require.NoError(t, repo.Update(ctx, taskID, &models.TaskBody{Title: "renamed", Description: "text"}, nil))
+		info, err := repo.GetByID(ctx, taskID)
+		require.NoError(t, err)
+		assert.Equal(t, "renamed", info.Title)
+		assert.Equal(t, "text", info.Description)
+		assert.Equal(t, []models.Category{{ID: work, Name: "work"}}, withoutTimestamps(info.Categories), "nil categories keep the links")
+
+		require.NoError(t, repo.Update(ctx, taskID, &models.TaskBody{Title: "renamed"}, []uuid.UUID{home}))
+		info, err = repo.GetByID(ctx, taskID)
+		require.NoError(t, err)
+		assert.Equal(t, []models.Category{{ID: home, Name: "home"}}, withoutTimestamps(info.Categories))
+
+		require.NoError(t, repo.Update(ctx, taskID, &models.TaskBody{Title: "renamed"}, []uuid.UUID{}))
+		info, err = repo.GetByID(ctx, taskID)
+		require.NoError(t, err)
+		assert.Empty(t, info.Categories, "an empty list removes the links")
+
+		err = repo.Update(ctx, uuid.New(), &models.TaskBody{Title: "missing"}, nil)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		err = repo.Update(ctx, taskID, &models.TaskBody{Title: "unknown"}, []uuid.UUID{work, uuid.New()})
+		assert.ErrorIs(t, err, models.ErrCategoryNotFound)
+		info, err = repo.GetByID(ctx, taskID)
+		require.NoError(t, err)
+		assert.Equal(t, "renamed", info.Title, "a failed update changes nothing")
+		assert.Empty(t, info.Categories)
+	})
+}
+
+func TestGormTaskRepository_CreateWithUnknownCategory(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		work := createCategory(t, db, userID, "work")
+
+		_, _, err := repo.CreateTask(ctx, userID, &models.TaskBody{Title: "report"}, []uuid.UUID{work, uuid.New()})
+		assert.ErrorIs(t, err, models.ErrCategoryNotFound)
+		assert.EqualValues(t, 0, count(t, db, "task", "user_id = ?", userID))
+
+		taskID := createTask(t, db, userID, "report", work, work)
+		assert.EqualValues(t, 1, count(t, db, "task_category", "task_id = ?", taskID), "duplicate IDs are linked once")
+	})
+}
+
+func TestGormTaskRepository_CreateIdempotent(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		bob := createUser(t, db, "bob")
+		work := createCategory(t, db, alice, "work")
+
+		first, _, err := repo.CreateTask(ctx, alice, &models.TaskBody{Title: "report", IdempotencyKey: "k1"}, []uuid.UUID{work})
+		require.NoError(t, err)
+		again, _, err := repo.CreateTask(ctx, alice, &models.TaskBody{Title: "changed", IdempotencyKey: "k1"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, first, again, "a replayed key returns the original task")
+		assert.EqualValues(t, 1, count(t, db, "task", "user_id = ?", alice))
+
+		other, _, err := repo.CreateTask(ctx, bob, &models.TaskBody{Title: "report", IdempotencyKey: "k1"}, nil)
+		require.NoError(t, err)
+		assert.NotEqual(t, first.ID, other.ID, "keys are scoped to the user")
+
+		createTask(t, db, alice, "plain")
+		createTask(t, db, alice, "plain")
+		assert.EqualValues(t, 3, count(t, db, "task", "user_id = ?", alice), "requests without a key never collide")
+	})
+}
+
+func TestGormTaskRepository_GetAllPagination(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		bob := createUser(t, db, "bob")
+		for _, title := range []string{"e", "c", "a", "d", "b"} {
+			createTask(t, db, alice, title)
+		}
+		createTask(t, db, bob, "bob's task")
+
+		tests := []struct {
+			name           string
+			pageIndex      int
+			recordsPerPage int
+			expected       []string
+		}{
+			{name: "first page", pageIndex: 1, recordsPerPage: 2, expected: []string{"a", "b"}},
+			{name: "middle page", pageIndex: 2, recordsPerPage: 2, expected: []string{"c", "d"}},
+			{name: "partial last page", pageIndex: 3, recordsPerPage: 2, expected: []string{"e"}},
+			{name: "past the end", pageIndex: 4, recordsPerPage: 2, expected: []string{}},
+			{name: "page larger than the list", pageIndex: 1, recordsPerPage: 100, expected: []string{"a", "b", "c", "d", "e"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tasks, err := repo.GetAll(ctx, alice, tt.pageIndex, tt.recordsPerPage)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, titles(tasks))
+			})
+		}
+	})
+}
+
+func TestGormTaskRepository_ListFilters(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		work := createCategory(t, db, alice, "work")
+		home := createCategory(t, db, alice, "home")
+		createTask(t, db, alice, "a", work)
+		b := createTask(t, db, alice, "b", work, home)
+		createTask(t, db, alice, "c")
+		_, err := repo.ToggleDone(ctx, b)
+		require.NoError(t, err)
+
+		done, notDone := true, false
+		tests := []struct {
+			name     string
+			filter   models.TaskFilter
+			expected []string
+		}{
+			{name: "no filter", filter: models.TaskFilter{}, expected: []string{"a", "b", "c"}},
+			{name: "done", filter: models.TaskFilter{IsDone: &done}, expected: []string{"b"}},
+			{name: "not done", filter: models.TaskFilter{IsDone: &notDone}, expected: []string{"a", "c"}},
+			{name: "category", filter: models.TaskFilter{CategoryID: &work}, expected: []string{"a", "b"}},
+			{name: "category and not done", filter: models.TaskFilter{CategoryID: &work, IsDone: &notDone}, expected: []string{"a"}},
+			{name: "category without tasks", filter: models.TaskFilter{CategoryID: &home, IsDone: &notDone}, expected: []string{}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tasks, err := repo.List(ctx, alice, tt.filter, 1, 10)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, titles(tasks))
+			})
+		}
+	})
+}
+
+func TestGormTaskRepository_Patch(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		work := createCategory(t, db, userID, "work")
+		home := createCategory(t, db, userID, "home")
+		created, _, err := repo.CreateTask(ctx, userID, &models.TaskBody{Title: "report", Description: "quarterly"}, []uuid.UUID{work})
+		require.NoError(t, err)
+
+		title := "annual report"
+		task, _, err := repo.Patch(ctx, created.ID, &models.TaskPatch{Title: &title})
+		require.NoError(t, err)
+		assert.Equal(t, "annual report", task.Title)
+		assert.Equal(t, "quarterly", task.Description, "absent fields keep their value")
+		assert.Equal(t, []models.Category{{ID: work, Name: "work"}}, withoutTimestamps(task.Categories))
+
+		done := true
+		task, _, err = repo.Patch(ctx, created.ID, &models.TaskPatch{IsDone: &done, CategoryIDs: []uuid.UUID{home}})
+		require.NoError(t, err)
+		assert.True(t, task.IsDone)
+		assert.Equal(t, "annual report", task.Title)
+		assert.Equal(t, []models.Category{{ID: home, Name: "home"}}, withoutTimestamps(task.Categories))
+
+		task, _, err = repo.Patch(ctx, created.ID, &models.TaskPatch{})
+		require.NoError(t, err, "an empty patch is valid")
+		assert.True(t, task.IsDone)
+
+		description := ""
+		_, _, err = repo.Patch(ctx, created.ID, &models.TaskPatch{Description: &description, CategoryIDs: []uuid.UUID{uuid.New()}})
+		assert.ErrorIs(t, err, models.ErrCategoryNotFound)
+		task, err = repo.GetByID(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "quarterly", task.Description, "a failed patch changes nothing")
+
+		_, _, err = repo.Patch(ctx, uuid.New(), &models.TaskPatch{Title: &title})
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+func TestGormTaskRepository_ToggleDone(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		taskID := createTask(t, db, userID, "report")
+
+		isDone, err := repo.ToggleDone(ctx, taskID)
+		require.NoError(t, err)
+		assert.True(t, isDone)
+		info, err := repo.GetByID(ctx, taskID)
+		require.NoError(t, err)
+		assert.True(t, info.IsDone)
+
+		isDone, err = repo.ToggleDone(ctx, taskID)
+		require.NoError(t, err)
+		assert.False(t, isDone)
+		info, err = repo.GetByID(ctx, taskID)
+		require.NoError(t, err)
+		assert.False(t, info.IsDone)
+
+		_, err = repo.ToggleDone(ctx, uuid.New())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
+
+// Every toggle must see the state left by the previous one: with n toggles
+// exactly n/2 of them complete the task, and concurrent edits of the title
+// never write back a stale flag.
+func TestGormTaskRepository_ConcurrentTogglesLoseNoUpdates(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		taskID := createTask(t, db, userID, "report")
+
+		const toggles = 40
+		var (
+			wg        sync.WaitGroup
+			completed atomic.Int32
+		)
+		for i := 0; i < toggles; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				isDone, err := repo.ToggleDone(ctx, taskID)
+				assert.NoError(t, err)
+				if isDone {
+					completed.Add(1)
+				}
+			}()
+			go func(i int) {
+				defer wg.Done()
+				assert.NoError(t, repo.Update(ctx, taskID, &models.TaskBody{Title: fmt.Sprintf("report %d", i)}, nil))
+			}(i)
+		}
+		wg.Wait()
+
+		assert.EqualValues(t, toggles/2, completed.Load())
+		info, err := repo.GetByID(ctx, taskID)
+		require.NoError(t, err)
+		assert.False(t, info.IsDone, "an even number of toggles restores the state")
+	})
+}
+
+func TestGormTaskRepository_DeleteRemovesCategoryLinks(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		work := createCategory(t, db, userID, "work")
+		taskID := createTask(t, db, userID, "report", work)
+
+		require.NoError(t, repo.Delete(ctx, taskID, 0))
+		assert.Zero(t, count(t, db, "task", "id_task = ?", taskID))
+		assert.Zero(t, count(t, db, "task_category", "task_id = ?", taskID))
+		assert.EqualValues(t, 1, count(t, db, "category", "id_category = ?", work), "the category stays")
+
+		assert.NoError(t, repo.Delete(ctx, uuid.New(), 0), "deleting a missing task is not an error")
+		assert.NoError(t, repo.Delete(ctx, uuid.New(), 1))
+	})
+}
+
+func TestGormTaskRepository_Versions(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormTaskRepository(db)
+		categories := NewCategoryRepositoryAdapter(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		work := createCategory(t, db, userID, "work")
+		created, _, err := repo.CreateTask(ctx, userID, &models.TaskBody{Title: "report"}, []uuid.UUID{work})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, created.Version)
+
+		version := func() int64 {
+			task, err := repo.GetByID(ctx, created.ID)
+			require.NoError(t, err)
+			return task.Version
+		}
+
+		require.NoError(t, repo.Update(ctx, created.ID, &models.TaskBody{Title: "renamed", IfVersion: 1}, nil))
+		assert.EqualValues(t, 2, version())
+		err = repo.Update(ctx, created.ID, &models.TaskBody{Title: "stale", IfVersion: 1}, nil)
+		assert.ErrorIs(t, err, models.ErrTaskModified)
+		err = repo.Update(ctx, uuid.New(), &models.TaskBody{Title: "missing", IfVersion: 1}, nil)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		_, err = repo.ToggleDone(ctx, created.ID)
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, version())
+
+		title := "patched"
+		_, _, err = repo.Patch(ctx, created.ID, &models.TaskPatch{Title: &title, IfVersion: 2})
+		assert.ErrorIs(t, err, models.ErrTaskModified)
+		task, _, err := repo.Patch(ctx, created.ID, &models.TaskPatch{Title: &title, IfVersion: 3})
+		require.NoError(t, err)
+		assert.EqualValues(t, 4, task.Version)
+
+		tasks, err := repo.GetAll(ctx, userID, 1, 10)
+		require.NoError(t, err)
+		require.Len(t, tasks, 1)
+		assert.EqualValues(t, 4, tasks[0].Version)
+
+		require.NoError(t, categories.Delete(ctx, work, userID))
+		assert.EqualValues(t, 5, version(), "losing a category changes the task")
+
+		assert.ErrorIs(t, repo.Delete(ctx, created.ID, 4), models.ErrTaskModified)
+		assert.EqualValues(t, 1, count(t, db, "task", "id_task = ?", created.ID))
+		require.NoError(t, repo.Delete(ctx, created.ID, 5))
+		assert.Zero(t, count(t, db, "task", "id_task = ?", created.ID))
+	})
+}
+
+func TestGormTaskRepository_Timestamps(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormTaskRepository(db)
+		categories := NewCategoryRepositoryAdapter(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		work := createCategory(t, db, userID, "work")
+		start := time.Now()
+		created, _, err := repo.CreateTask(ctx, userID, &models.TaskBody{Title: "report"}, []uuid.UUID{work})
+		require.NoError(t, err)
+		assert.WithinDuration(t, start, created.CreatedAt, time.Minute)
+		assert.True(t, created.CreatedAt.Equal(created.UpdatedAt))
+		assert.Nil(t, created.CompletedAt)
+		assert.False(t, created.Categories[0].CreatedAt.IsZero())
+
+		// age moves the task an hour back, so every write visibly advances
+		// updated_at while created_at stays.
+		past := time.Now().Add(-time.Hour).UTC()
+		age := func() {
+			err := db.Model(&Task{}).
+				Where("id_task = ?", created.ID).
+				UpdateColumns(map[string]interface{}{"created_at": past, "updated_at": past}).Error
+			require.NoError(t, err)
+		}
+		get := func() *models.TaskFullInfo {
+			task, err := repo.GetByID(ctx, created.ID)
+			require.NoError(t, err)
+			assert.WithinDuration(t, past, task.CreatedAt, time.Millisecond, "created_at never changes")
+			return task
+		}
+
+		age()
+		require.NoError(t, repo.Update(ctx, created.ID, &models.TaskBody{Title: "renamed"}, nil))
+		assert.True(t, get().UpdatedAt.After(past), "update")
+
+		age()
+		_, err = repo.ToggleDone(ctx, created.ID)
+		require.NoError(t, err)
+		task := get()
+		assert.True(t, task.UpdatedAt.After(past), "toggle")
+		require.NotNil(t, task.CompletedAt)
+		assert.True(t, task.CompletedAt.After(past))
+		_, err = repo.ToggleDone(ctx, created.ID)
+		require.NoError(t, err)
+		assert.Nil(t, get().CompletedAt, "undoing the task clears completed_at")
+
+		done := true
+		task, _, err = repo.Patch(ctx, created.ID, &models.TaskPatch{IsDone: &done})
+		require.NoError(t, err)
+		require.NotNil(t, task.CompletedAt)
+		completedAt := *task.CompletedAt
+		task, _, err = repo.Patch(ctx, created.ID, &models.TaskPatch{IsDone: &done})
+		require.NoError(t, err)
+		require.NotNil(t, task.CompletedAt)
+		assert.True(t, completedAt.Equal(*task.CompletedAt), "a done task keeps its completion time")
+		notDone := false
+		task, _, err = repo.Patch(ctx, created.ID, &models.TaskPatch{IsDone: &notDone})
+		require.NoError(t, err)
+		assert.Nil(t, task.CompletedAt)
+
+		age()
+		require.NoError(t, categories.Delete(ctx, work, userID))
+		assert.True(t, get().UpdatedAt.After(past), "losing a category")
+	})
+}
+
+func TestGormTaskRepository_ListSortAndTimeRanges(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		a := createTask(t, db, alice, "a")
+		b := createTask(t, db, alice, "b")
+		c := createTask(t, db, alice, "c")
+		for _, id := range []uuid.UUID{b, c} {
+			_, err := repo.ToggleDone(ctx, id)
+			require.NoError(t, err)
+		}
+
+		base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+		at := func(hours int) time.Time { return base.Add(time.Duration(hours) * time.Hour) }
+		set := func(id uuid.UUID, columns map[string]interface{}) {
+			require.NoError(t, db.Model(&Task{}).Where("id_task = ?", id).UpdateColumns(columns).Error)
+		}
+		set(a, map[string]interface{}{"created_at": at(2), "updated_at": at(2)})
+		set(b, map[string]interface{}{"created_at": at(0), "updated_at": at(3), "completed_at": at(3)})
+		set(c, map[string]interface{}{"created_at": at(1), "updated_at": at(1), "completed_at": at(2)})
+
+		from, to := at(1), at(2)
+		completedFrom, completedTo := at(0), at(3)
+		tests := []struct {
+			name     string
+			filter   models.TaskFilter
+			expected []string
+		}{
+			{name: "title descending", filter: models.TaskFilter{Sort: models.SortByTitle, Descending: true}, expected: []string{"c", "b", "a"}},
+			{name: "created", filter: models.TaskFilter{Sort: models.SortByCreated}, expected: []string{"b", "c", "a"}},
+			{name: "created descending", filter: models.TaskFilter{Sort: models.SortByCreated, Descending: true}, expected: []string{"a", "c", "b"}},
+			{name: "updated", filter: models.TaskFilter{Sort: models.SortByUpdated}, expected: []string{"c", "a", "b"}},
+			{name: "completed", filter: models.TaskFilter{Sort: models.SortByCompleted}, expected: []string{"c", "b", "a"}},
+			{name: "completed descending", filter: models.TaskFilter{Sort: models.SortByCompleted, Descending: true}, expected: []string{"b", "c", "a"}},
+			{name: "created range", filter: models.TaskFilter{Created: models.TimeRange{From: &from, To: &to}}, expected: []string{"c"}},
+			{name: "updated from", filter: models.TaskFilter{Updated: models.TimeRange{From: &to}}, expected: []string{"a", "b"}},
+			{name: "completed from", filter: models.TaskFilter{Completed: models.TimeRange{From: &completedFrom}}, expected: []string{"b", "c"}},
+			{name: "completed to", filter: models.TaskFilter{Completed: models.TimeRange{To: &completedTo}}, expected: []string{"c"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tasks, err := repo.List(ctx, alice, tt.filter, 1, 10)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, titles(tasks))
+			})
+		}
+	})
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// afterCommitKey holds the functions queued by AfterCommit for the outermost
+// unit of work.
+type afterCommitKey struct{}
+
+// Transactor keeps the transaction of a unit of work in the context, where
+// the repositories pick it up through conn. Nested units of work become
+// savepoints.
+type Transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(srcDB *gorm.DB) *Transactor {
+	return &Transactor{
+		db: srcDB,
+	}
+}
+
+func (t *Transactor) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	queued, nested := ctx.Value(afterCommitKey{}).(*[]func())
+	if !nested {
+		queued = new([]func())
+		ctx = context.WithValue(ctx, afterCommitKey{}, queued)
+	}
+	// A failed nested unit of work drops what it queued with its savepoint.
+	mark := len(*queued)
+	err := conn(ctx, t.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+	if err != nil {
+		*queued = (*queued)[:mark]
+		return err
+	}
+	if !nested {
+		for _, f := range *queued {
+			f()
+		}
+	}
+	return nil
+}
+
+func (t *Transactor) AfterCommit(ctx context.Context, fn func()) {
+	if queued, ok := ctx.Value(afterCommitKey{}).(*[]func()); ok {
+		*queued = append(*queued, fn)
+		return
+	}
+	fn()
+}
+
+// conn returns the transaction of the unit of work running in ctx, or the
+// pool when there is none.
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"todolist/internal/models"
+	"todolist/internal/pkg/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestTransactor_CommitAndRollback(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repos := struct {
+			Tasks      *GormTaskRepository
+			Categories *CategoryRepositoryAdapter
+			Transactor *Transactor
+		}{NewGormTaskRepository(db), NewCategoryRepositoryAdapter(db), NewTransactor(db)}
+		ctx := context.Background()
+		alice := createUser(t, db, "alice")
+
+		createCategoryAndTask := func(ctx context.Context, name string) error {
+			category, err := repos.Categories.CreateCategory(ctx, &models.CategoryBody{Name: name, UserID: alice})
+			if err != nil {
+				return err
+			}
+			_, _, err = repos.Tasks.CreateTask(ctx, alice, &models.TaskBody{Title: name + " task"}, []uuid.UUID{category.ID})
+			return err
+		}
+
+		require.NoError(t, repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			return createCategoryAndTask(ctx, "work")
+		}))
+		assert.EqualValues(t, 1, count(t, db, "category", "user_id = ?", alice))
+		assert.EqualValues(t, 1, count(t, db, "task_category", "1 = 1"), "the task sees the category of the same unit of work")
+
+		failure := errors.New("failed after both writes")
+		err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			if err := createCategoryAndTask(ctx, "home"); err != nil {
+				return err
+			}
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+		assert.EqualValues(t, 1, count(t, db, "category", "user_id = ?", alice), "the category is rolled back")
+		assert.EqualValues(t, 1, count(t, db, "task", "user_id = ?", alice), "the task is rolled back")
+	})
+}
+
+func TestTransactor_Nested(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repos := struct {
+			Tasks      *GormTaskRepository
+			Categories *CategoryRepositoryAdapter
+			Transactor *Transactor
+		}{NewGormTaskRepository(db), NewCategoryRepositoryAdapter(db), NewTransactor(db)}
+		ctx := context.Background()
+		alice := createUser(t, db, "alice")
+
+		err := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			_, _, err := repos.Tasks.CreateTask(ctx, alice, &models.TaskBody{Title: "outer"}, nil)
+			require.NoError(t, err)
+
+			inner := repos.Transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				_, _, err := repos.Tasks.CreateTask(ctx, alice, &models.TaskBody{Title: "inner"}, nil)
+				require.NoError(t, err)
+				return errors.New("inner failure")
+			})
+			assert.Error(t, inner)
+
+			tasks, err := repos.Tasks.GetAll(ctx, alice, 1, -1)
+			require.NoError(t, err)
+			require.Len(t, tasks, 1, "the failed inner unit only undoes its own changes")
+			assert.Equal(t, "outer", tasks[0].Title)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, count(t, db, "task", "user_id = ?", alice))
+	})
+}
+
+func TestTransactor_AfterCommit(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		transactor := NewTransactor(db)
+		ctx := context.Background()
+		var ran []string
+
+		transactor.AfterCommit(ctx, func() { ran = append(ran, "outside") })
+		assert.Equal(t, []string{"outside"}, ran, "without a unit of work it runs right away")
+
+		ran = nil
+		err := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			transactor.AfterCommit(ctx, func() { ran = append(ran, "outer") })
+			inner := transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				transactor.AfterCommit(ctx, func() { ran = append(ran, "failed inner") })
+				return errors.New("inner failure")
+			})
+			assert.Error(t, inner)
+			require.NoError(t, transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+				transactor.AfterCommit(ctx, func() { ran = append(ran, "inner") })
+				return nil
+			}))
+			assert.Empty(t, ran, "nothing runs before the outermost unit commits")
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"outer", "inner"}, ran)
+
+		ran = nil
+		err = transactor.WithinTransaction(ctx, func(ctx context.Context) error {
+			transactor.AfterCommit(ctx, func() { ran = append(ran, "rolled back") })
+			return errors.New("failure")
+		})
+		assert.Error(t, err)
+		assert.Empty(t, ran)
+	})
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type RecoveryCode struct {
+	ID       uuid.UUID `gorm:"primaryKey;column:id_recovery_code;type:uuid"`
+	UserID   uuid.UUID `gorm:"column:user_id;type:uuid;not null"`
+	CodeHash string    `gorm:"column:code_hash;not null"`
+	Used     bool      `gorm:"column:used;default:false"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recovery_code"
+}
+
+func (c *RecoveryCode) BeforeCreate(*gorm.DB) error {
+	assignID(&c.ID)
+	return nil
+}
+
+func (repo *UserRepositoryAdapter) SetTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	tx := conn(ctx, repo.db).
+		Model(&User{}).
+		Where("id_user = ?", userID).
+		Update("totp_secret", secret)
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "error saving totp secret")
+	}
+
+	if tx.RowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (repo *UserRepositoryAdapter) EnableTOTP(ctx context.Context, userID uuid.UUID, recoveryCodeHashes []string) error {
+	return conn(ctx, repo.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).
+			Where("id_user = ?", userID).
+			Update("totp_enabled", true)
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "error enabling totp")
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrUserNotFound
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+			return errors.Wrap(err, "error removing old recovery codes")
+		}
+
+		codes := make([]RecoveryCode, 0, len(recoveryCodeHashes))
+		for _, hash := range recoveryCodeHashes {
+			codes = append(codes, RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		if len(codes) > 0 {
+			if err := tx.Create(&codes).Error; err != nil {
+				return errors.Wrap(err, "error saving recovery codes")
+			}
+		}
+
+		return nil
+	})
+}
+
+func (repo *UserRepositoryAdapter) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	return conn(ctx, repo.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&User{}).
+			Where("id_user = ?", userID).
+			Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": ""})
+		if result.Error != nil {
+			return errors.Wrap(result.Error, "error disabling totp")
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrUserNotFound
+		}
+
+		if err := tx.Where("user_id = ?", userID).Delete(&RecoveryCode{}).Error; err != nil {
+			return errors.Wrap(err, "error removing recovery codes")
+		}
+
+		return nil
+	})
+}
+
+// UseTOTPStep records step as the last accepted TOTP time step and reports
+// whether it is later than the one recorded before. Like UseRecoveryCode, the
+// conditional update lets a code through only once.
+func (repo *UserRepositoryAdapter) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	tx := conn(ctx, repo.db).
+		Model(&User{}).
+		Where("id_user = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	if tx.Error != nil {
+		return false, errors.Wrap(tx.Error, "error using totp step")
+	}
+
+	return tx.RowsAffected > 0, nil
+}
+
+// UseRecoveryCode marks the matching unused code as used and reports whether
+// there was one. The conditional update makes every code single-use even
+// under concurrent sign-ins.
+func (repo *UserRepositoryAdapter) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	tx := conn(ctx, repo.db).
+		Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used = ?", userID, codeHash, false).
+		Update("used", true)
+	if tx.Error != nil {
+		return false, errors.Wrap(tx.Error, "error using recovery code")
+	}
+
+	return tx.RowsAffected > 0, nil
+}
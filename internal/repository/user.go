@@ -2,19 +2,32 @@ package repository
 
 import (
 	"context"
-	"fmt"
+	"time"
 	"todolist/internal/models"
 
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
 type User struct {
-	ID       uuid.UUID `gorm:"primaryKey;column:id_user;type:uuid;default:gen_random_uuid()"`
-	Name     string    `gorm:"unique;column:user_name"`
-	Password string    `gorm:"column:password_hash"`
+	ID                    uuid.UUID  `gorm:"primaryKey;column:id_user;type:uuid"`
+	Name                  string     `gorm:"unique;column:user_name"`
+	Password              string     `gorm:"column:password_hash"`
+	Role                  string     `gorm:"column:role;default:user"`
+	Disabled              bool       `gorm:"column:disabled;default:false"`
+	PasswordResetRequired bool       `gorm:"column:password_reset_required;default:false"`
+	TOTPSecret            string     `gorm:"column:totp_secret"`
+	TOTPEnabled           bool       `gorm:"column:totp_enabled;default:false"`
+	TOTPLastStep          int64      `gorm:"column:totp_last_step;default:0"`
+	DeletionScheduledAt   *time.Time `gorm:"column:deletion_scheduled_at"`
+	CreatedAt             time.Time  `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt             time.Time  `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (u *User) BeforeCreate(*gorm.DB) error {
+	assignID(&u.ID)
+	return nil
 }
 
 func ToDaUser(user models.UserAuth) User {
@@ -26,9 +39,18 @@ func ToDaUser(user models.UserAuth) User {
 
 func FromDaUser(user User) models.User {
 	return models.User{
-		ID:       user.ID,
-		Name:     user.Name,
-		Password: user.Password,
+		ID:                    user.ID,
+		Name:                  user.Name,
+		Password:              user.Password,
+		Role:                  user.Role,
+		Disabled:              user.Disabled,
+		PasswordResetRequired: user.PasswordResetRequired,
+		TOTPSecret:            user.TOTPSecret,
+		TOTPEnabled:           user.TOTPEnabled,
+		TOTPLastStep:          user.TOTPLastStep,
+		DeletionScheduledAt:   user.DeletionScheduledAt,
+		CreatedAt:             user.CreatedAt,
+		UpdatedAt:             user.UpdatedAt,
 	}
 }
 
@@ -46,7 +68,7 @@ func (repo *UserRepositoryAdapter) GetUserByID(ctx context.Context, id uuid.UUID
 	var userDA User
 	userDA.ID = id
 
-	tx := repo.db.WithContext(ctx).First(&userDA)
+	tx := conn(ctx, repo.db).First(&userDA)
 	if tx.Error != nil {
 		if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 			return nil, models.ErrUserNotFound
@@ -65,8 +87,7 @@ func (repo *UserRepositoryAdapter) GetUserByID(ctx context.Context, id uuid.UUID
 func (repo *UserRepositoryAdapter) GetUserByName(ctx context.Context, name string) (*models.User, error) {
 	var userDA User
 
-	tx := repo.db.WithContext(ctx).Where("user_name = ?", name).First(&userDA)
-	fmt.Print(userDA)
+	tx := conn(ctx, repo.db).Where("user_name = ?", name).First(&userDA)
 	if tx.Error != nil {
 		if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
 			return nil, models.ErrUserNotFound
@@ -85,7 +106,7 @@ func (repo *UserRepositoryAdapter) GetUserByName(ctx context.Context, name strin
 func (repo *UserRepositoryAdapter) CreateUser(ctx context.Context, user *models.UserAuth) error {
 	userDa := ToDaUser(*user)
 
-	tx := repo.db.WithContext(ctx).Create(&userDa)
+	tx := conn(ctx, repo.db).Create(&userDa)
 	if tx.Error != nil {
 		return errors.Wrap(tx.Error, "error creating user")
 	}
@@ -97,10 +118,27 @@ func (repo *UserRepositoryAdapter) CreateUser(ctx context.Context, user *models.
 	return nil
 }
 
+// UpdatePassword stores a new password hash and clears a pending forced reset.
+func (repo *UserRepositoryAdapter) UpdatePassword(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	tx := conn(ctx, repo.db).
+		Model(&User{}).
+		Where("id_user = ?", userID).
+		Updates(map[string]interface{}{"password_hash": passwordHash, "password_reset_required": false})
+	if tx.Error != nil {
+		return errors.Wrap(tx.Error, "error updating password")
+	}
+
+	if tx.RowsAffected == 0 {
+		return models.ErrUserNotFound
+	}
+
+	return nil
+}
+
 func (repo *UserRepositoryAdapter) CheckTaskOwnership(ctx context.Context, userID, taskID uuid.UUID) (bool, error) {
 	var isOwned bool
 
-	tx := repo.db.WithContext(ctx).
+	tx := conn(ctx, repo.db).
 		Raw("SELECT EXISTS(SELECT 1 FROM task WHERE id_task = ? AND user_id = ?)",
 			taskID, userID).
 		Scan(&isOwned)
@@ -121,20 +159,14 @@ func (repo *UserRepositoryAdapter) CheckCategoriesOwnership(ctx context.Context,
 		return true, nil
 	}
 
-	// Convert UUID slice to string slice
-	categoryStrings := make([]string, len(categories))
-	for i, cat := range categories {
-		categoryStrings[i] = cat.String()
-	}
-
 	var allOwned bool
 
-	tx := repo.db.WithContext(ctx).Raw(`
+	tx := conn(ctx, repo.db).Raw(`
         SELECT  NOT EXISTS (
             SELECT 1 FROM category 
-            WHERE id_category = ANY(?::uuid[]) 
+            WHERE id_category IN ? 
             AND user_id != ?
-        )`, pq.Array(categoryStrings), userID).Scan(&allOwned)
+        )`, categories, userID).Scan(&allOwned)
 
 	if tx.Error != nil {
 		return false, errors.Wrap(tx.Error, "failed raw ownership check")
@@ -148,7 +180,7 @@ func (repo *UserRepositoryAdapter) CheckCategoriesOwnership(ctx context.Context,
 }
 
 func (repo *UserRepositoryAdapter) DeleteUser(ctx context.Context, userID uuid.UUID) error {
-	tx := repo.db.WithContext(ctx).Delete(&User{}, "id_user = ?", userID)
+	tx := conn(ctx, repo.db).Delete(&User{}, "id_user = ?", userID)
 	if tx.Error != nil {
 		return errors.Wrap(tx.Error, "error deleting user")
 	}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todolist/internal/models"
+	"todolist/internal/pkg/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestUserRepositoryAdapter_CreateAndGet(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepositoryAdapter(db)
+		ctx := context.Background()
+
+		require.NoError(t, repo.CreateUser(ctx, &models.UserAuth{Name: "alice", Password: "hash"}))
+		assert.Error(t, repo.CreateUser(ctx, &models.UserAuth{Name: "alice", Password: "hash"}), "names are unique")
+
+		byName, err := repo.GetUserByName(ctx, "alice")
+		require.NoError(t, err)
+		assert.Equal(t, "hash", byName.Password)
+		assert.Equal(t, models.RoleUser, byName.Role)
+		assert.False(t, byName.Disabled)
+
+		byID, err := repo.GetUserByID(ctx, byName.ID)
+		require.NoError(t, err)
+		assert.Equal(t, byName, byID)
+
+		_, err = repo.GetUserByName(ctx, "nobody")
+		assert.ErrorIs(t, err, models.ErrUserNotFound)
+		_, err = repo.GetUserByID(ctx, uuid.New())
+		assert.ErrorIs(t, err, models.ErrUserNotFound)
+	})
+}
+
+func TestUserRepositoryAdapter_UpdatePassword(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepositoryAdapter(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		require.NoError(t, NewAdminRepositoryAdapter(db).RequirePasswordReset(ctx, userID))
+
+		require.NoError(t, repo.UpdatePassword(ctx, userID, "new-hash"))
+		user, err := repo.GetUserByID(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, "new-hash", user.Password)
+		assert.False(t, user.PasswordResetRequired, "a new password clears the forced reset")
+
+		assert.ErrorIs(t, repo.UpdatePassword(ctx, uuid.New(), "hash"), models.ErrUserNotFound)
+	})
+}
+
+func TestUserRepositoryAdapter_CheckTaskOwnership(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepositoryAdapter(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		bob := createUser(t, db, "bob")
+		taskID := createTask(t, db, alice, "report")
+
+		owned, err := repo.CheckTaskOwnership(ctx, alice, taskID)
+		require.NoError(t, err)
+		assert.True(t, owned)
+
+		owned, err = repo.CheckTaskOwnership(ctx, bob, taskID)
+		require.NoError(t, err)
+		assert.False(t, owned)
+
+		owned, err = repo.CheckTaskOwnership(ctx, alice, uuid.New())
+		require.NoError(t, err)
+		assert.False(t, owned)
+	})
+}
+
+func TestUserRepositoryAdapter_CheckCategoriesOwnership(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepositoryAdapter(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		bob := createUser(t, db, "bob")
+		work := createCategory(t, db, alice, "work")
+		home := createCategory(t, db, alice, "home")
+		bobs := createCategory(t, db, bob, "work")
+
+		tests := []struct {
+			name       string
+			categories []uuid.UUID
+			expected   bool
+		}{
+			{name: "no categories", categories: nil, expected: true},
+			{name: "own categories", categories: []uuid.UUID{work, home}, expected: true},
+			{name: "one foreign category", categories: []uuid.UUID{work, bobs}, expected: false},
+			{name: "only foreign categories", categories: []uuid.UUID{bobs}, expected: false},
+			// Unknown IDs belong to nobody else; CreateTask skips them.
+			{name: "unknown category", categories: []uuid.UUID{work, uuid.New()}, expected: true},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				owned, err := repo.CheckCategoriesOwnership(ctx, alice, tt.categories)
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, owned)
+			})
+		}
+	})
+}
+
+func TestUserRepositoryAdapter_TOTP(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepositoryAdapter(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		require.NoError(t, repo.SetTOTPSecret(ctx, userID, "SECRET"))
+		require.NoError(t, repo.EnableTOTP(ctx, userID, []string{"code-1", "code-2"}))
+
+		user, err := repo.GetUserByID(ctx, userID)
+		require.NoError(t, err)
+		assert.True(t, user.TOTPEnabled)
+		assert.Equal(t, "SECRET", user.TOTPSecret)
+
+		used, err := repo.UseRecoveryCode(ctx, userID, "code-1")
+		require.NoError(t, err)
+		assert.True(t, used)
+		used, err = repo.UseRecoveryCode(ctx, userID, "code-1")
+		require.NoError(t, err)
+		assert.False(t, used, "recovery codes are single-use")
+
+		for _, tt := range []struct {
+			step     int64
+			expected bool
+		}{{step: 100, expected: true}, {step: 100, expected: false}, {step: 99, expected: false}, {step: 101, expected: true}} {
+			used, err = repo.UseTOTPStep(ctx, userID, tt.step)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, used, "step %d", tt.step)
+		}
+		user, err = repo.GetUserByID(ctx, userID)
+		require.NoError(t, err)
+		assert.EqualValues(t, 101, user.TOTPLastStep)
+
+		require.NoError(t, repo.DisableTOTP(ctx, userID))
+		user, err = repo.GetUserByID(ctx, userID)
+		require.NoError(t, err)
+		assert.False(t, user.TOTPEnabled)
+		assert.Empty(t, user.TOTPSecret)
+		assert.Zero(t, count(t, db, "recovery_code", "user_id = ?", userID))
+
+		assert.ErrorIs(t, repo.EnableTOTP(ctx, uuid.New(), nil), models.ErrUserNotFound)
+	})
+}
+
+func TestUserRepositoryAdapter_DeleteUserCascades(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepositoryAdapter(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		bob := createUser(t, db, "bob")
+		work := createCategory(t, db, alice, "work")
+		taskID := createTask(t, db, alice, "report", work)
+		bobsTask := createTask(t, db, bob, "bob's task")
+		require.NoError(t, repo.RecordActivity(ctx, alice, models.ActivitySignIn))
+		require.NoError(t, repo.EnableTOTP(ctx, alice, []string{"code"}))
+		require.NoError(t, db.Create(&UserIdentity{UserID: alice, Issuer: "https://issuer", Subject: "alice"}).Error)
+
+		require.NoError(t, repo.DeleteUser(ctx, alice))
+
+		for table, column := range map[string]string{
+			"task":          "user_id",
+			"category":      "user_id",
+			"activity_log":  "user_id",
+			"recovery_code": "user_id",
+			"user_identity": "user_id",
+		} {
+			assert.Zero(t, count(t, db, table, column+" = ?", alice), "rows of %s are deleted", table)
+		}
+		assert.Zero(t, count(t, db, "task_category", "task_id = ?", taskID))
+		assert.EqualValues(t, 1, count(t, db, "task", "id_task = ?", bobsTask), "other users keep their data")
+
+		assert.ErrorIs(t, repo.DeleteUser(ctx, alice), models.ErrUserNotFound)
+	})
+}
+
+func TestUserRepositoryAdapter_RecordActivity(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewUserRepositoryAdapter(db)
+		ctx := context.Background()
+
+		userID := createUser(t, db, "alice")
+		before := time.Now().Add(-time.Minute)
+		require.NoError(t, repo.RecordActivity(ctx, userID, models.ActivitySignIn))
+
+		var entry ActivityLog
+		require.NoError(t, db.First(&entry, "user_id = ?", userID).Error)
+		assert.Equal(t, models.ActivitySignIn, entry.Event)
+		assert.True(t, entry.CreatedAt.After(before))
+
+		assert.Error(t, repo.RecordActivity(ctx, uuid.New(), models.ActivitySignIn), "the user must exist")
+	})
+}
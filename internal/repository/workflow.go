@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"todolist/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type TaskStatus struct {
+	ID        uuid.UUID `gorm:"column:id_status;type:uuid;primaryKey"`
+	UserID    uuid.UUID `gorm:"column:user_id;type:uuid;not null"`
+	Name      string    `gorm:"column:name;type:varchar(50);not null"`
+	Position  int       `gorm:"column:position;not null"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+func (TaskStatus) TableName() string {
+	return "task_status"
+}
+
+func (s *TaskStatus) BeforeCreate(*gorm.DB) error {
+	assignID(&s.ID)
+	return nil
+}
+
+type WorkflowRepositoryAdapter struct {
+	db *gorm.DB
+}
+
+func NewWorkflowRepositoryAdapter(srcDB *gorm.DB) *WorkflowRepositoryAdapter {
+	return &WorkflowRepositoryAdapter{
+		db: srcDB,
+	}
+}
+
+func (r *WorkflowRepositoryAdapter) GetStatuses(ctx context.Context, userID uuid.UUID) ([]models.TaskStatus, error) {
+	statuses, err := statusesOf(conn(ctx, r.db), userID)
+	if err != nil {
+		return nil, err
+	}
+	return toStatusModels(statuses), nil
+}
+
+// SetStatuses replaces the workflow of the user by statuses, in order. The
+// columns left out are deleted; an empty list removes the board. Tasks keep
+// their flag, so those in a deleted column or, after the terminal column
+// changed, in the wrong one move to the end of the first or the terminal
+// column. Every task that ends up in another column or position gets a new
+// version, like a move through the task API.
+func (r *WorkflowRepositoryAdapter) SetStatuses(ctx context.Context, userID uuid.UUID, statuses []models.StatusBody) ([]models.TaskStatus, error) {
+	var result []TaskStatus
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		existing, err := statusesOf(tx, userID)
+		if err != nil {
+			return err
+		}
+		before, err := placementsOf(tx, userID)
+		if err != nil {
+			return err
+		}
+		removed := make(map[uuid.UUID]bool, len(existing))
+		for _, status := range existing {
+			removed[status.ID] = true
+		}
+
+		for i, body := range statuses {
+			if body.ID == uuid.Nil {
+				status := TaskStatus{UserID: userID, Name: body.Name, Position: i}
+				if err = tx.Create(&status).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if !removed[body.ID] {
+				return errors.Wrapf(models.ErrStatusNotFound, "unknown status %s", body.ID)
+			}
+			delete(removed, body.ID)
+			err = tx.Model(&TaskStatus{}).
+				Where("id_status = ?", body.ID).
+				Updates(map[string]interface{}{"name": body.Name, "position": i}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(removed) > 0 {
+			ids := make([]uuid.UUID, 0, len(removed))
+			for id := range removed {
+				ids = append(ids, id)
+			}
+			// The foreign key clears the status of their tasks.
+			if err = tx.Where("id_status IN ?", ids).Delete(&TaskStatus{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err = syncBoard(tx, userID); err != nil {
+			return err
+		}
+		if err = touchMoved(tx, userID, before); err != nil {
+			return err
+		}
+		result, err = statusesOf(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toStatusModels(result), nil
+}
+
+// GetBoard returns the columns of the user with their tasks, or nothing
+// without a workflow.
+func (r *WorkflowRepositoryAdapter) GetBoard(ctx context.Context, userID uuid.UUID) ([]models.BoardColumn, error) {
+	db := conn(ctx, r.db)
+	statuses, err := statusesOf(db, userID)
+	if err != nil || len(statuses) == 0 {
+		return nil, err
+	}
+
+	var tasks []Task
+	err = db.Where("user_id = ? AND status_id IS NOT NULL", userID).
+		Order("position ASC, id_task ASC").
+		Find(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	board := make([]models.BoardColumn, len(statuses))
+	columns := make(map[uuid.UUID]*models.BoardColumn, len(statuses))
+	for i, status := range toStatusModels(statuses) {
+		board[i] = models.BoardColumn{TaskStatus: status, Tasks: []models.TaskShortInfo{}}
+		columns[status.ID] = &board[i]
+	}
+	for _, task := range tasks {
+		if column, ok := columns[*task.StatusID]; ok {
+			column.Tasks = append(column.Tasks, toTaskShortInfo(task))
+		}
+	}
+	return board, nil
+}
+
+// statusesOf returns the workflow of the user in board order.
+func statusesOf(tx *gorm.DB, userID uuid.UUID) ([]TaskStatus, error) {
+	var statuses []TaskStatus
+	err := tx.Where("user_id = ?", userID).Order("position ASC").Find(&statuses).Error
+	return statuses, err
+}
+
+func toStatusModels(statuses []TaskStatus) []models.TaskStatus {
+	result := make([]models.TaskStatus, len(statuses))
+	for i, status := range statuses {
+		result[i] = models.TaskStatus{
+			ID:       status.ID,
+			Name:     status.Name,
+			Position: i,
+			Terminal: i == len(statuses)-1,
+		}
+	}
+	return result
+}
+
+// columnFor returns the column a task belongs in: the terminal one when it
+// is done, the first one otherwise.
+func columnFor(statuses []TaskStatus, isDone bool) uuid.UUID {
+	if isDone {
+		return statuses[len(statuses)-1].ID
+	}
+	return statuses[0].ID
+}
+
+// placeTask moves the task to the end of the column its flag asks for, unless
+// its user has no workflow.
+func placeTask(tx *gorm.DB, taskID, userID uuid.UUID, isDone bool) error {
+	statuses, err := statusesOf(tx, userID)
+	if err != nil || len(statuses) == 0 {
+		return err
+	}
+	return appendToColumn(tx, columnFor(statuses, isDone), taskID)
+}
+
+// syncBoard moves every task of the user that is not in the column its flag
+// asks for to the end of that column, oldest first.
+func syncBoard(tx *gorm.DB, userID uuid.UUID) error {
+	statuses, err := statusesOf(tx, userID)
+	if err != nil || len(statuses) == 0 {
+		return err
+	}
+	terminal := columnFor(statuses, true)
+
+	for _, isDone := range []bool{false, true} {
+		query := tx.Model(&Task{}).Where("user_id = ? AND is_done = ?", userID, isDone)
+		if isDone {
+			query = query.Where("(status_id IS NULL OR status_id <> ?)", terminal)
+		} else {
+			query = query.Where("(status_id IS NULL OR status_id = ?)", terminal)
+		}
+		var ids []uuid.UUID
+		if err = query.Pluck("id_task", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		if err = appendToColumn(tx, columnFor(statuses, isDone), ids...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// placement is where a task is on the board.
+type placement struct {
+	StatusID *uuid.UUID
+	Position int
+}
+
+// placementsOf returns the placement of every task of the user.
+func placementsOf(tx *gorm.DB, userID uuid.UUID) (map[uuid.UUID]placement, error) {
+	var tasks []Task
+	err := tx.Select("id_task", "status_id", "position").Where("user_id = ?", userID).Find(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[uuid.UUID]placement, len(tasks))
+	for _, task := range tasks {
+		result[task.ID] = placement{StatusID: task.StatusID, Position: task.Position}
+	}
+	return result, nil
+}
+
+// touchMoved bumps the version and updated_at of the tasks of the user whose
+// placement differs from before, including those the foreign key took off a
+// deleted column.
+func touchMoved(tx *gorm.DB, userID uuid.UUID, before map[uuid.UUID]placement) error {
+	after, err := placementsOf(tx, userID)
+	if err != nil {
+		return err
+	}
+	var moved []uuid.UUID
+	for id, now := range after {
+		was, ok := before[id]
+		if !ok || was.Position != now.Position || !sameStatus(was.StatusID, now.StatusID) {
+			moved = append(moved, id)
+		}
+	}
+	if len(moved) == 0 {
+		return nil
+	}
+	return tx.Model(&Task{}).
+		Where("id_task IN ?", moved).
+		Updates(map[string]interface{}{"version": gorm.Expr("version + 1")}).Error
+}
+
+func sameStatus(a, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// appendToColumn puts the tasks, oldest first, after the other tasks of the
+// column in a single statement: each one's offset is the number of the given
+// tasks created before it. It writes neither the version nor updated_at: the
+// callers decide whether the move changes the task, SetStatuses through
+// touchMoved.
+func appendToColumn(tx *gorm.DB, statusID uuid.UUID, taskIDs ...uuid.UUID) error {
+	next, err := nextPosition(tx, statusID, taskIDs...)
+	if err != nil {
+		return err
+	}
+	offset := gorm.Expr(
+		"? + (SELECT COUNT(*) FROM task AS earlier WHERE earlier.id_task IN ? AND "+
+			"(earlier.created_at < task.created_at OR (earlier.created_at = task.created_at AND earlier.id_task < task.id_task)))",
+		next, taskIDs,
+	)
+	return tx.Model(&Task{}).
+		Where("id_task IN ?", taskIDs).
+		UpdateColumns(map[string]interface{}{"status_id": statusID, "position": offset}).Error
+}
+
+// nextPosition returns the position after the last task of the column,
+// leaving out the given tasks.
+func nextPosition(tx *gorm.DB, statusID uuid.UUID, except ...uuid.UUID) (int, error) {
+	var next int
+	err := tx.Model(&Task{}).
+		Select("COALESCE(MAX(position) + 1, 0)").
+		Where("status_id = ? AND id_task NOT IN ?", statusID, except).
+		Row().Scan(&next)
+	return next, err
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todolist/internal/models"
+	"todolist/internal/pkg/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestWorkflowRepository(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewWorkflowRepositoryAdapter(db)
+		tasks := NewGormTaskRepository(db)
+		ctx := context.Background()
+
+		alice := createUser(t, db, "alice")
+		// boardTitles returns the titles of every column of alice's board.
+		boardTitles := func() [][]string {
+			t.Helper()
+			board, err := repo.GetBoard(ctx, alice)
+			require.NoError(t, err)
+			result := make([][]string, len(board))
+			for i, column := range board {
+				result[i] = titles(column.Tasks)
+			}
+			return result
+		}
+
+		open := createTask(t, db, alice, "open")
+		done := createTask(t, db, alice, "done")
+		_, err := tasks.ToggleDone(ctx, done)
+		require.NoError(t, err)
+		require.NoError(t, db.Model(&Task{}).Where("id_task = ?", done).UpdateColumn("created_at", time.Now().Add(-time.Hour)).Error)
+
+		board, err := repo.GetBoard(ctx, alice)
+		require.NoError(t, err)
+		assert.Empty(t, board)
+		task, err := tasks.GetByID(ctx, open)
+		require.NoError(t, err)
+		assert.Nil(t, task.StatusID, "no workflow, no status")
+
+		statuses, err := repo.SetStatuses(ctx, alice, []models.StatusBody{{Name: "todo"}, {Name: "doing"}, {Name: "done"}})
+		require.NoError(t, err)
+		require.Len(t, statuses, 3)
+		todo, doing, finished := statuses[0].ID, statuses[1].ID, statuses[2].ID
+		assert.Equal(t, []bool{false, false, true}, []bool{statuses[0].Terminal, statuses[1].Terminal, statuses[2].Terminal})
+		assert.Equal(t, [][]string{{"open"}, {}, {"done"}}, boardTitles(), "existing tasks are placed by their flag")
+
+		late := createTask(t, db, alice, "late")
+		assert.Equal(t, [][]string{{"open", "late"}, {}, {"done"}}, boardTitles())
+
+		isDone, err := tasks.ToggleDone(ctx, late)
+		require.NoError(t, err)
+		require.True(t, isDone)
+		assert.Equal(t, [][]string{{"open"}, {}, {"done", "late"}}, boardTitles(), "toggling moves to the terminal column")
+
+		moved, _, err := tasks.Move(ctx, open, doing, 5, 0)
+		require.NoError(t, err)
+		assert.Equal(t, &doing, moved.StatusID)
+		assert.False(t, moved.IsDone)
+		assert.EqualValues(t, 3, moved.Version, "placed on the new board, then moved")
+
+		moved, _, err = tasks.Move(ctx, open, finished, 1, moved.Version)
+		require.NoError(t, err)
+		assert.True(t, moved.IsDone, "the terminal column completes the task")
+		assert.NotNil(t, moved.CompletedAt)
+		assert.Equal(t, [][]string{{}, {}, {"done", "open", "late"}}, boardTitles())
+
+		moved, _, err = tasks.Move(ctx, late, todo, 0, 0)
+		require.NoError(t, err)
+		assert.False(t, moved.IsDone, "leaving the terminal column reopens the task")
+		assert.Nil(t, moved.CompletedAt)
+
+		_, _, err = tasks.Move(ctx, late, doing, 0, 1)
+		assert.ErrorIs(t, err, models.ErrTaskModified)
+		_, _, err = tasks.Move(ctx, late, uuid.New(), 0, 0)
+		assert.ErrorIs(t, err, models.ErrStatusNotFound)
+		bob := createUser(t, db, "bob")
+		bobStatuses, err := repo.SetStatuses(ctx, bob, []models.StatusBody{{Name: "todo"}, {Name: "done"}})
+		require.NoError(t, err)
+		_, _, err = tasks.Move(ctx, late, bobStatuses[0].ID, 0, 0)
+		assert.ErrorIs(t, err, models.ErrStatusNotFound, "the status of another user")
+
+		done2 := true
+		_, _, err = tasks.Patch(ctx, late, &models.TaskPatch{IsDone: &done2})
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{{}, {}, {"done", "open", "late"}}, boardTitles(), "patching the flag moves the task")
+
+		moved, _, err = tasks.Move(ctx, late, doing, 0, 0)
+		require.NoError(t, err)
+		assert.False(t, moved.IsDone)
+		// version returns the version of a task.
+		version := func(id uuid.UUID) int64 {
+			t.Helper()
+			task, err := tasks.GetByID(ctx, id)
+			require.NoError(t, err)
+			return task.Version
+		}
+		lateVersion, openVersion := version(late), version(open)
+
+		_, err = repo.SetStatuses(ctx, alice, []models.StatusBody{{ID: todo, Name: "todo"}, {ID: doing, Name: "doing"}, {ID: finished, Name: "finished"}})
+		require.NoError(t, err)
+		assert.Equal(t, lateVersion, version(late), "renaming columns moves no task")
+		assert.Equal(t, openVersion, version(open))
+
+		// Drop "doing" and add a new terminal column.
+		statuses, err = repo.SetStatuses(ctx, alice, []models.StatusBody{{ID: todo, Name: "backlog"}, {ID: finished, Name: "review"}, {Name: "archive"}})
+		require.NoError(t, err)
+		assert.Equal(t, "backlog", statuses[0].Name)
+		assert.Equal(t, finished, statuses[1].ID)
+		assert.False(t, statuses[1].Terminal)
+		assert.Equal(t, [][]string{{"late"}, {}, {"done", "open"}}, boardTitles(), "done tasks follow the terminal column")
+		task, err = tasks.GetByID(ctx, late)
+		require.NoError(t, err)
+		assert.False(t, task.IsDone, "rearranging keeps the flags")
+		assert.Equal(t, &todo, task.StatusID)
+		assert.Equal(t, lateVersion+1, task.Version, "a moved task gets a new version")
+		assert.Equal(t, openVersion+1, version(open))
+		openVersion = version(open)
+
+		_, err = repo.SetStatuses(ctx, alice, []models.StatusBody{{ID: doing, Name: "doing"}, {Name: "done"}})
+		assert.ErrorIs(t, err, models.ErrStatusNotFound, "a deleted status")
+		_, err = repo.SetStatuses(ctx, alice, []models.StatusBody{{ID: bobStatuses[0].ID, Name: "todo"}, {Name: "done"}})
+		assert.ErrorIs(t, err, models.ErrStatusNotFound, "the status of another user")
+
+		statuses, err = repo.SetStatuses(ctx, alice, nil)
+		require.NoError(t, err)
+		assert.Empty(t, statuses)
+		board, err = repo.GetBoard(ctx, alice)
+		require.NoError(t, err)
+		assert.Empty(t, board)
+		task, err = tasks.GetByID(ctx, open)
+		require.NoError(t, err)
+		assert.Nil(t, task.StatusID)
+		assert.True(t, task.IsDone)
+		assert.Equal(t, openVersion+1, task.Version, "deleting the column changes its tasks")
+	})
+}
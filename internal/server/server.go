@@ -0,0 +1,130 @@
+// Package server builds the HTTP server from config: listen address, TLS and
+// timeouts, plus the request body limit and CORS middlewares for the router.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/cors"
+	"github.com/pkg/errors"
+)
+
+type Options struct {
+	Addr string
+
+	// TLSCertFile and TLSKeyFile switch the server to HTTPS. The files are
+	// reloaded when they change, so certificates can be renewed without a
+	// restart.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+}
+
+type Server struct {
+	srv *http.Server
+}
+
+func New(handler http.Handler, opts Options) (*Server, error) {
+	if (opts.TLSCertFile == "") != (opts.TLSKeyFile == "") {
+		return nil, errors.New("both TLS certificate and key files are required to enable TLS")
+	}
+
+	srv := &http.Server{
+		Addr:              opts.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		ReadTimeout:       opts.ReadTimeout,
+		WriteTimeout:      opts.WriteTimeout,
+		IdleTimeout:       opts.IdleTimeout,
+	}
+
+	if opts.TLSCertFile != "" {
+		reloader, err := NewCertReloader(opts.TLSCertFile, opts.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+	}
+
+	return &Server{srv: srv}, nil
+}
+
+func (s *Server) Addr() string {
+	return s.srv.Addr
+}
+
+func (s *Server) TLS() bool {
+	return s.srv.TLSConfig != nil
+}
+
+// ListenAndServe blocks until the server stops; after Shutdown it returns
+// http.ErrServerClosed.
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", s.srv.Addr)
+	}
+	return s.Serve(ln)
+}
+
+func (s *Server) Serve(ln net.Listener) error {
+	if s.TLS() {
+		// The certificate comes from TLSConfig.GetCertificate.
+		return s.srv.ServeTLS(ln, "", "")
+	}
+	return s.srv.Serve(ln)
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// Close stops the server without waiting for requests to finish.
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+// MaxBodySize makes reading more than limit bytes of a request body fail,
+// zero means no limit.
+func MaxBodySize(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CORS answers preflight requests and sets the CORS headers for the allowed
+// origins, "*" allows any. Without origins it does nothing. The request ID and
+// rate limit headers are exposed to scripts.
+func CORS(origins, methods []string) func(http.Handler) http.Handler {
+	if len(origins) == 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return cors.Handler(cors.Options{
+		AllowedOrigins: origins,
+		AllowedMethods: methods,
+		AllowedHeaders: []string{"Authorization", "Content-Type", "X-Request-ID", "Traceparent", "Idempotency-Key", "If-Match", "If-None-Match"},
+		ExposedHeaders: []string{"X-Request-ID", "Location", "ETag", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"},
+		MaxAge:         300,
+	})
+}
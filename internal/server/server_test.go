@@ -0,0 +1,176 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCert writes a self-signed certificate for localhost and returns it
+// parsed, for the client to trust.
+func writeCert(t *testing.T, certFile, keyFile, commonName string) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+// clientTrusting sends localhost as SNI: without a server name the TLS server
+// skips GetCertificate when it has static certificates, as httptest servers do.
+func clientTrusting(cert *x509.Certificate) *http.Client {
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: pool, ServerName: "localhost"},
+		DisableKeepAlives: true,
+	}}
+}
+
+func servedCommonName(t *testing.T, client *http.Client, url string) string {
+	t.Helper()
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.TLS.PeerCertificates[0].Subject.CommonName
+}
+
+func TestServer_TLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	cert := writeCert(t, certFile, keyFile, "first")
+
+	srv, err := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), Options{
+		Addr:              "127.0.0.1:0",
+		TLSCertFile:       certFile,
+		TLSKeyFile:        keyFile,
+		ReadHeaderTimeout: time.Second,
+	})
+	require.NoError(t, err)
+	require.True(t, srv.TLS())
+
+	ln, err := net.Listen("tcp", srv.Addr())
+	require.NoError(t, err)
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	assert.Equal(t, "first", servedCommonName(t, clientTrusting(cert), "https://"+ln.Addr().String()))
+}
+
+func TestNew_RequiresCertAndKey(t *testing.T) {
+	_, err := New(http.NotFoundHandler(), Options{TLSCertFile: "tls.crt"})
+	assert.Error(t, err)
+}
+
+func TestCertReloader_ReloadsChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	first := writeCert(t, certFile, keyFile, "first")
+
+	reloader, err := NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	now := time.Now()
+	reloader.now = func() time.Time { return now }
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.TLS = &tls.Config{GetCertificate: reloader.GetCertificate}
+	ts.StartTLS()
+	defer ts.Close()
+
+	assert.Equal(t, "first", servedCommonName(t, clientTrusting(first), ts.URL))
+
+	second := writeCert(t, certFile, keyFile, "second")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+	now = now.Add(2 * time.Second)
+
+	assert.Equal(t, "second", servedCommonName(t, clientTrusting(second), ts.URL))
+
+	// A broken pair is not picked up, the last good certificate stays.
+	require.NoError(t, os.WriteFile(certFile, []byte("garbage"), 0o600))
+	broken := later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, broken, broken))
+	now = now.Add(2 * time.Second)
+
+	assert.Equal(t, "second", servedCommonName(t, clientTrusting(second), ts.URL))
+}
+
+func TestMaxBodySize(t *testing.T) {
+	handler := MaxBodySize(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("1234")))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("12345")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, "declared length over the limit")
+
+	r := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("12345")))
+	r.ContentLength = -1
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code, "chunked body over the limit")
+}
+
+func TestCORS(t *testing.T) {
+	handler := CORS([]string{"https://app.example.com"}, []string{"GET", "POST"})(http.NotFoundHandler())
+
+	preflight := httptest.NewRequest(http.MethodOptions, "/api/v1/task/all", nil)
+	preflight.Header.Set("Origin", "https://app.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, preflight)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "POST")
+
+	other := httptest.NewRequest(http.MethodGet, "/", nil)
+	other.Header.Set("Origin", "https://evil.example.com")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, other)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	disabled := CORS(nil, nil)(http.NotFoundHandler())
+	w = httptest.NewRecorder()
+	disabled.ServeHTTP(w, preflight)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// CertReloader serves a certificate from disk and reloads it once the files
+// change, checking at most once per second.
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+	now       func() time.Time
+}
+
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile, now: time.Now}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := r.now(); now.Sub(r.checkedAt) >= time.Second {
+		r.checkedAt = now
+		modTime, err := r.latestModTime()
+		if err == nil && modTime.After(r.modTime) {
+			if err = r.load(); err != nil {
+				// Keep serving the old certificate until the new pair is
+				// complete and valid.
+				log.Warn().Err(err).Msg("CertReloader: failed to reload TLS certificate")
+			} else {
+				log.Info().Str("cert", r.certFile).Msg("CertReloader: TLS certificate reloaded")
+			}
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *CertReloader) load() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load TLS certificate")
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+func (r *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, errors.Wrapf(err, "failed to stat %s", file)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// GormPlugin creates a client span for every GORM operation as a child of the
+// span in the statement context.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	errs := []error{
+		cb.Create().Before("gorm:create").Register("tracing:before_create", start("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", end),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", start("query")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", end),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", start("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", end),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", start("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", end),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", start("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", end),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", start("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", end),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return errors.Wrap(err, "failed to register tracing callbacks")
+		}
+	}
+	return nil
+}
+
+func start(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Context == nil {
+			return
+		}
+		_, span := tracer().Start(db.Statement.Context, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				dbSystem(db.Dialector.Name()),
+				semconv.DBOperationName(operation),
+			),
+		)
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+// dbSystem maps the name of the GORM dialector to the db.system value of
+// the semantic conventions.
+func dbSystem(dialector string) attribute.KeyValue {
+	switch dialector {
+	case "postgres":
+		return semconv.DBSystemPostgreSQL
+	case "sqlite":
+		return semconv.DBSystemSqlite
+	default:
+		return semconv.DBSystemKey.String(dialector)
+	}
+}
+
+func end(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBCollectionName(db.Statement.Table),
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		RecordError(span, db.Error)
+	}
+}
@@ -0,0 +1,40 @@
+package tracing
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestGormPlugin(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(GormPlugin{}))
+
+	var one int
+	require.NoError(t, db.WithContext(context.Background()).Raw("SELECT 1").Scan(&one).Error)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "gorm.row", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), semconv.DBSystemSqlite, "db.system follows the dialector")
+}
+
+func TestDBSystem(t *testing.T) {
+	assert.Equal(t, semconv.DBSystemPostgreSQL, dbSystem("postgres"))
+	assert.Equal(t, semconv.DBSystemSqlite, dbSystem("sqlite"))
+	assert.Equal(t, semconv.DBSystemKey.String("mysql"), dbSystem("mysql"))
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HTTPMiddleware starts the server span of a request, continuing the trace
+// from the incoming traceparent header, and puts a logger carrying the trace
+// and span IDs into the request context for log.Ctx.
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		logger := log.Logger
+		if sc := span.SpanContext(); sc.IsValid() {
+			logger = logger.With().
+				Str("trace_id", sc.TraceID().String()).
+				Str("span_id", sc.SpanID().String()).
+				Logger()
+		}
+		ctx = logger.WithContext(ctx)
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		// The route pattern is only known once chi has routed the request.
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			if pattern := rctx.RoutePattern(); pattern != "" {
+				span.SetName(r.Method + " " + pattern)
+				span.SetAttributes(semconv.HTTPRoute(pattern))
+			}
+		}
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
+
+// Middleware wraps a middleware step in its own span, so that the time spent
+// in e.g. ownership checks shows up separately from the handler.
+func Middleware(name string, mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		// The span is ended when the step hands over to the next handler or
+		// answers the request itself, whichever comes first. The next handler
+		// keeps the context values the step added but gets the parent span
+		// back, so the steps show up as siblings instead of nesting.
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parent := trace.SpanFromContext(r.Context())
+			ctx, span := Start(r.Context(), name)
+			ended := false
+			end := func() {
+				if !ended {
+					ended = true
+					span.End()
+				}
+			}
+			defer end()
+
+			mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				end()
+				next.ServeHTTP(w, r.WithContext(trace.ContextWithSpan(r.Context(), parent)))
+			})).ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
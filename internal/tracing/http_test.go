@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	passThrough := func(next http.Handler) http.Handler { return next }
+
+	var logs bytes.Buffer
+	globalLogger := log.Logger
+	log.Logger = zerolog.New(&logs)
+	defer func() { log.Logger = globalLogger }()
+
+	r := chi.NewRouter()
+	r.Use(HTTPMiddleware)
+	r.With(Middleware("CheckTaskMiddleware", passThrough)).Get("/api/v1/task/{id}", func(w http.ResponseWriter, r *http.Request) {
+		zerolog.Ctx(r.Context()).Info().Msg("handled")
+		_, span := Start(r.Context(), "TaskAdapter.GetByID")
+		span.End()
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/task/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range spans {
+		assert.Equal(t, traceID, span.SpanContext().TraceID().String(), "trace continues from traceparent")
+		byName[span.Name()] = span
+	}
+
+	server, ok := byName["GET /api/v1/task/{id}"]
+	require.True(t, ok, "server span is named after the route pattern")
+	require.Contains(t, byName, "CheckTaskMiddleware")
+	require.Contains(t, byName, "TaskAdapter.GetByID")
+
+	assert.Equal(t, server.SpanContext().SpanID(), byName["CheckTaskMiddleware"].Parent().SpanID())
+	assert.Equal(t, server.SpanContext().SpanID(), byName["TaskAdapter.GetByID"].Parent().SpanID(),
+		"handler work is a sibling of the middleware step, not its child")
+	assert.Contains(t, logs.String(), `"trace_id":"`+traceID+`"`)
+}
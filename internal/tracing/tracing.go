@@ -0,0 +1,101 @@
+// Package tracing sets up OpenTelemetry tracing and provides the spans used
+// by the HTTP layer, the middlewares, the adapters and GORM.
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "todolist"
+
+// Exporters accepted by Setup.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+type Options struct {
+	Exporter    string
+	ServiceName string
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The OTLP exporter takes its endpoint and headers from the
+// standard OTEL_EXPORTER_OTLP_* variables. The returned function flushes and
+// stops the exporter.
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case ExporterNone, "":
+		// Spans are still created so that trace IDs get propagated and
+		// logged, they are just not sent anywhere.
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx)
+	default:
+		return nil, errors.Errorf("unknown tracing exporter %q", opts.Exporter)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s trace exporter", opts.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+	))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build trace resource")
+	}
+
+	providerOpts := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	}
+	if exporter != nil {
+		providerOpts = append(providerOpts, sdktrace.WithBatcher(exporter))
+	}
+	provider := sdktrace.NewTracerProvider(providerOpts...)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start begins a span named after the component and operation, e.g.
+// "TaskAdapter.CreateTask".
+func Start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, name)
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// RecordError marks the span as failed.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}